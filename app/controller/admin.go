@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"server/app/middleware"
+	"server/app/model"
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+)
+
+func NewAdmin(serv service.AdminInter) AdminInter {
+	return &AdminCtrl{
+		serv: serv,
+	}
+}
+
+type AdminInter interface {
+	SearchUsers(ctx *gin.Context)
+	SetRole(ctx *gin.Context)
+	Wallet(ctx *gin.Context)
+	Freeze(ctx *gin.Context)
+	DebitBlock(ctx *gin.Context)
+	Unfreeze(ctx *gin.Context)
+	Adjust(ctx *gin.Context)
+}
+
+type AdminCtrl struct {
+	serv service.AdminInter
+}
+
+// SearchUsers lists the users whose username or email contains q, optionally filtered by uid and role.
+func (a *AdminCtrl) SearchUsers(ctx *gin.Context) {
+	req := new(request.ReqUserSearch)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	res, err := a.serv.SearchUsers(ctx, req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// SetRole grants a role to a user, or takes it away with the role "none".
+func (a *AdminCtrl) SetRole(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqRole)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	if err := a.serv.SetRole(ctx, middleware.Actor(ctx), uid, req); err != nil {
+		writeAdminError(ctx, err, consts.ErrUserNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": consts.MsgSuccess})
+}
+
+// Wallet returns the wallet of a user with its status.
+func (a *AdminCtrl) Wallet(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	wallet, err := a.serv.Wallet(ctx, uid)
+	if err != nil {
+		writeAdminError(ctx, err, consts.ErrWalletNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wallet)
+}
+
+// Freeze stops all money movements of a wallet but manual adjustments.
+func (a *AdminCtrl) Freeze(ctx *gin.Context) {
+	a.setWalletStatus(ctx, model.WalletStatusFrozen)
+}
+
+// DebitBlock stops money from leaving a wallet while still letting it in.
+func (a *AdminCtrl) DebitBlock(ctx *gin.Context) {
+	a.setWalletStatus(ctx, model.WalletStatusDebitBlocked)
+}
+
+// Unfreeze lets the money of a frozen or debit-blocked wallet move again.
+func (a *AdminCtrl) Unfreeze(ctx *gin.Context) {
+	a.setWalletStatus(ctx, model.WalletStatusActive)
+}
+
+func (a *AdminCtrl) setWalletStatus(ctx *gin.Context, status model.WalletStatus) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqAdminAction)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	wallet, err := a.serv.SetWalletStatus(ctx, middleware.Actor(ctx), uid, status, req)
+	if err != nil {
+		writeAdminError(ctx, err, consts.ErrWalletNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wallet)
+}
+
+// Adjust credits a wallet by a positive amount or debits it by a negative one.
+func (a *AdminCtrl) Adjust(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqAdjustment)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	wallet, err := a.serv.Adjust(ctx, middleware.Actor(ctx), uid, req)
+	if err != nil {
+		writeAdminError(ctx, err, consts.ErrWalletNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wallet)
+}
+
+// bindUID returns the positive uid of the path, or responds with 400.
+func bindUID(ctx *gin.Context) (int64, bool) {
+	idReq := new(request.ReqUID)
+	if err := ctx.ShouldBindUri(idReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return 0, false
+	}
+
+	if idReq.UID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidUID})
+		return 0, false
+	}
+
+	return idReq.UID, true
+}
+
+// writeAdminError responds to a failed admin action, with notFound when the user or wallet
+// does not exist.
+func writeAdminError(ctx *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, service.ErrNotJustified):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrNotJustified, "details": err.Error()})
+	case errors.Is(err, service.ErrInvalidWalletStatus):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+	case errors.Is(err, service.ErrZeroAmount):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidAmount, "details": err.Error()})
+	default:
+		writeMoneyError(ctx, err, consts.ErrInternalServer)
+	}
+}
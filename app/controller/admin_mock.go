@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAdminInter is a mock implementation of the service.AdminInter interface
+type MockAdminInter struct {
+	mock.Mock
+}
+
+func (m *MockAdminInter) Authenticate(ctx context.Context, username, password string) (*model.User, error) {
+	args := m.Called(ctx, username, password)
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockAdminInter) SearchUsers(ctx *gin.Context, req *request.ReqUserSearch) (*request.ResUsers, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResUsers), args.Error(1)
+}
+
+func (m *MockAdminInter) Wallet(ctx *gin.Context, uid int64) (*model.Wallet, error) {
+	args := m.Called(ctx, uid)
+	return args.Get(0).(*model.Wallet), args.Error(1)
+}
+
+func (m *MockAdminInter) SetWalletStatus(ctx *gin.Context, actor *model.Actor, uid int64, status model.WalletStatus,
+	req *request.ReqAdminAction) (*model.Wallet, error) {
+	args := m.Called(ctx, actor, uid, status, req)
+	return args.Get(0).(*model.Wallet), args.Error(1)
+}
+
+func (m *MockAdminInter) Adjust(ctx *gin.Context, actor *model.Actor, uid int64,
+	req *request.ReqAdjustment) (*model.Wallet, error) {
+	args := m.Called(ctx, actor, uid, req)
+	return args.Get(0).(*model.Wallet), args.Error(1)
+}
+
+func (m *MockAdminInter) SetRole(ctx context.Context, actor *model.Actor, uid int64, req *request.ReqRole) error {
+	args := m.Called(ctx, actor, uid, req)
+	return args.Error(0)
+}
@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// Test cases for AdminCtrl.SearchUsers
+func TestAdminCtrl_SearchUsers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		query         string
+		mockSkip      bool
+		mockErr       error
+		expectedReq   *request.ReqUserSearch
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Filters",
+			query:        "q=ali&role=finance&page=2",
+			expectedReq:  &request.ReqUserSearch{Query: "ali", Role: model.RoleFinance, ReqPage: request.ReqPage{Page: 2}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Unknown role",
+			query:         "role=owner",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrValidationFailed,
+		},
+		{
+			name:          consts.ErrInternalServer,
+			mockErr:       errors.New("db down"),
+			expectedReq:   &request.ReqUserSearch{},
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: consts.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAdminInter)
+			adminCtrl := NewAdmin(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			var err error
+			ctx.Request, err = http.NewRequest("GET", "/?"+tt.query, http.NoBody)
+			require.NoError(t, err)
+
+			if !tt.mockSkip {
+				mockService.On("SearchUsers", ctx, tt.expectedReq).
+					Return(&request.ResUsers{List: []*model.User{}}, tt.mockErr)
+			}
+
+			adminCtrl.SearchUsers(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for AdminCtrl.Freeze, AdminCtrl.Unfreeze and AdminCtrl.Adjust
+func TestAdminCtrl_WalletActions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		action        string
+		uid           string
+		body          string
+		mockSkip      bool
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Freeze",
+			action:       "Freeze",
+			uid:          "1",
+			body:         `{"reason":"fraud","ticket":"SUP-1"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Debit block",
+			action:       "DebitBlock",
+			uid:          "1",
+			body:         `{"reason":"dispute","ticket":"SUP-2"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Unfreeze",
+			action:       "Unfreeze",
+			uid:          "1",
+			body:         `{"reason":"cleared","ticket":"SUP-1"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Adjust",
+			action:       "Adjust",
+			uid:          "1",
+			body:         `{"amount":"-2.5","reason":"refund","ticket":"FIN-1"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Invalid UID",
+			action:        "Freeze",
+			uid:           "0",
+			body:          `{}`,
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidUID,
+		},
+		{
+			name:          "Not justified",
+			action:        "Freeze",
+			uid:           "1",
+			body:          `{}`,
+			mockErr:       service.ErrNotJustified,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrNotJustified,
+		},
+		{
+			name:          "Wallet not found",
+			action:        "Adjust",
+			uid:           "1",
+			body:          `{"amount":"1","reason":"refund","ticket":"FIN-1"}`,
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrWalletNotFound,
+		},
+		{
+			name:          "Zero adjustment",
+			action:        "Adjust",
+			uid:           "1",
+			body:          `{"amount":"0","reason":"refund","ticket":"FIN-1"}`,
+			mockErr:       service.ErrZeroAmount,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidAmount,
+		},
+		{
+			name:          "Closed wallet",
+			action:        "Unfreeze",
+			uid:           "1",
+			body:          `{"reason":"cleared","ticket":"SUP-1"}`,
+			mockErr:       repository.ErrWalletClosed,
+			expectedCode:  http.StatusGone,
+			expectedError: consts.ErrWalletClosed,
+		},
+		{
+			name:          "Debit below the minimum",
+			action:        "Adjust",
+			uid:           "1",
+			body:          `{"amount":"-100","reason":"refund","ticket":"FIN-1"}`,
+			mockErr:       repository.ErrWriteRejected,
+			expectedCode:  http.StatusConflict,
+			expectedError: consts.ErrWalletBusy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAdminInter)
+			adminCtrl := NewAdmin(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: tt.uid}}
+
+			var err error
+			ctx.Request, err = http.NewRequest("POST", "", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			wallet := &model.Wallet{UID: 1, Balance: decimal.NewFromInt(10)}
+			handler := adminCtrl.Adjust
+			switch tt.action {
+			case "Freeze":
+				handler = adminCtrl.Freeze
+				if !tt.mockSkip {
+					mockService.On("SetWalletStatus", ctx, mock.Anything, int64(1), model.WalletStatusFrozen,
+						mock.Anything).Return(wallet, tt.mockErr)
+				}
+			case "DebitBlock":
+				handler = adminCtrl.DebitBlock
+				mockService.On("SetWalletStatus", ctx, mock.Anything, int64(1), model.WalletStatusDebitBlocked,
+					mock.Anything).Return(wallet, tt.mockErr)
+			case "Unfreeze":
+				handler = adminCtrl.Unfreeze
+				mockService.On("SetWalletStatus", ctx, mock.Anything, int64(1), model.WalletStatusActive,
+					mock.Anything).Return(wallet, tt.mockErr)
+			default:
+				mockService.On("Adjust", ctx, mock.Anything, int64(1), mock.Anything).Return(wallet, tt.mockErr)
+			}
+
+			handler(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for AdminCtrl.SetRole
+func TestAdminCtrl_SetRole(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		body          string
+		mockSkip      bool
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Success",
+			body:         `{"role":"support","reason":"new hire"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Unknown role",
+			body:          `{"role":"owner","reason":"new hire"}`,
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrValidationFailed,
+		},
+		{
+			name:          "User not found",
+			body:          `{"role":"support","reason":"new hire"}`,
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAdminInter)
+			adminCtrl := NewAdmin(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "2"}}
+
+			var err error
+			ctx.Request, err = http.NewRequest("PUT", "", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			if !tt.mockSkip {
+				mockService.On("SetRole", ctx, mock.Anything, int64(2),
+					&request.ReqRole{Role: model.RoleSupport, Reason: "new hire"}).Return(tt.mockErr)
+			}
+
+			adminCtrl.SetRole(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
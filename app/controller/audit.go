@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"server/app/model"
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+)
+
+func NewAudit(serv service.AuditInter) AuditInter {
+	return &AuditCtrl{
+		serv: serv,
+	}
+}
+
+type AuditInter interface {
+	List(ctx *gin.Context)
+	Verify(ctx *gin.Context)
+}
+
+type AuditCtrl struct {
+	serv service.AuditInter
+}
+
+// List returns the audit log of an entity, newest first.
+func (a *AuditCtrl) List(ctx *gin.Context) {
+	req := new(request.ReqAuditLog)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	if !model.IsAuditEntity(req.Entity) || req.EntityID < 0 || req.ActorID < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidFilter})
+		return
+	}
+
+	res, err := a.serv.List(ctx, req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Verify checks the hash chain of the whole audit log. A broken chain is reported with 200 and
+// the first record that does not match.
+func (a *AuditCtrl) Verify(ctx *gin.Context) {
+	res, err := a.serv.Verify(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
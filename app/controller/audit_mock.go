@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"context"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditInter is a mock implementation of the service.AuditInter interface
+type MockAuditInter struct {
+	mock.Mock
+}
+
+func (m *MockAuditInter) List(ctx *gin.Context, req *request.ReqAuditLog) (*request.ResAuditLog, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResAuditLog), args.Error(1)
+}
+
+func (m *MockAuditInter) Verify(ctx context.Context) (*model.AuditVerification, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*model.AuditVerification), args.Error(1)
+}
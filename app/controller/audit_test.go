@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"server/app/model"
+	"server/app/request"
+	"server/pkg/consts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// Test cases for AuditCtrl.List
+func TestAuditCtrl_List(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		query         string
+		mockSkip      bool
+		mockErr       error
+		expectedReq   *request.ReqAuditLog
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "By entity",
+			query:        "entity=wallet&entity_id=7&action=wallet.freeze",
+			expectedReq:  &request.ReqAuditLog{Entity: model.AuditEntityWallet, EntityID: 7, Action: model.AuditWalletFreeze},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Missing entity",
+			query:         "entity_id=7",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidFilter,
+		},
+		{
+			name:          "Unknown entity",
+			query:         "entity=order",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidFilter,
+		},
+		{
+			name:          "Invalid entity id",
+			query:         "entity=user&entity_id=x",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrValidationFailed,
+		},
+		{
+			name:          consts.ErrInternalServer,
+			query:         "entity=user",
+			mockErr:       errors.New("db down"),
+			expectedReq:   &request.ReqAuditLog{Entity: model.AuditEntityUser},
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: consts.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAuditInter)
+			auditCtrl := NewAudit(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			var err error
+			ctx.Request, err = http.NewRequest("GET", "/?"+tt.query, http.NoBody)
+			require.NoError(t, err)
+
+			if !tt.mockSkip {
+				mockService.On("List", ctx, tt.expectedReq).
+					Return(&request.ResAuditLog{List: []*model.AuditRecord{}}, tt.mockErr)
+			}
+
+			auditCtrl.List(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for AuditCtrl.Verify
+func TestAuditCtrl_Verify(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Broken chain", func(t *testing.T) {
+		mockService := new(MockAuditInter)
+		auditCtrl := NewAudit(mockService)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", "/", http.NoBody)
+
+		mockService.On("Verify", ctx).Return(&model.AuditVerification{Checked: 4, BrokenID: 5}, nil)
+
+		auditCtrl.Verify(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"checked":4,"valid":false,"broken_id":5}`, w.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockService := new(MockAuditInter)
+		auditCtrl := NewAudit(mockService)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", "/", http.NoBody)
+
+		mockService.On("Verify", ctx).Return((*model.AuditVerification)(nil), errors.New("db down"))
+
+		auditCtrl.Verify(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
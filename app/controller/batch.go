@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"server/app/model"
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+)
+
+func NewBatch(serv service.BatchInter) BatchInter {
+	return &BatchCtrl{
+		serv: serv,
+	}
+}
+
+type BatchInter interface {
+	Create(ctx *gin.Context)
+	Get(ctx *gin.Context)
+}
+
+type BatchCtrl struct {
+	serv service.BatchInter
+}
+
+// Create submits a batch of transfers out of a wallet. It is checked as a whole and run in the
+// background; the response is the pending batch, whose progress Get follows.
+func (b *BatchCtrl) Create(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqBatchTransfer)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	items := make([]*model.BatchItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = &model.BatchItem{ToUID: item.ToUID, Amount: item.Amount, Reference: item.Reference}
+	}
+
+	res, err := b.serv.Create(ctx, uid, req.Mode, items)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidBatch):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidBatch, "details": err.Error()})
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrWalletNotFound})
+		default:
+			writeMoneyError(ctx, err, consts.ErrInternalServer)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, res)
+}
+
+// Get returns a batch of a wallet with the outcome of each of its transfers.
+func (b *BatchCtrl) Get(ctx *gin.Context) {
+	idReq := new(request.ReqBatchID)
+	if err := ctx.ShouldBindUri(idReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	if idReq.UID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidUID})
+		return
+	}
+
+	res, err := b.serv.Get(ctx, idReq.UID, idReq.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrBatchNotFound})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"server/app/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBatchInter is a mock implementation of the service.BatchInter interface
+type MockBatchInter struct {
+	mock.Mock
+}
+
+func (m *MockBatchInter) Create(ctx *gin.Context, uid int64, mode model.BatchMode,
+	items []*model.BatchItem) (*model.Batch, error) {
+	args := m.Called(ctx, uid, mode, items)
+	return args.Get(0).(*model.Batch), args.Error(1)
+}
+
+func (m *MockBatchInter) Get(ctx *gin.Context, uid, id int64) (*model.Batch, error) {
+	args := m.Called(ctx, uid, id)
+	return args.Get(0).(*model.Batch), args.Error(1)
+}
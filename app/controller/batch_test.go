@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/app/model"
+	"server/app/service"
+	"server/pkg/consts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// Test cases for BatchCtrl.Create
+func TestBatchCtrl_Create(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		uid           string
+		body          string
+		mockSkip      bool
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Accepted",
+			uid:          "1",
+			body:         `{"mode":2,"items":[{"to_uid":2,"amount":"5","reference":"inv-1"}]}`,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:          "Invalid UID",
+			uid:           "0",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidUID,
+		},
+		{
+			name:          "Invalid body",
+			uid:           "1",
+			body:          `{"items":{}}`,
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrValidationFailed,
+		},
+		{
+			name:          "Invalid batch",
+			uid:           "1",
+			body:          `{"mode":2,"items":[{"to_uid":2,"amount":"5","reference":"inv-1"}]}`,
+			mockErr:       fmt.Errorf("%w: item 0: amount must be positive", service.ErrInvalidBatch),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidBatch,
+		},
+		{
+			name:          "Funding wallet not found",
+			uid:           "1",
+			body:          `{"mode":2,"items":[{"to_uid":2,"amount":"5","reference":"inv-1"}]}`,
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrWalletNotFound,
+		},
+		{
+			name:          "Transfers disabled",
+			uid:           "1",
+			body:          `{"mode":2,"items":[{"to_uid":2,"amount":"5","reference":"inv-1"}]}`,
+			mockErr:       fmt.Errorf("item 0: %w", service.ErrFeatureDisabled),
+			expectedCode:  http.StatusServiceUnavailable,
+			expectedError: consts.ErrOperationDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockBatchInter)
+			batchCtrl := NewBatch(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: tt.uid}}
+
+			var err error
+			ctx.Request, err = http.NewRequest("POST", "", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			if !tt.mockSkip {
+				mockService.On("Create", ctx, int64(1), model.BatchModeBestEffort,
+					mock.MatchedBy(func(items []*model.BatchItem) bool {
+						return len(items) == 1 && items[0].ToUID == 2 && items[0].Amount.Equal(decimal.NewFromInt(5)) &&
+							items[0].Reference == "inv-1"
+					})).Return(&model.Batch{ID: 7, UID: 1, Status: model.BatchStatusPending}, tt.mockErr)
+			}
+
+			batchCtrl.Create(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for BatchCtrl.Get
+func TestBatchCtrl_Get(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		uid           string
+		id            string
+		mockSkip      bool
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Found",
+			uid:          "1",
+			id:           "7",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Invalid UID",
+			uid:           "0",
+			id:            "7",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidUID,
+		},
+		{
+			name:          "Invalid ID",
+			uid:           "1",
+			id:            "seven",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrValidationFailed,
+		},
+		{
+			name:          "Not found",
+			uid:           "1",
+			id:            "7",
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrBatchNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockBatchInter)
+			batchCtrl := NewBatch(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: tt.uid}, {Key: "id", Value: tt.id}}
+
+			if !tt.mockSkip {
+				mockService.On("Get", ctx, int64(1), int64(7)).
+					Return(&model.Batch{ID: 7, UID: 1, Status: model.BatchStatusCompleted}, tt.mockErr)
+			}
+
+			batchCtrl.Get(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
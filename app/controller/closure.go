@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+)
+
+func NewClosure(serv service.ClosureInter) ClosureInter {
+	return &ClosureCtrl{
+		serv: serv,
+	}
+}
+
+type ClosureInter interface {
+	Close(ctx *gin.Context)
+}
+
+type ClosureCtrl struct {
+	serv service.ClosureInter
+}
+
+// Close closes the account of a user for good. The body is optional.
+func (c *ClosureCtrl) Close(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqCloseAccount)
+	if err := ctx.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	res, err := c.serv.Close(ctx, uid, req.Settle)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrUserNotFound})
+		case errors.Is(err, service.ErrBalanceNotSettled):
+			ctx.JSON(http.StatusConflict, gin.H{"error": consts.ErrBalanceNotSettled, "details": err.Error()})
+		default:
+			writeMoneyError(ctx, err, consts.ErrInternalServer)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
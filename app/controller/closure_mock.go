@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"server/app/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockClosureInter is a mock implementation of the service.ClosureInter interface
+type MockClosureInter struct {
+	mock.Mock
+}
+
+func (m *MockClosureInter) Close(ctx *gin.Context, uid int64, settle bool) (*model.Closure, error) {
+	args := m.Called(ctx, uid, settle)
+	return args.Get(0).(*model.Closure), args.Error(1)
+}
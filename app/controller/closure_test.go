@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/service"
+	"server/pkg/consts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// Test cases for ClosureCtrl.Close
+func TestClosureCtrl_Close(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		uid            string
+		body           string
+		mockSkip       bool
+		expectedSettle bool
+		mockErr        error
+		expectedCode   int
+		expectedError  string
+	}{
+		{
+			name:         "Without a body",
+			uid:          "1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:           "Settle",
+			uid:            "1",
+			body:           `{"settle":true}`,
+			expectedSettle: true,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:          "Invalid UID",
+			uid:           "0",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidUID,
+		},
+		{
+			name:          "Invalid body",
+			uid:           "1",
+			body:          `{"settle":"yes"}`,
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrValidationFailed,
+		},
+		{
+			name:          "Balance left",
+			uid:           "1",
+			mockErr:       fmt.Errorf("%w: 40 left", service.ErrBalanceNotSettled),
+			expectedCode:  http.StatusConflict,
+			expectedError: consts.ErrBalanceNotSettled,
+		},
+		{
+			name:          "Not found",
+			uid:           "1",
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrUserNotFound,
+		},
+		{
+			name:          "Frozen",
+			uid:           "1",
+			mockErr:       fmt.Errorf("%w for uid 1", repository.ErrWalletFrozen),
+			expectedCode:  http.StatusForbidden,
+			expectedError: consts.ErrWalletFrozen,
+		},
+		{
+			name:          "Already closed",
+			uid:           "1",
+			mockErr:       fmt.Errorf("%w for uid 1", repository.ErrWalletClosed),
+			expectedCode:  http.StatusGone,
+			expectedError: consts.ErrWalletClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockClosureInter)
+			closureCtrl := NewClosure(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: tt.uid}}
+
+			var err error
+			ctx.Request, err = http.NewRequest("POST", "", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			if !tt.mockSkip {
+				mockService.On("Close", ctx, int64(1), tt.expectedSettle).
+					Return(&model.Closure{UID: 1, Settled: decimal.Zero}, tt.mockErr)
+			}
+
+			closureCtrl.Close(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"server/pkg/health"
+)
+
+func NewHealth(probe *health.Probe) HealthInter {
+	return &HealthCtrl{
+		probe: probe,
+	}
+}
+
+type HealthInter interface {
+	Healthz(ctx *gin.Context)
+	Readyz(ctx *gin.Context)
+}
+
+type HealthCtrl struct {
+	probe *health.Probe
+}
+
+// Healthz reports that the process is alive. It never touches a dependency, so a slow
+// database does not get the container restarted.
+func (h *HealthCtrl) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": health.StatusUp})
+}
+
+// Readyz reports whether the service can take traffic, with the status of every dependency.
+func (h *HealthCtrl) Readyz(ctx *gin.Context) {
+	report, ok := h.probe.Ready(ctx.Request.Context())
+	if !ok {
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
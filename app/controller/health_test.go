@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server/pkg/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestHealthCtrl_Healthz(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	probe := health.New(time.Second)
+	probe.Add("postgres", func(ctx context.Context) error { return errors.New("down") })
+	ctrl := NewHealth(probe)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	ctrl.Healthz(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"status":"up"}`, recorder.Body.String())
+}
+
+func TestHealthCtrl_Readyz(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		checkErr       error
+		drain          bool
+		expectedCode   int
+		expectedStatus string
+		expectedCheck  string
+	}{
+		{
+			name:           "Ready",
+			expectedCode:   http.StatusOK,
+			expectedStatus: health.StatusUp,
+			expectedCheck:  "postgres",
+		},
+		{
+			name:           "Dependency down",
+			checkErr:       errors.New("connection refused"),
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: health.StatusDown,
+			expectedCheck:  "postgres",
+		},
+		{
+			name:           "Draining",
+			drain:          true,
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: health.StatusDown,
+			expectedCheck:  "shutdown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := health.New(time.Second)
+			probe.Add("postgres", func(ctx context.Context) error { return tt.checkErr })
+			if tt.drain {
+				probe.Drain()
+			}
+			ctrl := NewHealth(probe)
+
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			ctrl.Readyz(ctx)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+
+			var report health.Report
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+			assert.Equal(t, tt.expectedStatus, report.Status)
+			assert.Contains(t, report.Checks, tt.expectedCheck)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+)
+
+func NewPaymentRequest(serv service.PaymentRequestInter) PaymentRequestInter {
+	return &PaymentRequestCtrl{
+		serv: serv,
+	}
+}
+
+type PaymentRequestInter interface {
+	Create(ctx *gin.Context)
+	List(ctx *gin.Context)
+	GetByToken(ctx *gin.Context)
+	Accept(ctx *gin.Context)
+	Decline(ctx *gin.Context)
+	Cancel(ctx *gin.Context)
+}
+
+type PaymentRequestCtrl struct {
+	serv service.PaymentRequestInter
+}
+
+// Create makes a payment request from a wallet. The response carries the token to share with the
+// payer, which is not shown again.
+func (p *PaymentRequestCtrl) Create(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqPaymentRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	res, err := p.serv.Create(ctx, &model.PaymentRequest{RequesterUID: uid, PayerUID: req.PayerUID,
+		Amount: req.Amount, Memo: req.Memo, ExpiresAt: req.ExpiresAt})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrWalletNotFound})
+		default:
+			writePaymentRequestError(ctx, err, consts.ErrInternalServer)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// List returns the payment requests made by or to a wallet, the latest first.
+func (p *PaymentRequestCtrl) List(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqPaymentRequests)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+	req.UID = uid
+	req.ValidatePageSize()
+
+	if err := req.ValidateFilter(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidFilter, "details": err.Error()})
+		return
+	}
+
+	res, err := p.serv.List(ctx, req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetByToken shows the payment request of a shared token to whoever holds it.
+func (p *PaymentRequestCtrl) GetByToken(ctx *gin.Context) {
+	req := new(request.ReqPaymentToken)
+	if err := ctx.ShouldBindUri(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	res, err := p.serv.GetByToken(ctx, req.Token)
+	if err != nil {
+		writePaymentRequestError(ctx, err, consts.ErrInternalServer)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Accept pays a payment request from a wallet. The body, with the token of an open request, is
+// optional.
+func (p *PaymentRequestCtrl) Accept(ctx *gin.Context) {
+	idReq, ok := bindPaymentRequestID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqAcceptPaymentRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	res, err := p.serv.Accept(ctx, idReq.UID, idReq.ID, req.Token)
+	if err != nil {
+		writePaymentRequestError(ctx, err, consts.ErrTransferFailed)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Decline turns down a payment request made to a wallet.
+func (p *PaymentRequestCtrl) Decline(ctx *gin.Context) {
+	p.close(ctx, p.serv.Decline)
+}
+
+// Cancel withdraws a payment request made by a wallet.
+func (p *PaymentRequestCtrl) Cancel(ctx *gin.Context) {
+	p.close(ctx, p.serv.Cancel)
+}
+
+func (p *PaymentRequestCtrl) close(ctx *gin.Context,
+	execute func(ctx *gin.Context, uid, id int64) (*model.PaymentRequest, error)) {
+	idReq, ok := bindPaymentRequestID(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := execute(ctx, idReq.UID, idReq.ID)
+	if err != nil {
+		writePaymentRequestError(ctx, err, consts.ErrInternalServer)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func bindPaymentRequestID(ctx *gin.Context) (*request.ReqPaymentRequestID, bool) {
+	idReq := new(request.ReqPaymentRequestID)
+	if err := ctx.ShouldBindUri(idReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return nil, false
+	}
+
+	if idReq.UID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidUID})
+		return nil, false
+	}
+
+	return idReq, true
+}
+
+// writePaymentRequestError responds to a failed action on a payment request. The transfer of an
+// accepted request fails like any other transfer, with fallback for unexpected errors.
+func writePaymentRequestError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrPaymentRequestNotFound})
+	case errors.Is(err, service.ErrInvalidPaymentRequest):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidPaymentRequest, "details": err.Error()})
+	case errors.Is(err, service.ErrNonPositiveAmount):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidAmount, "details": err.Error()})
+	case errors.Is(err, service.ErrNotPayer), errors.Is(err, service.ErrNotRequester):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": consts.ErrForbidden, "details": err.Error()})
+	case errors.Is(err, repository.ErrPaymentRequestClosed):
+		ctx.JSON(http.StatusConflict, gin.H{"error": consts.ErrPaymentRequestClosed, "details": err.Error()})
+	default:
+		writeMoneyError(ctx, err, fallback)
+	}
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPaymentRequestInter is a mock implementation of the service.PaymentRequestInter interface
+type MockPaymentRequestInter struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRequestInter) Create(ctx *gin.Context, mod *model.PaymentRequest) (*model.PaymentRequest, error) {
+	args := m.Called(ctx, mod)
+	return args.Get(0).(*model.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestInter) List(ctx *gin.Context,
+	req *request.ReqPaymentRequests) (*request.ResPaymentRequests, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResPaymentRequests), args.Error(1)
+}
+
+func (m *MockPaymentRequestInter) GetByToken(ctx *gin.Context, token string) (*model.PaymentRequestView,
+	error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(*model.PaymentRequestView), args.Error(1)
+}
+
+func (m *MockPaymentRequestInter) Accept(ctx *gin.Context, uid, id int64, token string) (*model.PaymentRequest,
+	error) {
+	args := m.Called(ctx, uid, id, token)
+	return args.Get(0).(*model.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestInter) Decline(ctx *gin.Context, uid, id int64) (*model.PaymentRequest, error) {
+	args := m.Called(ctx, uid, id)
+	return args.Get(0).(*model.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestInter) Cancel(ctx *gin.Context, uid, id int64) (*model.PaymentRequest, error) {
+	args := m.Called(ctx, uid, id)
+	return args.Get(0).(*model.PaymentRequest), args.Error(1)
+}
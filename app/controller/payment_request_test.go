@@ -0,0 +1,361 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// Test cases for PaymentRequestCtrl.Create
+func TestPaymentRequestCtrl_Create(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		body          string
+		mockSkip      bool
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Created",
+			body:         `{"payer_uid":2,"amount":"10","memo":"dinner"}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:          "Invalid body",
+			body:          `{"amount":true}`,
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrValidationFailed,
+		},
+		{
+			name:          "Invalid request",
+			body:          `{"payer_uid":2,"amount":"10","memo":"dinner"}`,
+			mockErr:       fmt.Errorf("%w: memo longer than 140", service.ErrInvalidPaymentRequest),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidPaymentRequest,
+		},
+		{
+			name:          "Payer without a wallet",
+			body:          `{"payer_uid":2,"amount":"10","memo":"dinner"}`,
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrWalletNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPaymentRequestInter)
+			paymentRequestCtrl := NewPaymentRequest(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "1"}}
+
+			var err error
+			ctx.Request, err = http.NewRequest("POST", "", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			if !tt.mockSkip {
+				mockService.On("Create", ctx, mock.MatchedBy(func(mod *model.PaymentRequest) bool {
+					return mod.RequesterUID == 1 && mod.PayerUID == 2 && mod.Amount.Equal(decimal.NewFromInt(10)) &&
+						mod.Memo == "dinner"
+				})).Return(&model.PaymentRequest{ID: 5, Token: "secret"}, tt.mockErr)
+			}
+
+			paymentRequestCtrl.Create(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			} else {
+				assert.Contains(t, w.Body.String(), `"token":"secret"`)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for PaymentRequestCtrl.List
+func TestPaymentRequestCtrl_List(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		query         string
+		mockSkip      bool
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Incoming pending",
+			query:        "flow=incoming&status=1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Unknown flow",
+			query:         "flow=sideways",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidFilter,
+		},
+		{
+			name:          "Unknown status",
+			query:         "status=9",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidFilter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPaymentRequestInter)
+			paymentRequestCtrl := NewPaymentRequest(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "1"}}
+			ctx.Request = httptest.NewRequest("GET", "/?"+tt.query, nil)
+
+			if !tt.mockSkip {
+				mockService.On("List", ctx, mock.MatchedBy(func(req *request.ReqPaymentRequests) bool {
+					return req.UID == 1 && req.Flow == model.FlowIncoming && req.Status == model.PaymentRequestStatusPending
+				})).Return(&request.ResPaymentRequests{List: []*model.PaymentRequest{{ID: 5}}}, nil)
+			}
+
+			paymentRequestCtrl.List(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for PaymentRequestCtrl.Accept
+func TestPaymentRequestCtrl_Accept(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		uid           string
+		body          string
+		expectedToken string
+		mockSkip      bool
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Without a body",
+			uid:          "2",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "With a token",
+			uid:           "2",
+			body:          `{"token":"secret"}`,
+			expectedToken: "secret",
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "Invalid UID",
+			uid:           "0",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidUID,
+		},
+		{
+			name:          "Not the payer",
+			uid:           "2",
+			mockErr:       fmt.Errorf("%w 5", service.ErrNotPayer),
+			expectedCode:  http.StatusForbidden,
+			expectedError: consts.ErrForbidden,
+		},
+		{
+			name:          "Already paid",
+			uid:           "2",
+			mockErr:       fmt.Errorf("%w: 5", repository.ErrPaymentRequestClosed),
+			expectedCode:  http.StatusConflict,
+			expectedError: consts.ErrPaymentRequestClosed,
+		},
+		{
+			name:          "Not found",
+			uid:           "2",
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrPaymentRequestNotFound,
+		},
+		{
+			name:          "Insufficient balance",
+			uid:           "2",
+			mockErr:       fmt.Errorf("%w for transfer", service.ErrInsufficientBalance),
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: consts.ErrTransferFailed,
+		},
+		{
+			name:          "Frozen payer",
+			uid:           "2",
+			mockErr:       fmt.Errorf("%w for uid 2", repository.ErrWalletFrozen),
+			expectedCode:  http.StatusForbidden,
+			expectedError: consts.ErrWalletFrozen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPaymentRequestInter)
+			paymentRequestCtrl := NewPaymentRequest(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: tt.uid}, {Key: "id", Value: "5"}}
+
+			var err error
+			ctx.Request, err = http.NewRequest("POST", "", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			if !tt.mockSkip {
+				mockService.On("Accept", ctx, int64(2), int64(5), tt.expectedToken).
+					Return(&model.PaymentRequest{ID: 5, Status: model.PaymentRequestStatusPaid}, tt.mockErr)
+			}
+
+			paymentRequestCtrl.Accept(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for PaymentRequestCtrl.Decline and PaymentRequestCtrl.Cancel
+func TestPaymentRequestCtrl_Close(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		method        string
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{name: "Decline", method: "Decline", expectedCode: http.StatusOK},
+		{name: "Cancel", method: "Cancel", expectedCode: http.StatusOK},
+		{
+			name:          "Cancel someone else's request",
+			method:        "Cancel",
+			mockErr:       fmt.Errorf("%w 5", service.ErrNotRequester),
+			expectedCode:  http.StatusForbidden,
+			expectedError: consts.ErrForbidden,
+		},
+		{
+			name:          "Decline an expired request",
+			method:        "Decline",
+			mockErr:       fmt.Errorf("%w: 5 has status 5", repository.ErrPaymentRequestClosed),
+			expectedCode:  http.StatusConflict,
+			expectedError: consts.ErrPaymentRequestClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPaymentRequestInter)
+			paymentRequestCtrl := NewPaymentRequest(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "1"}, {Key: "id", Value: "5"}}
+
+			mockService.On(tt.method, ctx, int64(1), int64(5)).Return(&model.PaymentRequest{ID: 5}, tt.mockErr)
+
+			if tt.method == "Decline" {
+				paymentRequestCtrl.Decline(ctx)
+			} else {
+				paymentRequestCtrl.Cancel(ctx)
+			}
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for PaymentRequestCtrl.GetByToken
+func TestPaymentRequestCtrl_GetByToken(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range []struct {
+		name         string
+		mockErr      error
+		expectedCode int
+	}{
+		{"Found", nil, http.StatusOK},
+		{"Unknown token", sql.ErrNoRows, http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPaymentRequestInter)
+			paymentRequestCtrl := NewPaymentRequest(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "token", Value: "secret"}}
+
+			mockService.On("GetByToken", ctx, "secret").
+				Return(&model.PaymentRequestView{ID: 5, RequesterName: "alice"}, tt.mockErr)
+
+			paymentRequestCtrl.GetByToken(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NotContains(t, w.Body.String(), "uid", "the uids behind the request are not shown")
+			mockService.AssertExpectations(t)
+		})
+	}
+}
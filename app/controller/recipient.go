@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"server/app/repository"
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+)
+
+func NewRecipient(serv service.RecipientInter) RecipientInter {
+	return &RecipientCtrl{
+		serv: serv,
+	}
+}
+
+type RecipientInter interface {
+	Preview(ctx *gin.Context)
+	SetHandle(ctx *gin.Context)
+}
+
+type RecipientCtrl struct {
+	serv service.RecipientInter
+}
+
+// Preview shows who a transfer from a wallet to a username, an email or an @handle would reach,
+// with a masked name, before the sender confirms it.
+func (r *RecipientCtrl) Preview(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqRecipient)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	res, err := r.serv.Resolve(ctx, uid, req.To)
+	if err != nil {
+		writeRecipientError(ctx, err, consts.ErrInternalServer)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// SetHandle gives a wallet the handle others can transfer to.
+func (r *RecipientCtrl) SetHandle(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqHandle)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	if err := r.serv.SetHandle(ctx, uid, req.Handle); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrWalletNotFound})
+		default:
+			writeRecipientError(ctx, err, consts.ErrInternalServer)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": consts.MsgSuccess})
+}
+
+// writeRecipientError responds to a recipient that can't be found or a handle that can't be set,
+// falling back to the errors of money movements.
+func writeRecipientError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrRecipientNotFound})
+	case errors.Is(err, service.ErrInvalidRecipient):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidRecipient, "details": err.Error()})
+	case errors.Is(err, service.ErrInvalidHandle):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidHandle, "details": err.Error()})
+	case errors.Is(err, repository.ErrHandleTaken):
+		ctx.JSON(http.StatusConflict, gin.H{"error": consts.ErrHandleTaken, "details": err.Error()})
+	default:
+		writeMoneyError(ctx, err, fallback)
+	}
+}
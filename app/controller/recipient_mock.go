@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"server/app/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRecipientInter is a mock implementation of the service.RecipientInter interface
+type MockRecipientInter struct {
+	mock.Mock
+}
+
+func (m *MockRecipientInter) Resolve(ctx *gin.Context, fromUID int64, to string) (*model.Recipient, error) {
+	args := m.Called(ctx, fromUID, to)
+	return args.Get(0).(*model.Recipient), args.Error(1)
+}
+
+func (m *MockRecipientInter) SetHandle(ctx *gin.Context, uid int64, handle string) error {
+	args := m.Called(ctx, uid, handle)
+	return args.Error(0)
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/service"
+	"server/pkg/consts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// Test cases for RecipientCtrl.Preview
+func TestRecipientCtrl_Preview(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Found",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Not found",
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrRecipientNotFound,
+		},
+		{
+			name:          "Malformed handle",
+			mockErr:       fmt.Errorf("%w: malformed handle", service.ErrInvalidRecipient),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidRecipient,
+		},
+		{
+			name:          "Oneself",
+			mockErr:       fmt.Errorf("%w: uid 1", service.ErrSelfTransfer),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrSelfTransfer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockRecipientInter)
+			recipientCtrl := NewRecipient(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "1"}}
+			ctx.Request = httptest.NewRequest("GET", "/?to=%40alice_shop", nil)
+
+			mockService.On("Resolve", ctx, int64(1), "@alice_shop").
+				Return(&model.Recipient{UID: 2, Name: "a***e", Handle: "alice_shop"}, tt.mockErr)
+
+			recipientCtrl.Preview(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			} else {
+				assert.JSONEq(t, `{"name":"a***e","handle":"alice_shop"}`, w.Body.String(), "the uid is not shown")
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for RecipientCtrl.SetHandle
+func TestRecipientCtrl_SetHandle(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		mockErr       error
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "Set",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Invalid",
+			mockErr:       fmt.Errorf("%w: too short", service.ErrInvalidHandle),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidHandle,
+		},
+		{
+			name:          "Taken",
+			mockErr:       repository.ErrHandleTaken,
+			expectedCode:  http.StatusConflict,
+			expectedError: consts.ErrHandleTaken,
+		},
+		{
+			name:          "No wallet",
+			mockErr:       sql.ErrNoRows,
+			expectedCode:  http.StatusNotFound,
+			expectedError: consts.ErrWalletNotFound,
+		},
+		{
+			name:          "Closed wallet",
+			mockErr:       fmt.Errorf("%w for uid 1", repository.ErrWalletClosed),
+			expectedCode:  http.StatusGone,
+			expectedError: consts.ErrWalletClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockRecipientInter)
+			recipientCtrl := NewRecipient(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "1"}}
+			ctx.Request = httptest.NewRequest("PUT", "/", strings.NewReader(`{"handle":"alice_shop"}`))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			mockService.On("SetHandle", ctx, int64(1), "alice_shop").Return(tt.mockErr)
+
+			recipientCtrl.SetHandle(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"server/app/model"
+	"server/app/request"
+	"server/app/service"
+	"server/pkg/consts"
+)
+
+func NewReconcile(serv service.ReconcileInter) ReconcileInter {
+	return &ReconcileCtrl{
+		serv: serv,
+	}
+}
+
+type ReconcileInter interface {
+	Discrepancies(ctx *gin.Context)
+}
+
+type ReconcileCtrl struct {
+	serv service.ReconcileInter
+}
+
+// Discrepancies lists the reconciliation discrepancies, the open ones unless status is given.
+func (r *ReconcileCtrl) Discrepancies(ctx *gin.Context) {
+	req := new(request.ReqDiscrepancies)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+	req.ValidatePageSize()
+
+	if req.Status == 0 {
+		req.Status = model.DiscrepancyStatusOpen
+	}
+
+	if !req.Status.IsValid() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidFilter})
+		return
+	}
+
+	res, err := r.serv.ListDiscrepancies(ctx, req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"context"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReconcileInter is a mock implementation of the service.ReconcileInter interface
+type MockReconcileInter struct {
+	mock.Mock
+}
+
+func (m *MockReconcileInter) Run(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockReconcileInter) Reconcile(ctx context.Context) ([]*model.Discrepancy, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.Discrepancy), args.Error(1)
+}
+
+func (m *MockReconcileInter) ListDiscrepancies(ctx context.Context,
+	req *request.ReqDiscrepancies) (*request.ResDiscrepancies, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResDiscrepancies), args.Error(1)
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"server/app/model"
+	"server/app/request"
+	"server/pkg/consts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// Test cases for ReconcileCtrl.Discrepancies
+func TestReconcileCtrl_Discrepancies(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSkip       bool
+		mockErr        error
+		expectedStatus model.DiscrepancyStatus
+		expectedCode   int
+		expectedError  string
+	}{
+		{
+			name:           "Defaults to open",
+			expectedStatus: model.DiscrepancyStatusOpen,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "Resolved",
+			query:          "status=2",
+			expectedStatus: model.DiscrepancyStatusResolved,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:          "Unknown status",
+			query:         "status=9",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrInvalidFilter,
+		},
+		{
+			name:          "Invalid status",
+			query:         "status=open",
+			mockSkip:      true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: consts.ErrValidationFailed,
+		},
+		{
+			name:           consts.ErrInternalServer,
+			mockErr:        errors.New(consts.ErrInternalServer),
+			expectedStatus: model.DiscrepancyStatusOpen,
+			expectedCode:   http.StatusInternalServerError,
+			expectedError:  consts.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockReconcileInter)
+			reconcileCtrl := NewReconcile(mockService)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			var err error
+			ctx.Request, err = http.NewRequest("GET", "/?"+tt.query, http.NoBody)
+			require.NoError(t, err)
+
+			if !tt.mockSkip {
+				var res *request.ResDiscrepancies
+				if tt.mockErr == nil {
+					res = &request.ResDiscrepancies{List: []*model.Discrepancy{}}
+				}
+				mockService.On("ListDiscrepancies", ctx, mock.MatchedBy(func(req *request.ReqDiscrepancies) bool {
+					return req.Status == tt.expectedStatus && req.Page == 1
+				})).Return(res, tt.mockErr)
+			}
+
+			reconcileCtrl.Discrepancies(ctx)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 
 	"server/app/request"
+	"server/pkg/statement"
 )
 
 // MockTransactionInter is a mock implementation of TransactionInter
@@ -16,3 +20,19 @@ func (m *MockTransactionInter) GetTransactionsByUID(ctx *gin.Context, req *reque
 	args := m.Called(ctx, req)
 	return args.Get(0).(*request.ResTransactions), args.Error(1)
 }
+
+func (m *MockTransactionInter) Statement(ctx *gin.Context, req *request.ReqStatement, w statement.Writer) error {
+	args := m.Called(ctx, req, w)
+	return args.Error(0)
+}
+
+func (m *MockTransactionInter) BalanceAt(ctx *gin.Context, uid int64, at time.Time) (decimal.Decimal, error) {
+	args := m.Called(ctx, uid, at)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockTransactionInter) BalanceHistory(ctx *gin.Context,
+	req *request.ReqBalanceHistory) (*request.ResBalanceHistory, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResBalanceHistory), args.Error(1)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"server/app/model"
 	"server/app/request"
 	"server/app/service"
 	"server/pkg/consts"
@@ -50,6 +51,16 @@ func (c *UserCtrl) RegisterUser(ctx *gin.Context) {
 		return
 	}
 
+	if model.IsReservedUsername(req.Username) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": consts.ErrUsernameAlreadyExists})
+		return
+	}
+
+	if model.IsReservedEmail(req.Email) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": consts.ErrEmailAlreadyExists})
+		return
+	}
+
 	resUsername, err := c.serv.GetUserByUsername(ctx, req.Username)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer})
@@ -100,6 +100,32 @@ func TestUserCtrl_RegisterUser(t *testing.T) {
 			expectedStatus:       http.StatusConflict,
 			expectedError:        consts.ErrEmailAlreadyExists,
 		},
+		{
+			name: "Username kept for closed accounts",
+			req: &request.ReqRegisterUser{
+				Username: "closed-1",
+				Email:    "newuser@example.com",
+				Password: "password123",
+			},
+			mockGetUserByUsernameSkip: true,
+			mockGetUserByEmailSkip:    true,
+			mockRegisterUserSkip:      true,
+			expectedStatus:            http.StatusConflict,
+			expectedError:             consts.ErrUsernameAlreadyExists,
+		},
+		{
+			name: "Email kept for closed accounts",
+			req: &request.ReqRegisterUser{
+				Username: "newuser",
+				Email:    "closed-1@closed.invalid",
+				Password: "password123",
+			},
+			mockGetUserByUsernameSkip: true,
+			mockGetUserByEmailSkip:    true,
+			mockRegisterUserSkip:      true,
+			expectedStatus:            http.StatusConflict,
+			expectedError:             consts.ErrEmailAlreadyExists,
+		},
 		{
 			name: "Internal server error on GetUserByUsername",
 			req: &request.ReqRegisterUser{
@@ -3,21 +3,28 @@ package controller
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"server/app/model"
+	"server/app/repository"
 	"server/app/request"
 	"server/app/service"
 	"server/pkg/consts"
+	"server/pkg/lock"
+	"server/pkg/statement"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 )
 
-func NewWallet(serv service.WalletInter, servTransaction service.TransactionInter) WalletInter {
+func NewWallet(serv service.WalletInter, servTransaction service.TransactionInter,
+	servRecipient service.RecipientInter) WalletInter {
 	return &WalletCtrl{
 		serv:            serv,
 		servTransaction: servTransaction,
+		servRecipient:   servRecipient,
 	}
 }
 
@@ -26,16 +33,22 @@ type WalletInter interface {
 	Withdraw(ctx *gin.Context)
 	Transfer(ctx *gin.Context)
 	Balance(ctx *gin.Context)
+	Quote(ctx *gin.Context)
+	BalanceHistory(ctx *gin.Context)
 	Transactions(ctx *gin.Context)
+	Statement(ctx *gin.Context)
 }
 
 type WalletCtrl struct {
 	serv            service.WalletInter
 	servTransaction service.TransactionInter
+	servRecipient   service.RecipientInter
 }
 
 // handleWalletOperation is a generic handler function used to process deposit and withdrawal operations.
-func handleWalletOperation(ctx *gin.Context, operation func(ctx *gin.Context, uid int64, amount decimal.Decimal) error) {
+func (w *WalletCtrl) handleWalletOperation(ctx *gin.Context, operation string,
+	execute func(ctx *gin.Context, uid int64, amount decimal.Decimal, quoteID string,
+		details model.TransactionDetails) error) {
 	idReq := new(request.ReqUID)
 	if err := ctx.ShouldBindUri(idReq); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
@@ -58,21 +71,81 @@ func handleWalletOperation(ctx *gin.Context, operation func(ctx *gin.Context, ui
 		return
 	}
 
-	err := operation(ctx, idReq.UID, amountReq.Amount)
+	if w.dryRun(ctx, operation, idReq.UID, 0, amountReq.Amount, consts.ErrInternalServer) {
+		return
+	}
+
+	err := execute(ctx, idReq.UID, amountReq.Amount, amountReq.QuoteID, amountReq.TransactionDetails)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		writeMoneyError(ctx, err, consts.ErrInternalServer)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": consts.MsgSuccess})
 }
 
+// dryRun answers a money movement requested with ?dry_run=true with its outcome, without making
+// it, and reports whether it did. The checks fail with the same responses as the movement would.
+func (w *WalletCtrl) dryRun(ctx *gin.Context, operation string, uid, toUID int64, amount decimal.Decimal,
+	fallback string) bool {
+	req := new(request.ReqDryRun)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return true
+	}
+
+	if !req.DryRun {
+		return false
+	}
+
+	res, err := w.serv.DryRun(ctx, operation, uid, toUID, amount, req.Quote)
+	if err != nil {
+		writeMoneyError(ctx, err, fallback)
+		return true
+	}
+
+	ctx.JSON(http.StatusOK, res)
+	return true
+}
+
+// writeMoneyError responds to a failed money movement. Errors caused by the runtime settings or
+// by concurrent movements on the same wallet get their own status; anything else is reported
+// as fallback.
+func writeMoneyError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrFeatureDisabled):
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": consts.ErrOperationDisabled, "details": err.Error()})
+	case errors.Is(err, service.ErrAmountLimitExceeded):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidAmount, "details": err.Error()})
+	case errors.Is(err, service.ErrInvalidDetails):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidDetails, "details": err.Error()})
+	case errors.Is(err, service.ErrSelfTransfer):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrSelfTransfer, "details": err.Error()})
+	case errors.Is(err, repository.ErrWalletFrozen):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": consts.ErrWalletFrozen, "details": err.Error()})
+	case errors.Is(err, repository.ErrWalletDebitBlocked):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": consts.ErrWalletDebitBlocked, "details": err.Error()})
+	case errors.Is(err, repository.ErrWalletClosed):
+		ctx.JSON(http.StatusGone, gin.H{"error": consts.ErrWalletClosed, "details": err.Error()})
+	case errors.Is(err, repository.ErrQuoteExpired), errors.Is(err, service.ErrQuoteMismatch):
+		ctx.JSON(http.StatusConflict, gin.H{"error": consts.ErrInvalidQuote, "details": err.Error()})
+	case errors.Is(err, lock.ErrTimeout), errors.Is(err, repository.ErrWriteRejected):
+		ctx.JSON(http.StatusConflict, gin.H{"error": consts.ErrWalletBusy, "details": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback, "details": err.Error()})
+	}
+}
+
 func (w *WalletCtrl) Deposit(ctx *gin.Context) {
-	handleWalletOperation(ctx, w.serv.Deposit)
+	// Deposits are free, so there is no quote to hold to.
+	w.handleWalletOperation(ctx, model.Deposit, func(ctx *gin.Context, uid int64, amount decimal.Decimal, _ string,
+		details model.TransactionDetails) error {
+		return w.serv.Deposit(ctx, uid, amount, details)
+	})
 }
 
 func (w *WalletCtrl) Withdraw(ctx *gin.Context) {
-	handleWalletOperation(ctx, w.serv.Withdraw)
+	w.handleWalletOperation(ctx, model.Withdraw, w.serv.Withdraw)
 }
 
 func (w *WalletCtrl) Transfer(ctx *gin.Context) {
@@ -93,7 +166,12 @@ func (w *WalletCtrl) Transfer(ctx *gin.Context) {
 		return
 	}
 
-	if transferReq.ToUID <= 0 {
+	switch {
+	case transferReq.To != "" && transferReq.ToUID != 0:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidRecipient,
+			"details": "give either to_uid or to, not both"})
+		return
+	case transferReq.To == "" && transferReq.ToUID <= 0:
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidUID})
 		return
 	}
@@ -103,15 +181,62 @@ func (w *WalletCtrl) Transfer(ctx *gin.Context) {
 		return
 	}
 
-	err := w.serv.Transfer(ctx, idReq.UID, transferReq.ToUID, transferReq.Amount)
+	// A recipient addressed by username, email or handle is resolved to its wallet first.
+	if transferReq.To != "" {
+		recipient, err := w.servRecipient.Resolve(ctx, idReq.UID, transferReq.To)
+		if err != nil {
+			writeRecipientError(ctx, err, consts.ErrTransferFailed)
+			return
+		}
+		transferReq.ToUID = recipient.UID
+	}
+
+	if w.dryRun(ctx, model.Transfer, idReq.UID, transferReq.ToUID, transferReq.Amount, consts.ErrTransferFailed) {
+		return
+	}
+
+	err := w.serv.Transfer(ctx, idReq.UID, transferReq.ToUID, transferReq.Amount, transferReq.QuoteID,
+		transferReq.TransactionDetails)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrTransferFailed, "details": err.Error()})
+		writeMoneyError(ctx, err, consts.ErrTransferFailed)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": consts.MsgSuccess})
 }
 
+// Quote returns the fee a withdrawal or a transfer would be charged, without making it.
+func (w *WalletCtrl) Quote(ctx *gin.Context) {
+	uid, ok := bindUID(ctx)
+	if !ok {
+		return
+	}
+
+	req := new(request.ReqFeeQuote)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	res, err := w.serv.Quote(ctx, uid, req.Operation, req.Amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownOperation):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		case errors.Is(err, service.ErrNonPositiveAmount):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidAmount, "details": err.Error()})
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrUserNotFound})
+		default:
+			writeMoneyError(ctx, err, consts.ErrInternalServer)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Balance returns the current balance, or the balance at a point in time when "at" is given.
 func (w *WalletCtrl) Balance(ctx *gin.Context) {
 	var idReq request.ReqUID
 	if err := ctx.ShouldBindUri(&idReq); err != nil {
@@ -127,7 +252,17 @@ func (w *WalletCtrl) Balance(ctx *gin.Context) {
 		return
 	}
 
+	req := new(request.ReqBalance)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
 	balance, err := w.serv.Balance(ctx, idReq.UID)
+	if err == nil && !req.At.IsZero() {
+		balance, err = w.servTransaction.BalanceAt(ctx, idReq.UID, req.At)
+	}
+
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrUserNotFound})
@@ -144,6 +279,50 @@ func (w *WalletCtrl) Balance(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, res)
 }
 
+// BalanceHistory returns the closing balance of every day in a period.
+func (w *WalletCtrl) BalanceHistory(ctx *gin.Context) {
+	idReq := new(request.ReqUID)
+	if err := ctx.ShouldBindUri(idReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	if idReq.UID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidUID})
+		return
+	}
+
+	req := new(request.ReqBalanceHistory)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	if err := req.Validate(time.Now()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidFilter, "details": err.Error()})
+		return
+	}
+
+	req.UID = idReq.UID
+
+	if _, err := w.serv.Balance(ctx, req.UID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrUserNotFound})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		}
+		return
+	}
+
+	res, err := w.servTransaction.BalanceHistory(ctx, req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
 func (w *WalletCtrl) Transactions(ctx *gin.Context) {
 	idReq := new(request.ReqUID)
 	if err := ctx.ShouldBindUri(idReq); err != nil {
@@ -163,8 +342,17 @@ func (w *WalletCtrl) Transactions(ctx *gin.Context) {
 	}
 	req.ValidatePageSize()
 
-	if req.Type > model.TransactionTypeTransfer {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidTransactionType})
+	if err := req.ValidateFilter(); err != nil {
+		if errors.Is(err, request.ErrInvalidTransactionType) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidTransactionType, "details": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidFilter, "details": err.Error()})
+		return
+	}
+
+	if err := req.ValidateCursor(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidCursor})
 		return
 	}
 
@@ -178,3 +366,95 @@ func (w *WalletCtrl) Transactions(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, res)
 }
+
+// Statement streams a wallet statement as csv, jsonl or pdf.
+// Once the first bytes are out the status can no longer change, so later failures only cut the body short.
+func (w *WalletCtrl) Statement(ctx *gin.Context) {
+	idReq := new(request.ReqUID)
+	if err := ctx.ShouldBindUri(idReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	if idReq.UID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidUID})
+		return
+	}
+
+	req := new(request.ReqStatement)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrValidationFailed, "details": err.Error()})
+		return
+	}
+
+	if err := req.Validate(time.Now()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidFilter, "details": err.Error()})
+		return
+	}
+
+	req.UID = idReq.UID
+
+	if _, err := w.serv.Balance(ctx, req.UID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": consts.ErrUserNotFound})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		}
+		return
+	}
+
+	writer, err := statement.NewWriter(req.Format, newDeadlineWriter(ctx.Writer))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": consts.ErrInvalidFilter, "details": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", statement.ContentType(req.Format))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement_%d_%s_%s.%s"`,
+		req.UID, req.From.Format(time.DateOnly), req.To.Format(time.DateOnly), req.Format))
+
+	err = w.servTransaction.Statement(ctx, req, writer)
+	if err == nil {
+		return
+	}
+
+	if !ctx.Writer.Written() {
+		ctx.Writer.Header().Del("Content-Type")
+		ctx.Writer.Header().Del("Content-Disposition")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": consts.ErrInternalServer, "details": err.Error()})
+		return
+	}
+
+	_ = ctx.Error(err)
+	ctx.Abort()
+}
+
+// statementIdleTimeout is how long a statement download may go without a write before it is cut
+// off. It stands in for the write timeout of the server, which would cut off a long download
+// however steadily it is written.
+const statementIdleTimeout = time.Minute
+
+// deadlineWriter pushes the write deadline of a response statementIdleTimeout ahead on every
+// write.
+type deadlineWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func newDeadlineWriter(w http.ResponseWriter) *deadlineWriter {
+	d := &deadlineWriter{w: w, rc: http.NewResponseController(w)}
+	d.extend()
+
+	return d
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	d.extend()
+	return d.w.Write(p)
+}
+
+// extend moves the write deadline forward. A ResponseWriter without deadlines, such as a test
+// recorder, keeps the write timeout of the server.
+func (d *deadlineWriter) extend() {
+	_ = d.rc.SetWriteDeadline(time.Now().Add(statementIdleTimeout))
+}
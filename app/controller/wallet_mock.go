@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"server/app/model"
+
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
@@ -11,18 +13,27 @@ type MockWalletInter struct {
 	mock.Mock
 }
 
-func (m *MockWalletInter) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
-	args := m.Called(ctx, uid, amount)
+func (m *MockWalletInter) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal,
+	details model.TransactionDetails) error {
+	args := m.Called(ctx, uid, amount, details)
+	return args.Error(0)
+}
+
+func (m *MockWalletInter) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal, quoteID string,
+	details model.TransactionDetails) error {
+	args := m.Called(ctx, uid, amount, quoteID, details)
 	return args.Error(0)
 }
 
-func (m *MockWalletInter) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
-	args := m.Called(ctx, uid, amount)
+func (m *MockWalletInter) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	quoteID string, details model.TransactionDetails) error {
+	args := m.Called(ctx, fromUID, toUID, amount, quoteID, details)
 	return args.Error(0)
 }
 
-func (m *MockWalletInter) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal) error {
-	args := m.Called(ctx, fromUID, toUID, amount)
+func (m *MockWalletInter) TransferAndClaim(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	details model.TransactionDetails, claim model.PaymentClaim) error {
+	args := m.Called(ctx, fromUID, toUID, amount, details, claim)
 	return args.Error(0)
 }
 
@@ -30,3 +41,15 @@ func (m *MockWalletInter) Balance(ctx *gin.Context, uid int64) (decimal.Decimal,
 	args := m.Called(ctx, uid)
 	return args.Get(0).(decimal.Decimal), args.Error(1)
 }
+
+func (m *MockWalletInter) Quote(ctx *gin.Context, uid int64, operation string, amount decimal.Decimal) (
+	*model.FeeQuote, error) {
+	args := m.Called(ctx, uid, operation, amount)
+	return args.Get(0).(*model.FeeQuote), args.Error(1)
+}
+
+func (m *MockWalletInter) DryRun(ctx *gin.Context, operation string, uid, toUID int64, amount decimal.Decimal,
+	quote bool) (*model.DryRun, error) {
+	args := m.Called(ctx, operation, uid, toUID, amount, quote)
+	return args.Get(0).(*model.DryRun), args.Error(1)
+}
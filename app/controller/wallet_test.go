@@ -6,18 +6,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"server/app/model"
+	"server/app/repository"
 	"server/app/request"
+	"server/app/service"
 	"server/pkg/consts"
+	"server/pkg/lock"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 )
@@ -28,7 +34,7 @@ func TestWalletCtrl_Deposit(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	mockService := new(MockWalletInter)
-	walletCtrl := NewWallet(mockService, nil) // Assuming NewWallet only needs WalletInter for transactions
+	walletCtrl := NewWallet(mockService, nil, nil) // Assuming NewWallet only needs WalletInter for transactions
 
 	tests := []struct {
 		name            string
@@ -70,6 +76,38 @@ func TestWalletCtrl_Deposit(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedError:  consts.ErrInternalServer,
 		},
+		{
+			name:           "Operation disabled",
+			uid:            1,
+			amount:         decimal.NewFromInt(100),
+			mockDepositErr: fmt.Errorf("deposit %w", service.ErrFeatureDisabled),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedError:  consts.ErrOperationDisabled,
+		},
+		{
+			name:           "Amount limit",
+			uid:            1,
+			amount:         decimal.NewFromInt(100),
+			mockDepositErr: fmt.Errorf("deposit %w of 50", service.ErrAmountLimitExceeded),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrInvalidAmount,
+		},
+		{
+			name:           "Wallet closed",
+			uid:            1,
+			amount:         decimal.NewFromInt(100),
+			mockDepositErr: fmt.Errorf("%w for uid 1", repository.ErrWalletClosed),
+			expectedStatus: http.StatusGone,
+			expectedError:  consts.ErrWalletClosed,
+		},
+		{
+			name:           "Wallet busy",
+			uid:            1,
+			amount:         decimal.NewFromInt(100),
+			mockDepositErr: fmt.Errorf("%w wallet:1", lock.ErrTimeout),
+			expectedStatus: http.StatusConflict,
+			expectedError:  consts.ErrWalletBusy,
+		},
 	}
 
 	for _, tt := range tests {
@@ -90,7 +128,7 @@ func TestWalletCtrl_Deposit(t *testing.T) {
 			ctx.Request.Header.Set("Content-Type", "application/json")
 
 			if !tt.mockDepositSkip {
-				mockService.On("Deposit", ctx, tt.uid, tt.amount).Return(tt.mockDepositErr)
+				mockService.On("Deposit", ctx, tt.uid, tt.amount, model.TransactionDetails{}).Return(tt.mockDepositErr)
 			}
 
 			walletCtrl.Deposit(ctx)
@@ -112,7 +150,7 @@ func TestWalletCtrl_Withdraw(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	mockService := new(MockWalletInter)
-	walletCtrl := NewWallet(mockService, nil) // Assuming NewWallet only needs WalletInter for transactions
+	walletCtrl := NewWallet(mockService, nil, nil) // Assuming NewWallet only needs WalletInter for transactions
 
 	tests := []struct {
 		name             string
@@ -154,6 +192,14 @@ func TestWalletCtrl_Withdraw(t *testing.T) {
 			expectedStatus:  http.StatusInternalServerError,
 			expectedError:   "insufficient balance",
 		},
+		{
+			name:            "Debits blocked",
+			uid:             1,
+			amount:          decimal.NewFromInt(100),
+			mockWithdrawErr: fmt.Errorf("%w for uid 1", repository.ErrWalletDebitBlocked),
+			expectedStatus:  http.StatusForbidden,
+			expectedError:   consts.ErrWalletDebitBlocked,
+		},
 		{
 			name:            consts.ErrInternalServer,
 			uid:             1,
@@ -182,7 +228,7 @@ func TestWalletCtrl_Withdraw(t *testing.T) {
 			ctx.Request.Header.Set("Content-Type", "application/json")
 
 			if !tt.mockWithdrawSkip {
-				mockService.On("Withdraw", ctx, tt.uid, tt.amount).Return(tt.mockWithdrawErr)
+				mockService.On("Withdraw", ctx, tt.uid, tt.amount, "", model.TransactionDetails{}).Return(tt.mockWithdrawErr)
 			}
 
 			walletCtrl.Withdraw(ctx)
@@ -204,7 +250,7 @@ func TestWalletCtrl_Transfer(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	mockService := new(MockWalletInter)
-	walletCtrl := NewWallet(mockService, nil) // Assuming NewWallet only needs WalletInter for transactions
+	walletCtrl := NewWallet(mockService, nil, nil) // Assuming NewWallet only needs WalletInter for transactions
 
 	tests := []struct {
 		name             string
@@ -281,7 +327,7 @@ func TestWalletCtrl_Transfer(t *testing.T) {
 			ctx.Request.Header.Set("Content-Type", "application/json")
 
 			if !tt.mockTransferSkip {
-				mockService.On("Transfer", ctx, tt.uid, tt.toUID, tt.amount).Return(tt.mockTransfer)
+				mockService.On("Transfer", ctx, tt.uid, tt.toUID, tt.amount, "", model.TransactionDetails{}).Return(tt.mockTransfer)
 			}
 
 			walletCtrl.Transfer(ctx)
@@ -303,7 +349,7 @@ func TestWalletCtrl_Balance(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	mockService := new(MockWalletInter)
-	walletCtrl := NewWallet(mockService, nil) // Assuming NewWallet only needs WalletInter for transactions
+	walletCtrl := NewWallet(mockService, nil, nil) // Assuming NewWallet only needs WalletInter for transactions
 
 	tests := []struct {
 		name            string
@@ -352,6 +398,10 @@ func TestWalletCtrl_Balance(t *testing.T) {
 				{Key: "uid", Value: strconv.FormatInt(tt.uid, 10)},
 			}
 
+			var err error
+			ctx.Request, err = http.NewRequest("GET", "/", http.NoBody)
+			require.NoError(t, err)
+
 			if !tt.mockBalanceSkip {
 				mockService.On("Balance", ctx, tt.uid).
 					Return(tt.mockBalance, tt.mockBalanceErr)
@@ -376,7 +426,7 @@ func TestWalletCtrl_Transactions(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	transactionService := new(MockTransactionInter)
-	walletCtrl := NewWallet(nil, transactionService) // Assuming NewWallet only needs TransactionInter for transactions
+	walletCtrl := NewWallet(nil, transactionService, nil) // Assuming NewWallet only needs TransactionInter for transactions
 
 	tests := []struct {
 		name                string
@@ -449,6 +499,22 @@ func TestWalletCtrl_Transactions(t *testing.T) {
 			expectedStatus:      http.StatusBadRequest,
 			expectedError:       consts.ErrInvalidTransactionType,
 		},
+		{
+			name: "Fee transactions",
+			req: &request.ReqTransactions{
+				UID:  1,
+				Type: model.TransactionTypeFee,
+				ReqPage: request.ReqPage{
+					Page:     1,
+					PageSize: 10,
+				},
+			},
+			mockTransaction: &request.ResTransactions{
+				List:    []*model.TransactionWithUsername{},
+				HasMore: false,
+			},
+			expectedStatus: http.StatusOK,
+		},
 		{
 			name: "Empty result set",
 			req: &request.ReqTransactions{
@@ -465,6 +531,36 @@ func TestWalletCtrl_Transactions(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name: "Invalid filter",
+			req: &request.ReqTransactions{
+				UID:  1,
+				Type: 1,
+				Sort: "random",
+				ReqPage: request.ReqPage{
+					Page:     1,
+					PageSize: 10,
+				},
+			},
+			mockTransactionSkip: true,
+			expectedStatus:      http.StatusBadRequest,
+			expectedError:       consts.ErrInvalidFilter,
+		},
+		{
+			name: "Invalid cursor",
+			req: &request.ReqTransactions{
+				UID:    1,
+				Type:   1,
+				Cursor: "broken",
+				ReqPage: request.ReqPage{
+					Page:     1,
+					PageSize: 10,
+				},
+			},
+			mockTransactionSkip: true,
+			expectedStatus:      http.StatusBadRequest,
+			expectedError:       consts.ErrInvalidCursor,
+		},
 		{
 			name: consts.ErrInternalServer,
 			req: &request.ReqTransactions{
@@ -497,6 +593,12 @@ func TestWalletCtrl_Transactions(t *testing.T) {
 
 			var err error
 			var url = fmt.Sprintf("/?page=%d&page_size=%d&type=%d", tt.req.Page, tt.req.PageSize, tt.req.Type)
+			if tt.req.Cursor != "" {
+				url += "&cursor=" + tt.req.Cursor
+			}
+			if tt.req.Sort != "" {
+				url += "&sort=" + tt.req.Sort
+			}
 
 			ctx.Request, err = http.NewRequest("GET", url, http.NoBody)
 			require.NoError(t, err)
@@ -513,3 +615,766 @@ func TestWalletCtrl_Transactions(t *testing.T) {
 		})
 	}
 }
+
+// Test cases for WalletCtrl.Statement
+func TestWalletCtrl_Statement(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockWalletInter)
+	transactionService := new(MockTransactionInter)
+	walletCtrl := NewWallet(mockService, transactionService, nil)
+
+	tests := []struct {
+		name              string
+		uid               int64
+		query             string
+		mockBalanceSkip   bool
+		mockBalanceErr    error
+		mockStatementSkip bool
+		mockStatementErr  error
+		expectedStatus    int
+		expectedType      string
+		expectedError     string
+	}{
+		{
+			name:           "Valid csv statement",
+			uid:            1,
+			query:          "from=2024-05-01&to=2024-05-31",
+			expectedStatus: http.StatusOK,
+			expectedType:   "text/csv; charset=utf-8",
+		},
+		{
+			name:           "Valid pdf statement",
+			uid:            1,
+			query:          "from=2024-05-01&to=2024-05-31&format=pdf",
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/pdf",
+		},
+		{
+			name:              "Invalid UID",
+			uid:               0,
+			mockBalanceSkip:   true,
+			mockStatementSkip: true,
+			expectedStatus:    http.StatusBadRequest,
+			expectedError:     consts.ErrInvalidUID,
+		},
+		{
+			name:              "Invalid date",
+			uid:               1,
+			query:             "from=May",
+			mockBalanceSkip:   true,
+			mockStatementSkip: true,
+			expectedStatus:    http.StatusBadRequest,
+			expectedError:     consts.ErrValidationFailed,
+		},
+		{
+			name:              "Unknown format",
+			uid:               1,
+			query:             "format=xml",
+			mockBalanceSkip:   true,
+			mockStatementSkip: true,
+			expectedStatus:    http.StatusBadRequest,
+			expectedError:     consts.ErrInvalidFilter,
+		},
+		{
+			name:              "User not found",
+			uid:               2,
+			mockBalanceErr:    sql.ErrNoRows,
+			mockStatementSkip: true,
+			expectedStatus:    http.StatusNotFound,
+			expectedError:     consts.ErrUserNotFound,
+		},
+		{
+			name:             "Statement fails before writing",
+			uid:              3,
+			mockStatementErr: errors.New(consts.ErrInternalServer),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedType:     "application/json; charset=utf-8",
+			expectedError:    consts.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			ctx.Params = gin.Params{
+				{Key: "uid", Value: strconv.FormatInt(tt.uid, 10)},
+			}
+
+			var err error
+			ctx.Request, err = http.NewRequest("GET", "/?"+tt.query, http.NoBody)
+			require.NoError(t, err)
+
+			if !tt.mockBalanceSkip {
+				mockService.On("Balance", ctx, tt.uid).Return(decimal.Zero, tt.mockBalanceErr)
+			}
+
+			if !tt.mockStatementSkip {
+				transactionService.On("Statement", ctx, mock.Anything, mock.Anything).Return(tt.mockStatementErr)
+			}
+
+			walletCtrl.Statement(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, w.Header().Get("Content-Type"))
+			}
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+			transactionService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for deadlineWriter
+func TestDeadlineWriter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	// The download takes longer than the write timeout of the server, but writes steadily.
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := newDeadlineWriter(w)
+		for i := 0; i < 3; i++ {
+			time.Sleep(80 * time.Millisecond)
+			_, _ = writer.Write([]byte("row\n"))
+			_ = http.NewResponseController(w).Flush()
+		}
+	}))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	client := server.Client()
+	defer client.CloseIdleConnections()
+
+	res, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "row\nrow\nrow\n", string(body))
+}
+
+// Test cases for WalletCtrl.Balance with a point in time
+func TestWalletCtrl_BalanceAt(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockWalletInter)
+	transactionService := new(MockTransactionInter)
+	walletCtrl := NewWallet(mockService, transactionService, nil)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		uid               int64
+		query             string
+		mockBalanceSkip   bool
+		mockBalanceErr    error
+		mockBalanceAtSkip bool
+		mockBalanceAtErr  error
+		expectedStatus    int
+		expectedBody      string
+		expectedError     string
+	}{
+		{
+			name:           "Valid balance at",
+			uid:            1,
+			query:          "at=2024-05-01T12:00:00Z",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"balance":"42"}`,
+		},
+		{
+			name:              "Invalid timestamp",
+			uid:               1,
+			query:             "at=yesterday",
+			mockBalanceSkip:   true,
+			mockBalanceAtSkip: true,
+			expectedStatus:    http.StatusBadRequest,
+			expectedError:     consts.ErrValidationFailed,
+		},
+		{
+			name:              "User not found",
+			uid:               2,
+			query:             "at=2024-05-01T12:00:00Z",
+			mockBalanceErr:    sql.ErrNoRows,
+			mockBalanceAtSkip: true,
+			expectedStatus:    http.StatusNotFound,
+			expectedError:     consts.ErrUserNotFound,
+		},
+		{
+			name:             "Balance at fails",
+			uid:              3,
+			query:            "at=2024-05-01T12:00:00Z",
+			mockBalanceAtErr: errors.New(consts.ErrInternalServer),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedError:    consts.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			ctx.Params = gin.Params{
+				{Key: "uid", Value: strconv.FormatInt(tt.uid, 10)},
+			}
+
+			var err error
+			ctx.Request, err = http.NewRequest("GET", "/?"+tt.query, http.NoBody)
+			require.NoError(t, err)
+
+			if !tt.mockBalanceSkip {
+				mockService.On("Balance", ctx, tt.uid).Return(decimal.NewFromInt(100), tt.mockBalanceErr)
+			}
+
+			if !tt.mockBalanceAtSkip {
+				transactionService.On("BalanceAt", ctx, tt.uid, mock.MatchedBy(func(t time.Time) bool {
+					return t.Equal(at)
+				})).Return(decimal.NewFromInt(42), tt.mockBalanceAtErr)
+			}
+
+			walletCtrl.Balance(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+			transactionService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for WalletCtrl.BalanceHistory
+func TestWalletCtrl_BalanceHistory(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockWalletInter)
+	transactionService := new(MockTransactionInter)
+	walletCtrl := NewWallet(mockService, transactionService, nil)
+
+	tests := []struct {
+		name            string
+		uid             int64
+		query           string
+		mockBalanceSkip bool
+		mockBalanceErr  error
+		mockHistorySkip bool
+		mockHistoryErr  error
+		expectedStatus  int
+		expectedError   string
+	}{
+		{
+			name:           "Valid history",
+			uid:            1,
+			query:          "from=2024-05-01&to=2024-05-31",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "Invalid UID",
+			uid:             0,
+			mockBalanceSkip: true,
+			mockHistorySkip: true,
+			expectedStatus:  http.StatusBadRequest,
+			expectedError:   consts.ErrInvalidUID,
+		},
+		{
+			name:            "Invalid date",
+			uid:             1,
+			query:           "from=May",
+			mockBalanceSkip: true,
+			mockHistorySkip: true,
+			expectedStatus:  http.StatusBadRequest,
+			expectedError:   consts.ErrValidationFailed,
+		},
+		{
+			name:            "From after to",
+			uid:             1,
+			query:           "from=2024-05-31&to=2024-05-01",
+			mockBalanceSkip: true,
+			mockHistorySkip: true,
+			expectedStatus:  http.StatusBadRequest,
+			expectedError:   consts.ErrInvalidFilter,
+		},
+		{
+			name:            "User not found",
+			uid:             2,
+			query:           "from=2024-05-01&to=2024-05-31",
+			mockBalanceErr:  sql.ErrNoRows,
+			mockHistorySkip: true,
+			expectedStatus:  http.StatusNotFound,
+			expectedError:   consts.ErrUserNotFound,
+		},
+		{
+			name:           consts.ErrInternalServer,
+			uid:            3,
+			query:          "from=2024-05-01&to=2024-05-31",
+			mockHistoryErr: errors.New(consts.ErrInternalServer),
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  consts.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			ctx.Params = gin.Params{
+				{Key: "uid", Value: strconv.FormatInt(tt.uid, 10)},
+			}
+
+			var err error
+			ctx.Request, err = http.NewRequest("GET", "/?"+tt.query, http.NoBody)
+			require.NoError(t, err)
+
+			if !tt.mockBalanceSkip {
+				mockService.On("Balance", ctx, tt.uid).Return(decimal.Zero, tt.mockBalanceErr)
+			}
+
+			if !tt.mockHistorySkip {
+				var res *request.ResBalanceHistory
+				if tt.mockHistoryErr == nil {
+					res = &request.ResBalanceHistory{List: []*model.DailyBalance{}}
+				}
+				transactionService.On("BalanceHistory", ctx, mock.MatchedBy(func(req *request.ReqBalanceHistory) bool {
+					return req.UID == tt.uid
+				})).Return(res, tt.mockHistoryErr)
+			}
+
+			walletCtrl.BalanceHistory(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+			transactionService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for WalletCtrl.Quote
+func TestWalletCtrl_Quote(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	mockService := new(MockWalletInter)
+	walletCtrl := NewWallet(mockService, new(MockTransactionInter), nil)
+
+	tests := []struct {
+		name           string
+		uid            int64
+		query          string
+		mockSkip       bool
+		mockErr        error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "Valid quote",
+			uid:            1,
+			query:          "operation=withdraw&amount=100",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid UID",
+			uid:            0,
+			query:          "operation=withdraw&amount=100",
+			mockSkip:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrInvalidUID,
+		},
+		{
+			name:           "Invalid amount",
+			uid:            1,
+			query:          "operation=withdraw&amount=lots",
+			mockSkip:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrValidationFailed,
+		},
+		{
+			name:           "Unknown operation",
+			uid:            1,
+			query:          "operation=deposit&amount=100",
+			mockErr:        service.ErrUnknownOperation,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrValidationFailed,
+		},
+		{
+			name:           "Non-positive amount",
+			uid:            1,
+			query:          "operation=transfer&amount=0",
+			mockErr:        service.ErrNonPositiveAmount,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrInvalidAmount,
+		},
+		{
+			name:           "Operation disabled",
+			uid:            1,
+			query:          "operation=withdraw&amount=100",
+			mockErr:        fmt.Errorf("withdraw %w", service.ErrFeatureDisabled),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedError:  consts.ErrOperationDisabled,
+		},
+		{
+			name:           "Amount over the limit",
+			uid:            1,
+			query:          "operation=transfer&amount=100000",
+			mockErr:        fmt.Errorf("transfer %w of 10000", service.ErrAmountLimitExceeded),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrInvalidAmount,
+		},
+		{
+			name:           "User not found",
+			uid:            2,
+			query:          "operation=withdraw&amount=100",
+			mockErr:        sql.ErrNoRows,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  consts.ErrUserNotFound,
+		},
+		{
+			name:           consts.ErrInternalServer,
+			uid:            3,
+			query:          "operation=withdraw&amount=100",
+			mockErr:        errors.New(consts.ErrInternalServer),
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  consts.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			ctx.Params = gin.Params{
+				{Key: "uid", Value: strconv.FormatInt(tt.uid, 10)},
+			}
+
+			var err error
+			ctx.Request, err = http.NewRequest("GET", "/?"+tt.query, http.NoBody)
+			require.NoError(t, err)
+
+			if !tt.mockSkip {
+				var res *model.FeeQuote
+				if tt.mockErr == nil {
+					res = &model.FeeQuote{Operation: model.Withdraw, Amount: decimal.NewFromInt(100),
+						Fee: decimal.NewFromInt(1), Total: decimal.NewFromInt(101)}
+				}
+				mockService.On("Quote", ctx, tt.uid, mock.Anything, mock.Anything).Return(res, tt.mockErr)
+			}
+
+			walletCtrl.Quote(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			} else {
+				assert.Contains(t, w.Body.String(), `"total":"101"`)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for dry runs and quotes of WalletCtrl.Deposit, Withdraw and Transfer
+func TestWalletCtrl_DryRun(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	amount := decimal.NewFromInt(100)
+	dryRun := &model.DryRun{
+		FeeQuote: model.FeeQuote{Operation: model.Transfer, Amount: amount, Fee: decimal.NewFromInt(1),
+			Total: decimal.NewFromInt(101)},
+		Balance: decimal.NewFromInt(99),
+		QuoteID: "q1",
+	}
+
+	tests := []struct {
+		name           string
+		operation      string
+		query          string
+		body           string
+		mockDryRun     bool
+		quote          bool
+		mockDryRunErr  error
+		mockExecute    bool
+		mockExecuteErr error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Transfer dry run with a quote",
+			operation:      model.Transfer,
+			query:          "dry_run=true&quote=true",
+			body:           `{"to_uid":2,"amount":"100"}`,
+			mockDryRun:     true,
+			quote:          true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"quote_id":"q1"`,
+		},
+		{
+			name:           "Withdrawal dry run fails like the withdrawal",
+			operation:      model.Withdraw,
+			query:          "dry_run=true",
+			body:           `{"amount":"100"}`,
+			mockDryRun:     true,
+			mockDryRunErr:  fmt.Errorf("%w for uid 1", repository.ErrWalletFrozen),
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   consts.ErrWalletFrozen,
+		},
+		{
+			name:           "Deposit dry run",
+			operation:      model.Deposit,
+			query:          "dry_run=1",
+			body:           `{"amount":"100"}`,
+			mockDryRun:     true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"balance":"99"`,
+		},
+		{
+			name:           "Invalid dry run flag",
+			operation:      model.Withdraw,
+			query:          "dry_run=maybe",
+			body:           `{"amount":"100"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   consts.ErrValidationFailed,
+		},
+		{
+			name:           "Quote used",
+			operation:      model.Withdraw,
+			body:           `{"amount":"100","quote_id":"q1"}`,
+			mockExecute:    true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   consts.MsgSuccess,
+		},
+		{
+			name:           "Quote expired",
+			operation:      model.Transfer,
+			body:           `{"to_uid":2,"amount":"100","quote_id":"q1"}`,
+			mockExecute:    true,
+			mockExecuteErr: fmt.Errorf("%w: q1", repository.ErrQuoteExpired),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   consts.ErrInvalidQuote,
+		},
+		{
+			name:           "Quote of another movement",
+			operation:      model.Withdraw,
+			body:           `{"amount":"100","quote_id":"q1"}`,
+			mockExecute:    true,
+			mockExecuteErr: fmt.Errorf("%w: q1", service.ErrQuoteMismatch),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   consts.ErrInvalidQuote,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockWalletInter)
+			walletCtrl := NewWallet(mockService, nil, nil)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "1"}}
+
+			var err error
+			ctx.Request, err = http.NewRequest("POST", "/?"+tt.query, bytes.NewBufferString(tt.body))
+			require.NoError(t, err)
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			toUID := int64(0)
+			if tt.operation == model.Transfer {
+				toUID = 2
+			}
+
+			if tt.mockDryRun {
+				var res *model.DryRun
+				if tt.mockDryRunErr == nil {
+					res = dryRun
+				}
+				mockService.On("DryRun", ctx, tt.operation, int64(1), toUID, amount, tt.quote).
+					Return(res, tt.mockDryRunErr)
+			}
+
+			if tt.mockExecute {
+				if tt.operation == model.Transfer {
+					mockService.On("Transfer", ctx, int64(1), toUID, amount, "q1", model.TransactionDetails{}).
+						Return(tt.mockExecuteErr)
+				} else {
+					mockService.On("Withdraw", ctx, int64(1), amount, "q1", model.TransactionDetails{}).Return(tt.mockExecuteErr)
+				}
+			}
+
+			switch tt.operation {
+			case model.Deposit:
+				walletCtrl.Deposit(ctx)
+			case model.Withdraw:
+				walletCtrl.Withdraw(ctx)
+			default:
+				walletCtrl.Transfer(ctx)
+			}
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for the details of WalletCtrl.Transfer
+func TestWalletCtrl_TransferDetails(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	details := model.TransactionDetails{Memo: "rent", ExternalReference: "inv-42",
+		Metadata: json.RawMessage(`{"order":7}`)}
+
+	tests := []struct {
+		name           string
+		mockErr        error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "Stored with the transfer",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid details",
+			mockErr:        fmt.Errorf("%w: metadata has more than 16 keys", service.ErrInvalidDetails),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrInvalidDetails,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockWalletInter)
+			walletCtrl := NewWallet(mockService, nil, nil)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "1"}}
+
+			body := `{"to_uid":2,"amount":"10","memo":"rent","external_reference":"inv-42","metadata":{"order":7}}`
+			ctx.Request = httptest.NewRequest("POST", "/", bytes.NewBufferString(body))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			mockService.On("Transfer", ctx, int64(1), int64(2), mock.MatchedBy(func(amount decimal.Decimal) bool {
+				return amount.Equal(decimal.NewFromInt(10))
+			}), "", details).Return(tt.mockErr)
+
+			walletCtrl.Transfer(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Test cases for WalletCtrl.Transfer addressed by username, email or handle
+func TestWalletCtrl_TransferTo(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockResolve    error
+		mockSkip       bool
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "By handle",
+			body:           `{"to":"@alice_shop","amount":"10"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Both to_uid and to",
+			body:           `{"to_uid":2,"to":"alice","amount":"10"}`,
+			mockSkip:       true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrInvalidRecipient,
+		},
+		{
+			name:           "Unknown recipient",
+			body:           `{"to":"bob","amount":"10"}`,
+			mockResolve:    sql.ErrNoRows,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  consts.ErrRecipientNotFound,
+		},
+		{
+			name:           "Oneself",
+			body:           `{"to":"carol","amount":"10"}`,
+			mockResolve:    fmt.Errorf("%w: uid 1", service.ErrSelfTransfer),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  consts.ErrSelfTransfer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, mockRecipient := new(MockWalletInter), new(MockRecipientInter)
+			walletCtrl := NewWallet(mockService, nil, mockRecipient)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Params = gin.Params{{Key: "uid", Value: "1"}}
+			ctx.Request = httptest.NewRequest("POST", "/", bytes.NewBufferString(tt.body))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			if !tt.mockSkip {
+				mockRecipient.On("Resolve", ctx, int64(1), mock.Anything).
+					Return(&model.Recipient{UID: 2, Name: "a***e"}, tt.mockResolve)
+			}
+			if tt.expectedError == "" {
+				mockService.On("Transfer", ctx, int64(1), int64(2), mock.Anything, "", model.TransactionDetails{}).
+					Return(nil)
+			}
+
+			walletCtrl.Transfer(ctx)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+
+			mockService.AssertExpectations(t)
+			mockRecipient.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/pkg/consts"
+	"server/pkg/logger"
+)
+
+const keyAdmin = "admin"
+
+// Authenticator checks the credentials of a staff member.
+type Authenticator func(ctx context.Context, username, password string) (*model.User, error)
+
+// AdminAuth lets through staff members logging in with HTTP basic auth, and keeps them for
+// Admin. Users without a staff role are refused, as are all requests when enabled is false.
+func AdminAuth(enabled bool, authenticate Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !enabled {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": consts.ErrForbidden})
+			return
+		}
+
+		username, password, ok := ctx.Request.BasicAuth()
+		if !ok {
+			unauthorized(ctx, nil)
+			return
+		}
+
+		user, err := authenticate(ctx, username, password)
+		if err != nil {
+			unauthorized(ctx, err)
+			return
+		}
+
+		if !user.Role.IsStaff() {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": consts.ErrForbidden})
+			return
+		}
+
+		ctx.Set(keyAdmin, user)
+		repository.WithActor(ctx, Actor(ctx))
+		ctx.Next()
+	}
+}
+
+func unauthorized(ctx *gin.Context, err error) {
+	ctx.Header("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+
+	res := gin.H{"error": consts.ErrUnauthorized}
+	if err != nil {
+		res["details"] = err.Error()
+	}
+
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, res)
+}
+
+// Admin returns the staff member authenticated by AdminAuth, or nil outside of it.
+func Admin(ctx *gin.Context) *model.User {
+	user, _ := ctx.Value(keyAdmin).(*model.User)
+	return user
+}
+
+// Actor returns the staff member authenticated by AdminAuth as the actor of the audit log.
+func Actor(ctx *gin.Context) *model.Actor {
+	actor := &model.Actor{IP: ctx.ClientIP(), RequestID: GetRequestID(ctx)}
+	if user := Admin(ctx); user != nil {
+		actor.ID, actor.Role = user.ID, user.Role
+	}
+
+	return actor
+}
+
+// AuditActor records the client of each request as the actor of the changes it makes. AdminAuth
+// later replaces it by the authenticated staff member.
+func AuditActor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		repository.WithActor(ctx, Actor(ctx))
+		ctx.Next()
+	}
+}
+
+// RequireRole only lets through the staff members having one of roles. It must follow AdminAuth.
+func RequireRole(roles ...model.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := Admin(ctx)
+		if user == nil || !slices.Contains(roles, user.Role) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": consts.ErrForbidden})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// AdminAudit records every request to the admin API that got past AdminAuth, so that reads are
+// audited as well as changes: who called which route on which user, and how it ended. Only the
+// keys of the query are kept, since the values of a search are emails and usernames.
+func AdminAudit(audit repository.AuditInter, base *zap.SugaredLogger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if Admin(ctx) == nil {
+			return
+		}
+
+		entry := &model.AuditEntry{
+			Actor:  *Actor(ctx),
+			Action: model.AuditAdminRequest,
+			Entity: model.AuditEntityRoute,
+			After: gin.H{
+				"method": ctx.Request.Method,
+				"route":  ctx.FullPath(),
+				"params": ctx.Params,
+				"query":  queryKeys(ctx),
+				"status": ctx.Writer.Status(),
+			},
+		}
+		if status := ctx.Writer.Status(); status >= http.StatusBadRequest {
+			entry.Error = http.StatusText(status)
+		}
+
+		if err := audit.Record(ctx, entry); err != nil {
+			logger.FromContext(ctx, base).Errorw("record admin request failed", "route", ctx.FullPath(), "error", err)
+		}
+	}
+}
+
+// queryKeys returns the sorted keys of the query of the request, without their values.
+func queryKeys(ctx *gin.Context) []string {
+	keys := make([]string, 0)
+	for key := range ctx.Request.URL.Query() {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
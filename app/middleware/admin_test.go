@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+
+	"server/app/model"
+	"server/app/repository"
+)
+
+// staff authenticates "alice" as support and "bob" as a customer.
+func staff(_ context.Context, username, password string) (*model.User, error) {
+	if password != "secret" {
+		return nil, errors.New("invalid username or password")
+	}
+
+	switch username {
+	case "alice":
+		return &model.User{ID: 1, Username: username, Role: model.RoleSupport}, nil
+	case "bob":
+		return &model.User{ID: 2, Username: username}, nil
+	}
+
+	return nil, errors.New("invalid username or password")
+}
+
+// recordingAudit keeps the entries it is given.
+type recordingAudit struct {
+	entries []*model.AuditEntry
+}
+
+func (r *recordingAudit) Record(_ context.Context, entry *model.AuditEntry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func TestAdminAuth(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		enabled            bool
+		username, password string
+		role               model.Role
+		expectedStatus     int
+	}{
+		{"Staff", true, "alice", "secret", model.RoleSupport, http.StatusOK},
+		{"Role not allowed", true, "alice", "secret", model.RoleFinance, http.StatusForbidden},
+		{"Wrong password", true, "alice", "guess", model.RoleSupport, http.StatusUnauthorized},
+		{"Missing credentials", true, "", "", model.RoleSupport, http.StatusUnauthorized},
+		{"Not staff", true, "bob", "secret", model.RoleSupport, http.StatusForbidden},
+		{"Admin API disabled", false, "alice", "secret", model.RoleSupport, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/", AdminAuth(tt.enabled, staff), RequireRole(tt.role, model.RoleSuperadmin),
+				func(ctx *gin.Context) {
+					assert.Equal(t, "alice", Admin(ctx).Username)
+					ctx.Status(http.StatusOK)
+				})
+
+			req, err := http.NewRequest("GET", "/", http.NoBody)
+			require.NoError(t, err)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+			}
+		})
+	}
+}
+
+func TestAdminAudit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	audit := &recordingAudit{}
+	engine := gin.New()
+	engine.Use(RequestID())
+	admin := engine.Group("/admin", AdminAuth(true, staff), AdminAudit(audit, zap.NewNop().Sugar()))
+	admin.GET("/wallets/:uid", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	admin.GET("/users", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	admin.POST("/wallets/:uid/adjustments", RequireRole(model.RoleFinance), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	for _, target := range []string{
+		"GET /admin/wallets/7?x=1", "POST /admin/wallets/7/adjustments", "GET /admin/users?q=alice@example.com&limit=5",
+	} {
+		method, path, _ := strings.Cut(target, " ")
+		req, err := http.NewRequest(method, path, http.NoBody)
+		require.NoError(t, err)
+		req.SetBasicAuth("alice", "secret")
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// A failed login has no actor and is not recorded.
+	req, err := http.NewRequest("GET", "/admin/wallets/7", http.NoBody)
+	require.NoError(t, err)
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, audit.entries, 3)
+
+	read := audit.entries[0]
+	assert.Equal(t, int64(1), read.ID)
+	assert.Equal(t, model.RoleSupport, read.Role)
+	assert.NotEmpty(t, read.RequestID)
+	assert.Equal(t, model.AuditAdminRequest, read.Action)
+	assert.Equal(t, "/admin/wallets/:uid", read.After.(gin.H)["route"])
+	assert.Equal(t, []string{"x"}, read.After.(gin.H)["query"])
+	assert.Empty(t, read.Error)
+
+	denied := audit.entries[1]
+	assert.Equal(t, http.StatusForbidden, denied.After.(gin.H)["status"])
+	assert.Equal(t, "Forbidden", denied.Error)
+
+	// The search terms are not kept, only that there was a search.
+	search := audit.entries[2]
+	assert.Equal(t, []string{"limit", "q"}, search.After.(gin.H)["query"])
+	after, err := json.Marshal(search.After)
+	require.NoError(t, err)
+	assert.NotContains(t, string(after), "alice")
+}
+
+func TestAuditActor(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	var actors []model.Actor
+	keep := func(ctx *gin.Context) {
+		actors = append(actors, repository.ActorOf(ctx))
+		ctx.Next()
+	}
+
+	engine := gin.New()
+	engine.Use(RequestID(), AuditActor(), keep)
+	engine.GET("/admin", AdminAuth(true, staff), keep, func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/admin", http.NoBody)
+	req.Header.Set(HeaderRequestID, "req-1")
+	req.SetBasicAuth("alice", "secret")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, actors, 2)
+	assert.Equal(t, model.Actor{IP: "192.0.2.1", RequestID: "req-1"}, actors[0])
+	assert.Equal(t, model.Actor{ID: 1, Role: model.RoleSupport, IP: "192.0.2.1", RequestID: "req-1"}, actors[1])
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"server/pkg/logger"
+)
+
+// Logger scopes base to the request with its ID, route and UID, makes it available to every layer
+// through the request context, and writes one access log line when the request completes.
+// It must run after RequestID.
+func Logger(base *zap.SugaredLogger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		fields := []any{"request_id", GetRequestID(ctx), "method", ctx.Request.Method, "route", ctx.FullPath()}
+		if uid := ctx.Param("uid"); uid != "" {
+			fields = append(fields, "uid", uid)
+		}
+
+		scoped := base.With(fields...)
+		ctx.Request = ctx.Request.WithContext(logger.WithContext(ctx.Request.Context(), scoped))
+
+		ctx.Next()
+
+		access := []any{
+			"status", ctx.Writer.Status(),
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", ctx.ClientIP(),
+			"bytes", ctx.Writer.Size(),
+		}
+		if len(ctx.Errors) > 0 {
+			access = append(access, "errors", ctx.Errors.String())
+		}
+
+		switch status := ctx.Writer.Status(); {
+		case status >= 500:
+			scoped.Errorw("request", access...)
+		case status >= 400:
+			scoped.Warnw("request", access...)
+		default:
+			scoped.Infow("request", access...)
+		}
+	}
+}
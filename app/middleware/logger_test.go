@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"server/pkg/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		header    string
+		propagate bool
+	}{
+		{"Propagated", "abc-123", true},
+		{"Missing", "", false},
+		{"Malformed", "bad id\nwith newline", false},
+		{"Too long", string(make([]byte, 65)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			engine := gin.New()
+			engine.GET("/", RequestID(), func(ctx *gin.Context) {
+				seen = GetRequestID(ctx)
+				ctx.Status(http.StatusOK)
+			})
+
+			req, err := http.NewRequest("GET", "/", http.NoBody)
+			require.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set(HeaderRequestID, tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, seen, w.Header().Get(HeaderRequestID))
+			if tt.propagate {
+				assert.Equal(t, tt.header, seen)
+			} else {
+				assert.NotEqual(t, tt.header, seen)
+				assert.Len(t, seen, 36)
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	base := zap.New(core).Sugar()
+
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(RequestID(), Logger(base))
+	engine.GET("/api/wallets/:uid/balance", func(ctx *gin.Context) {
+		logger.FromContext(ctx, zap.NewNop().Sugar()).Infow("query balance")
+		ctx.Status(http.StatusNotFound)
+	})
+
+	req, err := http.NewRequest("GET", "/api/wallets/7/balance", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set(HeaderRequestID, "req-1")
+
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+
+	handler := entries[0].ContextMap()
+	assert.Equal(t, "query balance", entries[0].Message)
+	assert.Equal(t, "req-1", handler["request_id"])
+	assert.Equal(t, "/api/wallets/:uid/balance", handler["route"])
+	assert.Equal(t, "7", handler["uid"])
+
+	access := entries[1].ContextMap()
+	assert.Equal(t, "request", entries[1].Message)
+	assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+	assert.Equal(t, "req-1", access["request_id"])
+	assert.Equal(t, int64(http.StatusNotFound), access["status"])
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"server/pkg/metrics"
+)
+
+const routeUnmatched = "unmatched"
+
+// Metrics observes the latency and status of every request, labelled by the route pattern
+// rather than the raw path so that path parameters do not create new series.
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = routeUnmatched
+		}
+
+		metrics.HTTPRequestDuration.
+			WithLabelValues(ctx.Request.Method, route, strconv.Itoa(ctx.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"server/pkg/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(Metrics())
+	engine.GET("/api/wallets/:uid/balance", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/api/wallets/1/balance", "/api/wallets/2/balance", "/nowhere"} {
+		req, err := http.NewRequest("GET", path, http.NoBody)
+		require.NoError(t, err)
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.HTTPRequestDuration))
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/metrics", http.NoBody)
+	require.NoError(t, err)
+	metrics.Handler().ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body,
+		`wallet_http_request_duration_seconds_count{method="GET",route="/api/wallets/:uid/balance",status="200"} 2`)
+	assert.Contains(t, body,
+		`wallet_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+}
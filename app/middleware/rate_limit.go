@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"server/config"
+	"server/pkg/consts"
+	"server/pkg/logger"
+	"server/pkg/metrics"
+	"server/pkg/ratelimit"
+)
+
+const (
+	HeaderRetryAfter         = "Retry-After"
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+)
+
+// Scopes of the rate limits, also used as the metrics label.
+const (
+	scopeRoute = "route"
+	scopeIP    = "ip"
+	scopeUID   = "uid"
+)
+
+type rateLimit struct {
+	scope string
+	key   string
+	rate  config.RateConf
+}
+
+// RateLimit throttles requests per client IP, per wallet uid in the path and per route and
+// client IP, with the limits of runtime.rate_limit read on every request so they can be
+// reloaded. The client IP comes from ctx.ClientIP, so only http.trusted_proxies may set it.
+// If the limiter fails the request is let through.
+func RateLimit(limiter ratelimit.Limiter, base *zap.SugaredLogger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		conf := config.Runtime().RateLimit
+		if !conf.Enabled {
+			ctx.Next()
+			return
+		}
+
+		ip := ctx.ClientIP()
+		route := ctx.Request.Method + " " + ctx.FullPath()
+
+		limits := []rateLimit{
+			{scopeRoute, scopeRoute + ":" + route + ":" + ip, conf.Routes[route]},
+			{scopeIP, scopeIP + ":" + ip, conf.IP},
+		}
+		if uid := ctx.Param("uid"); uid != "" {
+			limits = append(limits, rateLimit{scopeUID, scopeUID + ":" + uid, conf.UID})
+		}
+
+		for _, l := range limits {
+			if l.rate.Limit <= 0 {
+				continue
+			}
+
+			res, err := limiter.Allow(ctx.Request.Context(), l.key, l.rate.Limit, l.rate.Window)
+			if err != nil {
+				logger.FromContext(ctx.Request.Context(), base).Errorw("rate limit failed", "scope", l.scope, "error", err)
+				continue
+			}
+
+			ctx.Header(HeaderRateLimitLimit, strconv.Itoa(l.rate.Limit))
+			ctx.Header(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+
+			if !res.Allowed {
+				metrics.RateLimited.WithLabelValues(l.scope).Inc()
+				ctx.Header(HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(res.RetryAfter)))
+				ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error":   consts.ErrTooManyRequests,
+					"details": "rate limit exceeded for " + l.scope,
+				})
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// retryAfterSeconds rounds d up to whole seconds, at least one, as Retry-After requires.
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
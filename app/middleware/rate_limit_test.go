@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server/config"
+	"server/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+type brokenLimiter struct{}
+
+func (brokenLimiter) Allow(context.Context, string, int, time.Duration) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("redis: connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { config.SetRuntime(nil) })
+
+	minute := config.RateConf{Limit: 2, Window: time.Minute}
+
+	tests := []struct {
+		name           string
+		conf           config.RateLimitConf
+		limiter        ratelimit.Limiter
+		paths          []string
+		expectedStatus []int
+		expectedScope  string
+	}{
+		{
+			name:           "Disabled",
+			conf:           config.RateLimitConf{IP: config.RateConf{Limit: 1, Window: time.Minute}},
+			paths:          []string{"/wallets/1", "/wallets/1"},
+			expectedStatus: []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:           "Per IP",
+			conf:           config.RateLimitConf{Enabled: true, IP: minute},
+			paths:          []string{"/wallets/1", "/wallets/2", "/wallets/3"},
+			expectedStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			expectedScope:  "ip",
+		},
+		{
+			name:           "Per UID",
+			conf:           config.RateLimitConf{Enabled: true, UID: minute},
+			paths:          []string{"/wallets/1", "/wallets/2", "/wallets/1", "/wallets/1"},
+			expectedStatus: []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			expectedScope:  "uid",
+		},
+		{
+			name: "Per route",
+			conf: config.RateLimitConf{Enabled: true, Routes: map[string]config.RateConf{
+				"GET /wallets/:uid": {Limit: 1, Window: time.Minute},
+			}},
+			paths:          []string{"/users", "/wallets/1", "/users", "/wallets/2"},
+			expectedStatus: []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			expectedScope:  "route",
+		},
+		{
+			name:           "Limiter failure lets requests through",
+			conf:           config.RateLimitConf{Enabled: true, IP: config.RateConf{Limit: 1, Window: time.Minute}},
+			limiter:        brokenLimiter{},
+			paths:          []string{"/wallets/1", "/wallets/1"},
+			expectedStatus: []int{http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.SetRuntime(&config.RuntimeConf{RateLimit: tt.conf})
+
+			limiter := tt.limiter
+			if limiter == nil {
+				limiter = ratelimit.NewMemory()
+			}
+
+			engine := gin.New()
+			group := engine.Group("", RateLimit(limiter, zap.NewNop().Sugar()))
+			ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+			group.GET("/users", ok)
+			group.GET("/wallets/:uid", ok)
+
+			var w *httptest.ResponseRecorder
+			for i, path := range tt.paths {
+				req, err := http.NewRequest(http.MethodGet, path, http.NoBody)
+				require.NoError(t, err)
+				req.RemoteAddr = "192.0.2.1:1234"
+
+				w = httptest.NewRecorder()
+				engine.ServeHTTP(w, req)
+				assert.Equal(t, tt.expectedStatus[i], w.Code, path)
+			}
+
+			if tt.expectedScope != "" {
+				assert.Equal(t, "60", w.Header().Get(HeaderRetryAfter))
+				assert.Equal(t, "0", w.Header().Get(HeaderRateLimitRemaining))
+				assert.Contains(t, w.Body.String(), "rate limit exceeded for "+tt.expectedScope)
+			}
+		})
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	assert.Equal(t, 1, retryAfterSeconds(0))
+	assert.Equal(t, 1, retryAfterSeconds(300*time.Millisecond))
+	assert.Equal(t, 2, retryAfterSeconds(1500*time.Millisecond))
+	assert.Equal(t, 60, retryAfterSeconds(time.Minute))
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderRequestID = "X-Request-ID"
+	keyRequestID    = "request_id"
+)
+
+// requestIDPattern bounds what is accepted from clients, so a request ID is always safe to log and echo.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID propagates the client's X-Request-ID, or assigns a new one when it is missing or malformed,
+// and echoes it in the response.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(HeaderRequestID)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		ctx.Set(keyRequestID, id)
+		ctx.Header(HeaderRequestID, id)
+
+		ctx.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned by RequestID, or "" outside of it.
+func GetRequestID(ctx *gin.Context) string {
+	return ctx.GetString(keyRequestID)
+}
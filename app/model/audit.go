@@ -0,0 +1,148 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Actor is who performs an action, as recorded in the audit log. The zero Actor is the system.
+type Actor struct {
+	ID        int64  `json:"actor_id"` // Foreign key to User.ID
+	Role      Role   `json:"actor_role"`
+	IP        string `json:"ip,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// AuditEntry records one action: who did what to which entity, why, and the entity before
+// and after it.
+type AuditEntry struct {
+	Actor
+	Action    string    `json:"action"`
+	Entity    string    `json:"entity"`
+	EntityID  int64     `json:"entity_id"`
+	Before    any       `json:"before,omitempty"`
+	After     any       `json:"after,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Ticket    string    `json:"ticket,omitempty"`
+	Error     string    `json:"error,omitempty"` // why the action failed, empty when it succeeded
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Entities of audit entries.
+const (
+	AuditEntityUser   = "user"
+	AuditEntityWallet = "wallet"
+	AuditEntityRoute  = "route"
+)
+
+// IsAuditEntity reports whether entity is one the audit log records.
+func IsAuditEntity(entity string) bool {
+	return entity == AuditEntityUser || entity == AuditEntityWallet || entity == AuditEntityRoute
+}
+
+// Actions of audit entries.
+const (
+	AuditAdminRequest     = "admin.request"
+	AuditUserRegister     = "user.register"
+	AuditUserUpdate       = "user.update"
+	AuditUserRole         = "user.role"
+	AuditUserClose        = "user.close"
+	AuditWalletDeposit    = "wallet.deposit"
+	AuditWalletWithdraw   = "wallet.withdraw"
+	AuditWalletTransfer   = "wallet.transfer"
+	AuditWalletFreeze     = "wallet.freeze"
+	AuditWalletDebitBlock = "wallet.debit_block"
+	AuditWalletUnfreeze   = "wallet.unfreeze"
+	AuditWalletAdjust     = "wallet.adjust"
+	AuditWalletClose      = "wallet.close"
+)
+
+// AuditRecord is an audit entry as stored in the audit log. Before and After hold the json they
+// were written as, and Hash chains the record to the one before it.
+type AuditRecord struct {
+	ID int64 `json:"id"`
+	AuditEntry
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// ComputeHash returns the hash of r: the sha256 of its PrevHash and of every field it was
+// written with, so that changing, removing or reordering records breaks the chain.
+func (r *AuditRecord) ComputeHash() (string, error) {
+	before, err := auditJSON(r.Before)
+	if err != nil {
+		return "", err
+	}
+
+	after, err := auditJSON(r.After)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := json.Marshal([]any{
+		r.PrevHash, r.Actor.ID, int(r.Actor.Role), r.IP, r.RequestID, r.Action, r.Entity, r.EntityID,
+		before, after, r.Reason, r.Ticket, r.Error, r.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// auditJSON returns v as the json stored in the audit log, nil standing for NULL.
+func auditJSON(v any) (json.RawMessage, error) {
+	switch raw := v.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return raw, nil
+	}
+
+	return json.Marshal(v)
+}
+
+// AuditVerification is the outcome of checking the hash chain of the audit log.
+type AuditVerification struct {
+	Checked  int64  `json:"checked"`
+	Valid    bool   `json:"valid"`
+	BrokenID int64  `json:"broken_id,omitempty"` // first record whose hash does not match
+	LastHash string `json:"last_hash,omitempty"`
+}
+
+const TableNameAuditLog = `t_audit_log`
+
+const ColumnAuditLog = `id, actor_id, actor_role, ip, request_id, action, entity, entity_id, before, after,
+		reason, ticket, error, created_at, prev_hash, hash`
+
+// QueryAuditLock serialises the appends to the audit log until the end of the transaction, so
+// that every record chains to the one committed just before it.
+const QueryAuditLock = `SELECT pg_advisory_xact_lock(hashtext('` + TableNameAuditLog + `'))`
+
+const QueryAuditLastHash = `SELECT hash FROM ` + TableNameAuditLog + ` ORDER BY id DESC LIMIT 1`
+
+const QueryAuditInsert = `INSERT INTO ` + TableNameAuditLog + `
+		(actor_id, actor_role, ip, request_id, action, entity, entity_id, before, after,
+		reason, ticket, error, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id`
+
+// QueryAuditChain returns the records following the one with id $1 in chain order, $2 at a time.
+const QueryAuditChain = `SELECT ` + ColumnAuditLog + ` FROM ` + TableNameAuditLog + `
+		WHERE id > $1 ORDER BY id ASC LIMIT $2`
+
+// SelectListAudit is the base of audit log listings; filters and paging are appended by the
+// repository through sqlbuilder.
+const SelectListAudit = `SELECT ` + ColumnAuditLog + ` FROM ` + TableNameAuditLog
+
+// Filter fragments for audit log listings, with "?" placeholders for sqlbuilder.
+const (
+	WhereAuditEntity   = `entity = ?`
+	WhereAuditEntityID = `entity_id = ?`
+	WhereAuditAction   = `action = ?`
+	WhereAuditActorID  = `actor_id = ?`
+	OrderAuditIDDesc   = `id DESC`
+)
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestAuditRecord_ComputeHash(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	newRecord := func() *AuditRecord {
+		return &AuditRecord{
+			AuditEntry: AuditEntry{
+				Actor:     Actor{ID: 9, Role: RoleSupport, IP: "10.0.0.1", RequestID: "req-1"},
+				Action:    AuditWalletFreeze,
+				Entity:    AuditEntityWallet,
+				EntityID:  1,
+				Before:    map[string]int{"status": 1},
+				After:     json.RawMessage(`{"status":2}`),
+				Reason:    "chargeback",
+				Ticket:    "SUP-1",
+				CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC),
+			},
+			PrevHash: "abc",
+		}
+	}
+
+	hash, err := newRecord().ComputeHash()
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	t.Run("Same content as read back", func(t *testing.T) {
+		rec := newRecord()
+		rec.Before = json.RawMessage(`{"status":1}`)
+		rec.CreatedAt = rec.CreatedAt.In(time.FixedZone("", 3600))
+
+		got, err := rec.ComputeHash()
+		require.NoError(t, err)
+		assert.Equal(t, hash, got)
+	})
+
+	changes := map[string]func(r *AuditRecord){
+		"prev hash": func(r *AuditRecord) { r.PrevHash = "abd" },
+		"actor":     func(r *AuditRecord) { r.Actor.ID = 8 },
+		"after":     func(r *AuditRecord) { r.After = json.RawMessage(`{"status":1}`) },
+		"no before": func(r *AuditRecord) { r.Before = nil },
+		"reason":    func(r *AuditRecord) { r.Reason = "mistake" },
+		"time":      func(r *AuditRecord) { r.CreatedAt = r.CreatedAt.Add(time.Microsecond) },
+	}
+
+	for name, change := range changes {
+		t.Run("Changed "+name, func(t *testing.T) {
+			rec := newRecord()
+			change(rec)
+
+			got, err := rec.ComputeHash()
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, got)
+		})
+	}
+}
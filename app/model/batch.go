@@ -0,0 +1,138 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxBatchItems is the most transfers a batch may hold.
+const MaxBatchItems = 1000
+
+// BatchMode decides what happens to a batch when one of its transfers fails.
+type BatchMode uint8
+
+const (
+	_ BatchMode = iota
+	BatchModeAtomic
+	BatchModeBestEffort
+)
+
+func (m BatchMode) IsValid() bool {
+	return m == BatchModeAtomic || m == BatchModeBestEffort
+}
+
+type BatchStatus uint8
+
+const (
+	_ BatchStatus = iota
+	BatchStatusPending
+	BatchStatusRunning
+	BatchStatusCompleted
+	BatchStatusFailed
+	BatchStatusPartial
+)
+
+type BatchItemStatus uint8
+
+const (
+	_ BatchItemStatus = iota
+	BatchItemStatusPending
+	BatchItemStatusSucceeded
+	BatchItemStatusFailed
+	BatchItemStatusDuplicate
+)
+
+// Batch pays out from the wallet of UID to many receivers. An atomic batch makes all of its
+// transfers in one transaction or none of them; a best-effort one makes each on its own.
+type Batch struct {
+	ID        int64           `db:"id" json:"id"`
+	UID       int64           `db:"uid" json:"uid"`       // the funding wallet
+	Mode      BatchMode       `db:"mode" json:"mode"`     // 1-atomic, 2-best-effort
+	Status    BatchStatus     `db:"status" json:"status"` // 1-pending, 2-running, 3-completed, 4-failed, 5-partially completed
+	ItemCount int             `db:"item_count" json:"item_count"`
+	Total     decimal.Decimal `db:"total" json:"total"` // sum of the amounts, without fees
+	Succeeded int             `db:"succeeded" json:"succeeded"`
+	Failed    int             `db:"failed" json:"failed"`
+	Error     string          `db:"error" json:"error,omitempty"` // why an atomic batch failed
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time       `db:"updated_at" json:"updated_at"`
+	Items     []*BatchItem    `json:"items,omitempty"`
+}
+
+// BatchItem is one transfer of a batch. A Reference is paid at most once from a funding wallet,
+// across all of its batches: a transfer whose reference was already paid is a duplicate and is
+// skipped.
+type BatchItem struct {
+	ID        int64           `db:"id" json:"id"`
+	BatchID   int64           `db:"batch_id" json:"-"`
+	Seq       int             `db:"seq" json:"seq"` // position in the batch, from 0
+	ToUID     int64           `db:"to_uid" json:"to_uid"`
+	Amount    decimal.Decimal `db:"amount" json:"amount"`
+	Reference string          `db:"reference" json:"reference,omitempty"`
+	Status    BatchItemStatus `db:"status" json:"status"` // 1-pending, 2-succeeded, 3-failed, 4-duplicate
+	Error     string          `db:"error" json:"error,omitempty"`
+	UpdatedAt time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+const TableNameBatch = `t_batch`
+const TableNameBatchItem = `t_batch_item`
+
+const ListColumnBatch = `id, uid, mode, status, item_count, total, succeeded, failed, error, created_at, updated_at`
+const ListColumnBatchItem = `id, batch_id, seq, to_uid, amount, reference, status, error, updated_at`
+
+const QueryInsertBatch = `INSERT INTO ` + TableNameBatch + ` (uid, mode, status, item_count, total)
+		VALUES ($1, $2, 1, $3, $4) RETURNING id, status, created_at, updated_at`
+
+const QueryInsertBatchItem = `INSERT INTO ` + TableNameBatchItem + ` (batch_id, uid, seq, to_uid, amount, reference, status)
+		VALUES ($1, $2, $3, $4, $5, $6, 1) RETURNING id, status, updated_at`
+
+const QueryGetBatch = `SELECT ` + ListColumnBatch + ` FROM ` + TableNameBatch + ` WHERE id = $1 AND uid = $2`
+
+const QueryListBatchItems = `SELECT ` + ListColumnBatchItem + ` FROM ` + TableNameBatchItem + ` WHERE batch_id = $1 ORDER BY seq`
+
+const QueryStartBatch = `UPDATE ` + TableNameBatch + ` SET status = 2, updated_at = NOW() WHERE id = $1 AND status = 1`
+
+// QueryFinishBatch stores the outcome of a running batch. A batch failed as stale in the
+// meantime is left as it is.
+const QueryFinishBatch = `UPDATE ` + TableNameBatch + ` SET status = $2, succeeded = $3, failed = $4, error = $5,
+		updated_at = NOW() WHERE id = $1 AND status = 2`
+
+// QueryFailPendingBatchItems fails the transfers of a batch that were never made.
+const QueryFailPendingBatchItems = `UPDATE ` + TableNameBatchItem + ` SET status = 3, error = $2, updated_at = NOW()
+		WHERE batch_id = $1 AND status = 1`
+
+// QuerySetBatchItemStatus stores the outcome of a transfer of a batch that is still pending.
+const QuerySetBatchItemStatus = `UPDATE ` + TableNameBatchItem + ` SET status = $2, error = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 1`
+
+// QueryStaleBatches locks the batches that are still pending or running but that neither they
+// nor their transfers have changed for $1 seconds: their run was interrupted.
+const QueryStaleBatches = `SELECT b.id FROM ` + TableNameBatch + ` AS b
+		WHERE b.status IN (1, 2) AND b.updated_at < NOW() - $1 * INTERVAL '1 second'
+		AND NOT EXISTS (SELECT 1 FROM ` + TableNameBatchItem + ` AS i
+			WHERE i.batch_id = b.id AND i.updated_at >= NOW() - $1 * INTERVAL '1 second')
+		ORDER BY b.id FOR UPDATE OF b SKIP LOCKED`
+
+// QueryCloseStaleBatch closes a stale batch with the outcome of its transfers, once those never
+// made are failed with the error $2.
+const QueryCloseStaleBatch = `UPDATE ` + TableNameBatch + ` AS b SET
+		status = CASE WHEN c.failed = 0 THEN 3 WHEN c.succeeded = 0 THEN 4 ELSE 5 END,
+		succeeded = c.succeeded, failed = c.failed, error = CASE WHEN c.failed = 0 THEN '' ELSE $2 END,
+		updated_at = NOW()
+		FROM (SELECT COUNT(*) FILTER (WHERE status = 2) AS succeeded, COUNT(*) FILTER (WHERE status = 3) AS failed
+			FROM ` + TableNameBatchItem + ` WHERE batch_id = $1) AS c
+		WHERE b.id = $1 AND b.status IN (1, 2)`
+
+// QueryBatchReferencePaid tells whether a transfer with the reference $2 was already paid from
+// the wallet of $1.
+const QueryBatchReferencePaid = `SELECT EXISTS (SELECT 1 FROM ` + TableNameBatchItem + `
+		WHERE uid = $1 AND reference = $2 AND status = 2)`
+
+// QueryBatchPaidReferences returns which of the references $2 were already paid from the wallet of $1.
+const QueryBatchPaidReferences = `SELECT reference FROM ` + TableNameBatchItem + `
+		WHERE uid = $1 AND reference = ANY($2) AND status = 2`
+
+// QueryMissingWallets returns which of the uids $1 have no wallet.
+const QueryMissingWallets = `SELECT u.uid FROM UNNEST($1::integer[]) AS u(uid)
+		WHERE NOT EXISTS (SELECT 1 FROM ` + TableNameWallet + ` AS w WHERE w.uid = u.uid)`
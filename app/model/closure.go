@@ -0,0 +1,43 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Closure is the outcome of closing the account of a user: the wallet is closed for good and the
+// personal data of the user is replaced, while the transactions stay for the retention period.
+type Closure struct {
+	UID      int64           `json:"uid"`
+	Settled  decimal.Decimal `json:"settled"` // balance withdrawn to settle the wallet before closing it
+	ClosedAt time.Time       `json:"closed_at"`
+}
+
+const (
+	anonymousUsernamePrefix = "closed-"
+	anonymousEmailDomain    = "@closed.invalid"
+)
+
+// AnonymousUser returns the user uid as it is left once its account is closed. The ".invalid"
+// top-level domain is reserved, so the email can never belong to anyone.
+func AnonymousUser(uid int64) *User {
+	return &User{
+		ID:       uid,
+		Username: fmt.Sprintf("%s%d", anonymousUsernamePrefix, uid),
+		Email:    fmt.Sprintf("%s%d%s", anonymousUsernamePrefix, uid, anonymousEmailDomain),
+		Status:   UserStatusDisabled,
+	}
+}
+
+// IsReservedUsername and IsReservedEmail report whether a new user would take a username or an
+// email kept for closed accounts, which would later stop its closure.
+func IsReservedUsername(username string) bool {
+	return strings.HasPrefix(strings.ToLower(username), anonymousUsernamePrefix)
+}
+
+func IsReservedEmail(email string) bool {
+	return strings.HasSuffix(strings.ToLower(email), anonymousEmailDomain)
+}
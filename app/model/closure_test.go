@@ -0,0 +1,20 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestAnonymousUser(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	user := AnonymousUser(42)
+	assert.Equal(t, &User{ID: 42, Username: "closed-42", Email: "closed-42@closed.invalid", Status: UserStatusDisabled}, user)
+	assert.True(t, IsReservedUsername(user.Username))
+	assert.True(t, IsReservedEmail(user.Email))
+	assert.True(t, IsReservedUsername("Closed-Alice"))
+	assert.False(t, IsReservedUsername("alice"))
+	assert.False(t, IsReservedEmail("alice@example.com"))
+}
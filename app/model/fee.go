@@ -0,0 +1,35 @@
+package model
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// FeeCharge is charged on a money movement on top of its amount and credited to the revenue wallet,
+// the wallet of RevenueUID. A zero Amount means no fee. QuoteID is the quote the fee was locked
+// in by, consumed together with the movement.
+type FeeCharge struct {
+	Amount     decimal.Decimal
+	RevenueUID int64
+	QuoteID    string
+}
+
+// IsCharged reports whether there is a fee to post.
+func (f FeeCharge) IsCharged() bool {
+	return f.Amount.IsPositive()
+}
+
+// FeeQuote is what a money movement costs, worked out before it is made.
+type FeeQuote struct {
+	Operation string          `json:"operation"`
+	Amount    decimal.Decimal `json:"amount"`
+	Fee       decimal.Decimal `json:"fee"`
+	Total     decimal.Decimal `json:"total"` // what leaves the wallet: the amount plus the fee
+}
+
+// QueryUserTier returns the pricing tier of the owner of a wallet.
+const QueryUserTier = `SELECT tier FROM ` + TableNameUser + ` WHERE id = $1`
+
+// QueryWalletCreditFee credits a fee to the revenue wallet. It is not bounded by the maximum
+// balance, so that a full revenue wallet never blocks the movements that pay into it.
+const QueryWalletCreditFee = `UPDATE ` + TableNameWallet + ` SET balance = balance + $1, updated_at = NOW()
+		WHERE uid = $2 AND ` + whereWalletCredit
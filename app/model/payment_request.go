@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type PaymentRequestStatus uint8
+
+const (
+	_ PaymentRequestStatus = iota
+	PaymentRequestStatusPending
+	PaymentRequestStatusPaid
+	PaymentRequestStatusDeclined
+	PaymentRequestStatusCancelled
+	PaymentRequestStatusExpired // never stored: a pending request past its expiry reads as expired
+)
+
+func (s PaymentRequestStatus) IsValid() bool {
+	return s >= PaymentRequestStatusPending && s <= PaymentRequestStatusExpired
+}
+
+// PaymentRequest asks the wallet of PayerUID for Amount, to be paid to the wallet of
+// RequesterUID by a transfer. Without a payer, the request is open: anyone holding its token can
+// pay it, and PayerUID is set to whoever does.
+type PaymentRequest struct {
+	ID            int64                `db:"id" json:"id"`
+	RequesterUID  int64                `db:"requester_uid" json:"requester_uid"`
+	RequesterName string               `db:"username" json:"requester,omitempty"` // only set when looked up by token
+	PayerUID      int64                `db:"payer_uid" json:"payer_uid"`
+	Amount        decimal.Decimal      `db:"amount" json:"amount"`
+	Memo          string               `db:"memo" json:"memo,omitempty"`
+	Status        PaymentRequestStatus `db:"status" json:"status"` // 1-pending, 2-paid, 3-declined, 4-cancelled, 5-expired
+	Token         string               `json:"token,omitempty"`    // only returned when the request is created
+	TokenHash     string               `db:"token_hash" json:"-"`
+	ExpiresAt     time.Time            `db:"expires_at" json:"expires_at"`
+	CreatedAt     time.Time            `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time            `db:"updated_at" json:"updated_at"`
+}
+
+// PaymentRequestView is a payment request as shown to whoever holds its token, who is told the
+// name of the requester but none of the uids behind it.
+type PaymentRequestView struct {
+	ID            int64                `json:"id"`
+	RequesterName string               `json:"requester"`
+	Amount        decimal.Decimal      `json:"amount"`
+	Memo          string               `json:"memo,omitempty"`
+	Status        PaymentRequestStatus `json:"status"`
+	ExpiresAt     time.Time            `json:"expires_at"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+// View returns the payment request as shown to whoever holds its token.
+func (p *PaymentRequest) View() *PaymentRequestView {
+	return &PaymentRequestView{
+		ID:            p.ID,
+		RequesterName: p.RequesterName,
+		Amount:        p.Amount,
+		Memo:          p.Memo,
+		Status:        p.Status,
+		ExpiresAt:     p.ExpiresAt,
+		CreatedAt:     p.CreatedAt,
+	}
+}
+
+// PaymentClaim is the payment request of ID that a transfer pays, to be marked as paid by the
+// wallet of PayerUID along with it.
+type PaymentClaim struct {
+	ID       int64
+	PayerUID int64
+}
+
+const TableNamePaymentRequest = `t_payment_request`
+
+// ListColumnPaymentRequest reads a pending request past its expiry as expired.
+const ListColumnPaymentRequest = `r.id, r.requester_uid, r.payer_uid, r.amount, r.memo,
+		CASE WHEN r.status = 1 AND r.expires_at <= NOW() THEN 5 ELSE r.status END AS status,
+		r.token_hash, r.expires_at, r.created_at, r.updated_at`
+
+const QueryInsertPaymentRequest = `INSERT INTO ` + TableNamePaymentRequest + `
+		(requester_uid, payer_uid, amount, memo, token_hash, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, 1, $6) RETURNING id, status, created_at, updated_at`
+
+const QueryGetPaymentRequest = `SELECT ` + ListColumnPaymentRequest + ` FROM ` + TableNamePaymentRequest + ` AS r
+		WHERE r.id = $1`
+
+const QueryGetPaymentRequestByToken = `SELECT ` + ListColumnPaymentRequest + `, u.username
+		FROM ` + TableNamePaymentRequest + ` AS r JOIN ` + TableNameUser + ` AS u ON u.id = r.requester_uid
+		WHERE r.token_hash = $1`
+
+// QueryListPaymentRequests lists the requests made by ($2 = 'outgoing') or to ($2 = 'incoming')
+// the wallet of $1, or both, with the status $3 unless it is 0.
+const QueryListPaymentRequests = `SELECT * FROM (SELECT ` + ListColumnPaymentRequest + `
+		FROM ` + TableNamePaymentRequest + ` AS r
+		WHERE (r.requester_uid = $1 AND $2 <> 'incoming') OR (r.payer_uid = $1 AND $2 <> 'outgoing')) AS l
+		WHERE $3 = 0 OR l.status = $3 ORDER BY l.id DESC LIMIT $4 OFFSET $5`
+
+// QueryClaimPaymentRequest marks a pending request as paid by $2, in the DB transaction of the
+// transfer that pays it, so that it is paid at most once.
+const QueryClaimPaymentRequest = `UPDATE ` + TableNamePaymentRequest + ` SET status = 2, payer_uid = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 1 AND expires_at > NOW()`
+
+const QueryClosePaymentRequest = `UPDATE ` + TableNamePaymentRequest + ` SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 1 AND expires_at > NOW()`
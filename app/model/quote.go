@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Quote locks in the fee a dry run worked out for a withdrawal or a transfer until ExpiresAt.
+// It is consumed by the movement it was issued for, which must match it exactly.
+type Quote struct {
+	ID         string          `db:"id" json:"id"`
+	Operation  string          `db:"operation" json:"operation"`
+	UID        int64           `db:"uid" json:"uid"`
+	ToUID      int64           `db:"to_uid" json:"to_uid"` // 0 for a withdrawal
+	Amount     decimal.Decimal `db:"amount" json:"amount"`
+	Fee        decimal.Decimal `db:"fee" json:"fee"`
+	RevenueUID int64           `db:"revenue_uid" json:"-"`
+	ExpiresAt  time.Time       `db:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
+}
+
+// DryRun is the outcome of a money movement worked out without making it: what it costs and
+// the balances it leaves. QuoteID, when set, locks in its fee until ExpiresAt.
+type DryRun struct {
+	FeeQuote
+	Balance         decimal.Decimal  `json:"balance"`                    // of the wallet of the request
+	ReceiverBalance *decimal.Decimal `json:"receiver_balance,omitempty"` // of the receiver of a transfer
+	QuoteID         string           `json:"quote_id,omitempty"`
+	ExpiresAt       *time.Time       `json:"expires_at,omitempty"`
+}
+
+const TableNameQuote = `t_quote`
+const ListColumnQuote = `id, operation, uid, to_uid, amount, fee, revenue_uid, expires_at, created_at`
+
+// QueryInsertQuote stores a quote that expires $8 milliseconds from now. Expiry is on the clock of
+// the database, like the checks of QueryGetQuote and QueryConsumeQuote.
+const QueryInsertQuote = `INSERT INTO ` + TableNameQuote + ` (id, operation, uid, to_uid, amount, fee, revenue_uid, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW() + $8 * INTERVAL '1 millisecond') RETURNING expires_at, created_at`
+
+// QueryGetQuote returns a quote that has not expired yet.
+const QueryGetQuote = `SELECT ` + ListColumnQuote + ` FROM ` + TableNameQuote + ` WHERE id = $1 AND expires_at > NOW()`
+
+// QueryConsumeQuote deletes a quote that has not expired yet, so that it is only ever used once.
+const QueryConsumeQuote = `DELETE FROM ` + TableNameQuote + ` WHERE id = $1 AND expires_at > NOW()`
+
+// QueryDeleteExpiredQuotes clears the quotes that can no longer be used.
+const QueryDeleteExpiredQuotes = `DELETE FROM ` + TableNameQuote + ` WHERE expires_at <= NOW()`
@@ -0,0 +1,10 @@
+package model
+
+// Recipient is the wallet a transfer is addressed to, as shown to the sender before confirming
+// it. Name is the username of its owner, masked. UID is kept out of the preview, since it would
+// reveal the user behind the address.
+type Recipient struct {
+	UID    int64  `json:"-"`
+	Name   string `json:"name"`
+	Handle string `json:"handle,omitempty"`
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type DiscrepancyStatus int8
+
+const (
+	DiscrepancyStatusOpen     DiscrepancyStatus = 1
+	DiscrepancyStatusResolved DiscrepancyStatus = 2
+)
+
+func (s DiscrepancyStatus) IsValid() bool {
+	return s == DiscrepancyStatusOpen || s == DiscrepancyStatusResolved
+}
+
+// Discrepancy records a wallet whose stored balance differs from the net of its transactions.
+// A wallet has at most one open discrepancy; it is updated on every run while the mismatch
+// persists and resolved by the first run that finds the two balances equal again.
+type Discrepancy struct {
+	ID            int64             `db:"id" json:"id"`
+	UID           int64             `db:"uid" json:"uid"` // Foreign key to User.ID
+	WalletBalance decimal.Decimal   `db:"wallet_balance" json:"wallet_balance"`
+	LedgerBalance decimal.Decimal   `db:"ledger_balance" json:"ledger_balance"`
+	Difference    decimal.Decimal   `db:"difference" json:"difference"` // wallet_balance - ledger_balance
+	Status        DiscrepancyStatus `db:"status" json:"status"`         // 1-open, 2-resolved
+	DetectedAt    time.Time         `db:"detected_at" json:"detected_at"`
+	CheckedAt     time.Time         `db:"checked_at" json:"checked_at"`
+	ResolvedAt    *time.Time        `db:"resolved_at" json:"resolved_at,omitempty"`
+}
+
+const TableNameDiscrepancy = `t_reconcile_discrepancy`
+const ListColumnDiscrepancy = `id, uid, wallet_balance, ledger_balance, difference, status, detected_at, checked_at, resolved_at`
+
+// QueryReconcile recomputes every wallet's balance from its full history in a single statement,
+// so the wallet balances and the transactions are read from the same snapshot.
+// Open discrepancies that no longer mismatch are resolved, the remaining mismatches are upserted,
+// and the open discrepancies are returned.
+const QueryReconcile = `WITH ledger AS (
+			SELECT w.uid, w.balance AS wallet_balance,
+				COALESCE(SUM(CASE WHEN t.receiver_wallet_id = w.uid THEN t.amount ELSE 0 END), 0)
+				- COALESCE(SUM(CASE WHEN t.sender_wallet_id = w.uid THEN t.amount ELSE 0 END), 0) AS ledger_balance
+			FROM t_wallet AS w
+			LEFT JOIN ` + TableNameTransaction + ` AS t ON t.sender_wallet_id = w.uid OR t.receiver_wallet_id = w.uid
+			GROUP BY w.uid, w.balance
+		), mismatch AS (
+			SELECT uid, wallet_balance, ledger_balance FROM ledger WHERE wallet_balance <> ledger_balance
+		), resolved AS (
+			UPDATE ` + TableNameDiscrepancy + ` AS d SET status = 2, resolved_at = NOW(), checked_at = NOW()
+			WHERE d.status = 1 AND NOT EXISTS (SELECT 1 FROM mismatch AS m WHERE m.uid = d.uid)
+		)
+		INSERT INTO ` + TableNameDiscrepancy + ` (uid, wallet_balance, ledger_balance, difference, status, detected_at, checked_at)
+		SELECT uid, wallet_balance, ledger_balance, wallet_balance - ledger_balance, 1, NOW(), NOW() FROM mismatch
+		ON CONFLICT (uid) WHERE status = 1 DO UPDATE SET
+			wallet_balance = EXCLUDED.wallet_balance,
+			ledger_balance = EXCLUDED.ledger_balance,
+			difference = EXCLUDED.difference,
+			checked_at = EXCLUDED.checked_at
+		RETURNING ` + ListColumnDiscrepancy
+
+const QueryListDiscrepancy = `SELECT ` + ListColumnDiscrepancy + ` FROM ` + TableNameDiscrepancy + `
+		WHERE status = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
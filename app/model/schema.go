@@ -0,0 +1,9 @@
+package model
+
+// SchemaVersion is the version of config/ddl.sql this code expects.
+// Bump it together with the version inserted at the end of the ddl.
+const SchemaVersion = 10
+
+const TableNameSchemaVersion = `t_schema_version`
+
+const QuerySchemaVersion = `SELECT COALESCE(MAX(version), 0) FROM ` + TableNameSchemaVersion
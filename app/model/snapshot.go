@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BalanceSnapshot is a wallet's closing balance at the end of a day,
+// i.e. the net of every transaction created before the following midnight.
+type BalanceSnapshot struct {
+	ID        int64           `db:"id" json:"id"`
+	UID       int64           `db:"uid" json:"uid"` // Foreign key to User.ID
+	Day       time.Time       `db:"day" json:"day"`
+	Balance   decimal.Decimal `db:"balance" json:"balance"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// DailyBalance is one point of a balance time series.
+type DailyBalance struct {
+	Day     time.Time       `json:"day"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+const TableNameBalanceSnapshot = `t_balance_snapshot`
+
+// QueryBalanceSnapshotInsert snapshots every wallet for day $1, starting from each wallet's
+// latest earlier snapshot and adding the transactions since. Existing snapshots are kept.
+const QueryBalanceSnapshotInsert = `INSERT INTO ` + TableNameBalanceSnapshot + ` (uid, day, balance)
+		SELECT w.uid, $1::date, COALESCE(p.balance, 0) + COALESCE(SUM(
+			CASE WHEN t.receiver_wallet_id = w.uid THEN t.amount ELSE 0 END -
+			CASE WHEN t.sender_wallet_id = w.uid THEN t.amount ELSE 0 END
+		), 0)
+		FROM ` + TableNameWallet + ` AS w
+		LEFT JOIN LATERAL (
+			SELECT s.day, s.balance FROM ` + TableNameBalanceSnapshot + ` AS s
+			WHERE s.uid = w.uid AND s.day < $1::date ORDER BY s.day DESC LIMIT 1
+		) AS p ON TRUE
+		LEFT JOIN ` + TableNameTransaction + ` AS t
+			ON (t.sender_wallet_id = w.uid OR t.receiver_wallet_id = w.uid)
+			AND t.created_at >= COALESCE((p.day + 1)::timestamp, '-infinity'::timestamp)
+			AND t.created_at < ($1::date + 1)::timestamp
+		GROUP BY w.uid, p.balance
+		ON CONFLICT (uid, day) DO NOTHING`
+
+// QueryDailyBalance returns the closing balance of uid for every day from $2 to $3,
+// each built from the nearest snapshot plus the transactions after it.
+const QueryDailyBalance = `SELECT g.day::date, COALESCE(p.balance, 0) + COALESCE((
+			SELECT SUM(
+				CASE WHEN t.receiver_wallet_id = $1 THEN t.amount ELSE 0 END -
+				CASE WHEN t.sender_wallet_id = $1 THEN t.amount ELSE 0 END
+			) FROM ` + TableNameTransaction + ` AS t
+			WHERE (t.sender_wallet_id = $1 OR t.receiver_wallet_id = $1)
+				AND t.created_at >= COALESCE((p.day + 1)::timestamp, '-infinity'::timestamp)
+				AND t.created_at < g.day + INTERVAL '1 day'
+		), 0)
+		FROM generate_series($2::timestamp, $3::timestamp, INTERVAL '1 day') AS g(day)
+		LEFT JOIN LATERAL (
+			SELECT s.day, s.balance FROM ` + TableNameBalanceSnapshot + ` AS s
+			WHERE s.uid = $1 AND s.day <= g.day::date ORDER BY s.day DESC LIMIT 1
+		) AS p ON TRUE
+		ORDER BY g.day`
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -12,8 +13,18 @@ type Transaction struct {
 	SenderWalletID   int64           `db:"sender_wallet_id" json:"sender_wallet_id"`     // Foreign key to Wallet.ID
 	ReceiverWalletID int64           `db:"receiver_wallet_id" json:"receiver_wallet_id"` // Foreign key to Wallet.ID, can be null
 	Amount           decimal.Decimal `db:"amount" json:"amount"`
-	TransactionType  TransactionType `db:"transaction_type" json:"transaction_type"` // 1-deposit, 2-withdraw, 3-transfer
+	TransactionType  TransactionType `db:"transaction_type" json:"transaction_type"` // 1-deposit, 2-withdraw, 3-transfer, 4-adjustment, 5-fee
+	ParentID         int64           `db:"parent_id" json:"parent_id,omitempty"`     // The transaction a fee was charged on
 	CreatedAt        time.Time       `db:"created_at" json:"created_at"`
+	TransactionDetails
+}
+
+// TransactionDetails is what a client attaches to a deposit, a withdrawal or a transfer: a memo,
+// the reference of the movement in its own system and a small JSON object of metadata.
+type TransactionDetails struct {
+	Memo              string          `db:"memo" json:"memo,omitempty"`
+	ExternalReference string          `db:"external_reference" json:"external_reference,omitempty"`
+	Metadata          json.RawMessage `db:"metadata" json:"metadata,omitempty"`
 }
 
 type TransactionWithUsername struct {
@@ -25,36 +36,108 @@ type TransactionWithUsername struct {
 
 const TableNameTransaction = `t_transaction`
 const ListColumnTransaction = `t.id, t.sender_wallet_id, COALESCE(s.username, '') AS sender_username, 
-		t.receiver_wallet_id, COALESCE(r.username, '') AS receiver_username, amount, t.transaction_type, t.created_at`
+		t.receiver_wallet_id, COALESCE(r.username, '') AS receiver_username, amount, t.transaction_type, t.created_at, t.parent_id,
+		t.memo, t.external_reference, COALESCE(t.metadata::text, '') AS metadata`
+
+// QueryInsertTransaction records a transaction with its details; an empty metadata $7 is stored
+// as NULL.
 const QueryInsertTransaction = `INSERT INTO ` + TableNameTransaction + `
-    (sender_wallet_id, receiver_wallet_id, amount, transaction_type, created_at) 
-					VALUES ($1, $2, $3, $4, NOW())`
-const LogInsertTransaction = `INSERT INTO ` + TableNameTransaction + ` 
-    (sender_wallet_id, receiver_wallet_id, amount, transaction_type, created_at) 
-					VALUES (%d, %d, %v, %d, NOW())`
+    (sender_wallet_id, receiver_wallet_id, amount, transaction_type, memo, external_reference, metadata, created_at) 
+					VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::jsonb, NOW())`
 
-const QueryListTransaction = `SELECT ` + ListColumnTransaction + ` FROM ` + TableNameTransaction + ` AS t
-		LEFT JOIN t_user AS s ON t.sender_wallet_id = s.id
-		LEFT JOIN t_user AS r ON t.receiver_wallet_id = r.id
-		WHERE 
- 			(t.sender_wallet_id = $1 OR t.receiver_wallet_id = $1) 
-			AND (
-        		NOT ($2::smallint BETWEEN $3::smallint AND $4::smallint) 
-        		OR (t.transaction_type = $2::smallint)
-    		)
-		ORDER BY t.id DESC
-		LIMIT $5 OFFSET $6`
-const LogListTransaction = `SELECT ` + ListColumnTransaction + ` FROM ` + TableNameTransaction + ` AS t
+// QueryInsertTransactionID records a transaction that fees are linked to and returns its id.
+const QueryInsertTransactionID = QueryInsertTransaction + ` RETURNING id`
+
+// QueryInsertLinkedTransaction records a transaction linked to the transaction $5, such as the fee
+// charged on it.
+const QueryInsertLinkedTransaction = `INSERT INTO ` + TableNameTransaction + `
+    (sender_wallet_id, receiver_wallet_id, amount, transaction_type, parent_id, created_at)
+					VALUES ($1, $2, $3, $4, $5, NOW())`
+
+// SelectListTransaction is the base of every transaction listing; filters, ordering and
+// paging are appended by the repository through sqlbuilder.
+const SelectListTransaction = `SELECT ` + ListColumnTransaction + ` FROM ` + TableNameTransaction + ` AS t
 		LEFT JOIN t_user AS s ON t.sender_wallet_id = s.id
-		LEFT JOIN t_user AS r ON t.receiver_wallet_id = r.id
-		WHERE 
-			(t.sender_wallet_id = %d OR t.receiver_wallet_id = %d)
-			AND (
-        		NOT (%d::smallint BETWEEN %d::smallint AND %d::smallint) 
-				OR (t.transaction_type = %d::smallint)
-    		)
-		ORDER BY t.id DESC
-		LIMIT %d OFFSET %d`
+		LEFT JOIN t_user AS r ON t.receiver_wallet_id = r.id`
+
+// Filter fragments for transaction listings, with "?" placeholders for sqlbuilder.
+const (
+	WhereTransactionUID          = `t.sender_wallet_id = ? OR t.receiver_wallet_id = ?`
+	WhereTransactionIncoming     = `t.receiver_wallet_id = ?`
+	WhereTransactionOutgoing     = `t.sender_wallet_id = ?`
+	WhereTransactionFrom         = `t.created_at >= ?::timestamp`
+	WhereTransactionTo           = `t.created_at < ?::timestamp`
+	WhereTransactionMinAmount    = `t.amount >= ?`
+	WhereTransactionMaxAmount    = `t.amount <= ?`
+	WhereTransactionBefore       = `(t.created_at, t.id) < (?::timestamp, ?)`
+	WhereTransactionAfter        = `(t.created_at, t.id) > (?::timestamp, ?)`
+	ColumnTransactionType        = `t.transaction_type`
+	WhereTransactionCounterparty = `(t.sender_wallet_id = ? AND t.receiver_wallet_id = ?)
+			OR (t.receiver_wallet_id = ? AND t.sender_wallet_id = ?)`
+	WhereTransactionCounterpartyName = `(t.sender_wallet_id = ? AND r.username = ?)
+			OR (t.receiver_wallet_id = ? AND s.username = ?)`
+	WhereTransactionExternalReference = `t.external_reference = ?`
+)
+
+const (
+	OrderTransactionCreatedAtDesc = `t.created_at DESC, t.id DESC`
+	OrderTransactionCreatedAtAsc  = `t.created_at ASC, t.id ASC`
+	OrderTransactionAmountDesc    = `t.amount DESC, t.id DESC`
+	OrderTransactionAmountAsc     = `t.amount ASC, t.id ASC`
+)
+
+// Sort orders accepted by transaction listings.
+const (
+	SortCreatedAtDesc = "created_at_desc"
+	SortCreatedAtAsc  = "created_at_asc"
+	SortAmountDesc    = "amount_desc"
+	SortAmountAsc     = "amount_asc"
+)
+
+// Money flow relative to the wallet being listed.
+const (
+	FlowIncoming = "incoming"
+	FlowOutgoing = "outgoing"
+)
+
+var transactionSortMap = map[string]string{
+	"":                OrderTransactionCreatedAtDesc,
+	SortCreatedAtDesc: OrderTransactionCreatedAtDesc,
+	SortCreatedAtAsc:  OrderTransactionCreatedAtAsc,
+	SortAmountDesc:    OrderTransactionAmountDesc,
+	SortAmountAsc:     OrderTransactionAmountAsc,
+}
+
+// GetTransactionSortOrder returns the ORDER BY terms for a sort name.
+// If the sort name does not exist, it returns an empty string.
+func GetTransactionSortOrder(sort string) string {
+	str, ok := transactionSortMap[sort]
+	if !ok {
+		return ""
+	}
+
+	return str
+}
+
+// IsCursorSort reports whether a sort order is keyed by (created_at, id) and can be paged by cursor.
+func IsCursorSort(sort string) bool {
+	return sort == "" || sort == SortCreatedAtDesc || sort == SortCreatedAtAsc
+}
+
+// QueryTransactionBalanceAt returns a wallet's balance at a point in time: the latest balance
+// snapshot that closed before it plus the transactions since. Without a snapshot the whole
+// history is netted. A transfer to oneself counts as both incoming and outgoing and nets to zero.
+const QueryTransactionBalanceAt = `WITH p AS (
+			SELECT (day + 1)::timestamp AS since, balance FROM ` + TableNameBalanceSnapshot + `
+			WHERE uid = $1 AND (day + 1)::timestamp <= $2::timestamp ORDER BY day DESC LIMIT 1
+		)
+		SELECT COALESCE((SELECT balance FROM p), 0) + COALESCE(SUM(
+			CASE WHEN receiver_wallet_id = $1 THEN amount ELSE 0 END -
+			CASE WHEN sender_wallet_id = $1 THEN amount ELSE 0 END
+		), 0) FROM ` + TableNameTransaction + `
+		WHERE (sender_wallet_id = $1 OR receiver_wallet_id = $1)
+			AND created_at >= COALESCE((SELECT since FROM p), '-infinity'::timestamp)
+			AND created_at < $2::timestamp`
 
 // TransactionType represents the type of transaction
 type TransactionType uint8
@@ -64,18 +147,38 @@ const (
 	TransactionTypeDeposit
 	TransactionTypeWithdraw
 	TransactionTypeTransfer
+	TransactionTypeAdjustment
+	TransactionTypeFee
 )
 
 const (
-	Deposit  = "deposit"
-	Withdraw = "withdraw"
-	Transfer = "transfer"
+	Deposit    = "deposit"
+	Withdraw   = "withdraw"
+	Transfer   = "transfer"
+	Adjustment = "adjustment"
+	Fee        = "fee"
 )
 
 var transactionTypeMap = map[TransactionType]string{
-	TransactionTypeDeposit:  Deposit,
-	TransactionTypeWithdraw: Withdraw,
-	TransactionTypeTransfer: Transfer,
+	TransactionTypeDeposit:    Deposit,
+	TransactionTypeWithdraw:   Withdraw,
+	TransactionTypeTransfer:   Transfer,
+	TransactionTypeAdjustment: Adjustment,
+	TransactionTypeFee:        Fee,
+}
+
+// SignedAmount returns the amount as seen from the wallet of uid: positive when money
+// comes in, negative when it goes out and zero for a transfer to oneself.
+func (t *Transaction) SignedAmount(uid int64) decimal.Decimal {
+	amount := decimal.Zero
+	if t.ReceiverWalletID == uid {
+		amount = amount.Add(t.Amount)
+	}
+	if t.SenderWalletID == uid {
+		amount = amount.Sub(t.Amount)
+	}
+
+	return amount
 }
 
 // GetTransactionTypeString returns the string representation of the TransactionType
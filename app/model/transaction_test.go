@@ -1,6 +1,7 @@
 package model
 
 import (
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/goleak"
 
@@ -18,7 +19,9 @@ func TestTransactionTypeString(t *testing.T) {
 		{"Deposit", TransactionTypeDeposit, Deposit},
 		{"Withdraw", TransactionTypeWithdraw, Withdraw},
 		{"Transfer", TransactionTypeTransfer, Transfer},
-		{"Unknown", 4, ""},
+		{"Adjustment", TransactionTypeAdjustment, Adjustment},
+		{"Fee", TransactionTypeFee, Fee},
+		{"Unknown", 6, ""},
 	}
 
 	for _, tt := range tests {
@@ -28,3 +31,50 @@ func TestTransactionTypeString(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTransactionSortOrder(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		name     string
+		sort     string
+		expected string
+		cursor   bool
+	}{
+		{"Default", "", OrderTransactionCreatedAtDesc, true},
+		{"CreatedAtAsc", SortCreatedAtAsc, OrderTransactionCreatedAtAsc, true},
+		{"AmountDesc", SortAmountDesc, OrderTransactionAmountDesc, false},
+		{"Unknown", "id; DROP TABLE t_user", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GetTransactionSortOrder(tt.sort))
+			assert.Equal(t, tt.cursor, IsCursorSort(tt.sort))
+		})
+	}
+}
+
+func TestTransaction_SignedAmount(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	amount := decimal.NewFromInt(5)
+
+	tests := []struct {
+		name     string
+		mod      Transaction
+		expected decimal.Decimal
+	}{
+		{"Deposit", Transaction{SenderWalletID: 0, ReceiverWalletID: 1, Amount: amount}, amount},
+		{"Withdraw", Transaction{SenderWalletID: 1, ReceiverWalletID: 0, Amount: amount}, amount.Neg()},
+		{"TransferOut", Transaction{SenderWalletID: 1, ReceiverWalletID: 2, Amount: amount}, amount.Neg()},
+		{"TransferIn", Transaction{SenderWalletID: 2, ReceiverWalletID: 1, Amount: amount}, amount},
+		{"Self", Transaction{SenderWalletID: 1, ReceiverWalletID: 1, Amount: amount}, decimal.Zero},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.expected.Equal(tt.mod.SignedAmount(1)))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -11,6 +12,7 @@ type User struct {
 	Email        string     `db:"email" json:"email"`
 	PasswordHash []byte     `db:"password_hash" json:"-"`
 	Status       UserStatus `db:"status" json:"status"` // 1-Valid, 2-Invalid, 3-Disabled
+	Role         Role       `db:"role" json:"role,omitempty"`
 	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
 }
@@ -26,21 +28,107 @@ const (
 	UserStatusDisabled
 )
 
+// Role grants a staff member access to the admin API. Customers have RoleNone.
+type Role uint8
+
+const (
+	RoleNone Role = iota
+	RoleSupport
+	RoleFinance
+	RoleSuperadmin
+)
+
+var roleNames = map[Role]string{
+	RoleNone:       "none",
+	RoleSupport:    "support",
+	RoleFinance:    "finance",
+	RoleSuperadmin: "superadmin",
+}
+
+// ParseRole returns the role called name.
+func ParseRole(name string) (Role, error) {
+	for role, n := range roleNames {
+		if n == name {
+			return role, nil
+		}
+	}
+
+	return RoleNone, fmt.Errorf("unknown role %q", name)
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("role(%d)", r)
+}
+
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Role) UnmarshalText(text []byte) error {
+	role, err := ParseRole(string(text))
+	if err != nil {
+		return err
+	}
+
+	*r = role
+
+	return nil
+}
+
+// UnmarshalParam parses a role given as a query parameter.
+func (r *Role) UnmarshalParam(param string) error {
+	return r.UnmarshalText([]byte(param))
+}
+
+// IsStaff reports whether r may use the admin API at all.
+func (r Role) IsStaff() bool {
+	return r == RoleSupport || r == RoleFinance || r == RoleSuperadmin
+}
+
 const FirstColumnUser = `id, username, email, status, created_at, updated_at`
 
 const QueryUserInsert = `INSERT INTO ` + TableNameUser + `(username, email, password_hash) VALUES($1, $2, $3) RETURNING id`
-const LogUserInsert = `INSERT INTO ` + TableNameUser + `(username, email, password_hash) VALUES(%s, %s, %s) RETURNING id`
 
 const QueryUserUpdate = `UPDATE ` + TableNameUser + ` SET username=$1, email=$2 WHERE id=$3`
-const LogUserUpdate = `UPDATE ` + TableNameUser + ` SET username='%s', email='%s' WHERE id=%d`
 
 const QueryUserBy = `SELECT ` + FirstColumnUser + ` FROM ` + TableNameUser + ` WHERE`
-const LogUserByField = QueryUserBy + ` %s = %v`
 
 const QueryUserByID = QueryUserBy + ` id = $1`
 const QueryUserByUsername = QueryUserBy + ` username = $1`
 const QueryUserByEmail = QueryUserBy + ` email = $1`
 
+// QueryUserCredentials returns what the admin API needs to authenticate a staff member.
+const QueryUserCredentials = `SELECT id, username, email, password_hash, status, role FROM ` + TableNameUser +
+	` WHERE username = $1`
+
+// QueryUserRoleUpdate changes the role of a user and returns the role it had.
+const QueryUserRoleUpdate = `UPDATE ` + TableNameUser + ` AS u SET role = $1, updated_at = NOW()
+		FROM (SELECT id, role FROM ` + TableNameUser + ` WHERE id = $2 FOR UPDATE) AS old
+		WHERE u.id = old.id RETURNING old.role`
+
+// QueryUserAnonymise replaces the personal data and the credentials of a user with $2 and $3,
+// disables it and returns what it replaced.
+const QueryUserAnonymise = `UPDATE ` + TableNameUser + ` AS u SET username = $2, email = $3, password_hash = '',
+		status = 3, role = 0, updated_at = NOW()
+		FROM (SELECT id, username, email, status FROM ` + TableNameUser + ` WHERE id = $1 FOR UPDATE) AS old
+		WHERE u.id = old.id RETURNING old.username, old.email, old.status`
+
+// SelectSearchUser is the base of the admin user search; filters and paging are appended by the
+// repository through sqlbuilder.
+const SelectSearchUser = `SELECT ` + FirstColumnUser + `, role FROM ` + TableNameUser
+
+// Filter fragments for the admin user search, with "?" placeholders for sqlbuilder.
+const (
+	WhereUserID      = `id = ?`
+	WhereUserPattern = `username ILIKE ? OR email ILIKE ?`
+	WhereUserRole    = `role = ?`
+	OrderUserID      = `id ASC`
+)
+
 var QueryByFieldMap = map[string]string{
 	"id":       QueryUserByID,
 	"username": QueryUserByUsername,
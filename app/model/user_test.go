@@ -29,3 +29,39 @@ func TestGetQueryByField(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRole(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		name     string
+		expected Role
+		wantErr  bool
+	}{
+		{"none", RoleNone, false},
+		{"support", RoleSupport, false},
+		{"finance", RoleFinance, false},
+		{"superadmin", RoleSuperadmin, false},
+		{"owner", RoleNone, true},
+		{"", RoleNone, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := ParseRole(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRole(%q) error = %v; wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if role != tt.expected {
+				t.Errorf("ParseRole(%q) = %v; want %v", tt.name, role, tt.expected)
+			}
+			if !tt.wantErr && role.String() != tt.name {
+				t.Errorf("%v.String() = %q; want %q", role, role.String(), tt.name)
+			}
+		})
+	}
+
+	if RoleNone.IsStaff() || !RoleSupport.IsStaff() || Role(9).IsStaff() {
+		t.Error("IsStaff must only hold for the staff roles")
+	}
+}
@@ -1,8 +1,11 @@
 package model
 
 import (
+	"regexp"
 	"time"
 
+	"server/config"
+
 	"github.com/shopspring/decimal"
 )
 
@@ -11,39 +14,125 @@ type Wallet struct {
 	ID        int64           `db:"id" json:"id"`
 	UID       int64           `db:"uid" json:"uid"` // Foreign key to User.ID
 	Balance   decimal.Decimal `db:"balance" json:"balance"`
+	Status    WalletStatus    `db:"status" json:"status"` // 1-active, 2-frozen, 3-debit-blocked, 4-closed
+	Handle    string          `db:"handle" json:"handle,omitempty"`
 	CreatedAt time.Time       `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time       `db:"updated_at" json:"updated_at"`
 }
 
 const TableNameWallet = `t_wallet`
 
+// WalletStatus decides whether money may move in and out of a wallet.
+type WalletStatus uint8
+
+const (
+	_ WalletStatus = iota
+	WalletStatusActive
+	WalletStatusFrozen
+	WalletStatusDebitBlocked
+	WalletStatusClosed
+)
+
+// CanCredit reports whether money may come into a wallet of status s.
+func (s WalletStatus) CanCredit() bool {
+	return s == WalletStatusActive || s == WalletStatusDebitBlocked
+}
+
+// CanDebit reports whether money may leave a wallet of status s.
+func (s WalletStatus) CanDebit() bool {
+	return s == WalletStatusActive
+}
+
+// IsOpen reports whether a wallet of status s still exists for its user. Only manual
+// adjustments reach the balance of an open wallet that can't be credited or debited.
+func (s WalletStatus) IsOpen() bool {
+	return s >= WalletStatusActive && s < WalletStatusClosed
+}
+
+// HandlePrefix marks a transfer address as a wallet handle rather than a username.
+const HandlePrefix = "@"
+
+// handlePattern is what a wallet handle may look like: 3 to 32 lowercase letters, digits or
+// underscores.
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,32}$`)
+
+// IsValidHandle reports whether handle can be given to a wallet.
+func IsValidHandle(handle string) bool {
+	return handlePattern.MatchString(handle)
+}
+
 const (
 	MinBalance = 0
 	MaxBalance = 1000000
 )
 
-const FirstColumnWallet = `id, uid, balance, created_at, updated_at`
+// BalanceLimit returns the maximum balance of a wallet: runtime.limits.max_balance when set,
+// MaxBalance otherwise.
+func BalanceLimit() int64 {
+	if limit := config.Runtime().Limits.MaxBalance; limit > 0 {
+		return limit
+	}
+
+	return MaxBalance
+}
 
-const QueryWalletByField = `SELECT ` + FirstColumnWallet + ` FROM ` + TableNameWallet + ` WHERE $2 = $3`
-const LogWalletByField = `SELECT ` + FirstColumnWallet + ` FROM ` + TableNameWallet + ` WHERE %s = %v`
+const FirstColumnWallet = `id, uid, balance, status, COALESCE(handle, '') AS handle, created_at, updated_at`
+
+const QueryWalletByUID = `SELECT ` + FirstColumnWallet + ` FROM ` + TableNameWallet + ` WHERE uid = $1`
+
+const QueryWalletByHandle = `SELECT ` + FirstColumnWallet + ` FROM ` + TableNameWallet + ` WHERE handle = $1`
+
+// QueryWalletSetHandle gives a wallet that is not closed the handle $1.
+const QueryWalletSetHandle = `UPDATE ` + TableNameWallet + ` SET handle = $1, updated_at = NOW()
+		WHERE uid = $2 AND status <> 4`
+
+const QueryWalletStatus = `SELECT status FROM ` + TableNameWallet + ` WHERE uid = $1`
+
+// QueryWalletSetStatus changes the status of a wallet unless it is closed, which is final.
+const QueryWalletSetStatus = `UPDATE ` + TableNameWallet + ` SET status = $1, updated_at = NOW()
+		WHERE uid = $2 AND status <> 4`
 
 const QueryWalletBalance = `SELECT balance FROM ` + TableNameWallet + ` WHERE uid = $1`
-const LogWalletBalance = `SELECT balance FROM ` + TableNameWallet + ` WHERE uid = %d`
+
+// QueryLockWallets locks the rows of the wallets of the uids $1 for the rest of a transaction and
+// returns their balances. Every money movement that writes more than one wallet locks them this
+// way first, always in uid order, so that no two movements can deadlock.
+const QueryLockWallets = `SELECT uid, balance FROM ` + TableNameWallet + ` WHERE uid = ANY($1) ORDER BY uid FOR UPDATE`
 
 const QueryWalletInsert = `INSERT INTO ` + TableNameWallet + ` (uid, balance) VALUES($1, $2) RETURNING id`
-const LogWalletInert = `INSERT INTO ` + TableNameWallet + ` (uid, balance) VALUES(%d, %v) RETURNING id`
-
-const QueryWalletDeposit = `UPDATE ` + TableNameWallet +
-	` SET balance = balance + $1, updated_at = NOW() WHERE uid = $2 AND balance + $1 <= $3`
-const LogWalletDeposit = `UPDATE ` + TableNameWallet +
-	` SET balance = balance + %v, updated_at = NOW() WHERE uid = %d AND balance + %v <= %d`
-
-const QueryWalletWithdraw = `UPDATE ` + TableNameWallet + ` SET balance = balance - $1, updated_at = NOW() 
-		WHERE uid = $2 AND balance - $1 >= $3`
-const LogWalletWithdraw = `UPDATE ` + TableNameWallet + ` SET balance = balance - %v, updated_at = NOW() 
-		WHERE uid = %d AND balance - %v >= %d`
-
-const QueryWalletTransfer = `UPDATE ` + TableNameWallet + ` SET balance = balance + $1, updated_at = NOW() 
-			WHERE uid = $2 AND balance + $1 < $3`
-const LogWalletTransfer = `UPDATE ` + TableNameWallet + ` SET balance = balance + %v, updated_at = NOW() 
-			WHERE uid = %d AND balance + %v < %d`
+
+// The guarded updates take the fencing token of the wallet lock as $4 and refuse a token older
+// than the last one written, so a holder whose lock expired can't overwrite a newer holder.
+// A token of 0 means the write is not fenced. The status conditions match WalletStatus.CanCredit,
+// CanDebit and IsOpen.
+const (
+	setWalletFence    = `fence_token = GREATEST(fence_token, $4)`
+	whereWalletFence  = `($4 = 0 OR fence_token <= $4)`
+	whereWalletCredit = `status IN (1, 3)`
+	whereWalletDebit  = `status = 1`
+	whereWalletOpen   = `status IN (1, 2, 3)`
+)
+
+const QueryWalletDeposit = `UPDATE ` + TableNameWallet + ` SET balance = balance + $1, ` + setWalletFence + `,
+		updated_at = NOW() WHERE uid = $2 AND balance + $1 <= $3 AND ` + whereWalletFence + ` AND ` + whereWalletCredit
+
+const QueryWalletWithdraw = `UPDATE ` + TableNameWallet + ` SET balance = balance - $1, ` + setWalletFence + `,
+		updated_at = NOW() WHERE uid = $2 AND balance - $1 >= $3 AND ` + whereWalletFence + ` AND ` + whereWalletDebit
+
+const QueryWalletTransfer = `UPDATE ` + TableNameWallet + ` SET balance = balance + $1, ` + setWalletFence + `,
+		updated_at = NOW() WHERE uid = $2 AND balance + $1 < $3 AND ` + whereWalletFence + ` AND ` + whereWalletCredit
+
+// QueryWalletAdjust applies a manual adjustment of either sign to an open wallet, whether it is
+// frozen or not, as long as the balance stays between $5 and $3.
+const QueryWalletAdjust = `UPDATE ` + TableNameWallet + ` SET balance = balance + $1, ` + setWalletFence + `,
+		updated_at = NOW() WHERE uid = $2 AND balance + $1 <= $3 AND balance + $1 >= $5 AND ` + whereWalletFence + `
+		AND ` + whereWalletOpen
+
+// QueryWalletClose closes an active wallet for good, after withdrawing $1 from it, as long as
+// that leaves exactly $3. Its handle is freed.
+const QueryWalletClose = `UPDATE ` + TableNameWallet + ` SET balance = balance - $1, status = 4, ` + setWalletFence + `,
+		handle = NULL, updated_at = NOW() WHERE uid = $2 AND balance - $1 = $3 AND ` + whereWalletFence + ` AND ` + whereWalletDebit
+
+const QueryNextWalletFence = `SELECT nextval('wallet_fence_seq')`
+
+const QueryWalletTotals = `SELECT COALESCE(SUM(balance), 0), COUNT(*) FROM ` + TableNameWallet
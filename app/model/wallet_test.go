@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestWalletStatus(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		status                  WalletStatus
+		credit, debit, wantOpen bool
+	}{
+		{WalletStatusActive, true, true, true},
+		{WalletStatusFrozen, false, false, true},
+		{WalletStatusDebitBlocked, true, false, true},
+		{WalletStatusClosed, false, false, false},
+		{0, false, false, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.credit, tt.status.CanCredit(), "CanCredit of %d", tt.status)
+		assert.Equal(t, tt.debit, tt.status.CanDebit(), "CanDebit of %d", tt.status)
+		assert.Equal(t, tt.wantOpen, tt.status.IsOpen(), "IsOpen of %d", tt.status)
+	}
+}
+
+func TestIsValidHandle(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		handle string
+		valid  bool
+	}{
+		{"alice_shop", true},
+		{"abc", true},
+		{"ab", false},
+		{"Alice", false},
+		{"alice-shop", false},
+		{"@alice", false},
+		{"a23456789012345678901234567890123", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.valid, IsValidHandle(tt.handle), "IsValidHandle of %q", tt.handle)
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"server/app/model"
+	"server/app/request"
+	"server/pkg/logger"
+	"server/pkg/sqlbuilder"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func NewAdmin(db *sql.DB, logger *zap.SugaredLogger) AdminInter {
+	return &AdminRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// AdminInter holds the user queries only the admin API needs.
+type AdminInter interface {
+	Credentials(ctx context.Context, username string) (*model.User, error)
+	SearchUsers(ctx *gin.Context, req *request.ReqUserSearch) (*request.ResUsers, error)
+	SetRole(ctx context.Context, uid int64, role model.Role) (model.Role, error)
+}
+
+type AdminRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// Credentials returns the user called username with its password hash and role.
+func (a *AdminRepo) Credentials(ctx context.Context, username string) (*model.User, error) {
+	mod := &model.User{}
+
+	err := a.db.QueryRowContext(ctx, model.QueryUserCredentials, username).
+		Scan(&mod.ID, &mod.Username, &mod.Email, &mod.PasswordHash, &mod.Status, &mod.Role)
+	if err != nil {
+		return mod, err
+	}
+
+	return mod, nil
+}
+
+// SearchUsers lists the users matching req by id, a page at a time.
+func (a *AdminRepo) SearchUsers(ctx *gin.Context, req *request.ReqUserSearch) (*request.ResUsers, error) {
+	req.ValidatePageSize()
+	res := &request.ResUsers{List: []*model.User{}}
+
+	builder := sqlbuilder.New(model.SelectSearchUser)
+	if req.Query != "" {
+		pattern := "%" + escapeLike(req.Query) + "%"
+		builder.Where(model.WhereUserPattern, pattern, pattern)
+	}
+	if req.UID > 0 {
+		builder.Where(model.WhereUserID, req.UID)
+	}
+	if req.Role != model.RoleNone {
+		builder.Where(model.WhereUserRole, req.Role)
+	}
+
+	query, args := builder.OrderBy(model.OrderUserID).
+		Limit(req.PageSize + 1).
+		Offset((req.Page - 1) * req.PageSize).
+		Build()
+
+	a.log(ctx).Infow("search users", "uid", req.UID, "role", req.Role, "page", req.Page, "page_size", req.PageSize)
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		a.log(ctx).Errorw("search users failed", "error", err)
+		return res, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		mod := &model.User{}
+		err = rows.Scan(&mod.ID, &mod.Username, &mod.Email, &mod.Status, &mod.CreatedAt, &mod.UpdatedAt, &mod.Role)
+		if err != nil {
+			return res, fmt.Errorf("failed to scan row: %w", err)
+		}
+		res.List = append(res.List, mod)
+	}
+
+	if err = rows.Err(); err != nil {
+		return res, fmt.Errorf("rows error: %w", err)
+	}
+
+	if len(res.List) > req.PageSize {
+		res.List = res.List[:req.PageSize]
+		res.HasMore = true
+	}
+
+	return res, nil
+}
+
+// SetRole changes the role of uid and returns the role it had. It returns sql.ErrNoRows if there
+// is no such user.
+func (a *AdminRepo) SetRole(ctx context.Context, uid int64, role model.Role) (model.Role, error) {
+	a.log(ctx).Infow("set role", "uid", uid, "role", role)
+
+	var old model.Role
+	err := a.db.QueryRowContext(ctx, model.QueryUserRoleUpdate, role, uid).Scan(&old)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		a.log(ctx).Errorw("set role failed", "uid", uid, "error", err)
+	}
+
+	return old, err
+}
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (a *AdminRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, a.logger))
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match itself literally in a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
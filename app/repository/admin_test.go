@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestAdminRepo_Credentials(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAdmin(db, zap.NewExample().Sugar())
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserCredentials)).WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "status", "role"}).
+				AddRow(1, "alice", "alice@example.com", []byte("hash"), 1, 2))
+
+		user, err := repo.Credentials(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, model.RoleFinance, user.Role)
+		assert.Equal(t, []byte("hash"), user.PasswordHash)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserCredentials)).WithArgs("bob").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.Credentials(ctx, "bob")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAdminRepo_SearchUsers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAdmin(db, zap.NewExample().Sugar())
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	columns := []string{"id", "username", "email", "status", "created_at", "updated_at", "role"}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Filters and has more", func(t *testing.T) {
+		query := model.SelectSearchUser + " WHERE (username ILIKE $1 OR email ILIKE $2) AND (id = $3) AND (role = $4) " +
+			"ORDER BY " + model.OrderUserID + " LIMIT $5 OFFSET $6"
+		mock.ExpectQuery(regexp.QuoteMeta(query)).
+			WithArgs(`%50\%\_off%`, `%50\%\_off%`, int64(7), model.RoleSupport, 2, 0).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(7, "a", "a@example.com", 1, now, now, 1).
+				AddRow(8, "b", "b@example.com", 1, now, now, 1))
+
+		res, err := repo.SearchUsers(ctx, &request.ReqUserSearch{
+			Query: "50%_off", UID: 7, Role: model.RoleSupport,
+			ReqPage: request.ReqPage{Page: 1, PageSize: 1},
+		})
+		require.NoError(t, err)
+		require.Len(t, res.List, 1)
+		assert.True(t, res.HasMore)
+		assert.Equal(t, model.RoleSupport, res.List[0].Role)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.SelectSearchUser)).
+			WillReturnError(errors.New("query execution error"))
+
+		res, err := repo.SearchUsers(ctx, &request.ReqUserSearch{})
+		require.Error(t, err)
+		assert.Empty(t, res.List)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAdminRepo_SetRole(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAdmin(db, zap.NewExample().Sugar())
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserRoleUpdate)).WithArgs(model.RoleSuperadmin, int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(1))
+
+		old, err := repo.SetRole(ctx, 2, model.RoleSuperadmin)
+		require.NoError(t, err)
+		assert.Equal(t, model.RoleSupport, old)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserRoleUpdate)).WithArgs(model.RoleNone, int64(3)).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.SetRole(ctx, 3, model.RoleNone)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"server/app/model"
+	"server/app/request"
+	"server/pkg/logger"
+	"server/pkg/sqlbuilder"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const ctxKeyActor = "repository.actor"
+
+// auditChainBatch is how many records Verify reads at a time.
+const auditChainBatch = 1000
+
+// WithActor makes actor the one recorded for the changes of the rest of the request.
+func WithActor(ctx *gin.Context, actor *model.Actor) {
+	ctx.Set(ctxKeyActor, actor)
+}
+
+// ActorOf returns the actor set for the request of ctx by WithActor, or the system outside of one.
+func ActorOf(ctx context.Context) model.Actor {
+	if actor, ok := ctx.Value(ctxKeyActor).(*model.Actor); ok && actor != nil {
+		return *actor
+	}
+
+	return model.Actor{}
+}
+
+// AuditInter records audit entries.
+type AuditInter interface {
+	Record(ctx context.Context, entry *model.AuditEntry) error
+}
+
+// AuditLogInter is the audit log kept in the database, which can also be listed and verified.
+type AuditLogInter interface {
+	AuditInter
+	ListAudit(ctx *gin.Context, req *request.ReqAuditLog) (*request.ResAuditLog, error)
+	VerifyAudit(ctx context.Context) (*model.AuditVerification, error)
+}
+
+// NewAudit keeps audit entries in the append-only audit log table, each chained to the one
+// before it by its hash.
+func NewAudit(db *sql.DB, logger *zap.SugaredLogger) AuditLogInter {
+	return &AuditRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type AuditRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// Record appends entry to the audit log. Appends are serialised so that the hash of each record
+// covers the hash of the one committed before it.
+func (a *AuditRepo) Record(ctx context.Context, entry *model.AuditEntry) (err error) {
+	// The database keeps microseconds, and the hash must cover the time as it is read back.
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+
+	rec := &model.AuditRecord{AuditEntry: *entry}
+	before, err := json.Marshal(entry.Before)
+	if err != nil {
+		return fmt.Errorf("failed to encode before: %w", err)
+	}
+	after, err := json.Marshal(entry.After)
+	if err != nil {
+		return fmt.Errorf("failed to encode after: %w", err)
+	}
+	rec.Before, rec.After = nullJSON(before), nullJSON(after)
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		a.log(ctx).Errorw("record audit failed to begin transaction", "action", entry.Action, "error", err)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = tx.Rollback() // err is non-nil; don't change it
+		} else {
+			err = tx.Commit() // if Commit returns error update err with commit err
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, model.QueryAuditLock); err != nil {
+		a.log(ctx).Errorw("record audit failed to lock the chain", "action", entry.Action, "error", err)
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, model.QueryAuditLastHash).Scan(&rec.PrevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		a.log(ctx).Errorw("record audit failed to read the chain", "action", entry.Action, "error", err)
+		return err
+	}
+
+	if rec.Hash, err = rec.ComputeHash(); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, model.QueryAuditInsert,
+		rec.Actor.ID, rec.Actor.Role, rec.IP, rec.RequestID, rec.Action, rec.Entity, rec.EntityID,
+		rawOrNil(rec.Before), rawOrNil(rec.After), rec.Reason, rec.Ticket, rec.Error, rec.CreatedAt,
+		rec.PrevHash, rec.Hash).Scan(&rec.ID)
+	if err != nil {
+		a.log(ctx).Errorw("record audit failed to insert", "action", entry.Action, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// ListAudit lists the records matching req, newest first, a page at a time.
+func (a *AuditRepo) ListAudit(ctx *gin.Context, req *request.ReqAuditLog) (*request.ResAuditLog, error) {
+	req.ValidatePageSize()
+	res := &request.ResAuditLog{List: []*model.AuditRecord{}}
+
+	builder := sqlbuilder.New(model.SelectListAudit)
+	if req.Entity != "" {
+		builder.Where(model.WhereAuditEntity, req.Entity)
+	}
+	if req.EntityID > 0 {
+		builder.Where(model.WhereAuditEntityID, req.EntityID)
+	}
+	if req.Action != "" {
+		builder.Where(model.WhereAuditAction, req.Action)
+	}
+	if req.ActorID > 0 {
+		builder.Where(model.WhereAuditActorID, req.ActorID)
+	}
+
+	query, args := builder.OrderBy(model.OrderAuditIDDesc).
+		Limit(req.PageSize + 1).
+		Offset((req.Page - 1) * req.PageSize).
+		Build()
+
+	a.log(ctx).Infow("list audit log", "entity", req.Entity, "entity_id", req.EntityID, "action", req.Action,
+		"actor_id", req.ActorID, "page", req.Page, "page_size", req.PageSize)
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		a.log(ctx).Errorw("list audit log failed", "error", err)
+		return res, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	res.List, err = scanAuditRecords(rows)
+	if err != nil {
+		return res, err
+	}
+
+	if len(res.List) > req.PageSize {
+		res.List = res.List[:req.PageSize]
+		res.HasMore = true
+	}
+
+	return res, nil
+}
+
+// VerifyAudit walks the whole audit log in order and recomputes every hash. It stops at the first
+// record that was changed, or whose predecessor was changed, removed or inserted out of order.
+func (a *AuditRepo) VerifyAudit(ctx context.Context) (*model.AuditVerification, error) {
+	res := &model.AuditVerification{Valid: true}
+
+	a.log(ctx).Infow("verify audit log")
+
+	var lastID int64
+	for {
+		rows, err := a.db.QueryContext(ctx, model.QueryAuditChain, lastID, auditChainBatch)
+		if err != nil {
+			a.log(ctx).Errorw("verify audit log failed", "after_id", lastID, "error", err)
+			return res, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		list, err := scanAuditRecords(rows)
+		_ = rows.Close()
+		if err != nil {
+			return res, err
+		}
+
+		for _, rec := range list {
+			hash, err := rec.ComputeHash()
+			if err != nil {
+				return res, err
+			}
+
+			if rec.PrevHash != res.LastHash || rec.Hash != hash {
+				a.log(ctx).Errorw("audit log chain broken", "id", rec.ID)
+				res.Valid, res.BrokenID = false, rec.ID
+				return res, nil
+			}
+
+			res.Checked++
+			res.LastHash = rec.Hash
+			lastID = rec.ID
+		}
+
+		if len(list) < auditChainBatch {
+			return res, nil
+		}
+	}
+}
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (a *AuditRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, a.logger))
+}
+
+func scanAuditRecords(rows *sql.Rows) ([]*model.AuditRecord, error) {
+	list := []*model.AuditRecord{}
+	for rows.Next() {
+		rec := &model.AuditRecord{}
+		var before, after []byte
+		err := rows.Scan(&rec.ID, &rec.Actor.ID, &rec.Actor.Role, &rec.IP, &rec.RequestID, &rec.Action,
+			&rec.Entity, &rec.EntityID, &before, &after, &rec.Reason, &rec.Ticket, &rec.Error, &rec.CreatedAt,
+			&rec.PrevHash, &rec.Hash)
+		if err != nil {
+			return list, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		rec.Before, rec.After = nullJSON(before), nullJSON(after)
+		list = append(list, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return list, fmt.Errorf("rows error: %w", err)
+	}
+
+	return list, nil
+}
+
+// nullJSON returns data as the Before or After of a record, nil standing for a NULL column.
+func nullJSON(data []byte) any {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+
+	return json.RawMessage(data)
+}
+
+// rawOrNil returns v as a query argument for a json column.
+func rawOrNil(v any) any {
+	if raw, ok := v.(json.RawMessage); ok {
+		return string(raw)
+	}
+
+	return nil
+}
+
+// NewAuditLogger records audit entries as info lines of the "audit" logger.
+func NewAuditLogger(logger *zap.SugaredLogger) AuditInter {
+	return &AuditLogger{
+		logger: logger.Named("audit"),
+	}
+}
+
+type AuditLogger struct {
+	logger *zap.SugaredLogger
+}
+
+func (a *AuditLogger) Record(ctx context.Context, entry *model.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	tracing.WithTrace(ctx, a.logger).Infow(entry.Action,
+		"actor_id", entry.ID,
+		"actor_role", entry.Role,
+		"ip", entry.IP,
+		"request_id", entry.RequestID,
+		"entity", entry.Entity,
+		"entity_id", entry.EntityID,
+		"before", entry.Before,
+		"after", entry.After,
+		"reason", entry.Reason,
+		"ticket", entry.Ticket,
+		"error", entry.Error,
+		"created_at", entry.CreatedAt,
+	)
+
+	return nil
+}
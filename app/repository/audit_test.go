@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAuditLogger_Record(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	audit := NewAuditLogger(zap.New(core).Sugar())
+
+	entry := &model.AuditEntry{
+		Actor:    model.Actor{ID: 9, Role: model.RoleSupport, IP: "10.0.0.1"},
+		Action:   model.AuditWalletFreeze,
+		Entity:   model.AuditEntityWallet,
+		EntityID: 1,
+		Reason:   "chargeback",
+		Ticket:   "SUP-1",
+	}
+	require.NoError(t, audit.Record(context.Background(), entry))
+	assert.False(t, entry.CreatedAt.IsZero())
+
+	require.Equal(t, 1, logs.Len())
+	log := logs.All()[0]
+	assert.Equal(t, "audit", log.LoggerName)
+	assert.Equal(t, model.AuditWalletFreeze, log.Message)
+	assert.Equal(t, int64(9), log.ContextMap()["actor_id"])
+	assert.Equal(t, "SUP-1", log.ContextMap()["ticket"])
+}
+
+var auditColumns = []string{
+	"id", "actor_id", "actor_role", "ip", "request_id", "action", "entity", "entity_id", "before", "after",
+	"reason", "ticket", "error", "created_at", "prev_hash", "hash",
+}
+
+// captureArg matches any argument and keeps it.
+type captureArg struct {
+	value driver.Value
+}
+
+func (c *captureArg) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+// auditRow returns rec as a row of the audit log.
+func auditRow(rec *model.AuditRecord) []driver.Value {
+	before, _ := rec.Before.(json.RawMessage)
+	after, _ := rec.After.(json.RawMessage)
+
+	return []driver.Value{
+		rec.ID, rec.Actor.ID, int64(rec.Actor.Role), rec.IP, rec.RequestID, rec.Action, rec.Entity, rec.EntityID,
+		[]byte(before), []byte(after), rec.Reason, rec.Ticket, rec.Error, rec.CreatedAt, rec.PrevHash, rec.Hash,
+	}
+}
+
+// chainAudit returns n records chained to each other.
+func chainAudit(t *testing.T, n int) []*model.AuditRecord {
+	list := make([]*model.AuditRecord, n)
+	prev := ""
+	for i := range list {
+		rec := &model.AuditRecord{
+			ID: int64(i + 1),
+			AuditEntry: model.AuditEntry{
+				Action:    model.AuditWalletDeposit,
+				Entity:    model.AuditEntityWallet,
+				EntityID:  1,
+				After:     json.RawMessage(`{"balance":"10"}`),
+				CreatedAt: time.Date(2024, 5, 1, 0, 0, i, 0, time.UTC),
+			},
+			PrevHash: prev,
+		}
+
+		var err error
+		rec.Hash, err = rec.ComputeHash()
+		require.NoError(t, err)
+
+		prev = rec.Hash
+		list[i] = rec
+	}
+
+	return list
+}
+
+func TestActorOf(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Equal(t, model.Actor{}, ActorOf(ctx))
+
+	WithActor(ctx, &model.Actor{ID: 3, IP: "10.0.0.1"})
+	assert.Equal(t, model.Actor{ID: 3, IP: "10.0.0.1"}, ActorOf(ctx))
+	assert.Equal(t, model.Actor{}, ActorOf(context.Background()))
+}
+
+func TestAuditRepo_Record(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAudit(db, zap.NewExample().Sugar())
+	ctx := context.Background()
+
+	entry := &model.AuditEntry{
+		Actor:     model.Actor{ID: 9, Role: model.RoleFinance},
+		Action:    model.AuditWalletAdjust,
+		Entity:    model.AuditEntityWallet,
+		EntityID:  1,
+		Before:    map[string]string{"balance": "10"},
+		Reason:    "refund",
+		Ticket:    "FIN-1",
+		CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 123456789, time.UTC),
+	}
+
+	for _, prev := range []string{"", "abc"} {
+		t.Run("Chained to "+prev, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(model.QueryAuditLock)).WillReturnResult(sqlmock.NewResult(0, 0))
+			last := mock.ExpectQuery(regexp.QuoteMeta(model.QueryAuditLastHash))
+			if prev == "" {
+				last.WillReturnError(sql.ErrNoRows)
+			} else {
+				last.WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow(prev))
+			}
+
+			hash := &captureArg{}
+			mock.ExpectQuery(regexp.QuoteMeta(model.QueryAuditInsert)).
+				WithArgs(int64(9), model.RoleFinance, "", "", model.AuditWalletAdjust, model.AuditEntityWallet,
+					int64(1), `{"balance":"10"}`, nil, "refund", "FIN-1", "",
+					time.Date(2024, 5, 1, 0, 0, 0, 123456000, time.UTC), prev, hash).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectCommit()
+
+			require.NoError(t, repo.Record(ctx, entry))
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			rec := &model.AuditRecord{AuditEntry: *entry, PrevHash: prev}
+			rec.Before = json.RawMessage(`{"balance":"10"}`)
+			expected, err := rec.ComputeHash()
+			require.NoError(t, err)
+			assert.Equal(t, expected, hash.value)
+		})
+	}
+
+	t.Run("Insert error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryAuditLock)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryAuditLastHash)).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryAuditInsert)).WillReturnError(errors.New("insert failed"))
+		mock.ExpectRollback()
+
+		require.Error(t, repo.Record(ctx, entry))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAuditRepo_ListAudit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAudit(db, zap.NewExample().Sugar())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	list := chainAudit(t, 2)
+
+	query := model.SelectListAudit + " WHERE (entity = $1) AND (entity_id = $2) ORDER BY id DESC LIMIT $3 OFFSET $4"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(model.AuditEntityWallet, int64(1), 2, 0).
+		WillReturnRows(sqlmock.NewRows(auditColumns).AddRow(auditRow(list[1])...).AddRow(auditRow(list[0])...))
+
+	res, err := repo.ListAudit(ctx, &request.ReqAuditLog{
+		Entity: model.AuditEntityWallet, EntityID: 1, ReqPage: request.ReqPage{Page: 1, PageSize: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.List, 1)
+	assert.True(t, res.HasMore)
+	assert.Equal(t, list[1].Hash, res.List[0].Hash)
+	assert.Nil(t, res.List[0].Before)
+	assert.Equal(t, json.RawMessage(`{"balance":"10"}`), res.List[0].After)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepo_VerifyAudit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAudit(db, zap.NewExample().Sugar())
+	ctx := context.Background()
+
+	t.Run("Valid", func(t *testing.T) {
+		list := chainAudit(t, 3)
+		rows := sqlmock.NewRows(auditColumns)
+		for _, rec := range list {
+			rows.AddRow(auditRow(rec)...)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryAuditChain)).WithArgs(int64(0), auditChainBatch).WillReturnRows(rows)
+
+		res, err := repo.VerifyAudit(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, &model.AuditVerification{Checked: 3, Valid: true, LastHash: list[2].Hash}, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryAuditChain)).WillReturnRows(sqlmock.NewRows(auditColumns))
+
+		res, err := repo.VerifyAudit(ctx)
+		require.NoError(t, err)
+		assert.True(t, res.Valid)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	tampering := map[string]func(list []*model.AuditRecord) []*model.AuditRecord{
+		"Changed": func(list []*model.AuditRecord) []*model.AuditRecord {
+			list[1].Reason = "changed"
+			return list
+		},
+		"Removed": func(list []*model.AuditRecord) []*model.AuditRecord {
+			return append(list[:1], list[2:]...)
+		},
+		"Reordered": func(list []*model.AuditRecord) []*model.AuditRecord {
+			list[1], list[2] = list[2], list[1]
+			return list
+		},
+	}
+
+	for name, tamper := range tampering {
+		t.Run(name, func(t *testing.T) {
+			list := tamper(chainAudit(t, 3))
+			rows := sqlmock.NewRows(auditColumns)
+			for _, rec := range list {
+				rows.AddRow(auditRow(rec)...)
+			}
+			mock.ExpectQuery(regexp.QuoteMeta(model.QueryAuditChain)).WillReturnRows(rows)
+
+			res, err := repo.VerifyAudit(ctx)
+			require.NoError(t, err)
+			assert.False(t, res.Valid)
+			assert.Equal(t, int64(1), res.Checked)
+			assert.Equal(t, list[1].ID, res.BrokenID)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryAuditChain)).WillReturnError(errors.New("db down"))
+
+		_, err := repo.VerifyAudit(ctx)
+		require.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,492 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"server/app/model"
+	"server/pkg/cache"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrDuplicateReference is returned when the reference of a transfer was already paid from the
+	// funding wallet.
+	ErrDuplicateReference = errors.New("reference already paid")
+	// ErrBatchClosed is returned when a batch was failed as stale while it was still being run.
+	ErrBatchClosed = errors.New("batch is no longer running")
+)
+
+func NewBatch(db *sql.DB, logger *zap.SugaredLogger) BatchInter {
+	return &BatchRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type BatchInter interface {
+	CreateBatch(ctx *gin.Context, mod *model.Batch) error
+	GetBatch(ctx *gin.Context, uid, id int64) (*model.Batch, error)
+	MissingWallets(ctx *gin.Context, uids []int64) ([]int64, error)
+	PaidReferences(ctx *gin.Context, uid int64, references []string) ([]string, error)
+	StartBatch(ctx *gin.Context, id int64) error
+	TransferItem(ctx *gin.Context, uid int64, item *model.BatchItem, fee model.FeeCharge) error
+	TransferAll(ctx *gin.Context, uid int64, items []*model.BatchItem, fees []model.FeeCharge) (
+		map[int64]decimal.Decimal, error)
+	SetItemStatus(ctx *gin.Context, item *model.BatchItem) error
+	FinishBatch(ctx *gin.Context, mod *model.Batch) error
+}
+
+type BatchRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// CreateBatch stores a pending batch and its items, setting their ids.
+func (b *BatchRepo) CreateBatch(ctx *gin.Context, mod *model.Batch) (err error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		b.log(ctx).Errorw("create batch failed to begin transaction", "uid", mod.UID, "error", err)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = tx.Rollback() // err is non-nil; don't change it
+		} else {
+			err = tx.Commit() // if Commit returns error update err with commit err
+		}
+	}()
+
+	b.log(ctx).Infow("create batch", "uid", mod.UID, "mode", mod.Mode, "items", mod.ItemCount, "total", mod.Total)
+
+	err = tx.QueryRowContext(ctx, model.QueryInsertBatch, mod.UID, mod.Mode, mod.ItemCount, mod.Total).
+		Scan(&mod.ID, &mod.Status, &mod.CreatedAt, &mod.UpdatedAt)
+	if err != nil {
+		b.log(ctx).Errorw("create batch failed to insert batch", "uid", mod.UID, "error", err)
+		return err
+	}
+
+	for _, item := range mod.Items {
+		item.BatchID = mod.ID
+		err = tx.QueryRowContext(ctx, model.QueryInsertBatchItem, mod.ID, mod.UID, item.Seq, item.ToUID, item.Amount,
+			item.Reference).Scan(&item.ID, &item.Status, &item.UpdatedAt)
+		if err != nil {
+			b.log(ctx).Errorw("create batch failed to insert item", "batch_id", mod.ID, "seq", item.Seq, "error", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetBatch returns the batch of id funded by the wallet of uid, with its items.
+func (b *BatchRepo) GetBatch(ctx *gin.Context, uid, id int64) (*model.Batch, error) {
+	mod := &model.Batch{}
+
+	err := b.db.QueryRowContext(ctx, model.QueryGetBatch, id, uid).Scan(&mod.ID, &mod.UID, &mod.Mode, &mod.Status,
+		&mod.ItemCount, &mod.Total, &mod.Succeeded, &mod.Failed, &mod.Error, &mod.CreatedAt, &mod.UpdatedAt)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			b.log(ctx).Errorw("query batch failed", "batch_id", id, "error", err)
+		}
+		return nil, err
+	}
+
+	rows, err := b.db.QueryContext(ctx, model.QueryListBatchItems, id)
+	if err != nil {
+		b.log(ctx).Errorw("query batch items failed", "batch_id", id, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	mod.Items = make([]*model.BatchItem, 0, mod.ItemCount)
+	for rows.Next() {
+		item := &model.BatchItem{}
+		err = rows.Scan(&item.ID, &item.BatchID, &item.Seq, &item.ToUID, &item.Amount, &item.Reference, &item.Status,
+			&item.Error, &item.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		mod.Items = append(mod.Items, item)
+	}
+
+	return mod, rows.Err()
+}
+
+// MissingWallets returns which of uids have no wallet.
+func (b *BatchRepo) MissingWallets(ctx *gin.Context, uids []int64) ([]int64, error) {
+	rows, err := b.db.QueryContext(ctx, model.QueryMissingWallets, pq.Array(uids))
+	if err != nil {
+		b.log(ctx).Errorw("query missing wallets failed", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missing []int64
+	for rows.Next() {
+		var uid int64
+		if err = rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		missing = append(missing, uid)
+	}
+
+	return missing, rows.Err()
+}
+
+// PaidReferences returns which of references were already paid from the wallet of uid.
+func (b *BatchRepo) PaidReferences(ctx *gin.Context, uid int64, references []string) ([]string, error) {
+	rows, err := b.db.QueryContext(ctx, model.QueryBatchPaidReferences, uid, pq.Array(references))
+	if err != nil {
+		b.log(ctx).Errorw("query paid references failed", "uid", uid, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paid []string
+	for rows.Next() {
+		var reference string
+		if err = rows.Scan(&reference); err != nil {
+			return nil, err
+		}
+		paid = append(paid, reference)
+	}
+
+	return paid, rows.Err()
+}
+
+// StartBatch marks a pending batch as running.
+func (b *BatchRepo) StartBatch(ctx *gin.Context, id int64) error {
+	if _, err := b.db.ExecContext(ctx, model.QueryStartBatch, id); err != nil {
+		b.log(ctx).Errorw("start batch failed", "batch_id", id, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// TransferItem makes the transfer of item from the wallet of uid and marks it succeeded, in one
+// transaction. It fails with ErrDuplicateReference when its reference was already paid.
+func (b *BatchRepo) TransferItem(ctx *gin.Context, uid int64, item *model.BatchItem, fee model.FeeCharge) (err error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		b.log(ctx).Errorw("batch transfer failed to begin transaction", "batch_id", item.BatchID, "error", err)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = tx.Rollback() // err is non-nil; don't change it
+		} else {
+			err = tx.Commit() // if Commit returns error update err with commit err
+		}
+	}()
+
+	if _, err = lockRows(ctx, tx, withRevenue(fee, uid, item.ToUID)...); err != nil {
+		b.log(ctx).Errorw("batch transfer failed to lock wallets", "batch_id", item.BatchID, "error", err)
+		return err
+	}
+
+	if err = b.transferItem(ctx, tx, uid, item, fee, fenceToken(ctx)); err != nil {
+		b.log(ctx).Errorw("batch transfer failed", "batch_id", item.BatchID, "seq", item.Seq, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// TransferAll makes the transfers of items from the wallet of uid, each with the fee at the same
+// index of fees, and marks them succeeded, all in one transaction. Only the lock of the funding
+// wallet is held: the rows of every wallet of the batch are locked first instead, and the
+// receivers are credited without fencing. It returns the balances of those wallets before the
+// transfers, as read from their locked rows.
+func (b *BatchRepo) TransferAll(ctx *gin.Context, uid int64, items []*model.BatchItem, fees []model.FeeCharge) (
+	balances map[int64]decimal.Decimal, err error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		b.log(ctx).Errorw("batch transfer failed to begin transaction", "uid", uid, "error", err)
+		return nil, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = tx.Rollback() // err is non-nil; don't change it
+		} else {
+			err = tx.Commit() // if Commit returns error update err with commit err
+		}
+	}()
+
+	uids := []int64{uid}
+	for i, item := range items {
+		uids = append(withRevenue(fees[i], uids...), item.ToUID)
+	}
+
+	if balances, err = lockRows(ctx, tx, uids...); err != nil {
+		b.log(ctx).Errorw("batch transfer failed to lock wallets", "uid", uid, "error", err)
+		return nil, err
+	}
+
+	for i, item := range items {
+		if err = b.transferItem(ctx, tx, uid, item, fees[i], 0); err != nil {
+			b.log(ctx).Errorw("batch transfer failed", "batch_id", item.BatchID, "seq", item.Seq, "error", err)
+			return nil, fmt.Errorf("item %d: %w", item.Seq, err)
+		}
+	}
+
+	return balances, nil
+}
+
+// transferItem makes the transfer of item within tx, like WalletRepo.Transfer, with its reference
+// as the external reference of the transaction, and marks it succeeded. The receiver is credited
+// with the fencing token receiverFence. The unique index on paid references catches a concurrent
+// payment of the same reference that the check misses.
+func (b *BatchRepo) transferItem(ctx *gin.Context, tx *sql.Tx, uid int64, item *model.BatchItem,
+	fee model.FeeCharge, receiverFence int64) error {
+	if item.Reference != "" {
+		var paid bool
+		if err := tx.QueryRowContext(ctx, model.QueryBatchReferencePaid, uid, item.Reference).Scan(&paid); err != nil {
+			return err
+		}
+		if paid {
+			return fmt.Errorf("%w: %s", ErrDuplicateReference, item.Reference)
+		}
+	}
+
+	err := execGuarded(ctx, tx, model.QueryWalletWithdraw, model.WalletStatus.CanDebit, item.Amount.Add(fee.Amount), uid,
+		model.MinBalance)
+	if err != nil {
+		return err
+	}
+
+	err = execFenced(ctx, tx, receiverFence, model.QueryWalletTransfer, model.WalletStatus.CanCredit, item.Amount,
+		item.ToUID, model.BalanceLimit())
+	if err != nil {
+		return err
+	}
+
+	if err = insertTransaction(ctx, tx, uid, item.ToUID, item.Amount, model.TransactionTypeTransfer, fee,
+		model.TransactionDetails{ExternalReference: item.Reference}); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, model.QuerySetBatchItemStatus, item.ID, model.BatchItemStatusSucceeded, "")
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %s", ErrDuplicateReference, item.Reference)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The item is no longer pending when its batch was failed as stale in the meantime.
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errors.Join(err, fmt.Errorf("%w: %d", ErrBatchClosed, item.BatchID))
+	}
+
+	return nil
+}
+
+// SetItemStatus stores the status and the error of item.
+func (b *BatchRepo) SetItemStatus(ctx *gin.Context, item *model.BatchItem) error {
+	if _, err := b.db.ExecContext(ctx, model.QuerySetBatchItemStatus, item.ID, item.Status, item.Error); err != nil {
+		b.log(ctx).Errorw("set batch item status failed", "batch_id", item.BatchID, "seq", item.Seq, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// FinishBatch stores the outcome of a batch, failing its items that are still pending with the
+// error of the batch.
+func (b *BatchRepo) FinishBatch(ctx *gin.Context, mod *model.Batch) (err error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		b.log(ctx).Errorw("finish batch failed to begin transaction", "batch_id", mod.ID, "error", err)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = tx.Rollback() // err is non-nil; don't change it
+		} else {
+			err = tx.Commit() // if Commit returns error update err with commit err
+		}
+	}()
+
+	b.log(ctx).Infow("finish batch", "batch_id", mod.ID, "status", mod.Status, "succeeded", mod.Succeeded,
+		"failed", mod.Failed)
+
+	res, err := tx.ExecContext(ctx, model.QueryFinishBatch, mod.ID, mod.Status, mod.Succeeded, mod.Failed, mod.Error)
+	if err != nil {
+		b.log(ctx).Errorw("finish batch failed to update batch", "batch_id", mod.ID, "error", err)
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errors.Join(err, fmt.Errorf("%w: %d", ErrBatchClosed, mod.ID))
+	}
+
+	_, err = tx.ExecContext(ctx, model.QueryFailPendingBatchItems, mod.ID, mod.Error)
+	if err != nil {
+		b.log(ctx).Errorw("finish batch failed to update items", "batch_id", mod.ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (b *BatchRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, b.logger))
+}
+
+func NewBatchSweep(db *sql.DB, logger *zap.SugaredLogger) BatchSweepInter {
+	return &BatchRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// BatchSweepInter is used by background jobs, which run outside any HTTP request,
+// so it takes a plain context.Context.
+type BatchSweepInter interface {
+	FailStaleBatches(ctx context.Context, staleAfter time.Duration, reason string) (int64, error)
+}
+
+// FailStaleBatches closes the batches whose run was interrupted, as seen by nothing of them
+// changing for staleAfter. Their transfers that were never made are failed with reason, and
+// the others keep their outcome. It returns how many batches were closed.
+func (b *BatchRepo) FailStaleBatches(ctx context.Context, staleAfter time.Duration, reason string) (n int64,
+	err error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		b.log(ctx).Errorw("fail stale batches failed to begin transaction", "error", err)
+		return 0, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = tx.Rollback() // err is non-nil; don't change it
+		} else {
+			err = tx.Commit() // if Commit returns error update err with commit err
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, model.QueryStaleBatches, staleAfter.Seconds())
+	if err != nil {
+		b.log(ctx).Errorw("query stale batches failed", "error", err)
+		return 0, err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		b.log(ctx).Warnw("fail stale batch", "batch_id", id)
+
+		if _, err = tx.ExecContext(ctx, model.QueryFailPendingBatchItems, id, reason); err != nil {
+			b.log(ctx).Errorw("fail stale batch failed to update items", "batch_id", id, "error", err)
+			return 0, err
+		}
+
+		if _, err = tx.ExecContext(ctx, model.QueryCloseStaleBatch, id, reason); err != nil {
+			b.log(ctx).Errorw("fail stale batch failed to update batch", "batch_id", id, "error", err)
+			return 0, err
+		}
+	}
+
+	return int64(len(ids)), nil
+}
+
+// NewBatchCache drops the cached balances of the wallets that the transfers of batches made
+// through repo touched, from the cache of NewWalletCache.
+func NewBatchCache(repo BatchInter, balances *cache.Cache, logger *zap.SugaredLogger) BatchInter {
+	return &BatchCacheRepo{
+		repo:     repo,
+		balances: &WalletCacheRepo{cache: balances, logger: logger},
+	}
+}
+
+type BatchCacheRepo struct {
+	repo     BatchInter
+	balances *WalletCacheRepo
+}
+
+func (b *BatchCacheRepo) CreateBatch(ctx *gin.Context, mod *model.Batch) error {
+	return b.repo.CreateBatch(ctx, mod)
+}
+
+func (b *BatchCacheRepo) GetBatch(ctx *gin.Context, uid, id int64) (*model.Batch, error) {
+	return b.repo.GetBatch(ctx, uid, id)
+}
+
+func (b *BatchCacheRepo) MissingWallets(ctx *gin.Context, uids []int64) ([]int64, error) {
+	return b.repo.MissingWallets(ctx, uids)
+}
+
+func (b *BatchCacheRepo) PaidReferences(ctx *gin.Context, uid int64, references []string) ([]string, error) {
+	return b.repo.PaidReferences(ctx, uid, references)
+}
+
+func (b *BatchCacheRepo) StartBatch(ctx *gin.Context, id int64) error {
+	return b.repo.StartBatch(ctx, id)
+}
+
+func (b *BatchCacheRepo) TransferItem(ctx *gin.Context, uid int64, item *model.BatchItem, fee model.FeeCharge) error {
+	defer b.balances.invalidate(ctx, withRevenue(fee, uid, item.ToUID)...)
+	return b.repo.TransferItem(ctx, uid, item, fee)
+}
+
+func (b *BatchCacheRepo) TransferAll(ctx *gin.Context, uid int64, items []*model.BatchItem,
+	fees []model.FeeCharge) (map[int64]decimal.Decimal, error) {
+	uids := []int64{uid}
+	for i, item := range items {
+		uids = append(withRevenue(fees[i], uids...), item.ToUID)
+	}
+
+	defer b.balances.invalidate(ctx, uids...)
+	return b.repo.TransferAll(ctx, uid, items, fees)
+}
+
+func (b *BatchCacheRepo) SetItemStatus(ctx *gin.Context, item *model.BatchItem) error {
+	return b.repo.SetItemStatus(ctx, item)
+}
+
+func (b *BatchCacheRepo) FinishBatch(ctx *gin.Context, mod *model.Batch) error {
+	return b.repo.FinishBatch(ctx, mod)
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"server/app/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestBatchRepo(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	batchRepo := &BatchRepo{db: db, logger: zap.NewNop().Sugar()}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	amount := decimal.NewFromInt(5)
+
+	t.Run("TestCreateBatch", func(t *testing.T) {
+		batch := &model.Batch{UID: 1, Mode: model.BatchModeAtomic, ItemCount: 2, Total: decimal.NewFromInt(10),
+			Items: []*model.BatchItem{
+				{Seq: 0, ToUID: 2, Amount: amount, Reference: "inv-1"},
+				{Seq: 1, ToUID: 3, Amount: amount},
+			}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryInsertBatch)).
+			WithArgs(int64(1), model.BatchModeAtomic, 2, batch.Total).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at", "updated_at"}).AddRow(7, 1, now, now))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryInsertBatchItem)).
+			WithArgs(int64(7), int64(1), 0, int64(2), amount, "inv-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "updated_at"}).AddRow(70, 1, now))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryInsertBatchItem)).
+			WithArgs(int64(7), int64(1), 1, int64(3), amount, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "updated_at"}).AddRow(71, 1, now))
+		mock.ExpectCommit()
+
+		require.NoError(t, batchRepo.CreateBatch(ctx, batch))
+		assert.Equal(t, int64(7), batch.ID)
+		assert.Equal(t, model.BatchStatusPending, batch.Status)
+		assert.Equal(t, int64(71), batch.Items[1].ID)
+		assert.Equal(t, int64(7), batch.Items[1].BatchID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestGetBatch", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryGetBatch)).
+			WithArgs(int64(7), int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "mode", "status", "item_count", "total", "succeeded",
+				"failed", "error", "created_at", "updated_at"}).
+				AddRow(7, 1, 2, 5, 2, "10", 1, 1, "", now, now))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListBatchItems)).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "batch_id", "seq", "to_uid", "amount", "reference", "status",
+				"error", "updated_at"}).
+				AddRow(70, 7, 0, 2, "5", "inv-1", 2, "", now).
+				AddRow(71, 7, 1, 3, "5", "", 3, "insufficient balance for transfer", now))
+
+		batch, err := batchRepo.GetBatch(ctx, 1, 7)
+		require.NoError(t, err)
+		assert.Equal(t, model.BatchStatusPartial, batch.Status)
+		require.Len(t, batch.Items, 2)
+		assert.Equal(t, model.BatchItemStatusFailed, batch.Items[1].Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestGetBatch_OfAnotherWallet", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryGetBatch)).
+			WithArgs(int64(7), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := batchRepo.GetBatch(ctx, 2, 7)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestMissingWallets", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryMissingWallets)).
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"uid"}).AddRow(3))
+
+		missing, err := batchRepo.MissingWallets(ctx, []int64{1, 2, 3})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{3}, missing)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestTransferItem", func(t *testing.T) {
+		item := &model.BatchItem{ID: 70, BatchID: 7, ToUID: 2, Amount: amount, Reference: "inv-1"}
+
+		mock.ExpectBegin()
+		expectLockRows(mock, 1, 2)
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryBatchReferencePaid)).
+			WithArgs(int64(1), "inv-1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WithArgs(amount, int64(1), model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
+			WithArgs(amount, int64(2), model.MaxBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WithArgs(int64(1), int64(2), amount, model.TransactionTypeTransfer, "", "inv-1", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QuerySetBatchItemStatus)).
+			WithArgs(int64(70), model.BatchItemStatusSucceeded, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, batchRepo.TransferItem(ctx, 1, item, model.FeeCharge{}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestTransferItem_ReferencePaid", func(t *testing.T) {
+		item := &model.BatchItem{ID: 70, BatchID: 7, ToUID: 2, Amount: amount, Reference: "inv-1"}
+
+		mock.ExpectBegin()
+		expectLockRows(mock, 1, 2)
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryBatchReferencePaid)).
+			WithArgs(int64(1), "inv-1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		require.ErrorIs(t, batchRepo.TransferItem(ctx, 1, item, model.FeeCharge{}), ErrDuplicateReference)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestTransferItem_ReferencePaidConcurrently", func(t *testing.T) {
+		item := &model.BatchItem{ID: 70, BatchID: 7, ToUID: 2, Amount: amount, Reference: "inv-1"}
+
+		mock.ExpectBegin()
+		expectLockRows(mock, 1, 2)
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryBatchReferencePaid)).
+			WithArgs(int64(1), "inv-1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QuerySetBatchItemStatus)).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		require.ErrorIs(t, batchRepo.TransferItem(ctx, 1, item, model.FeeCharge{}), ErrDuplicateReference)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestTransferItem_BatchClosed", func(t *testing.T) {
+		item := &model.BatchItem{ID: 70, BatchID: 7, ToUID: 2, Amount: amount}
+
+		mock.ExpectBegin()
+		expectLockRows(mock, 1, 2)
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QuerySetBatchItemStatus)).
+			WithArgs(int64(70), model.BatchItemStatusSucceeded, "").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		require.ErrorIs(t, batchRepo.TransferItem(ctx, 1, item, model.FeeCharge{}), ErrBatchClosed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestTransferAll_RolledBack", func(t *testing.T) {
+		items := []*model.BatchItem{
+			{ID: 70, BatchID: 7, Seq: 0, ToUID: 2, Amount: amount},
+			{ID: 71, BatchID: 7, Seq: 1, ToUID: 3, Amount: amount},
+		}
+
+		// Only the funding wallet is locked, so only its writes are fenced.
+		fenced, _ := gin.CreateTestContext(httptest.NewRecorder())
+		WithFenceToken(fenced, 9)
+
+		mock.ExpectBegin()
+		expectLockRows(mock, 1, 2, 3)
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WithArgs(amount, int64(1), model.MinBalance, int64(9)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
+			WithArgs(amount, int64(2), model.MaxBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WithArgs(int64(1), int64(2), amount, model.TransactionTypeTransfer, "", "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QuerySetBatchItemStatus)).
+			WithArgs(int64(70), model.BatchItemStatusSucceeded, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WithArgs(amount, int64(1), model.MinBalance, int64(9)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(model.WalletStatusFrozen))
+		mock.ExpectRollback()
+
+		_, err := batchRepo.TransferAll(fenced, 1, items, []model.FeeCharge{{}, {}})
+		require.ErrorIs(t, err, ErrWalletFrozen)
+		assert.Contains(t, err.Error(), "item 1")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestTransferAll_LockedBalances", func(t *testing.T) {
+		items := []*model.BatchItem{{ID: 70, BatchID: 7, ToUID: 2, Amount: amount}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryLockWallets)).
+			WithArgs(pq.Array([]int64{1, 2})).
+			WillReturnRows(sqlmock.NewRows([]string{"uid", "balance"}).AddRow(1, "50").AddRow(2, "7"))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QuerySetBatchItemStatus)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		balances, err := batchRepo.TransferAll(ctx, 1, items, []model.FeeCharge{{}})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(50).Equal(balances[1]))
+		assert.True(t, decimal.NewFromInt(7).Equal(balances[2]))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestFinishBatch", func(t *testing.T) {
+		batch := &model.Batch{ID: 7, Status: model.BatchStatusFailed, Failed: 2, Error: "item 1: wallet is frozen"}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryFinishBatch)).
+			WithArgs(int64(7), model.BatchStatusFailed, 0, 2, batch.Error).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryFailPendingBatchItems)).
+			WithArgs(int64(7), batch.Error).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		require.NoError(t, batchRepo.FinishBatch(ctx, batch))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestFinishBatch_FailedAsStale", func(t *testing.T) {
+		batch := &model.Batch{ID: 7, Status: model.BatchStatusCompleted, Succeeded: 2}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryFinishBatch)).
+			WithArgs(int64(7), model.BatchStatusCompleted, 2, 0, "").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		require.ErrorIs(t, batchRepo.FinishBatch(ctx, batch), ErrBatchClosed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestFailStaleBatches", func(t *testing.T) {
+		sweepRepo := NewBatchSweep(db, zap.NewNop().Sugar())
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryStaleBatches)).
+			WithArgs(float64(600)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7).AddRow(9))
+		for _, id := range []int64{7, 9} {
+			mock.ExpectExec(regexp.QuoteMeta(model.QueryFailPendingBatchItems)).
+				WithArgs(id, "interrupted").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(model.QueryCloseStaleBatch)).
+				WithArgs(id, "interrupted").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+
+		n, err := sweepRepo.FailStaleBatches(context.Background(), 10*time.Minute, "interrupted")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"server/app/model"
+	"server/pkg/cache"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const ctxKeyFresh = "repository.fresh"
+
+// Fresh makes the cached repositories read through to the database for the rest of the request.
+// Money movements call it so that their checks never see a stale balance.
+func Fresh(ctx *gin.Context) {
+	ctx.Set(ctxKeyFresh, true)
+}
+
+func isFresh(ctx *gin.Context) bool {
+	return ctx.GetBool(ctxKeyFresh)
+}
+
+// NewWalletCache caches the balances read through repo. Every write through it invalidates the
+// balances it touches; the TTL of c bounds how long a balance changed elsewhere can be served.
+func NewWalletCache(repo WalletInter, c *cache.Cache, logger *zap.SugaredLogger) WalletInter {
+	return &WalletCacheRepo{
+		repo:   repo,
+		cache:  c,
+		logger: logger,
+	}
+}
+
+type WalletCacheRepo struct {
+	repo   WalletInter
+	cache  *cache.Cache
+	logger *zap.SugaredLogger
+}
+
+func (w *WalletCacheRepo) CreateWallet(ctx *gin.Context, mod *model.Wallet) (*model.Wallet, error) {
+	defer w.invalidate(ctx, mod.UID)
+	return w.repo.CreateWallet(ctx, mod)
+}
+
+func (w *WalletCacheRepo) GetWalletByUID(ctx *gin.Context, uid int64) (*model.Wallet, error) {
+	return w.repo.GetWalletByUID(ctx, uid)
+}
+
+func (w *WalletCacheRepo) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal,
+	details model.TransactionDetails) error {
+	defer w.invalidate(ctx, uid)
+	return w.repo.Deposit(ctx, uid, amount, details)
+}
+
+// Withdraw and Transfer also invalidate the balance of the revenue wallet when a fee is charged.
+func (w *WalletCacheRepo) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal, fee model.FeeCharge,
+	details model.TransactionDetails) error {
+	defer w.invalidate(ctx, withRevenue(fee, uid)...)
+	return w.repo.Withdraw(ctx, uid, amount, fee, details)
+}
+
+func (w *WalletCacheRepo) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	fee model.FeeCharge, details model.TransactionDetails) error {
+	defer w.invalidate(ctx, withRevenue(fee, fromUID, toUID)...)
+	return w.repo.Transfer(ctx, fromUID, toUID, amount, fee, details)
+}
+
+func (w *WalletCacheRepo) TransferAndClaim(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	fee model.FeeCharge, details model.TransactionDetails, claim model.PaymentClaim) error {
+	defer w.invalidate(ctx, withRevenue(fee, fromUID, toUID)...)
+	return w.repo.TransferAndClaim(ctx, fromUID, toUID, amount, fee, details, claim)
+}
+
+func (w *WalletCacheRepo) Tier(ctx *gin.Context, uid int64) (string, error) {
+	return w.repo.Tier(ctx, uid)
+}
+
+func (w *WalletCacheRepo) CreateQuote(ctx *gin.Context, mod *model.Quote, ttl time.Duration) error {
+	return w.repo.CreateQuote(ctx, mod, ttl)
+}
+
+func (w *WalletCacheRepo) GetQuote(ctx *gin.Context, id string) (*model.Quote, error) {
+	return w.repo.GetQuote(ctx, id)
+}
+
+// withRevenue adds the revenue wallet to uids when fee is charged.
+func withRevenue(fee model.FeeCharge, uids ...int64) []int64 {
+	if fee.IsCharged() {
+		return append(uids, fee.RevenueUID)
+	}
+
+	return uids
+}
+
+func (w *WalletCacheRepo) Adjust(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
+	defer w.invalidate(ctx, uid)
+	return w.repo.Adjust(ctx, uid, amount)
+}
+
+func (w *WalletCacheRepo) SetStatus(ctx *gin.Context, uid int64, status model.WalletStatus) error {
+	return w.repo.SetStatus(ctx, uid, status)
+}
+
+func (w *WalletCacheRepo) GetWalletByHandle(ctx *gin.Context, handle string) (*model.Wallet, error) {
+	return w.repo.GetWalletByHandle(ctx, handle)
+}
+
+func (w *WalletCacheRepo) SetHandle(ctx *gin.Context, uid int64, handle string) error {
+	return w.repo.SetHandle(ctx, uid, handle)
+}
+
+// Balance returns the cached balance of uid, reading it through on a miss,
+// unless the request asked for fresh data.
+func (w *WalletCacheRepo) Balance(ctx *gin.Context, uid int64) (decimal.Decimal, error) {
+	if isFresh(ctx) {
+		return w.repo.Balance(ctx, uid)
+	}
+
+	key := w.key(uid)
+
+	var balance decimal.Decimal
+	hit, err := w.cache.Get(ctx, key, &balance)
+	if err != nil {
+		w.log(ctx).Warnw("read balance cache failed", "uid", uid, "error", err)
+	}
+	if hit {
+		return balance, nil
+	}
+
+	balance, err = w.repo.Balance(ctx, uid)
+	if err != nil {
+		return balance, err
+	}
+
+	if err = w.cache.Set(ctx, key, balance); err != nil {
+		w.log(ctx).Warnw("write balance cache failed", "uid", uid, "error", err)
+	}
+
+	return balance, nil
+}
+
+// invalidate drops the cached balances of uids. It runs whether the write succeeded or not,
+// since a failed write may still have been committed.
+func (w *WalletCacheRepo) invalidate(ctx *gin.Context, uids ...int64) {
+	keys := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		keys = append(keys, w.key(uid))
+	}
+
+	if err := w.cache.Delete(ctx, keys...); err != nil {
+		w.log(ctx).Errorw("invalidate balance cache failed", "uids", uids, "error", err)
+	}
+}
+
+func (w *WalletCacheRepo) key(uid int64) string {
+	return w.cache.Key(strconv.FormatInt(uid, 10))
+}
+
+func (w *WalletCacheRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, w.logger))
+}
+
+// NewUserCache caches the users looked up through repo by id, username and email.
+// Users that are not found are not cached, so a new user is visible right away.
+func NewUserCache(repo UserInter, c *cache.Cache, logger *zap.SugaredLogger) UserInter {
+	return &UserCacheRepo{
+		repo:   repo,
+		cache:  c,
+		logger: logger,
+	}
+}
+
+type UserCacheRepo struct {
+	repo   UserInter
+	cache  *cache.Cache
+	logger *zap.SugaredLogger
+}
+
+func (u *UserCacheRepo) CreateUser(ctx *gin.Context, mod *model.User) (*model.User, error) {
+	defer u.invalidate(ctx, mod)
+	return u.repo.CreateUser(ctx, mod)
+}
+
+// UpdateUser also invalidates the lookups by the previous username and email.
+func (u *UserCacheRepo) UpdateUser(ctx *gin.Context, mod *model.User) error {
+	old, err := u.repo.GetUserByID(ctx, mod.ID)
+	if err == nil {
+		defer u.invalidate(ctx, old)
+	}
+
+	defer u.invalidate(ctx, mod)
+
+	return u.repo.UpdateUser(ctx, mod)
+}
+
+func (u *UserCacheRepo) GetUserByID(ctx *gin.Context, id int64) (*model.User, error) {
+	return u.get(ctx, u.keyID(id), func() (*model.User, error) {
+		return u.repo.GetUserByID(ctx, id)
+	})
+}
+
+func (u *UserCacheRepo) GetUserByUsername(ctx *gin.Context, username string) (*model.User, error) {
+	return u.get(ctx, u.keyUsername(username), func() (*model.User, error) {
+		return u.repo.GetUserByUsername(ctx, username)
+	})
+}
+
+func (u *UserCacheRepo) GetUserByEmail(ctx *gin.Context, email string) (*model.User, error) {
+	return u.get(ctx, u.keyEmail(email), func() (*model.User, error) {
+		return u.repo.GetUserByEmail(ctx, email)
+	})
+}
+
+// get returns the user cached under key, reading it with load on a miss.
+func (u *UserCacheRepo) get(ctx *gin.Context, key string, load func() (*model.User, error)) (*model.User, error) {
+	if isFresh(ctx) {
+		return load()
+	}
+
+	mod := &model.User{}
+	hit, err := u.cache.Get(ctx, key, mod)
+	if err != nil {
+		u.log(ctx).Warnw("read user cache failed", "error", err)
+	}
+	if hit {
+		return mod, nil
+	}
+
+	mod, err = load()
+	if err != nil {
+		return mod, err
+	}
+
+	if err = u.cache.Set(ctx, key, mod); err != nil {
+		u.log(ctx).Warnw("write user cache failed", "uid", mod.ID, "error", err)
+	}
+
+	return mod, nil
+}
+
+func (u *UserCacheRepo) invalidate(ctx *gin.Context, mod *model.User) {
+	keys := []string{u.keyUsername(mod.Username), u.keyEmail(mod.Email)}
+	if mod.ID > 0 {
+		keys = append(keys, u.keyID(mod.ID))
+	}
+
+	if err := u.cache.Delete(ctx, keys...); err != nil {
+		u.log(ctx).Errorw("invalidate user cache failed", "uid", mod.ID, "error", err)
+	}
+}
+
+func (u *UserCacheRepo) keyID(id int64) string {
+	return u.cache.Key("id", strconv.FormatInt(id, 10))
+}
+
+// The username and email are hashed so that no personal data ends up in Redis keys.
+func (u *UserCacheRepo) keyUsername(username string) string {
+	return u.cache.Key("username", cache.Hash(username))
+}
+
+func (u *UserCacheRepo) keyEmail(email string) string {
+	return u.cache.Key("email", cache.Hash(email))
+}
+
+func (u *UserCacheRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, u.logger))
+}
@@ -0,0 +1,428 @@
+package repository
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server/app/model"
+	"server/pkg/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+// stubWalletRepo keeps balances in a map and counts the balance reads.
+type stubWalletRepo struct {
+	balances map[int64]decimal.Decimal
+	reads    int
+}
+
+func (s *stubWalletRepo) CreateWallet(_ *gin.Context, mod *model.Wallet) (*model.Wallet, error) {
+	s.balances[mod.UID] = mod.Balance
+	return mod, nil
+}
+
+func (s *stubWalletRepo) GetWalletByUID(_ *gin.Context, uid int64) (*model.Wallet, error) {
+	return &model.Wallet{UID: uid, Balance: s.balances[uid]}, nil
+}
+
+func (s *stubWalletRepo) GetWalletByHandle(_ *gin.Context, _ string) (*model.Wallet, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubWalletRepo) SetHandle(_ *gin.Context, _ int64, _ string) error {
+	return nil
+}
+
+func (s *stubWalletRepo) Deposit(_ *gin.Context, uid int64, amount decimal.Decimal, _ model.TransactionDetails) error {
+	s.balances[uid] = s.balances[uid].Add(amount)
+	return nil
+}
+
+func (s *stubWalletRepo) Withdraw(_ *gin.Context, uid int64, amount decimal.Decimal, fee model.FeeCharge,
+	_ model.TransactionDetails) error {
+	s.balances[uid] = s.balances[uid].Sub(amount).Sub(fee.Amount)
+	s.balances[fee.RevenueUID] = s.balances[fee.RevenueUID].Add(fee.Amount)
+	return nil
+}
+
+func (s *stubWalletRepo) Transfer(_ *gin.Context, fromUID, toUID int64, amount decimal.Decimal, fee model.FeeCharge,
+	_ model.TransactionDetails) error {
+	s.balances[fromUID] = s.balances[fromUID].Sub(amount).Sub(fee.Amount)
+	s.balances[toUID] = s.balances[toUID].Add(amount)
+	s.balances[fee.RevenueUID] = s.balances[fee.RevenueUID].Add(fee.Amount)
+	return nil
+}
+
+func (s *stubWalletRepo) TransferAndClaim(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	fee model.FeeCharge, details model.TransactionDetails, _ model.PaymentClaim) error {
+	return s.Transfer(ctx, fromUID, toUID, amount, fee, details)
+}
+
+func (s *stubWalletRepo) Tier(*gin.Context, int64) (string, error) {
+	return "", nil
+}
+
+func (s *stubWalletRepo) CreateQuote(*gin.Context, *model.Quote, time.Duration) error {
+	return nil
+}
+
+func (s *stubWalletRepo) GetQuote(*gin.Context, string) (*model.Quote, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubWalletRepo) Adjust(_ *gin.Context, uid int64, amount decimal.Decimal) error {
+	s.balances[uid] = s.balances[uid].Add(amount)
+	return nil
+}
+
+func (s *stubWalletRepo) SetStatus(*gin.Context, int64, model.WalletStatus) error {
+	return nil
+}
+
+func (s *stubWalletRepo) Balance(_ *gin.Context, uid int64) (decimal.Decimal, error) {
+	s.reads++
+	balance, ok := s.balances[uid]
+	if !ok {
+		return decimal.Zero, sql.ErrNoRows
+	}
+
+	return balance, nil
+}
+
+// stubUserRepo keeps users in a map and counts the lookups.
+type stubUserRepo struct {
+	users map[int64]*model.User
+	reads int
+}
+
+func (s *stubUserRepo) CreateUser(_ *gin.Context, mod *model.User) (*model.User, error) {
+	mod.ID = int64(len(s.users) + 1)
+	copied := *mod
+	s.users[mod.ID] = &copied
+	return mod, nil
+}
+
+func (s *stubUserRepo) UpdateUser(_ *gin.Context, mod *model.User) error {
+	copied := *mod
+	s.users[mod.ID] = &copied
+	return nil
+}
+
+func (s *stubUserRepo) find(match func(u *model.User) bool) (*model.User, error) {
+	s.reads++
+	for _, u := range s.users {
+		if match(u) {
+			copied := *u
+			return &copied, nil
+		}
+	}
+
+	return &model.User{}, sql.ErrNoRows
+}
+
+func (s *stubUserRepo) GetUserByID(_ *gin.Context, id int64) (*model.User, error) {
+	return s.find(func(u *model.User) bool { return u.ID == id })
+}
+
+func (s *stubUserRepo) GetUserByUsername(_ *gin.Context, username string) (*model.User, error) {
+	return s.find(func(u *model.User) bool { return u.Username == username })
+}
+
+func (s *stubUserRepo) GetUserByEmail(_ *gin.Context, email string) (*model.User, error) {
+	return s.find(func(u *model.User) bool { return u.Email == email })
+}
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	return mr, redis.NewClient(&redis.Options{Addr: mr.Addr()})
+}
+
+func TestWalletCacheRepo(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mr, rdb := newTestRedis(t)
+	defer mr.Close()
+	defer rdb.Close()
+
+	gin.SetMode(gin.TestMode)
+	newCtx := func() *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("GET", "/", nil)
+		return ctx
+	}
+
+	inner := &stubWalletRepo{balances: map[int64]decimal.Decimal{1: decimal.NewFromInt(100), 2: decimal.Zero}}
+	repo := NewWalletCache(inner, cache.New(rdb, "balance", time.Minute), zap.NewNop().Sugar())
+
+	t.Run("Read through", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			balance, err := repo.Balance(newCtx(), 1)
+			require.NoError(t, err)
+			assert.True(t, decimal.NewFromInt(100).Equal(balance))
+		}
+		assert.Equal(t, 1, inner.reads, "the second read is a hit")
+		assert.Greater(t, mr.TTL("cache:balance:1"), time.Duration(0))
+	})
+
+	t.Run("Writes invalidate", func(t *testing.T) {
+		require.NoError(t, repo.Transfer(newCtx(), 1, 2, decimal.NewFromInt(30), model.FeeCharge{}, model.TransactionDetails{}))
+		assert.False(t, mr.Exists("cache:balance:1"))
+
+		balance, err := repo.Balance(newCtx(), 1)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(70).Equal(balance))
+
+		balance, err = repo.Balance(newCtx(), 2)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(30).Equal(balance))
+	})
+
+	t.Run("Fresh bypasses the cache", func(t *testing.T) {
+		// A change made elsewhere is not visible to cached reads until the TTL ends...
+		inner.balances[1] = decimal.NewFromInt(5)
+		balance, err := repo.Balance(newCtx(), 1)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(70).Equal(balance))
+
+		// ...but is to money movements.
+		ctx := newCtx()
+		Fresh(ctx)
+		balance, err = repo.Balance(ctx, 1)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(5).Equal(balance))
+	})
+
+	t.Run("Misses are not cached", func(t *testing.T) {
+		_, err := repo.Balance(newCtx(), 3)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		assert.False(t, mr.Exists("cache:balance:3"))
+	})
+
+	t.Run("Redis down falls back to the repository", func(t *testing.T) {
+		mr.SetError("LOADING")
+		defer mr.SetError("")
+
+		balance, err := repo.Balance(newCtx(), 2)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(30).Equal(balance))
+	})
+
+	t.Run("Fees invalidate the revenue wallet", func(t *testing.T) {
+		inner.balances[9] = decimal.Zero
+		_, err := repo.Balance(newCtx(), 9)
+		require.NoError(t, err)
+		assert.True(t, mr.Exists("cache:balance:9"))
+
+		fee := model.FeeCharge{Amount: decimal.NewFromInt(1), RevenueUID: 9}
+		require.NoError(t, repo.Withdraw(newCtx(), 2, decimal.NewFromInt(10), fee, model.TransactionDetails{}))
+		assert.False(t, mr.Exists("cache:balance:9"))
+
+		balance, err := repo.Balance(newCtx(), 9)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(1).Equal(balance))
+	})
+}
+
+func TestUserCacheRepo(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mr, rdb := newTestRedis(t)
+	defer mr.Close()
+	defer rdb.Close()
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest("GET", "/", nil)
+
+	inner := &stubUserRepo{users: map[int64]*model.User{}}
+	repo := NewUserCache(inner, cache.New(rdb, "user", time.Minute), zap.NewNop().Sugar())
+
+	mod, err := repo.CreateUser(ctx, &model.User{Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	t.Run("Read through by every key", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, err = repo.GetUserByID(ctx, mod.ID)
+			require.NoError(t, err)
+			_, err = repo.GetUserByUsername(ctx, "alice")
+			require.NoError(t, err)
+			got, err := repo.GetUserByEmail(ctx, "alice@example.com")
+			require.NoError(t, err)
+			assert.Equal(t, "alice", got.Username)
+		}
+		assert.Equal(t, 3, inner.reads)
+	})
+
+	t.Run("No personal data in keys", func(t *testing.T) {
+		for _, key := range mr.Keys() {
+			assert.NotContains(t, key, "alice")
+		}
+	})
+
+	t.Run("Update invalidates old and new lookups", func(t *testing.T) {
+		require.NoError(t, repo.UpdateUser(ctx, &model.User{ID: mod.ID, Username: "alicia", Email: "alicia@example.com"}))
+
+		_, err = repo.GetUserByUsername(ctx, "alice")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		got, err := repo.GetUserByID(ctx, mod.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "alicia", got.Username)
+
+		got, err = repo.GetUserByEmail(ctx, "alicia@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, mod.ID, got.ID)
+	})
+}
+
+// stubClosureRepo anonymises the users of a stubUserRepo and empties their balances.
+type stubClosureRepo struct {
+	users   *stubUserRepo
+	wallets *stubWalletRepo
+}
+
+func (s *stubClosureRepo) Close(_ *gin.Context, uid int64, _ decimal.Decimal) (*model.User, error) {
+	before := *s.users.users[uid]
+	s.users.users[uid] = model.AnonymousUser(uid)
+	s.wallets.balances[uid] = decimal.Zero
+	return &before, nil
+}
+
+func TestClosureCacheRepo(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mr, rdb := newTestRedis(t)
+	defer mr.Close()
+	defer rdb.Close()
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest("GET", "/", nil)
+
+	users := &stubUserRepo{users: map[int64]*model.User{}}
+	wallets := &stubWalletRepo{balances: map[int64]decimal.Decimal{}}
+	userCache := cache.New(rdb, "user", time.Minute)
+	balanceCache := cache.New(rdb, "balance", time.Minute)
+	logger := zap.NewNop().Sugar()
+
+	userRepo := NewUserCache(users, userCache, logger)
+	walletRepo := NewWalletCache(wallets, balanceCache, logger)
+	repo := NewClosureCache(&stubClosureRepo{users: users, wallets: wallets}, userCache, balanceCache, logger)
+
+	mod, err := userRepo.CreateUser(ctx, &model.User{Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+	wallets.balances[mod.ID] = decimal.NewFromInt(40)
+
+	// Warm every cached lookup.
+	_, err = userRepo.GetUserByID(ctx, mod.ID)
+	require.NoError(t, err)
+	_, err = userRepo.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	_, err = userRepo.GetUserByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	_, err = walletRepo.Balance(ctx, mod.ID)
+	require.NoError(t, err)
+
+	_, err = repo.Close(ctx, mod.ID, decimal.NewFromInt(40))
+	require.NoError(t, err)
+
+	_, err = userRepo.GetUserByUsername(ctx, "alice")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = userRepo.GetUserByEmail(ctx, "alice@example.com")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	got, err := userRepo.GetUserByID(ctx, mod.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AnonymousUser(mod.ID).Username, got.Username)
+
+	balance, err := walletRepo.Balance(ctx, mod.ID)
+	require.NoError(t, err)
+	assert.True(t, balance.IsZero())
+}
+
+// stubBatchRepo makes the transfers of batches on the balances of a stubWalletRepo.
+type stubBatchRepo struct {
+	BatchInter
+	wallets *stubWalletRepo
+}
+
+func (s *stubBatchRepo) TransferItem(ctx *gin.Context, uid int64, item *model.BatchItem, fee model.FeeCharge) error {
+	return s.wallets.Transfer(ctx, uid, item.ToUID, item.Amount, fee, model.TransactionDetails{})
+}
+
+func (s *stubBatchRepo) TransferAll(ctx *gin.Context, uid int64, items []*model.BatchItem,
+	fees []model.FeeCharge) (map[int64]decimal.Decimal, error) {
+	for i, item := range items {
+		if err := s.wallets.Transfer(ctx, uid, item.ToUID, item.Amount, fees[i], model.TransactionDetails{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func TestBatchCacheRepo(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mr, rdb := newTestRedis(t)
+	defer mr.Close()
+	defer rdb.Close()
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest("GET", "/", nil)
+
+	wallets := &stubWalletRepo{balances: map[int64]decimal.Decimal{
+		1: decimal.NewFromInt(100), 2: decimal.Zero, 3: decimal.Zero, 9: decimal.Zero,
+	}}
+	balanceCache := cache.New(rdb, "balance", time.Minute)
+	logger := zap.NewNop().Sugar()
+
+	walletRepo := NewWalletCache(wallets, balanceCache, logger)
+	repo := NewBatchCache(&stubBatchRepo{wallets: wallets}, balanceCache, logger)
+
+	warm := func() {
+		for _, uid := range []int64{1, 2, 3, 9} {
+			_, err := walletRepo.Balance(ctx, uid)
+			require.NoError(t, err)
+		}
+	}
+
+	fee := model.FeeCharge{Amount: decimal.NewFromInt(1), RevenueUID: 9}
+
+	t.Run("One transfer", func(t *testing.T) {
+		warm()
+		require.NoError(t, repo.TransferItem(ctx, 1, &model.BatchItem{ToUID: 2, Amount: decimal.NewFromInt(10)}, fee))
+
+		for _, key := range []string{"cache:balance:1", "cache:balance:2", "cache:balance:9"} {
+			assert.False(t, mr.Exists(key), key)
+		}
+		assert.True(t, mr.Exists("cache:balance:3"))
+	})
+
+	t.Run("All transfers", func(t *testing.T) {
+		warm()
+		items := []*model.BatchItem{{ToUID: 2, Amount: decimal.NewFromInt(10)}, {ToUID: 3, Amount: decimal.NewFromInt(5)}}
+		_, err := repo.TransferAll(ctx, 1, items, []model.FeeCharge{fee, fee})
+		require.NoError(t, err)
+
+		balance, err := walletRepo.Balance(ctx, 3)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(5).Equal(balance))
+
+		balance, err = walletRepo.Balance(ctx, 1)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(72).Equal(balance))
+	})
+}
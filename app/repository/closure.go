@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"server/app/model"
+	"server/pkg/cache"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func NewClosure(db *sql.DB, logger *zap.SugaredLogger) ClosureInter {
+	return &ClosureRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type ClosureInter interface {
+	Close(ctx *gin.Context, uid int64, settlement decimal.Decimal) (*model.User, error)
+}
+
+type ClosureRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// Close withdraws settlement from the wallet of uid, which must empty it, closes the wallet and
+// anonymises the user, all in one transaction. The transactions of the user are left untouched.
+// It returns the user as it was before, with at least its id set even on failure.
+func (c *ClosureRepo) Close(ctx *gin.Context, uid int64, settlement decimal.Decimal) (before *model.User, err error) {
+	before = &model.User{ID: uid}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.log(ctx).Errorw("close failed to begin transaction", "uid", uid, "error", err)
+		return before, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = tx.Rollback() // err is non-nil; don't change it
+		} else {
+			err = tx.Commit() // if Commit returns error update err with commit err
+		}
+	}()
+
+	c.log(ctx).Infow("close account", "uid", uid, "settlement", settlement)
+
+	err = execGuarded(ctx, tx, model.QueryWalletClose, model.WalletStatus.CanDebit, settlement, uid, model.MinBalance)
+	if err != nil {
+		c.log(ctx).Errorw("close failed to close wallet", "uid", uid, "settlement", settlement, "error", err)
+		return before, err
+	}
+
+	if settlement.IsPositive() {
+		err = insertTransaction(ctx, tx, uid, 0, settlement, model.TransactionTypeWithdraw, model.FeeCharge{},
+			model.TransactionDetails{})
+		if err != nil {
+			c.log(ctx).Errorw("close failed to insert settlement", "uid", uid, "settlement", settlement, "error", err)
+			return before, err
+		}
+	}
+
+	anonymous := model.AnonymousUser(uid)
+	err = tx.QueryRowContext(ctx, model.QueryUserAnonymise, uid, anonymous.Username, anonymous.Email).
+		Scan(&before.Username, &before.Email, &before.Status)
+	if err != nil {
+		c.log(ctx).Errorw("close failed to anonymise user", "uid", uid, "error", err)
+		return before, err
+	}
+
+	c.log(ctx).Infow("account closed", "uid", uid)
+
+	return before, nil
+}
+
+func (c *ClosureRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, c.logger))
+}
+
+// NewClosureCache drops the cached user and balance of every account closed through repo, from
+// the caches of NewUserCache and NewWalletCache.
+func NewClosureCache(repo ClosureInter, users, balances *cache.Cache, logger *zap.SugaredLogger) ClosureInter {
+	return &ClosureCacheRepo{
+		repo:     repo,
+		users:    &UserCacheRepo{cache: users, logger: logger},
+		balances: &WalletCacheRepo{cache: balances, logger: logger},
+	}
+}
+
+type ClosureCacheRepo struct {
+	repo     ClosureInter
+	users    *UserCacheRepo
+	balances *WalletCacheRepo
+}
+
+// Close invalidates the lookups of the user by its previous username and email when they are
+// known, and by id and balance in any case.
+func (c *ClosureCacheRepo) Close(ctx *gin.Context, uid int64, settlement decimal.Decimal) (*model.User, error) {
+	before, err := c.repo.Close(ctx, uid, settlement)
+
+	c.users.invalidate(ctx, before)
+	c.balances.invalidate(ctx, uid)
+
+	return before, err
+}
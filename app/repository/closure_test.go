@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"server/app/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestClosureRepo_Close(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewClosure(db, zap.NewExample().Sugar())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	uid := int64(7)
+	anonymous := model.AnonymousUser(uid)
+	userRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"username", "email", "status"}).
+			AddRow("alice", "alice@example.com", model.UserStatusValid)
+	}
+
+	t.Run("Settle and close", func(t *testing.T) {
+		settlement := decimal.NewFromInt(40)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletClose)).
+			WithArgs(settlement, uid, model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WithArgs(uid, 0, settlement, model.TransactionTypeWithdraw, "", "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserAnonymise)).
+			WithArgs(uid, anonymous.Username, anonymous.Email).
+			WillReturnRows(userRows())
+		mock.ExpectCommit()
+
+		before, err := repo.Close(ctx, uid, settlement)
+		require.NoError(t, err)
+		assert.Equal(t, &model.User{ID: uid, Username: "alice", Email: "alice@example.com",
+			Status: model.UserStatusValid}, before)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Empty wallet has no settlement", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletClose)).
+			WithArgs(decimal.Zero, uid, model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserAnonymise)).
+			WithArgs(uid, anonymous.Username, anonymous.Email).
+			WillReturnRows(userRows())
+		mock.ExpectCommit()
+
+		_, err := repo.Close(ctx, uid, decimal.Zero)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Frozen wallet", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletClose)).
+			WithArgs(decimal.Zero, uid, model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(model.WalletStatusFrozen))
+		mock.ExpectRollback()
+
+		before, err := repo.Close(ctx, uid, decimal.Zero)
+		require.ErrorIs(t, err, ErrWalletFrozen)
+		assert.Equal(t, uid, before.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Balance moved since it was read", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletClose)).
+			WithArgs(decimal.Zero, uid, model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(model.WalletStatusActive))
+		mock.ExpectRollback()
+
+		_, err := repo.Close(ctx, uid, decimal.Zero)
+		require.ErrorIs(t, err, ErrWriteRejected)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletClose)).
+			WithArgs(decimal.Zero, uid, model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserAnonymise)).
+			WithArgs(uid, anonymous.Username, anonymous.Email).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := repo.Close(ctx, uid, decimal.Zero)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
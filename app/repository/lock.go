@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"server/app/model"
+	"server/pkg/lock"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ctxKeyFenceToken = "repository.fence_token"
+
+// WithFenceToken makes the wallet writes of the rest of the request carry the fencing token of
+// the wallet lock held for it, so that they are refused once a newer holder has written.
+func WithFenceToken(ctx *gin.Context, token int64) {
+	ctx.Set(ctxKeyFenceToken, token)
+}
+
+func fenceToken(ctx *gin.Context) int64 {
+	return ctx.GetInt64(ctxKeyFenceToken)
+}
+
+// NewFence returns a lock.Fence drawing tokens from the wallet fencing sequence, shared by every
+// lock backend so that tokens keep growing when the backend changes.
+func NewFence(db *sql.DB) lock.Fence {
+	return func(ctx context.Context) (int64, error) {
+		var token int64
+		err := db.QueryRowContext(ctx, model.QueryNextWalletFence).Scan(&token)
+
+		return token, err
+	}
+}
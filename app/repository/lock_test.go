@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"server/app/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestNewFence(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	fence := NewFence(db)
+
+	t.Run("Next token", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryNextWalletFence)).
+			WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(7))
+
+		token, err := fence(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), token)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryNextWalletFence)).
+			WillReturnError(errors.New("db down"))
+
+		_, err := fence(context.Background())
+		require.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
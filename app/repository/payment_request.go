@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"server/app/model"
+	"server/app/request"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ErrPaymentRequestClosed is returned when a payment request is no longer pending: it was paid,
+// declined or cancelled, or it expired.
+var ErrPaymentRequestClosed = errors.New("payment request is no longer pending")
+
+func NewPaymentRequest(db *sql.DB, logger *zap.SugaredLogger) PaymentRequestInter {
+	return &PaymentRequestRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type PaymentRequestInter interface {
+	CreatePaymentRequest(ctx *gin.Context, mod *model.PaymentRequest) error
+	GetPaymentRequest(ctx *gin.Context, id int64) (*model.PaymentRequest, error)
+	GetPaymentRequestByToken(ctx *gin.Context, tokenHash string) (*model.PaymentRequest, error)
+	ListPaymentRequests(ctx *gin.Context, req *request.ReqPaymentRequests) (*request.ResPaymentRequests, error)
+	ClosePaymentRequest(ctx *gin.Context, id int64, status model.PaymentRequestStatus) error
+}
+
+type PaymentRequestRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// CreatePaymentRequest stores a pending payment request and sets its id.
+func (p *PaymentRequestRepo) CreatePaymentRequest(ctx *gin.Context, mod *model.PaymentRequest) error {
+	p.log(ctx).Infow("create payment request", "requester_uid", mod.RequesterUID, "payer_uid", mod.PayerUID,
+		"amount", mod.Amount)
+
+	err := p.db.QueryRowContext(ctx, model.QueryInsertPaymentRequest, mod.RequesterUID, mod.PayerUID, mod.Amount,
+		mod.Memo, mod.TokenHash, mod.ExpiresAt).Scan(&mod.ID, &mod.Status, &mod.CreatedAt, &mod.UpdatedAt)
+	if err != nil {
+		p.log(ctx).Errorw("create payment request failed", "requester_uid", mod.RequesterUID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (p *PaymentRequestRepo) GetPaymentRequest(ctx *gin.Context, id int64) (*model.PaymentRequest, error) {
+	mod := &model.PaymentRequest{}
+
+	err := p.db.QueryRowContext(ctx, model.QueryGetPaymentRequest, id).Scan(&mod.ID, &mod.RequesterUID, &mod.PayerUID,
+		&mod.Amount, &mod.Memo, &mod.Status, &mod.TokenHash, &mod.ExpiresAt, &mod.CreatedAt, &mod.UpdatedAt)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			p.log(ctx).Errorw("query payment request failed", "id", id, "error", err)
+		}
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+// GetPaymentRequestByToken returns the payment request whose token hashes to tokenHash, with the
+// username of its requester.
+func (p *PaymentRequestRepo) GetPaymentRequestByToken(ctx *gin.Context, tokenHash string) (*model.PaymentRequest,
+	error) {
+	mod := &model.PaymentRequest{}
+
+	err := p.db.QueryRowContext(ctx, model.QueryGetPaymentRequestByToken, tokenHash).Scan(&mod.ID, &mod.RequesterUID,
+		&mod.PayerUID, &mod.Amount, &mod.Memo, &mod.Status, &mod.TokenHash, &mod.ExpiresAt, &mod.CreatedAt,
+		&mod.UpdatedAt, &mod.RequesterName)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			p.log(ctx).Errorw("query payment request by token failed", "error", err)
+		}
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+// ListPaymentRequests lists the payment requests made by or to the wallet of req.UID, the latest
+// first.
+func (p *PaymentRequestRepo) ListPaymentRequests(ctx *gin.Context,
+	req *request.ReqPaymentRequests) (*request.ResPaymentRequests, error) {
+	res := &request.ResPaymentRequests{}
+
+	offset := (req.Page - 1) * req.PageSize
+
+	rows, err := p.db.QueryContext(ctx, model.QueryListPaymentRequests, req.UID, req.Flow, req.Status,
+		req.PageSize+1, offset)
+	if err != nil {
+		p.log(ctx).Errorw("list payment requests failed", "uid", req.UID, "error", err)
+		return res, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	list := make([]*model.PaymentRequest, 0, req.PageSize+1)
+	for rows.Next() {
+		mod := &model.PaymentRequest{}
+		err = rows.Scan(&mod.ID, &mod.RequesterUID, &mod.PayerUID, &mod.Amount, &mod.Memo, &mod.Status,
+			&mod.TokenHash, &mod.ExpiresAt, &mod.CreatedAt, &mod.UpdatedAt)
+		if err != nil {
+			return res, fmt.Errorf("failed to scan row: %w", err)
+		}
+		list = append(list, mod)
+	}
+
+	if err = rows.Err(); err != nil {
+		return res, err
+	}
+
+	res.HasMore = len(list) == req.PageSize+1
+	if res.HasMore {
+		list = list[:req.PageSize]
+	}
+
+	res.List = list
+
+	return res, nil
+}
+
+// ClosePaymentRequest declines or cancels a pending payment request. It fails with
+// ErrPaymentRequestClosed when the request is no longer pending.
+func (p *PaymentRequestRepo) ClosePaymentRequest(ctx *gin.Context, id int64,
+	status model.PaymentRequestStatus) error {
+	p.log(ctx).Infow("close payment request", "id", id, "status", status)
+
+	return p.exec(ctx, model.QueryClosePaymentRequest, id, status)
+}
+
+// exec runs a status change of the payment request of id, which must change exactly it.
+func (p *PaymentRequestRepo) exec(ctx *gin.Context, query string, id int64, arg any) error {
+	res, err := p.db.ExecContext(ctx, query, id, arg)
+	if err != nil {
+		p.log(ctx).Errorw("update payment request failed", "id", id, "error", err)
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%w: %d", ErrPaymentRequestClosed, id)
+	}
+
+	return nil
+}
+
+// claimPayment marks the payment request of claim as paid within tx. It fails with
+// ErrPaymentRequestClosed when the request is no longer pending.
+func claimPayment(ctx *gin.Context, tx *sql.Tx, claim *model.PaymentClaim) error {
+	res, err := tx.ExecContext(ctx, model.QueryClaimPaymentRequest, claim.ID, claim.PayerUID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%w: %d", ErrPaymentRequestClosed, claim.ID)
+	}
+
+	return nil
+}
+
+func (p *PaymentRequestRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, p.logger))
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestPaymentRequestRepo(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	paymentRequestRepo := &PaymentRequestRepo{db: db, logger: zap.NewNop().Sugar()}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	amount := decimal.NewFromInt(10)
+	columns := []string{"id", "requester_uid", "payer_uid", "amount", "memo", "status", "token_hash", "expires_at",
+		"created_at", "updated_at"}
+
+	t.Run("TestCreatePaymentRequest", func(t *testing.T) {
+		mod := &model.PaymentRequest{RequesterUID: 1, Amount: amount, Memo: "dinner", TokenHash: "hash",
+			ExpiresAt: now.Add(time.Hour)}
+
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryInsertPaymentRequest)).
+			WithArgs(int64(1), int64(0), amount, "dinner", "hash", mod.ExpiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at", "updated_at"}).AddRow(5, 1, now, now))
+
+		require.NoError(t, paymentRequestRepo.CreatePaymentRequest(ctx, mod))
+		assert.Equal(t, int64(5), mod.ID)
+		assert.Equal(t, model.PaymentRequestStatusPending, mod.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestGetPaymentRequestByToken", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryGetPaymentRequestByToken)).
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(append(columns, "username")).
+				AddRow(5, 1, 0, "10", "dinner", 1, "hash", now.Add(time.Hour), now, now, "alice"))
+
+		mod, err := paymentRequestRepo.GetPaymentRequestByToken(ctx, "hash")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", mod.RequesterName)
+		assert.True(t, amount.Equal(mod.Amount))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestGetPaymentRequest_NotFound", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryGetPaymentRequest)).
+			WithArgs(int64(6)).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := paymentRequestRepo.GetPaymentRequest(ctx, 6)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestListPaymentRequests", func(t *testing.T) {
+		req := &request.ReqPaymentRequests{UID: 1, Flow: model.FlowOutgoing, ReqPage: request.ReqPage{Page: 1, PageSize: 1}}
+
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListPaymentRequests)).
+			WithArgs(int64(1), model.FlowOutgoing, model.PaymentRequestStatus(0), 2, 0).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(7, 1, 2, "10", "", 1, "h7", now.Add(time.Hour), now, now).
+				AddRow(5, 1, 0, "10", "dinner", 5, "h5", now, now, now))
+
+		res, err := paymentRequestRepo.ListPaymentRequests(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.List, 1)
+		assert.True(t, res.HasMore)
+		assert.Equal(t, int64(7), res.List[0].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestClosePaymentRequest", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryClosePaymentRequest)).
+			WithArgs(int64(5), model.PaymentRequestStatusCancelled).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, paymentRequestRepo.ClosePaymentRequest(ctx, 5, model.PaymentRequestStatusCancelled))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
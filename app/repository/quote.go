@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"server/app/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrQuoteExpired is returned when a quote is unknown, has expired or was already used.
+var ErrQuoteExpired = errors.New("quote not found or expired")
+
+// CreateQuote stores a quote valid for ttl and sets when it expires, clearing the expired ones on
+// the way.
+func (w *WalletRepo) CreateQuote(ctx *gin.Context, mod *model.Quote, ttl time.Duration) error {
+	if _, err := w.db.ExecContext(ctx, model.QueryDeleteExpiredQuotes); err != nil {
+		w.log(ctx).Warnw("delete expired quotes failed", "error", err)
+	}
+
+	w.log(ctx).Infow("create quote", "quote_id", mod.ID, "operation", mod.Operation, "uid", mod.UID, "fee", mod.Fee)
+
+	err := w.db.QueryRowContext(ctx, model.QueryInsertQuote, mod.ID, mod.Operation, mod.UID, mod.ToUID, mod.Amount,
+		mod.Fee, mod.RevenueUID, ttl.Milliseconds()).Scan(&mod.ExpiresAt, &mod.CreatedAt)
+	if err != nil {
+		w.log(ctx).Errorw("create quote failed", "quote_id", mod.ID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetQuote returns the quote of id, or ErrQuoteExpired when it can no longer be used.
+func (w *WalletRepo) GetQuote(ctx *gin.Context, id string) (*model.Quote, error) {
+	mod := &model.Quote{}
+
+	err := w.db.QueryRowContext(ctx, model.QueryGetQuote, id).Scan(&mod.ID, &mod.Operation, &mod.UID, &mod.ToUID,
+		&mod.Amount, &mod.Fee, &mod.RevenueUID, &mod.ExpiresAt, &mod.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrQuoteExpired, id)
+	}
+	if err != nil {
+		w.log(ctx).Errorw("query quote failed", "quote_id", id, "error", err)
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+// consumeQuote deletes the quote of id as part of the movement it was issued for, failing with
+// ErrQuoteExpired when it expired or was used in the meantime.
+func consumeQuote(ctx *gin.Context, tx *sql.Tx, id string) error {
+	res, err := tx.ExecContext(ctx, model.QueryConsumeQuote, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrQuoteExpired, id)
+	}
+
+	return nil
+}
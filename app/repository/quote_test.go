@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"server/app/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestWalletRepo_Quote(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	walletRepo := &WalletRepo{db: db, logger: zap.NewNop().Sugar()}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	amount := decimal.NewFromInt(100)
+
+	t.Run("TestCreateQuote", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryDeleteExpiredQuotes)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryInsertQuote)).
+			WithArgs("q1", model.Transfer, int64(1), int64(2), amount, decimal.NewFromInt(1), int64(9), int64(30000)).
+			WillReturnRows(sqlmock.NewRows([]string{"expires_at", "created_at"}).AddRow(now.Add(30*time.Second), now))
+
+		quote := &model.Quote{ID: "q1", Operation: model.Transfer, UID: 1, ToUID: 2, Amount: amount,
+			Fee: decimal.NewFromInt(1), RevenueUID: 9}
+		require.NoError(t, walletRepo.CreateQuote(ctx, quote, 30*time.Second))
+		assert.Equal(t, now.Add(30*time.Second), quote.ExpiresAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestGetQuote", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryGetQuote)).
+			WithArgs("q1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "operation", "uid", "to_uid", "amount", "fee", "revenue_uid",
+				"expires_at", "created_at"}).
+				AddRow("q1", model.Transfer, 1, 2, "100", "1", 9, now.Add(30*time.Second), now))
+
+		quote, err := walletRepo.GetQuote(ctx, "q1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), quote.ToUID)
+		assert.True(t, decimal.NewFromInt(1).Equal(quote.Fee))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestGetQuote_Expired", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryGetQuote)).
+			WithArgs("q2").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := walletRepo.GetQuote(ctx, "q2")
+		require.ErrorIs(t, err, ErrQuoteExpired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestQuote_ConsumedWithTheMovement", func(t *testing.T) {
+		fee := model.FeeCharge{Amount: decimal.NewFromInt(1), RevenueUID: 9, QuoteID: "q1"}
+
+		mock.ExpectBegin()
+		expectLockRows(mock, 1, 9)
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WithArgs(decimal.NewFromInt(101), int64(1), model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryConsumeQuote)).
+			WithArgs("q1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryInsertTransactionID)).
+			WithArgs(int64(1), 0, amount, model.TransactionTypeWithdraw, "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletCreditFee)).
+			WithArgs(fee.Amount, fee.RevenueUID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertLinkedTransaction)).
+			WithArgs(int64(1), fee.RevenueUID, fee.Amount, model.TransactionTypeFee, int64(7)).
+			WillReturnResult(sqlmock.NewResult(8, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, walletRepo.Withdraw(ctx, 1, amount, fee, model.TransactionDetails{}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestQuote_UsedTwice", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLockRows(mock, 1, 9)
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WithArgs(decimal.NewFromInt(101), int64(1), model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryConsumeQuote)).
+			WithArgs("q1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := walletRepo.Withdraw(ctx, 1, amount, model.FeeCharge{Amount: decimal.NewFromInt(1), RevenueUID: 9,
+			QuoteID: "q1"}, model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrQuoteExpired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
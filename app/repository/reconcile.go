@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"server/app/model"
+	"server/app/request"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+func NewReconcile(db *sql.DB, logger *zap.SugaredLogger) ReconcileInter {
+	return &ReconcileRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ReconcileInter is used by the reconciliation job and CLI as well as the admin API,
+// so it takes a plain context.Context.
+type ReconcileInter interface {
+	Reconcile(ctx context.Context) ([]*model.Discrepancy, error)
+	ListDiscrepancies(ctx context.Context, req *request.ReqDiscrepancies) (*request.ResDiscrepancies, error)
+}
+
+type ReconcileRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// Reconcile compares every wallet's balance with the net of its transactions,
+// records the mismatches and returns the discrepancies that are still open.
+func (r *ReconcileRepo) Reconcile(ctx context.Context) ([]*model.Discrepancy, error) {
+	r.log(ctx).Infow("reconcile wallets")
+
+	rows, err := r.db.QueryContext(ctx, model.QueryReconcile)
+	if err != nil {
+		r.log(ctx).Errorw("reconcile failed", "error", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanDiscrepancies(rows)
+}
+
+func (r *ReconcileRepo) ListDiscrepancies(ctx context.Context,
+	req *request.ReqDiscrepancies) (*request.ResDiscrepancies, error) {
+	res := &request.ResDiscrepancies{}
+
+	offset := (req.Page - 1) * req.PageSize
+
+	r.log(ctx).Infow("list discrepancies", "status", req.Status, "page", req.Page, "page_size", req.PageSize)
+
+	rows, err := r.db.QueryContext(ctx, model.QueryListDiscrepancy, req.Status, req.PageSize+1, offset)
+	if err != nil {
+		r.log(ctx).Errorw("list discrepancies failed", "error", err)
+		return res, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	list, err := r.scanDiscrepancies(rows)
+	if err != nil {
+		return res, err
+	}
+
+	res.HasMore = len(list) == req.PageSize+1
+	if res.HasMore {
+		list = list[:req.PageSize]
+	}
+
+	res.List = list
+
+	return res, nil
+}
+
+func (r *ReconcileRepo) scanDiscrepancies(rows *sql.Rows) ([]*model.Discrepancy, error) {
+	var list []*model.Discrepancy
+	for rows.Next() {
+		mod := &model.Discrepancy{}
+		err := rows.Scan(&mod.ID, &mod.UID, &mod.WalletBalance, &mod.LedgerBalance, &mod.Difference,
+			&mod.Status, &mod.DetectedAt, &mod.CheckedAt, &mod.ResolvedAt)
+		if err != nil {
+			r.logger.Errorw("scan discrepancies failed", "error", err)
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		list = append(list, mod)
+	}
+
+	if rows.Err() != nil {
+		r.logger.Errorw("scan discrepancies failed", "error", rows.Err())
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (r *ReconcileRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, r.logger))
+}
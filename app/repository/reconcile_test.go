@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+var discrepancyColumns = []string{
+	"id", "uid", "wallet_balance", "ledger_balance", "difference", "status", "detected_at", "checked_at", "resolved_at",
+}
+
+func TestReconcileRepo_NewReconcile(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	inter := NewReconcile(db, zap.NewExample().Sugar())
+	repo, ok := inter.(*ReconcileRepo)
+	assert.True(t, ok)
+	assert.Equal(t, db, repo.db)
+}
+
+func TestReconcileRepo_Reconcile(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &ReconcileRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryReconcile)).
+			WillReturnRows(sqlmock.NewRows(discrepancyColumns).
+				AddRow(1, 7, "100.00", "90.00", "10.00", 1, now, now, nil))
+
+		list, err := repo.Reconcile(ctx)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, int64(7), list[0].UID)
+		assert.Equal(t, "10", list[0].Difference.String())
+		assert.Equal(t, model.DiscrepancyStatusOpen, list[0].Status)
+		assert.Nil(t, list[0].ResolvedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No discrepancies", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryReconcile)).
+			WillReturnRows(sqlmock.NewRows(discrepancyColumns))
+
+		list, err := repo.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryReconcile)).
+			WillReturnError(errors.New("query execution error"))
+
+		list, err := repo.Reconcile(ctx)
+		require.Error(t, err)
+		assert.Nil(t, list)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReconcileRepo_ListDiscrepancies(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &ReconcileRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	req := &request.ReqDiscrepancies{
+		ReqPage: request.ReqPage{Page: 2, PageSize: 1},
+		Status:  model.DiscrepancyStatusResolved,
+	}
+
+	t.Run("HasMore", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListDiscrepancy)).
+			WithArgs(model.DiscrepancyStatusResolved, 2, 1).
+			WillReturnRows(sqlmock.NewRows(discrepancyColumns).
+				AddRow(2, 7, "100.00", "100.00", "0.00", 2, now, now, now).
+				AddRow(1, 8, "5.00", "5.00", "0.00", 2, now, now, now))
+
+		res, err := repo.ListDiscrepancies(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.List, 1)
+		assert.True(t, res.HasMore)
+		require.NotNil(t, res.List[0].ResolvedAt)
+		assert.Equal(t, now, *res.List[0].ResolvedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListDiscrepancy)).
+			WithArgs(model.DiscrepancyStatusResolved, 2, 1).
+			WillReturnError(errors.New("query execution error"))
+
+		res, err := repo.ListDiscrepancies(ctx, req)
+		require.Error(t, err)
+		assert.Empty(t, res.List)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
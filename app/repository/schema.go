@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"server/app/model"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+func NewSchema(db *sql.DB, logger *zap.SugaredLogger) SchemaInter {
+	return &SchemaRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SchemaInter is used by the readiness probe, so it takes a plain context.Context.
+type SchemaInter interface {
+	Version(ctx context.Context) (int64, error)
+}
+
+type SchemaRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// Version returns the latest schema version applied to the database.
+func (s *SchemaRepo) Version(ctx context.Context) (int64, error) {
+	var version int64
+	err := s.db.QueryRowContext(ctx, model.QuerySchemaVersion).Scan(&version)
+	if err != nil {
+		s.log(ctx).Errorw("query schema version failed", "error", err)
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (s *SchemaRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, s.logger))
+}
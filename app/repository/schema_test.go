@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"server/app/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestSchemaRepo_Version(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSchema(db, zap.NewExample().Sugar())
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QuerySchemaVersion)).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(model.SchemaVersion))
+
+		version, err := repo.Version(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(model.SchemaVersion), version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QuerySchemaVersion)).
+			WillReturnError(errors.New(`relation "t_schema_version" does not exist`))
+
+		_, err := repo.Version(ctx)
+		require.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
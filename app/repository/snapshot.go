@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"server/app/model"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+func NewSnapshot(db *sql.DB, logger *zap.SugaredLogger) SnapshotInter {
+	return &SnapshotRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SnapshotInter is used by background jobs, which run outside any HTTP request,
+// so it takes a plain context.Context.
+type SnapshotInter interface {
+	CreateDailySnapshots(ctx context.Context, day time.Time) (int64, error)
+}
+
+type SnapshotRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// CreateDailySnapshots stores the closing balance of every wallet for the given day.
+// Wallets that already have a snapshot for that day are left untouched.
+func (s *SnapshotRepo) CreateDailySnapshots(ctx context.Context, day time.Time) (int64, error) {
+	s.log(ctx).Infow("create daily snapshots", "day", day.Format(time.DateOnly))
+
+	res, err := s.db.ExecContext(ctx, model.QueryBalanceSnapshotInsert, day.Format(time.DateOnly))
+	if err != nil {
+		s.log(ctx).Errorw("create daily snapshots failed", "day", day.Format(time.DateOnly), "error", err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (s *SnapshotRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, s.logger))
+}
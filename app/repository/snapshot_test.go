@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"server/app/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestSnapshotRepo_NewSnapshot(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	inter := NewSnapshot(db, zap.NewExample().Sugar())
+	repo, ok := inter.(*SnapshotRepo)
+	assert.True(t, ok)
+	assert.Equal(t, db, repo.db)
+}
+
+func TestSnapshotRepo_CreateDailySnapshots(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &SnapshotRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	ctx := context.Background()
+	day := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryBalanceSnapshotInsert)).
+			WithArgs("2024-05-01").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		count, err := repo.CreateDailySnapshots(ctx, day)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryBalanceSnapshotInsert)).
+			WithArgs("2024-05-01").
+			WillReturnError(errors.New("exec error"))
+
+		count, err := repo.CreateDailySnapshots(ctx, day)
+		require.Error(t, err)
+		assert.Zero(t, count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"server/app/model"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func NewStats(db *sql.DB, logger *zap.SugaredLogger) StatsInter {
+	return &StatsRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// StatsInter is used by the metrics collector, which runs outside any HTTP request,
+// so it takes a plain context.Context.
+type StatsInter interface {
+	WalletTotals(ctx context.Context) (decimal.Decimal, int64, error)
+}
+
+type StatsRepo struct {
+	db     *sql.DB
+	logger *zap.SugaredLogger
+}
+
+// WalletTotals returns the sum of all wallet balances and the number of wallets.
+func (s *StatsRepo) WalletTotals(ctx context.Context) (decimal.Decimal, int64, error) {
+	s.log(ctx).Debugw("query wallet totals")
+
+	var total decimal.Decimal
+	var count int64
+	err := s.db.QueryRowContext(ctx, model.QueryWalletTotals).Scan(&total, &count)
+	if err != nil {
+		s.log(ctx).Errorw("query wallet totals failed", "error", err)
+		return decimal.Zero, 0, err
+	}
+
+	return total, count, nil
+}
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (s *StatsRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, s.logger))
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"server/app/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestStatsRepo_WalletTotals(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewStats(db, zap.NewExample().Sugar())
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletTotals)).
+			WillReturnRows(sqlmock.NewRows([]string{"sum", "count"}).AddRow("1234.50", 3))
+
+		total, count, err := repo.WalletTotals(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "1234.5", total.String())
+		assert.Equal(t, int64(3), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletTotals)).
+			WillReturnError(errors.New("query execution error"))
+
+		total, count, err := repo.WalletTotals(ctx)
+		require.Error(t, err)
+		assert.True(t, total.IsZero())
+		assert.Zero(t, count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
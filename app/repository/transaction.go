@@ -1,13 +1,21 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"time"
 
 	"server/app/model"
 	"server/app/request"
+	"server/pkg/logger"
+	"server/pkg/sqlbuilder"
+	"server/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +28,10 @@ func NewTransaction(db *sql.DB, logger *zap.SugaredLogger) TransactionInter {
 
 type TransactionInter interface {
 	GetTransactionsByUID(ctx *gin.Context, req *request.ReqTransactions) (*request.ResTransactions, error)
+	EachTransactionByUID(ctx *gin.Context, req *request.ReqTransactions,
+		fn func(mod *model.TransactionWithUsername) error) error
+	BalanceAt(ctx *gin.Context, uid int64, at time.Time) (decimal.Decimal, error)
+	DailyBalances(ctx *gin.Context, uid int64, from, to time.Time) ([]*model.DailyBalance, error)
 }
 
 type TransactionRepo struct {
@@ -28,44 +40,99 @@ type TransactionRepo struct {
 }
 
 // GetTransactionsByUID retrieves a list of transactions related to a user ID with pagination.
+// Requests carrying a cursor are paged by (created_at, id), all others by offset.
 func (t *TransactionRepo) GetTransactionsByUID(ctx *gin.Context,
+	req *request.ReqTransactions) (*request.ResTransactions, error) {
+	req.ValidatePageSize()
+
+	if req.IsCursor() {
+		return t.listByCursor(ctx, req)
+	}
+
+	return t.listByOffset(ctx, req)
+}
+
+func (t *TransactionRepo) listByOffset(ctx *gin.Context,
 	req *request.ReqTransactions) (*request.ResTransactions, error) {
 	res := &request.ResTransactions{}
 
-	req.ValidatePageSize()
 	// Calculate offset based on page number and page size
 	offset := (req.Page - 1) * req.PageSize
 
-	t.logger.Infof(model.LogListTransaction, req.UID, req.UID, req.Type,
-		model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.Type, req.PageSize+1, offset)
+	query, args := filterTransactions(req).
+		OrderBy(model.GetTransactionSortOrder(req.Sort)).
+		Limit(req.PageSize + 1).
+		Offset(offset).
+		Build()
+
+	t.log(ctx).Infow("list transactions", "uid", req.UID, "page", req.Page, "page_size", req.PageSize, "sort", req.Sort)
 
-	rows, err := t.db.QueryContext(ctx, model.QueryListTransaction, req.UID, req.Type,
-		model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, offset)
+	rows, err := t.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		t.logger.Errorf("query transactions error: %s", err)
+		t.log(ctx).Errorw("list transactions failed", "uid", req.UID, "error", err)
 		return res, fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer rows.Close()
 
-	var transactions []*model.TransactionWithUsername
-	for rows.Next() {
-		mod := &model.TransactionWithUsername{}
+	transactions, err := t.scanTransactions(rows)
+	if err != nil {
+		return res, err
+	}
 
-		err = rows.Scan(&mod.ID, &mod.SenderWalletID, &mod.SenderUsername, &mod.ReceiverWalletID, &mod.ReceiverUsername,
-			&mod.Amount, &mod.TransactionType, &mod.CreatedAt)
-		if err != nil {
-			t.logger.Errorf("GetTransactionsByUID failed to scan rows: %v", err)
-			return res, fmt.Errorf("failed to scan row: %w", err)
+	hasMore := len(transactions) == req.PageSize+1
+	if hasMore {
+		transactions = transactions[:req.PageSize]
+	}
+
+	res.List = transactions
+	res.HasMore = hasMore
+
+	// Cursors only make sense when the page is ordered by (created_at, id).
+	if len(transactions) > 0 && model.IsCursorSort(req.Sort) {
+		if req.Page > 1 {
+			res.PrevCursor = cursorOf(transactions[0])
 		}
+		if hasMore {
+			res.NextCursor = cursorOf(transactions[len(transactions)-1])
+		}
+	}
 
-		mod.TransactionTypeName = model.GetTransactionTypeString(mod.TransactionType)
+	return res, nil
+}
 
-		transactions = append(transactions, mod)
+func (t *TransactionRepo) listByCursor(ctx *gin.Context,
+	req *request.ReqTransactions) (*request.ResTransactions, error) {
+	res := &request.ResTransactions{}
+
+	cursor, err := request.DecodeCursor(req.Cursor)
+	if err != nil {
+		return res, err
 	}
 
-	if rows.Err() != nil {
-		t.logger.Errorf("GetTransactionsByUID failed to scan rows: %v", rows.Err())
-		return res, fmt.Errorf("rows iteration error: %w", rows.Err())
+	// Walk the index in listing order for "next", against it for "prev".
+	ascending := (req.Sort == model.SortCreatedAtAsc) != req.IsPrev()
+
+	builder := filterTransactions(req)
+	if ascending {
+		builder.Where(model.WhereTransactionAfter, cursor.CreatedAt, cursor.ID).OrderBy(model.OrderTransactionCreatedAtAsc)
+	} else {
+		builder.Where(model.WhereTransactionBefore, cursor.CreatedAt, cursor.ID).OrderBy(model.OrderTransactionCreatedAtDesc)
+	}
+
+	query, args := builder.Limit(req.PageSize + 1).Build()
+
+	t.log(ctx).Infow("list transactions by cursor", "uid", req.UID, "page_size", req.PageSize, "direction", req.Direction, "sort", req.Sort)
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		t.log(ctx).Errorw("list transactions by cursor failed", "uid", req.UID, "error", err)
+		return res, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	transactions, err := t.scanTransactions(rows)
+	if err != nil {
+		return res, err
 	}
 
 	hasMore := len(transactions) == req.PageSize+1
@@ -73,8 +140,204 @@ func (t *TransactionRepo) GetTransactionsByUID(ctx *gin.Context,
 		transactions = transactions[:req.PageSize]
 	}
 
+	// Pages are always returned in listing order, whichever way the query walked.
+	if req.IsPrev() {
+		slices.Reverse(transactions)
+	}
+
 	res.List = transactions
 	res.HasMore = hasMore
 
+	if len(transactions) > 0 {
+		// Coming back from a later page there is always a next page, and vice versa.
+		if !req.IsPrev() || hasMore {
+			res.PrevCursor = cursorOf(transactions[0])
+		}
+		if req.IsPrev() || hasMore {
+			res.NextCursor = cursorOf(transactions[len(transactions)-1])
+		}
+	}
+
 	return res, nil
 }
+
+// filterTransactions turns the request filters into WHERE conditions.
+// Only SQL fragments from the model package are used; request values are always bound.
+func filterTransactions(req *request.ReqTransactions) *sqlbuilder.Builder {
+	builder := sqlbuilder.New(model.SelectListTransaction).Where(model.WhereTransactionUID, req.UID, req.UID)
+
+	if types := req.TransactionTypes(); len(types) > 0 {
+		values := make([]any, 0, len(types))
+		for _, tType := range types {
+			values = append(values, tType)
+		}
+		builder.WhereIn(model.ColumnTransactionType, values...)
+	}
+
+	if !req.From.IsZero() {
+		builder.Where(model.WhereTransactionFrom, req.From)
+	}
+
+	if !req.To.IsZero() {
+		builder.Where(model.WhereTransactionTo, req.To)
+	}
+
+	if req.MinAmount.Valid {
+		builder.Where(model.WhereTransactionMinAmount, req.MinAmount.Decimal)
+	}
+
+	if req.MaxAmount.Valid {
+		builder.Where(model.WhereTransactionMaxAmount, req.MaxAmount.Decimal)
+	}
+
+	switch req.Flow {
+	case model.FlowIncoming:
+		builder.Where(model.WhereTransactionIncoming, req.UID)
+	case model.FlowOutgoing:
+		builder.Where(model.WhereTransactionOutgoing, req.UID)
+	}
+
+	if req.CounterpartyUID > 0 {
+		builder.Where(model.WhereTransactionCounterparty, req.UID, req.CounterpartyUID, req.UID, req.CounterpartyUID)
+	}
+
+	if req.Counterparty != "" {
+		builder.Where(model.WhereTransactionCounterpartyName, req.UID, req.Counterparty, req.UID, req.Counterparty)
+	}
+
+	if req.ExternalReference != "" {
+		builder.Where(model.WhereTransactionExternalReference, req.ExternalReference)
+	}
+
+	return builder
+}
+
+// EachTransactionByUID streams every transaction matching the filters to fn, one row at a time,
+// without paging. It stops at the first error returned by fn.
+func (t *TransactionRepo) EachTransactionByUID(ctx *gin.Context, req *request.ReqTransactions,
+	fn func(mod *model.TransactionWithUsername) error) error {
+	query, args := filterTransactions(req).OrderBy(model.GetTransactionSortOrder(req.Sort)).Build()
+
+	t.log(ctx).Infow("stream transactions", "uid", req.UID, "from", req.From, "to", req.To)
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		t.log(ctx).Errorw("stream transactions failed", "uid", req.UID, "error", err)
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		mod, errScan := scanTransaction(rows)
+		if errScan != nil {
+			t.log(ctx).Errorw("scan transactions failed", "uid", req.UID, "error", errScan)
+			return fmt.Errorf("failed to scan row: %w", errScan)
+		}
+
+		if err = fn(mod); err != nil {
+			return err
+		}
+	}
+
+	if rows.Err() != nil {
+		t.log(ctx).Errorw("scan transactions failed", "uid", req.UID, "error", rows.Err())
+		return fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	return nil
+}
+
+// BalanceAt returns the balance a wallet had at the given time, derived from its transactions.
+func (t *TransactionRepo) BalanceAt(ctx *gin.Context, uid int64, at time.Time) (decimal.Decimal, error) {
+	t.log(ctx).Infow("query balance at", "uid", uid, "at", at)
+
+	var balance decimal.Decimal
+	err := t.db.QueryRowContext(ctx, model.QueryTransactionBalanceAt, uid, at).Scan(&balance)
+	if err != nil {
+		t.log(ctx).Errorw("query balance at failed", "uid", uid, "error", err)
+		return decimal.Zero, err
+	}
+
+	return balance, nil
+}
+
+// DailyBalances returns the closing balance of every day from from to to, both inclusive.
+func (t *TransactionRepo) DailyBalances(ctx *gin.Context, uid int64, from, to time.Time) ([]*model.DailyBalance, error) {
+	t.log(ctx).Infow("query daily balances", "uid", uid, "from", from.Format(time.DateOnly), "to", to.Format(time.DateOnly))
+
+	rows, err := t.db.QueryContext(ctx, model.QueryDailyBalance, uid, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		t.log(ctx).Errorw("query daily balances failed", "uid", uid, "error", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*model.DailyBalance
+	for rows.Next() {
+		mod := &model.DailyBalance{}
+		if err = rows.Scan(&mod.Day, &mod.Balance); err != nil {
+			t.log(ctx).Errorw("scan daily balances failed", "uid", uid, "error", err)
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		list = append(list, mod)
+	}
+
+	if rows.Err() != nil {
+		t.log(ctx).Errorw("scan daily balances failed", "uid", uid, "error", rows.Err())
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	return list, nil
+}
+
+func (t *TransactionRepo) scanTransactions(rows *sql.Rows) ([]*model.TransactionWithUsername, error) {
+	var transactions []*model.TransactionWithUsername
+	for rows.Next() {
+		mod, err := scanTransaction(rows)
+		if err != nil {
+			t.logger.Errorw("scan transactions failed", "error", err)
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		transactions = append(transactions, mod)
+	}
+
+	if rows.Err() != nil {
+		t.logger.Errorw("scan transactions failed", "error", rows.Err())
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	return transactions, nil
+}
+
+func scanTransaction(rows *sql.Rows) (*model.TransactionWithUsername, error) {
+	mod := &model.TransactionWithUsername{}
+
+	// The metadata is selected as text, which the driver returns as a string: json.RawMessage
+	// can't be scanned from one.
+	var metadata string
+	err := rows.Scan(&mod.ID, &mod.SenderWalletID, &mod.SenderUsername, &mod.ReceiverWalletID, &mod.ReceiverUsername,
+		&mod.Amount, &mod.TransactionType, &mod.CreatedAt, &mod.ParentID, &mod.Memo, &mod.ExternalReference, &metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata != "" {
+		mod.Metadata = json.RawMessage(metadata)
+	}
+
+	mod.TransactionTypeName = model.GetTransactionTypeString(mod.TransactionType)
+
+	return mod, nil
+}
+
+func cursorOf(mod *model.TransactionWithUsername) string {
+	return request.EncodeCursor(mod.CreatedAt, mod.ID)
+}
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (t *TransactionRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, t.logger))
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
@@ -58,7 +59,7 @@ func TestGetTransactionsByUID(t *testing.T) {
 
 	columns := []string{
 		"id", "sender_wallet_id", "sender_username", "receiver_wallet_id", "receiver_username",
-		"amount", "transaction_type", "created_at",
+		"amount", "transaction_type", "created_at", "parent_id", "memo", "external_reference", "metadata",
 	}
 
 	req := &request.ReqTransactions{
@@ -69,12 +70,15 @@ func TestGetTransactionsByUID(t *testing.T) {
 		},
 	}
 
+	listQuery := model.SelectListTransaction + ` WHERE (t.sender_wallet_id = $1 OR t.receiver_wallet_id = $2)` +
+		` ORDER BY ` + model.OrderTransactionCreatedAtDesc + ` LIMIT $3 OFFSET $4`
+
 	t.Run("Test with invalid page number", func(t *testing.T) {
 		req.Page = -1 // Invalid page number
 
 		rows := sqlmock.NewRows([]string{})
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, req.PageSize+1, 0).
 			WillReturnRows(rows)
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -88,8 +92,8 @@ func TestGetTransactionsByUID(t *testing.T) {
 
 		rows := sqlmock.NewRows([]string{})
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, 1+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, 1+1, 0).
 			WillReturnRows(rows)
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -103,8 +107,8 @@ func TestGetTransactionsByUID(t *testing.T) {
 
 		rows := sqlmock.NewRows([]string{})
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, 100+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, 100+1, 0).
 			WillReturnRows(rows)
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -116,8 +120,8 @@ func TestGetTransactionsByUID(t *testing.T) {
 	t.Run("Test with empty result set", func(t *testing.T) {
 		rows := sqlmock.NewRows(columns)
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, req.PageSize+1, 0).
 			WillReturnRows(rows)
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -130,17 +134,22 @@ func TestGetTransactionsByUID(t *testing.T) {
 
 	t.Run("Test with valid input", func(t *testing.T) {
 		rows := sqlmock.NewRows(columns).
-			AddRow(1, 101, "sender1", 102, "receiver1", 100.0, model.TransactionTypeDeposit, time.Now()).
-			AddRow(2, 103, "sender2", 104, "receiver2", 200.0, model.TransactionTypeWithdraw, time.Now())
+			AddRow(1, 101, "sender1", 102, "receiver1", 100.0, model.TransactionTypeDeposit, time.Now(), 0, "rent",
+				"inv-42", `{"order": 7}`).
+			AddRow(2, 103, "sender2", 104, "receiver2", 200.0, model.TransactionTypeWithdraw, time.Now(), 0, "", "", "")
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, req.PageSize+1, 0).
 			WillReturnRows(rows)
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
 		require.NoError(t, err)
 		assert.Len(t, res.List, 2)
 		assert.False(t, res.HasMore)
+		assert.Equal(t, "rent", res.List[0].Memo)
+		assert.Equal(t, "inv-42", res.List[0].ExternalReference)
+		assert.JSONEq(t, `{"order": 7}`, string(res.List[0].Metadata))
+		assert.Empty(t, res.List[1].Metadata)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -148,8 +157,8 @@ func TestGetTransactionsByUID(t *testing.T) {
 	t.Run("Test with no transactions", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{})
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, req.PageSize+1, 0).
 			WillReturnRows(rows)
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -166,8 +175,8 @@ func TestGetTransactionsByUID(t *testing.T) {
 			HasMore: false,
 		}
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, req.PageSize+1, 0).
 			WillReturnError(errors.New("statement preparation error"))
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -183,8 +192,8 @@ func TestGetTransactionsByUID(t *testing.T) {
 			HasMore: false,
 		}
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, req.PageSize+1, 0).
 			WillReturnError(errors.New("query execution error"))
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -196,16 +205,16 @@ func TestGetTransactionsByUID(t *testing.T) {
 
 	t.Run("Test with error scanning row", func(t *testing.T) {
 		rows := sqlmock.NewRows(columns).
-			AddRow(1, 101, "sender1", 102, "receiver1", 100.0, model.TransactionTypeWithdraw, "2023-04-01").
-			AddRow(2, 103, "sender2", 104, "receiver2", 200.0, model.TransactionTypeDeposit, "invalid date")
+			AddRow(1, 101, "sender1", 102, "receiver1", 100.0, model.TransactionTypeWithdraw, "2023-04-01", 0, "", "", "").
+			AddRow(2, 103, "sender2", 104, "receiver2", 200.0, model.TransactionTypeDeposit, "invalid date", 0, "", "", "")
 
 		expectedRes := &request.ResTransactions{
 			List:    []*model.TransactionWithUsername(nil),
 			HasMore: false,
 		}
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, req.PageSize+1, 0).
 			WillReturnRows(rows)
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -218,8 +227,8 @@ func TestGetTransactionsByUID(t *testing.T) {
 	t.Run("No more data", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{})
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryListTransaction)).
-			WithArgs(req.UID, req.Type, model.TransactionTypeDeposit, model.TransactionTypeTransfer, req.PageSize+1, 0).
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).
+			WithArgs(req.UID, req.UID, req.PageSize+1, 0).
 			WillReturnRows(rows)
 
 		res, err := repo.GetTransactionsByUID(ctx, req)
@@ -230,3 +239,320 @@ func TestGetTransactionsByUID(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestGetTransactionsByUID_Cursor(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &TransactionRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	columns := []string{
+		"id", "sender_wallet_id", "sender_username", "receiver_wallet_id", "receiver_username",
+		"amount", "transaction_type", "created_at", "parent_id", "memo", "external_reference", "metadata",
+	}
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	cursor := request.EncodeCursor(createdAt, 10)
+
+	uidCond := ` WHERE (t.sender_wallet_id = $1 OR t.receiver_wallet_id = $2)`
+	beforeQuery := model.SelectListTransaction + uidCond + ` AND ((t.created_at, t.id) < ($3::timestamp, $4))` +
+		` ORDER BY ` + model.OrderTransactionCreatedAtDesc + ` LIMIT $5`
+	afterQuery := model.SelectListTransaction + uidCond + ` AND ((t.created_at, t.id) > ($3::timestamp, $4))` +
+		` ORDER BY ` + model.OrderTransactionCreatedAtAsc + ` LIMIT $5`
+
+	t.Run("Next page with more rows", func(t *testing.T) {
+		req := &request.ReqTransactions{UID: 1, Cursor: cursor, ReqPage: request.ReqPage{PageSize: 2}}
+
+		rows := sqlmock.NewRows(columns).
+			AddRow(9, 1, "a", 2, "b", 1.0, model.TransactionTypeTransfer, createdAt.Add(-time.Minute), 0, "", "", "").
+			AddRow(8, 1, "a", 2, "b", 2.0, model.TransactionTypeTransfer, createdAt.Add(-2*time.Minute), 0, "", "", "").
+			AddRow(7, 1, "a", 2, "b", 3.0, model.TransactionTypeTransfer, createdAt.Add(-3*time.Minute), 0, "", "", "")
+
+		mock.ExpectQuery(regexp.QuoteMeta(beforeQuery)).
+			WithArgs(req.UID, req.UID, createdAt, 10, 3).
+			WillReturnRows(rows)
+
+		res, err := repo.GetTransactionsByUID(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.List, 2)
+		assert.Equal(t, int64(9), res.List[0].ID)
+		assert.True(t, res.HasMore)
+		assert.Equal(t, request.EncodeCursor(res.List[1].CreatedAt, 8), res.NextCursor)
+		assert.Equal(t, request.EncodeCursor(res.List[0].CreatedAt, 9), res.PrevCursor)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Prev page is returned newest first", func(t *testing.T) {
+		req := &request.ReqTransactions{
+			UID: 1, Cursor: cursor, Direction: request.DirectionPrev, ReqPage: request.ReqPage{PageSize: 2},
+		}
+
+		rows := sqlmock.NewRows(columns).
+			AddRow(11, 1, "a", 2, "b", 1.0, model.TransactionTypeTransfer, createdAt.Add(time.Minute), 0, "", "", "").
+			AddRow(12, 1, "a", 2, "b", 2.0, model.TransactionTypeTransfer, createdAt.Add(2*time.Minute), 0, "", "", "")
+
+		mock.ExpectQuery(regexp.QuoteMeta(afterQuery)).
+			WithArgs(req.UID, req.UID, createdAt, 10, 3).
+			WillReturnRows(rows)
+
+		res, err := repo.GetTransactionsByUID(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.List, 2)
+		assert.Equal(t, int64(12), res.List[0].ID)
+		assert.Equal(t, int64(11), res.List[1].ID)
+		assert.False(t, res.HasMore)
+		assert.Empty(t, res.PrevCursor)
+		assert.Equal(t, request.EncodeCursor(res.List[1].CreatedAt, 11), res.NextCursor)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		req := &request.ReqTransactions{UID: 1, Cursor: "broken"}
+
+		_, err := repo.GetTransactionsByUID(ctx, req)
+		require.ErrorIs(t, err, request.ErrInvalidCursor)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		req := &request.ReqTransactions{UID: 1, Cursor: cursor, ReqPage: request.ReqPage{PageSize: 2}}
+
+		mock.ExpectQuery(regexp.QuoteMeta(beforeQuery)).
+			WillReturnError(errors.New("query execution error"))
+
+		_, err := repo.GetTransactionsByUID(ctx, req)
+		require.Error(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetTransactionsByUID_Filter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &TransactionRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("All filters", func(t *testing.T) {
+		req := &request.ReqTransactions{
+			UID:               1,
+			Type:              model.TransactionTypeDeposit,
+			Types:             []model.TransactionType{model.TransactionTypeTransfer},
+			From:              from,
+			To:                to,
+			MinAmount:         decimal.NewNullDecimal(decimal.NewFromInt(10)),
+			MaxAmount:         decimal.NewNullDecimal(decimal.NewFromInt(100)),
+			Flow:              model.FlowOutgoing,
+			CounterpartyUID:   2,
+			Counterparty:      "bob",
+			ExternalReference: "inv-42",
+			Sort:              model.SortAmountDesc,
+			ReqPage:           request.ReqPage{Page: 2, PageSize: 5},
+		}
+
+		query := model.SelectListTransaction +
+			` WHERE (t.sender_wallet_id = $1 OR t.receiver_wallet_id = $2)` +
+			` AND (t.transaction_type IN ($3, $4))` +
+			` AND (t.created_at >= $5::timestamp)` +
+			` AND (t.created_at < $6::timestamp)` +
+			` AND (t.amount >= $7)` +
+			` AND (t.amount <= $8)` +
+			` AND (t.sender_wallet_id = $9)` +
+			` AND ((t.sender_wallet_id = $10 AND t.receiver_wallet_id = $11)
+			OR (t.receiver_wallet_id = $12 AND t.sender_wallet_id = $13))` +
+			` AND ((t.sender_wallet_id = $14 AND r.username = $15)
+			OR (t.receiver_wallet_id = $16 AND s.username = $17))` +
+			` AND (t.external_reference = $18)` +
+			` ORDER BY ` + model.OrderTransactionAmountDesc + ` LIMIT $19 OFFSET $20`
+
+		mock.ExpectQuery(regexp.QuoteMeta(query)).
+			WithArgs(req.UID, req.UID, model.TransactionTypeTransfer, model.TransactionTypeDeposit, from, to,
+				req.MinAmount.Decimal, req.MaxAmount.Decimal, req.UID, req.UID, int64(2), req.UID, int64(2),
+				req.UID, "bob", req.UID, "bob", "inv-42", 6, 5).
+			WillReturnRows(sqlmock.NewRows([]string{}))
+
+		res, err := repo.GetTransactionsByUID(ctx, req)
+		require.NoError(t, err)
+		assert.Empty(t, res.List)
+		assert.Empty(t, res.NextCursor)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEachTransactionByUID(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &TransactionRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	columns := []string{
+		"id", "sender_wallet_id", "sender_username", "receiver_wallet_id", "receiver_username",
+		"amount", "transaction_type", "created_at", "parent_id", "memo", "external_reference", "metadata",
+	}
+
+	req := &request.ReqTransactions{UID: 1, Sort: model.SortCreatedAtAsc}
+	query := model.SelectListTransaction + ` WHERE (t.sender_wallet_id = $1 OR t.receiver_wallet_id = $2)` +
+		` ORDER BY ` + model.OrderTransactionCreatedAtAsc
+
+	t.Run("Streams every row", func(t *testing.T) {
+		rows := sqlmock.NewRows(columns).
+			AddRow(1, 0, "", 1, "a", 10.0, model.TransactionTypeDeposit, time.Now(), 0, "", "", "").
+			AddRow(2, 1, "a", 0, "", 5.0, model.TransactionTypeWithdraw, time.Now(), 0, "", "", "")
+
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(req.UID, req.UID).WillReturnRows(rows)
+
+		var ids []int64
+		err := repo.EachTransactionByUID(ctx, req, func(mod *model.TransactionWithUsername) error {
+			ids = append(ids, mod.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Stops on callback error", func(t *testing.T) {
+		rows := sqlmock.NewRows(columns).
+			AddRow(1, 0, "", 1, "a", 10.0, model.TransactionTypeDeposit, time.Now(), 0, "", "", "").
+			AddRow(2, 1, "a", 0, "", 5.0, model.TransactionTypeWithdraw, time.Now(), 0, "", "", "")
+
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(req.UID, req.UID).WillReturnRows(rows)
+
+		calls := 0
+		errStop := errors.New("client went away")
+		err := repo.EachTransactionByUID(ctx, req, func(*model.TransactionWithUsername) error {
+			calls++
+			return errStop
+		})
+		require.ErrorIs(t, err, errStop)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnError(errors.New("query execution error"))
+
+		err := repo.EachTransactionByUID(ctx, req, func(*model.TransactionWithUsername) error { return nil })
+		require.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestBalanceAt(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &TransactionRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryTransactionBalanceAt)).
+			WithArgs(1, at).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("42.50"))
+
+		balance, err := repo.BalanceAt(ctx, 1, at)
+		require.NoError(t, err)
+		assert.Equal(t, "42.5", balance.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryTransactionBalanceAt)).
+			WithArgs(1, at).
+			WillReturnError(errors.New("query execution error"))
+
+		balance, err := repo.BalanceAt(ctx, 1, at)
+		require.Error(t, err)
+		assert.True(t, balance.IsZero())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDailyBalances(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &TransactionRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryDailyBalance)).
+			WithArgs(1, "2024-05-01", "2024-05-02").
+			WillReturnRows(sqlmock.NewRows([]string{"day", "balance"}).
+				AddRow(from, "10.00").
+				AddRow(to, "12.50"))
+
+		list, err := repo.DailyBalances(ctx, 1, from, to)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, from, list[0].Day)
+		assert.Equal(t, "12.5", list[1].Balance.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryDailyBalance)).
+			WithArgs(1, "2024-05-01", "2024-05-02").
+			WillReturnError(errors.New("query execution error"))
+
+		list, err := repo.DailyBalances(ctx, 1, from, to)
+		require.Error(t, err)
+		assert.Nil(t, list)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
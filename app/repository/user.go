@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
 	"server/app/model"
+	"server/pkg/logger"
+	"server/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -31,16 +34,16 @@ type UserRepo struct {
 }
 
 func (u *UserRepo) CreateUser(ctx *gin.Context, mod *model.User) (*model.User, error) {
-	u.logger.Infof(model.LogUserInsert, mod.Username, mod.Email, mod.PasswordHash)
+	u.log(ctx).Infow("create user", "username", mod.Username, "email", logger.RedactEmail(mod.Email))
 
 	var id int64
 	err := u.db.QueryRowContext(ctx, model.QueryUserInsert, mod.Username, mod.Email, mod.PasswordHash).Scan(&id)
 	if err != nil {
-		u.logger.Errorf("CreateUser QueryUserInsert err: %s", err.Error())
+		u.log(ctx).Errorw("create user failed", "username", mod.Username, "error", err)
 		return mod, err
 	}
 
-	u.logger.Infof("User created with ID: %d", id)
+	u.log(ctx).Infow("user created", "uid", id)
 
 	mod.ID = id
 
@@ -48,11 +51,11 @@ func (u *UserRepo) CreateUser(ctx *gin.Context, mod *model.User) (*model.User, e
 }
 
 func (u *UserRepo) UpdateUser(ctx *gin.Context, mod *model.User) error {
-	u.logger.Infof(model.LogUserUpdate, mod.Username, mod.Email, mod.ID)
+	u.log(ctx).Infow("update user", "uid", mod.ID, "username", mod.Username, "email", logger.RedactEmail(mod.Email))
 
 	_, err := u.db.ExecContext(ctx, model.QueryUserUpdate, mod.Username, mod.Email, mod.ID)
 	if err != nil {
-		u.logger.Errorf("UpdateUser error: %s", err.Error())
+		u.log(ctx).Errorw("update user failed", "uid", mod.ID, "error", err)
 	}
 
 	return err
@@ -72,7 +75,7 @@ func (u *UserRepo) GetUserByEmail(ctx *gin.Context, email string) (*model.User,
 
 // queryModelByField is a reusable function to query a model by a field.
 func (u *UserRepo) queryModelByField(ctx *gin.Context, field string, value any) (*model.User, error) {
-	u.logger.Infof(model.LogUserByField, field, value)
+	u.log(ctx).Infow("query user", "field", field, "value", redactUserField(field, value))
 
 	mod := &model.User{}
 	err := u.db.QueryRowContext(ctx, model.GetQueryByField(field), value).
@@ -82,10 +85,25 @@ func (u *UserRepo) queryModelByField(ctx *gin.Context, field string, value any)
 			return mod, err
 		}
 
-		u.logger.Errorf("queryModelByField error: %s", err.Error())
+		u.log(ctx).Errorw("query user failed", "field", field, "error", err)
 
 		return mod, err
 	}
 
 	return mod, nil
 }
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (u *UserRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, u.logger))
+}
+
+// redactUserField hides the values of lookups by personal data.
+func redactUserField(field string, value any) any {
+	if email, ok := value.(string); ok && field == "email" {
+		return logger.RedactEmail(email)
+	}
+
+	return value
+}
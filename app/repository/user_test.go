@@ -15,6 +15,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestUserRepo_NewUser(t *testing.T) {
@@ -127,3 +129,37 @@ func TestUserRepo_GetUserByID(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestUserRepo_CreateUser_RedactsLogs(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	repo := &UserRepo{
+		db:     db,
+		logger: zap.New(core).Sugar(),
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mod := &model.User{Username: "alice", Email: "alice@example.com", PasswordHash: []byte("$2a$10$secret")}
+
+	mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserInsert)).
+		WithArgs(mod.Username, mod.Email, mod.PasswordHash).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	_, err = repo.CreateUser(ctx, mod)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	for _, entry := range logs.All() {
+		for _, value := range entry.ContextMap() {
+			assert.NotContains(t, fmt.Sprint(value), "alice@example.com")
+			assert.NotContains(t, fmt.Sprint(value), "secret")
+		}
+	}
+	assert.Equal(t, "a***@example.com", logs.FilterMessage("create user").All()[0].ContextMap()["email"])
+}
@@ -1,17 +1,40 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"server/app/model"
+	"server/pkg/logger"
+	"server/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
+// ErrWriteRejected is returned when a guarded wallet update changed nothing: the wallet is gone,
+// its balance moved past the guard since it was checked, or a newer lock holder wrote it.
+var ErrWriteRejected = errors.New("wallet update rejected")
+
+// Errors returned when the status of a wallet refuses a money movement.
+var (
+	ErrWalletFrozen       = errors.New("wallet is frozen")
+	ErrWalletDebitBlocked = errors.New("wallet is blocked for debits")
+	ErrWalletClosed       = errors.New("wallet is closed")
+)
+
+// ErrHandleTaken is returned when a wallet handle is already given to another wallet.
+var ErrHandleTaken = errors.New("wallet handle is taken")
+
+// ErrRevenueWallet is returned when the revenue wallet is missing or can't be credited, which
+// refuses every movement that charges a fee.
+var ErrRevenueWallet = errors.New("revenue wallet can't take fees")
+
 func NewWallet(db *sql.DB, logger *zap.SugaredLogger) WalletInter {
 	return &WalletRepo{
 		db:     db,
@@ -22,10 +45,21 @@ func NewWallet(db *sql.DB, logger *zap.SugaredLogger) WalletInter {
 type WalletInter interface {
 	CreateWallet(ctx *gin.Context, mod *model.Wallet) (*model.Wallet, error)
 	GetWalletByUID(ctx *gin.Context, uid int64) (*model.Wallet, error)
-	Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal) error
-	Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal) error
-	Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal) error
+	GetWalletByHandle(ctx *gin.Context, handle string) (*model.Wallet, error)
+	SetHandle(ctx *gin.Context, uid int64, handle string) error
+	Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal, details model.TransactionDetails) error
+	Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal, fee model.FeeCharge,
+		details model.TransactionDetails) error
+	Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal, fee model.FeeCharge,
+		details model.TransactionDetails) error
+	TransferAndClaim(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal, fee model.FeeCharge,
+		details model.TransactionDetails, claim model.PaymentClaim) error
 	Balance(ctx *gin.Context, uid int64) (decimal.Decimal, error)
+	Tier(ctx *gin.Context, uid int64) (string, error)
+	CreateQuote(ctx *gin.Context, mod *model.Quote, ttl time.Duration) error
+	GetQuote(ctx *gin.Context, id string) (*model.Quote, error)
+	Adjust(ctx *gin.Context, uid int64, amount decimal.Decimal) error
+	SetStatus(ctx *gin.Context, uid int64, status model.WalletStatus) error
 }
 
 type WalletRepo struct {
@@ -37,7 +71,7 @@ type WalletRepo struct {
 func (w *WalletRepo) CreateWallet(ctx *gin.Context, mod *model.Wallet) (*model.Wallet, error) {
 	var id int64
 
-	w.logger.Infof(model.LogWalletInert, mod.UID, mod.Balance)
+	w.log(ctx).Infow("create wallet", "uid", mod.UID, "balance", mod.Balance)
 	err := w.db.QueryRowContext(ctx, model.QueryWalletInsert, mod.UID, mod.Balance).Scan(&id)
 	if err != nil {
 		return mod, fmt.Errorf("failed to insert wallet: %w", err)
@@ -45,16 +79,54 @@ func (w *WalletRepo) CreateWallet(ctx *gin.Context, mod *model.Wallet) (*model.W
 
 	mod.ID = id
 
-	w.logger.Infof("Wallet created with ID: %d", id)
+	w.log(ctx).Infow("wallet created", "uid", mod.UID, "wallet_id", id)
 
 	return mod, err
 }
 
-// Deposit adds money to a user's wallet and records the transaction
-func (w *WalletRepo) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
+// Deposit adds money to a user's wallet and records the transaction with its details
+func (w *WalletRepo) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal, details model.TransactionDetails) error {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		w.log(ctx).Errorw("deposit failed to begin transaction", "uid", uid, "error", err)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = tx.Rollback() // err is non-nil; don't change it
+		} else {
+			err = tx.Commit() // if Commit returns error update err with commit err
+		}
+	}()
+
+	w.log(ctx).Infow("deposit", "uid", uid, "amount", amount)
+
+	err = execGuarded(ctx, tx, model.QueryWalletDeposit, model.WalletStatus.CanCredit, amount, uid, model.BalanceLimit())
+	if err != nil {
+		w.log(ctx).Errorw("deposit failed to update wallet", "uid", uid, "amount", amount, "error", err)
+		return err
+	}
+
+	err = insertTransaction(ctx, tx, 0, uid, amount, model.TransactionTypeDeposit, model.FeeCharge{}, details)
+	if err != nil {
+		w.log(ctx).Errorw("deposit failed to insert transaction", "uid", uid, "amount", amount, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Withdraw removes money and the fee on it from a user's wallet and records the transaction with
+// its details
+func (w *WalletRepo) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal, fee model.FeeCharge,
+	details model.TransactionDetails) error {
 	tx, err := w.db.BeginTx(ctx, nil)
 	if err != nil {
-		w.logger.Errorf("Deposit failed to begin transaction: %v", err)
+		w.log(ctx).Errorw("withdraw failed to begin transaction", "uid", uid, "error", err)
 		return err
 	}
 
@@ -69,29 +141,52 @@ func (w *WalletRepo) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal
 		}
 	}()
 
-	w.logger.Infof(model.LogWalletDeposit, amount, uid, amount, model.MaxBalance)
-	w.logger.Infof(model.LogInsertTransaction, 0, uid, amount, model.TransactionTypeDeposit)
+	w.log(ctx).Infow("withdraw", "uid", uid, "amount", amount, "fee", fee.Amount)
+
+	// The fee also credits the revenue wallet, so both rows are locked like a transfer's.
+	if fee.IsCharged() {
+		if _, err = lockRows(ctx, tx, uid, fee.RevenueUID); err != nil {
+			w.log(ctx).Errorw("withdraw failed to lock wallets", "uid", uid, "error", err)
+			return err
+		}
+	}
 
-	_, err = tx.ExecContext(ctx, model.QueryWalletDeposit, amount, uid, model.MaxBalance)
+	err = execGuarded(ctx, tx, model.QueryWalletWithdraw, model.WalletStatus.CanDebit, amount.Add(fee.Amount), uid,
+		model.MinBalance)
 	if err != nil {
-		w.logger.Errorf("Deposit failed to query wallet deposit: %v", err)
+		w.log(ctx).Errorw("withdraw failed to update wallet", "uid", uid, "amount", amount, "error", err)
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, model.QueryInsertTransaction, 0, uid, amount, model.TransactionTypeDeposit)
+	err = insertTransaction(ctx, tx, uid, 0, amount, model.TransactionTypeWithdraw, fee, details)
 	if err != nil {
-		w.logger.Errorf("Deposit failed to query insert transaction: %v", err)
+		w.log(ctx).Errorw("withdraw failed to insert transaction", "uid", uid, "amount", amount, "error", err)
 		return err
 	}
 
 	return nil
 }
 
-// Withdraw removes money from a user's wallet and records the transaction
-func (w *WalletRepo) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
+// Transfer moves money from one wallet to another, taking the fee on it from the sender, and
+// records the transaction with its details
+func (w *WalletRepo) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal, fee model.FeeCharge,
+	details model.TransactionDetails) error {
+	return w.transfer(ctx, fromUID, toUID, amount, fee, details, nil)
+}
+
+// TransferAndClaim makes the transfer that pays a payment request and marks the request as paid
+// in the same DB transaction, so that it is paid exactly when the money moves, and at most once.
+// It fails with ErrPaymentRequestClosed when the request is no longer pending.
+func (w *WalletRepo) TransferAndClaim(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	fee model.FeeCharge, details model.TransactionDetails, claim model.PaymentClaim) error {
+	return w.transfer(ctx, fromUID, toUID, amount, fee, details, &claim)
+}
+
+func (w *WalletRepo) transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal, fee model.FeeCharge,
+	details model.TransactionDetails, claim *model.PaymentClaim) (err error) {
 	tx, err := w.db.BeginTx(ctx, nil)
 	if err != nil {
-		w.logger.Errorf("Withdraw failed to begin transaction: %v", err)
+		w.log(ctx).Errorw("transfer failed to begin transaction", "from_uid", fromUID, "to_uid", toUID, "error", err)
 		return err
 	}
 
@@ -106,28 +201,163 @@ func (w *WalletRepo) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decima
 		}
 	}()
 
-	w.logger.Infof(model.LogWalletWithdraw, amount, uid, amount, model.MinBalance)
-	w.logger.Infof(model.LogInsertTransaction, uid, 0, amount, model.TransactionTypeWithdraw)
+	w.log(ctx).Infow("transfer", "from_uid", fromUID, "to_uid", toUID, "amount", amount, "fee", fee.Amount)
+
+	if claim != nil {
+		if err = claimPayment(ctx, tx, claim); err != nil {
+			w.log(ctx).Errorw("transfer failed to claim payment request", "id", claim.ID, "error", err)
+			return err
+		}
+	}
+
+	if _, err = lockRows(ctx, tx, withRevenue(fee, fromUID, toUID)...); err != nil {
+		w.log(ctx).Errorw("transfer failed to lock wallets", "from_uid", fromUID, "to_uid", toUID, "error", err)
+		return err
+	}
+
+	err = execGuarded(ctx, tx, model.QueryWalletWithdraw, model.WalletStatus.CanDebit, amount.Add(fee.Amount), fromUID,
+		model.MinBalance)
+	if err != nil {
+		w.log(ctx).Errorw("transfer failed to debit sender", "from_uid", fromUID, "amount", amount, "error", err)
+		return err
+	}
 
-	_, err = tx.ExecContext(ctx, model.QueryWalletWithdraw, amount, uid, model.MinBalance)
+	err = execGuarded(ctx, tx, model.QueryWalletTransfer, model.WalletStatus.CanCredit, amount, toUID, model.BalanceLimit())
 	if err != nil {
-		w.logger.Errorf("Withdraw failed to query wallet withdraw: %v", err)
+		w.log(ctx).Errorw("transfer failed to credit receiver", "to_uid", toUID, "amount", amount, "error", err)
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, model.QueryInsertTransaction, uid, 0, amount, model.TransactionTypeWithdraw)
+	err = insertTransaction(ctx, tx, fromUID, toUID, amount, model.TransactionTypeTransfer, fee, details)
 	if err != nil {
-		w.logger.Errorf("Withdraw failed to query insert transaction: %v", err)
+		w.log(ctx).Errorw("transfer failed to insert transaction", "from_uid", fromUID, "to_uid", toUID, "error", err)
 		return err
 	}
 
 	return nil
 }
 
-func (w *WalletRepo) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal) error {
+// lockRows locks the rows of the wallets of uids within tx, in uid order, and returns their
+// balances.
+func lockRows(ctx context.Context, tx *sql.Tx, uids ...int64) (map[int64]decimal.Decimal, error) {
+	rows, err := tx.QueryContext(ctx, model.QueryLockWallets, pq.Array(uids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[int64]decimal.Decimal, len(uids))
+	for rows.Next() {
+		var uid int64
+		var balance decimal.Decimal
+		if err = rows.Scan(&uid, &balance); err != nil {
+			return nil, err
+		}
+		balances[uid] = balance
+	}
+
+	return balances, rows.Err()
+}
+
+// insertTransaction records a money movement with its details. When fee is charged, it also
+// credits the fee to the revenue wallet and records it as its own transaction from the sender,
+// linked to the movement. The quote the fee was locked in by, if any, is used up.
+func insertTransaction(ctx *gin.Context, tx *sql.Tx, sender, receiver int64, amount decimal.Decimal,
+	tType model.TransactionType, fee model.FeeCharge, details model.TransactionDetails) error {
+	args := []any{sender, receiver, amount, tType, details.Memo, details.ExternalReference, string(details.Metadata)}
+
+	if fee.QuoteID != "" {
+		if err := consumeQuote(ctx, tx, fee.QuoteID); err != nil {
+			return err
+		}
+	}
+
+	if !fee.IsCharged() {
+		_, err := tx.ExecContext(ctx, model.QueryInsertTransaction, args...)
+		return err
+	}
+
+	var id int64
+	err := tx.QueryRowContext(ctx, model.QueryInsertTransactionID, args...).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, model.QueryWalletCreditFee, fee.Amount, fee.RevenueUID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%w: uid %d", ErrRevenueWallet, fee.RevenueUID)
+	}
+
+	_, err = tx.ExecContext(ctx, model.QueryInsertLinkedTransaction, sender, fee.RevenueUID, fee.Amount,
+		model.TransactionTypeFee, id)
+
+	return err
+}
+
+// execGuarded runs a guarded wallet update with the fencing token of ctx, followed by extra
+// arguments. When it changed no row, it fails with the error of the wallet status if allows
+// refuses it, and with ErrWriteRejected otherwise.
+func execGuarded(ctx *gin.Context, tx *sql.Tx, query string, allows func(model.WalletStatus) bool,
+	amount decimal.Decimal, uid int64, bound any, extra ...any) error {
+	return execFenced(ctx, tx, fenceToken(ctx), query, allows, amount, uid, bound, extra...)
+}
+
+// execFenced is execGuarded with the fencing token fence, 0 for a write to a wallet whose lock is
+// not held, which then neither checks nor moves its token.
+func execFenced(ctx *gin.Context, tx *sql.Tx, fence int64, query string, allows func(model.WalletStatus) bool,
+	amount decimal.Decimal, uid int64, bound any, extra ...any) error {
+	args := append([]any{amount, uid, bound, fence}, extra...)
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return nil
+	}
+
+	var status model.WalletStatus
+	if err = tx.QueryRowContext(ctx, model.QueryWalletStatus, uid).Scan(&status); err == nil && !allows(status) {
+		return WalletStatusError(status, uid)
+	}
+
+	return fmt.Errorf("%w for uid %d", ErrWriteRejected, uid)
+}
+
+// WalletStatusError returns the error of a money movement refused by the status of the wallet of uid.
+func WalletStatusError(status model.WalletStatus, uid int64) error {
+	switch status {
+	case model.WalletStatusFrozen:
+		return fmt.Errorf("%w for uid %d", ErrWalletFrozen, uid)
+	case model.WalletStatusDebitBlocked:
+		return fmt.Errorf("%w for uid %d", ErrWalletDebitBlocked, uid)
+	case model.WalletStatusClosed:
+		return fmt.Errorf("%w for uid %d", ErrWalletClosed, uid)
+	}
+
+	return fmt.Errorf("%w for uid %d", ErrWriteRejected, uid)
+}
+
+// Adjust moves amount, credited when positive and debited when negative, in or out of any open
+// wallet, and records it as an adjustment.
+func (w *WalletRepo) Adjust(ctx *gin.Context, uid int64, amount decimal.Decimal) (err error) {
 	tx, err := w.db.BeginTx(ctx, nil)
 	if err != nil {
-		w.logger.Errorf("Transfer failed to begin transaction: %v", err)
+		w.log(ctx).Errorw("adjust failed to begin transaction", "uid", uid, "error", err)
 		return err
 	}
 
@@ -142,66 +372,147 @@ func (w *WalletRepo) Transfer(ctx *gin.Context, fromUID, toUID int64, amount dec
 		}
 	}()
 
-	w.logger.Infof(model.LogWalletWithdraw, amount, fromUID, amount, model.MinBalance)
-	w.logger.Infof(model.LogWalletTransfer, amount, toUID, amount, model.MaxBalance)
-	w.logger.Infof(model.LogInsertTransaction, fromUID, toUID, amount, model.TransactionTypeTransfer)
+	w.log(ctx).Infow("adjust", "uid", uid, "amount", amount)
+
+	err = execGuarded(ctx, tx, model.QueryWalletAdjust, model.WalletStatus.IsOpen, amount, uid, model.BalanceLimit(), model.MinBalance)
+	if err != nil {
+		w.log(ctx).Errorw("adjust failed to update wallet", "uid", uid, "amount", amount, "error", err)
+		return err
+	}
+
+	sender, receiver := int64(0), uid
+	if amount.IsNegative() {
+		sender, receiver = uid, 0
+	}
+
+	err = insertTransaction(ctx, tx, sender, receiver, amount.Abs(), model.TransactionTypeAdjustment, model.FeeCharge{},
+		model.TransactionDetails{})
+	if err != nil {
+		w.log(ctx).Errorw("adjust failed to insert transaction", "uid", uid, "amount", amount, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// SetStatus changes the status of the wallet of uid. It returns sql.ErrNoRows if there is none
+// and ErrWalletClosed if it is closed.
+func (w *WalletRepo) SetStatus(ctx *gin.Context, uid int64, status model.WalletStatus) error {
+	w.log(ctx).Infow("set wallet status", "uid", uid, "status", status)
+
+	res, err := w.db.ExecContext(ctx, model.QueryWalletSetStatus, status, uid)
+	if err != nil {
+		w.log(ctx).Errorw("set wallet status failed", "uid", uid, "error", err)
+		return err
+	}
 
-	_, err = tx.ExecContext(ctx, model.QueryWalletWithdraw, amount, fromUID, model.MinBalance)
+	n, err := res.RowsAffected()
 	if err != nil {
-		_ = tx.Rollback()
-		w.logger.Errorf("Transfer failed to query wallet withdraw: %v", err)
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, model.QueryWalletTransfer, amount, toUID, model.MaxBalance)
+	if n > 0 {
+		return nil
+	}
+
+	// Nothing changed: the wallet is either missing or closed for good.
+	var current model.WalletStatus
+	if err = w.db.QueryRowContext(ctx, model.QueryWalletStatus, uid).Scan(&current); err != nil {
+		return err
+	}
+
+	return WalletStatusError(current, uid)
+}
+
+// SetHandle gives the wallet of uid a handle. It returns ErrHandleTaken if another wallet has it,
+// sql.ErrNoRows if there is no wallet and ErrWalletClosed if it is closed.
+func (w *WalletRepo) SetHandle(ctx *gin.Context, uid int64, handle string) error {
+	w.log(ctx).Infow("set wallet handle", "uid", uid, "handle", handle)
+
+	res, err := w.db.ExecContext(ctx, model.QueryWalletSetHandle, handle, uid)
 	if err != nil {
-		_ = tx.Rollback()
-		w.logger.Errorf("Transfer failed to query wallet transfer: %v", err)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("%w: %s", ErrHandleTaken, handle)
+		}
+
+		w.log(ctx).Errorw("set wallet handle failed", "uid", uid, "error", err)
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, model.QueryInsertTransaction, fromUID, toUID, amount, model.TransactionTypeTransfer)
+	n, err := res.RowsAffected()
 	if err != nil {
-		_ = tx.Rollback()
-		w.logger.Errorf("Transfer failed to query inert transaction: %v", err)
 		return err
 	}
 
-	return tx.Commit()
+	if n > 0 {
+		return nil
+	}
+
+	var current model.WalletStatus
+	if err = w.db.QueryRowContext(ctx, model.QueryWalletStatus, uid).Scan(&current); err != nil {
+		return err
+	}
+
+	return WalletStatusError(current, uid)
 }
 
 func (w *WalletRepo) Balance(ctx *gin.Context, uid int64) (decimal.Decimal, error) {
-	w.logger.Infof(model.LogWalletBalance, uid)
+	w.log(ctx).Debugw("query balance", "uid", uid)
 
 	var balance decimal.Decimal
 	err := w.db.QueryRowContext(ctx, model.QueryWalletBalance, uid).Scan(&balance)
 	if err != nil {
-		w.logger.Errorf("Balance failed to get query wallet balance: %v", err)
+		w.log(ctx).Errorw("query balance failed", "uid", uid, "error", err)
 		return decimal.Zero, err
 	}
 
 	return balance, nil
 }
 
+// Tier returns the pricing tier of the owner of the wallet of uid.
+func (w *WalletRepo) Tier(ctx *gin.Context, uid int64) (string, error) {
+	var tier string
+	err := w.db.QueryRowContext(ctx, model.QueryUserTier, uid).Scan(&tier)
+	if err != nil {
+		w.log(ctx).Errorw("query tier failed", "uid", uid, "error", err)
+		return "", err
+	}
+
+	return tier, nil
+}
+
 func (w *WalletRepo) GetWalletByUID(ctx *gin.Context, uid int64) (*model.Wallet, error) {
-	return w.queryModelByField(ctx, "uid", uid)
+	return w.queryWallet(ctx, model.QueryWalletByUID, "uid", uid)
 }
 
-func (w *WalletRepo) queryModelByField(ctx *gin.Context, field string, value any) (*model.Wallet, error) {
+// GetWalletByHandle returns the wallet with handle, or sql.ErrNoRows if there is none.
+func (w *WalletRepo) GetWalletByHandle(ctx *gin.Context, handle string) (*model.Wallet, error) {
+	return w.queryWallet(ctx, model.QueryWalletByHandle, "handle", handle)
+}
+
+// queryWallet reads the wallet whose field has value with query.
+func (w *WalletRepo) queryWallet(ctx *gin.Context, query, field string, value any) (*model.Wallet, error) {
 	mod := &model.Wallet{}
 
-	w.logger.Infof(model.LogWalletByField, field, value)
+	w.log(ctx).Infow("query wallet", field, value)
 
-	err := w.db.QueryRowContext(ctx, model.QueryWalletByField, field, value).
-		Scan(&mod.ID, &mod.UID, &mod.Balance, &mod.CreatedAt, &mod.UpdatedAt)
+	err := w.db.QueryRowContext(ctx, query, value).
+		Scan(&mod.ID, &mod.UID, &mod.Balance, &mod.Status, &mod.Handle, &mod.CreatedAt, &mod.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return mod, err
 		}
 
-		w.logger.Errorf("queryModelByField failed to query wallet by field: %v", err)
+		w.log(ctx).Errorw("query wallet failed", field, value, "error", err)
 		return mod, err
 	}
 
 	return mod, nil
 }
+
+// log returns the request-scoped logger of ctx, falling back to the repository logger,
+// with the trace of ctx attached.
+func (w *WalletRepo) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, w.logger))
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http/httptest"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -140,21 +142,23 @@ func TestWalletRepo_GetWalletByUID(t *testing.T) {
 	recorder := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(recorder)
 
-	filed := "uid"
 	t.Run("GetWalletByUID_Normal", func(t *testing.T) {
 		uid := int64(123)
 		expectedWallet := &model.Wallet{
 			ID:        1,
 			UID:       uid,
 			Balance:   decimal.NewFromFloat(100.5),
+			Status:    model.WalletStatusActive,
+			Handle:    "alice_shop",
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByField)).
-			WithArgs(filed, uid).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "balance", "created_at", "updated_at"}).
-				AddRow(expectedWallet.ID, expectedWallet.UID, expectedWallet.Balance, expectedWallet.CreatedAt, expectedWallet.UpdatedAt))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByUID)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "balance", "status", "handle", "created_at", "updated_at"}).
+				AddRow(expectedWallet.ID, expectedWallet.UID, expectedWallet.Balance, expectedWallet.Status,
+					expectedWallet.Handle, expectedWallet.CreatedAt, expectedWallet.UpdatedAt))
 
 		wallet, err := walletRepo.GetWalletByUID(ctx, uid)
 		require.NoError(t, err)
@@ -165,9 +169,9 @@ func TestWalletRepo_GetWalletByUID(t *testing.T) {
 	t.Run("GetWalletByUID_NoRows", func(t *testing.T) {
 		uid := int64(456)
 		expectedErr := fmt.Errorf("sql: no rows in result set")
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByField)).
-			WithArgs(filed, uid).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "balance", "created_at", "updated_at"}))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByUID)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "balance", "status", "handle", "created_at", "updated_at"}))
 
 		wallet, err := walletRepo.GetWalletByUID(ctx, uid)
 		assert.Equal(t, &model.Wallet{}, wallet)
@@ -178,7 +182,7 @@ func TestWalletRepo_GetWalletByUID(t *testing.T) {
 	t.Run("GetWalletByUID_PrepareError", func(t *testing.T) {
 		uid := int64(789)
 		expectedErr := fmt.Errorf("failed to query model by field: %w", fmt.Errorf("simulated prepare error"))
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByField)).
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByUID)).
 			WillReturnError(expectedErr)
 
 		wallet, err := walletRepo.GetWalletByUID(ctx, uid)
@@ -190,8 +194,8 @@ func TestWalletRepo_GetWalletByUID(t *testing.T) {
 	t.Run("GetWalletByUID_QueryError", func(t *testing.T) {
 		uid := int64(101112)
 		expectedErr := fmt.Errorf("simulated query error")
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByField)).
-			WithArgs(filed, uid).
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByUID)).
+			WithArgs(uid).
 			WillReturnError(expectedErr)
 
 		wallet, err := walletRepo.GetWalletByUID(ctx, uid)
@@ -201,7 +205,7 @@ func TestWalletRepo_GetWalletByUID(t *testing.T) {
 	})
 }
 
-func TestWalletRepo_queryModelByField(t *testing.T) {
+func TestWalletRepo_Deposit(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
 	db, mock, err := sqlmock.New()
@@ -216,137 +220,114 @@ func TestWalletRepo_queryModelByField(t *testing.T) {
 	recorder := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(recorder)
 
-	field := "uid"
-	t.Run("queryModelByField_Normal", func(t *testing.T) {
-		value := int64(123)
-		expectedWallet := &model.Wallet{
-			ID:        1,
-			UID:       value,
-			Balance:   decimal.NewFromFloat(100.5),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
+	t.Run("TestDeposit_Success", func(t *testing.T) {
+		uid := int64(123)
+		amount := decimal.NewFromFloat(100.5)
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByField)).
-			WithArgs("uid", value).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "balance", "created_at", "updated_at"}).
-				AddRow(expectedWallet.ID, expectedWallet.UID, expectedWallet.Balance, expectedWallet.CreatedAt, expectedWallet.UpdatedAt))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletDeposit)).
+			WithArgs(amount, uid, model.MaxBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WithArgs(0, uid, amount, model.TransactionTypeDeposit, "", "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
-		wallet, err := walletRepo.queryModelByField(ctx, field, value)
+		err := walletRepo.Deposit(ctx, uid, amount, model.TransactionDetails{})
 		require.NoError(t, err)
-		assert.Equal(t, expectedWallet, wallet)
-		assert.NoError(t, mock.ExpectationsWereMet())
-	})
-
-	t.Run("queryModelByField_NoRows", func(t *testing.T) {
-		value := int64(456)
-		expectedErr := fmt.Errorf("sql: no rows in result set")
-
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByField)).
-			WithArgs("uid", value).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "balance", "created_at", "updated_at"}))
-
-		wallet, err := walletRepo.queryModelByField(ctx, field, value)
-		assert.Equal(t, &model.Wallet{}, wallet)
-		assert.Equal(t, expectedErr, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("queryModelByField_PrepareError", func(t *testing.T) {
-		value := int64(789)
-		expectedErr := fmt.Errorf("simulated prepare error")
+	t.Run("TestDeposit_UpdateError", func(t *testing.T) {
+		uid := int64(456)
+		amount := decimal.NewFromFloat(100.5)
+		expectedErr := fmt.Errorf("update failed")
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByField)).
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletDeposit)).
+			WithArgs(amount, uid, model.MaxBalance, int64(0)).
 			WillReturnError(expectedErr)
+		mock.ExpectRollback()
 
-		wallet, err := walletRepo.queryModelByField(ctx, field, value)
-		assert.Equal(t, &model.Wallet{}, wallet)
+		err := walletRepo.Deposit(ctx, uid, amount, model.TransactionDetails{})
+		require.Error(t, err)
 		assert.Equal(t, expectedErr, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("queryModelByField_QueryError", func(t *testing.T) {
-		value := int64(101112)
-		expectedErr := fmt.Errorf("simulated query error")
+	t.Run("TestDeposit_InsertError", func(t *testing.T) {
+		uid := int64(789)
+		amount := decimal.NewFromFloat(100.5)
+		expectedErr := fmt.Errorf("insert failed")
 
-		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByField)).
-			WithArgs("uid", value).
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletDeposit)).
+			WithArgs(amount, uid, model.MaxBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WithArgs(0, uid, amount, model.TransactionTypeDeposit, "", "", "").
 			WillReturnError(expectedErr)
+		mock.ExpectRollback()
 
-		wallet, err := walletRepo.queryModelByField(ctx, field, value)
-		assert.Equal(t, &model.Wallet{}, wallet)
+		err := walletRepo.Deposit(ctx, uid, amount, model.TransactionDetails{})
+		require.Error(t, err)
 		assert.Equal(t, expectedErr, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-}
-
-func TestWalletRepo_Deposit(t *testing.T) {
-	defer goleak.VerifyNone(t)
-
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
-
-	walletRepo := &WalletRepo{
-		db:     db,
-		logger: zap.NewExample().Sugar(),
-	}
 
-	recorder := httptest.NewRecorder()
-	ctx, _ := gin.CreateTestContext(recorder)
-
-	t.Run("TestDeposit_Success", func(t *testing.T) {
+	t.Run("TestDeposit_Fenced", func(t *testing.T) {
 		uid := int64(123)
 		amount := decimal.NewFromFloat(100.5)
 
+		fenced, _ := gin.CreateTestContext(httptest.NewRecorder())
+		WithFenceToken(fenced, 42)
+
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletDeposit)).
-			WithArgs(amount, uid, model.MaxBalance).
+			WithArgs(amount, uid, model.MaxBalance, int64(42)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
-			WithArgs(0, uid, amount, model.TransactionTypeDeposit).
+			WithArgs(0, uid, amount, model.TransactionTypeDeposit, "", "", "").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
-		err := walletRepo.Deposit(ctx, uid, amount)
-		require.NoError(t, err)
+		require.NoError(t, walletRepo.Deposit(fenced, uid, amount, model.TransactionDetails{}))
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("TestDeposit_UpdateError", func(t *testing.T) {
-		uid := int64(456)
+	t.Run("TestDeposit_Rejected", func(t *testing.T) {
+		uid := int64(123)
 		amount := decimal.NewFromFloat(100.5)
-		expectedErr := fmt.Errorf("update failed")
 
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletDeposit)).
-			WithArgs(amount, uid, model.MaxBalance).
-			WillReturnError(expectedErr)
+			WithArgs(amount, uid, model.MaxBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(model.WalletStatusActive))
 		mock.ExpectRollback()
 
-		err := walletRepo.Deposit(ctx, uid, amount)
-		require.Error(t, err)
-		assert.Equal(t, expectedErr, err)
+		err := walletRepo.Deposit(ctx, uid, amount, model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrWriteRejected)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("TestDeposit_InsertError", func(t *testing.T) {
-		uid := int64(789)
+	t.Run("TestDeposit_Frozen", func(t *testing.T) {
+		uid := int64(123)
 		amount := decimal.NewFromFloat(100.5)
-		expectedErr := fmt.Errorf("insert failed")
 
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletDeposit)).
-			WithArgs(amount, uid, model.MaxBalance).
-			WillReturnResult(sqlmock.NewResult(1, 1))
-		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
-			WithArgs(0, uid, amount, model.TransactionTypeDeposit).
-			WillReturnError(expectedErr)
+			WithArgs(amount, uid, model.MaxBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(model.WalletStatusFrozen))
 		mock.ExpectRollback()
 
-		err := walletRepo.Deposit(ctx, uid, amount)
-		require.Error(t, err)
-		assert.Equal(t, expectedErr, err)
+		err := walletRepo.Deposit(ctx, uid, amount, model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrWalletFrozen)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -372,14 +353,14 @@ func TestWalletRepo_Withdraw(t *testing.T) {
 
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
-			WithArgs(amount, uid, model.MinBalance).
+			WithArgs(amount, uid, model.MinBalance, int64(0)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
-			WithArgs(uid, 0, amount, model.TransactionTypeWithdraw).
+			WithArgs(uid, 0, amount, model.TransactionTypeWithdraw, "", "", "").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
-		err := walletRepo.Withdraw(ctx, uid, amount)
+		err := walletRepo.Withdraw(ctx, uid, amount, model.FeeCharge{}, model.TransactionDetails{})
 		require.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -391,11 +372,11 @@ func TestWalletRepo_Withdraw(t *testing.T) {
 
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
-			WithArgs(amount, uid, model.MinBalance).
+			WithArgs(amount, uid, model.MinBalance, int64(0)).
 			WillReturnError(expectedErr)
 		mock.ExpectRollback()
 
-		err := walletRepo.Withdraw(ctx, uid, amount)
+		err := walletRepo.Withdraw(ctx, uid, amount, model.FeeCharge{}, model.TransactionDetails{})
 		require.Error(t, err)
 		assert.Equal(t, expectedErr, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -408,20 +389,32 @@ func TestWalletRepo_Withdraw(t *testing.T) {
 
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
-			WithArgs(amount, uid, model.MinBalance).
+			WithArgs(amount, uid, model.MinBalance, int64(0)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
-			WithArgs(uid, 0, amount, model.TransactionTypeWithdraw).
+			WithArgs(uid, 0, amount, model.TransactionTypeWithdraw, "", "", "").
 			WillReturnError(expectedErr)
 		mock.ExpectRollback()
 
-		err := walletRepo.Withdraw(ctx, uid, amount)
+		err := walletRepo.Withdraw(ctx, uid, amount, model.FeeCharge{}, model.TransactionDetails{})
 		require.Error(t, err)
 		assert.Equal(t, expectedErr, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
+// expectLockRows expects the rows of the wallets of uids to be locked, each with a zero balance.
+func expectLockRows(mock sqlmock.Sqlmock, uids ...int64) {
+	rows := sqlmock.NewRows([]string{"uid", "balance"})
+	for _, uid := range uids {
+		rows.AddRow(uid, "0")
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(model.QueryLockWallets)).
+		WithArgs(pq.Array(uids)).
+		WillReturnRows(rows)
+}
+
 func TestWalletRepo_Transfer(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
@@ -443,22 +436,62 @@ func TestWalletRepo_Transfer(t *testing.T) {
 		amount := decimal.NewFromFloat(100.5)
 
 		mock.ExpectBegin()
+		expectLockRows(mock, fromUID, toUID)
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WithArgs(amount, fromUID, model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
+			WithArgs(amount, toUID, model.MaxBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+			WithArgs(fromUID, toUID, amount, model.TransactionTypeTransfer, "", "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := walletRepo.Transfer(ctx, fromUID, toUID, amount, model.FeeCharge{}, model.TransactionDetails{})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestTransfer_PaymentClaimed", func(t *testing.T) {
+		amount := decimal.NewFromInt(10)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryClaimPaymentRequest)).
+			WithArgs(int64(5), int64(123)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectLockRows(mock, 123, 456)
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
-			WithArgs(amount, fromUID, model.MinBalance).
+			WithArgs(amount, int64(123), model.MinBalance, int64(0)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
-			WithArgs(amount, toUID, model.MaxBalance).
+			WithArgs(amount, int64(456), model.MaxBalance, int64(0)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
-			WithArgs(fromUID, toUID, amount, model.TransactionTypeTransfer).
+			WithArgs(int64(123), int64(456), amount, model.TransactionTypeTransfer, "", "", "").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
-		err := walletRepo.Transfer(ctx, fromUID, toUID, amount)
+		err := walletRepo.TransferAndClaim(ctx, 123, 456, amount, model.FeeCharge{}, model.TransactionDetails{},
+			model.PaymentClaim{ID: 5, PayerUID: 123})
 		require.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("TestTransfer_PaymentNoLongerPending", func(t *testing.T) {
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryClaimPaymentRequest)).
+			WithArgs(int64(5), int64(123)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := walletRepo.TransferAndClaim(ctx, 123, 456, decimal.NewFromInt(10), model.FeeCharge{},
+			model.TransactionDetails{}, model.PaymentClaim{ID: 5, PayerUID: 123})
+		require.ErrorIs(t, err, ErrPaymentRequestClosed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("TestTransfer_WithdrawError", func(t *testing.T) {
 		fromUID := int64(123)
 		toUID := int64(456)
@@ -466,12 +499,13 @@ func TestWalletRepo_Transfer(t *testing.T) {
 		expectedErr := fmt.Errorf("withdraw failed")
 
 		mock.ExpectBegin()
+		expectLockRows(mock, fromUID, toUID)
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
-			WithArgs(amount, fromUID, model.MinBalance).
+			WithArgs(amount, fromUID, model.MinBalance, int64(0)).
 			WillReturnError(expectedErr)
 		mock.ExpectRollback()
 
-		err := walletRepo.Transfer(ctx, fromUID, toUID, amount)
+		err := walletRepo.Transfer(ctx, fromUID, toUID, amount, model.FeeCharge{}, model.TransactionDetails{})
 		require.Error(t, err)
 		assert.Equal(t, expectedErr, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -484,15 +518,16 @@ func TestWalletRepo_Transfer(t *testing.T) {
 		expectedErr := fmt.Errorf("transfer failed")
 
 		mock.ExpectBegin()
+		expectLockRows(mock, fromUID, toUID)
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
-			WithArgs(amount, fromUID, model.MinBalance).
+			WithArgs(amount, fromUID, model.MinBalance, int64(0)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
-			WithArgs(amount, toUID, model.MaxBalance).
+			WithArgs(amount, toUID, model.MaxBalance, int64(0)).
 			WillReturnError(expectedErr)
 		mock.ExpectRollback()
 
-		err := walletRepo.Transfer(ctx, fromUID, toUID, amount)
+		err := walletRepo.Transfer(ctx, fromUID, toUID, amount, model.FeeCharge{}, model.TransactionDetails{})
 		require.Error(t, err)
 		assert.Equal(t, expectedErr, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -505,18 +540,19 @@ func TestWalletRepo_Transfer(t *testing.T) {
 		expectedErr := fmt.Errorf("insert transaction failed")
 
 		mock.ExpectBegin()
+		expectLockRows(mock, fromUID, toUID)
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
-			WithArgs(amount, fromUID, model.MinBalance).
+			WithArgs(amount, fromUID, model.MinBalance, int64(0)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletTransfer)).
-			WithArgs(amount, toUID, model.MaxBalance).
+			WithArgs(amount, toUID, model.MaxBalance, int64(0)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
-			WithArgs(fromUID, toUID, amount, model.TransactionTypeTransfer).
+			WithArgs(fromUID, toUID, amount, model.TransactionTypeTransfer, "", "", "").
 			WillReturnError(expectedErr)
 		mock.ExpectRollback()
 
-		err := walletRepo.Transfer(ctx, fromUID, toUID, amount)
+		err := walletRepo.Transfer(ctx, fromUID, toUID, amount, model.FeeCharge{}, model.TransactionDetails{})
 		require.Error(t, err)
 		assert.Equal(t, expectedErr, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -586,3 +622,298 @@ func TestWalletRepo_Balance(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestWalletRepo_Adjust(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	walletRepo := &WalletRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	uid := int64(123)
+
+	tests := []struct {
+		name             string
+		amount           decimal.Decimal
+		sender, receiver int64
+	}{
+		{"Credit", decimal.NewFromInt(25), 0, uid},
+		{"Debit", decimal.NewFromInt(-25), uid, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletAdjust)).
+				WithArgs(tt.amount, uid, model.MaxBalance, int64(0), model.MinBalance).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertTransaction)).
+				WithArgs(tt.sender, tt.receiver, decimal.NewFromInt(25), model.TransactionTypeAdjustment, "", "", "").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+
+			require.NoError(t, walletRepo.Adjust(ctx, uid, tt.amount))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("Out of bounds", func(t *testing.T) {
+		amount := decimal.NewFromInt(-25)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletAdjust)).
+			WithArgs(amount, uid, model.MaxBalance, int64(0), model.MinBalance).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(model.WalletStatusActive))
+		mock.ExpectRollback()
+
+		require.ErrorIs(t, walletRepo.Adjust(ctx, uid, amount), ErrWriteRejected)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWalletRepo_SetStatus(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	walletRepo := &WalletRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletSetStatus)).
+			WithArgs(model.WalletStatusFrozen, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, walletRepo.SetStatus(ctx, 1, model.WalletStatusFrozen))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("No wallet", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletSetStatus)).
+			WithArgs(model.WalletStatusFrozen, int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(int64(2)).
+			WillReturnError(sql.ErrNoRows)
+
+		require.ErrorIs(t, walletRepo.SetStatus(ctx, 2, model.WalletStatusFrozen), sql.ErrNoRows)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Closed wallet", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletSetStatus)).
+			WithArgs(model.WalletStatusActive, int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(model.WalletStatusClosed))
+
+		require.ErrorIs(t, walletRepo.SetStatus(ctx, 3, model.WalletStatusActive), ErrWalletClosed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWalletRepo_Handle(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	walletRepo := &WalletRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	t.Run("Get by handle", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletByHandle)).
+			WithArgs("alice_shop").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "balance", "status", "handle", "created_at", "updated_at"}).
+				AddRow(1, 7, "10", model.WalletStatusActive, "alice_shop", now, now))
+
+		wallet, err := walletRepo.GetWalletByHandle(ctx, "alice_shop")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), wallet.UID)
+		assert.Equal(t, "alice_shop", wallet.Handle)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletSetHandle)).
+			WithArgs("alice_shop", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, walletRepo.SetHandle(ctx, 1, "alice_shop"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Taken", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletSetHandle)).
+			WithArgs("alice_shop", int64(2)).
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		require.ErrorIs(t, walletRepo.SetHandle(ctx, 2, "alice_shop"), ErrHandleTaken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Closed wallet", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletSetHandle)).
+			WithArgs("alice_shop", int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(model.WalletStatusClosed))
+
+		require.ErrorIs(t, walletRepo.SetHandle(ctx, 3, "alice_shop"), ErrWalletClosed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWalletRepo_StatusGuard(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	walletRepo := &WalletRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	uid := int64(123)
+	amount := decimal.NewFromInt(10)
+
+	tests := []struct {
+		name     string
+		debit    bool
+		status   model.WalletStatus
+		expected error
+	}{
+		{"Deposit to a frozen wallet", false, model.WalletStatusFrozen, ErrWalletFrozen},
+		{"Deposit to a debit-blocked wallet", false, model.WalletStatusDebitBlocked, ErrWriteRejected},
+		{"Deposit to a closed wallet", false, model.WalletStatusClosed, ErrWalletClosed},
+		{"Withdraw from a frozen wallet", true, model.WalletStatusFrozen, ErrWalletFrozen},
+		{"Withdraw from a debit-blocked wallet", true, model.WalletStatusDebitBlocked, ErrWalletDebitBlocked},
+		{"Withdraw from a closed wallet", true, model.WalletStatusClosed, ErrWalletClosed},
+		{"Withdraw past the balance", true, model.WalletStatusActive, ErrWriteRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, bound := model.QueryWalletDeposit, any(model.MaxBalance)
+			if tt.debit {
+				query, bound = model.QueryWalletWithdraw, any(model.MinBalance)
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(query)).
+				WithArgs(amount, uid, bound, int64(0)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(model.QueryWalletStatus)).
+				WithArgs(uid).
+				WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(tt.status))
+			mock.ExpectRollback()
+
+			if tt.debit {
+				err = walletRepo.Withdraw(ctx, uid, amount, model.FeeCharge{}, model.TransactionDetails{})
+			} else {
+				err = walletRepo.Deposit(ctx, uid, amount, model.TransactionDetails{})
+			}
+			require.ErrorIs(t, err, tt.expected)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWalletRepo_Fee(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	walletRepo := &WalletRepo{
+		db:     db,
+		logger: zap.NewExample().Sugar(),
+	}
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	uid := int64(123)
+	amount := decimal.NewFromInt(100)
+	fee := model.FeeCharge{Amount: decimal.NewFromInt(2), RevenueUID: 9}
+
+	t.Run("TestFee_Posted", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLockRows(mock, uid, fee.RevenueUID)
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WithArgs(decimal.NewFromInt(102), uid, model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryInsertTransactionID)).
+			WithArgs(uid, 0, amount, model.TransactionTypeWithdraw, "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(77)))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletCreditFee)).
+			WithArgs(fee.Amount, fee.RevenueUID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryInsertLinkedTransaction)).
+			WithArgs(uid, fee.RevenueUID, fee.Amount, model.TransactionTypeFee, int64(77)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := walletRepo.Withdraw(ctx, uid, amount, fee, model.TransactionDetails{})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestFee_MissingRevenueWallet", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLockRows(mock, uid, fee.RevenueUID)
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletWithdraw)).
+			WithArgs(decimal.NewFromInt(102), uid, model.MinBalance, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryInsertTransactionID)).
+			WithArgs(uid, 0, amount, model.TransactionTypeWithdraw, "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(78)))
+		mock.ExpectExec(regexp.QuoteMeta(model.QueryWalletCreditFee)).
+			WithArgs(fee.Amount, fee.RevenueUID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := walletRepo.Withdraw(ctx, uid, amount, fee, model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrRevenueWallet)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestTier", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(model.QueryUserTier)).
+			WithArgs(uid).
+			WillReturnRows(sqlmock.NewRows([]string{"tier"}).AddRow("premium"))
+
+		tier, err := walletRepo.Tier(ctx, uid)
+		require.NoError(t, err)
+		assert.Equal(t, "premium", tier)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
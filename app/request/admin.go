@@ -0,0 +1,38 @@
+package request
+
+import (
+	"github.com/shopspring/decimal"
+
+	"server/app/model"
+)
+
+// ReqUserSearch searches users for the admin API. Query matches part of the username or email,
+// and Role only filters staff roles. All filters are optional and combined with AND.
+type ReqUserSearch struct {
+	Query string     `form:"q"`
+	UID   int64      `form:"uid"`
+	Role  model.Role `form:"role"`
+	ReqPage
+}
+
+type ResUsers struct {
+	List    []*model.User `json:"list"`
+	HasMore bool          `json:"has_more"`
+}
+
+// ReqAdminAction justifies an admin action that changes an account.
+type ReqAdminAction struct {
+	Reason string `json:"reason"`
+	Ticket string `json:"ticket"`
+}
+
+// ReqAdjustment credits a wallet when Amount is positive and debits it when negative.
+type ReqAdjustment struct {
+	Amount decimal.Decimal `json:"amount"`
+	ReqAdminAction
+}
+
+type ReqRole struct {
+	Role   model.Role `json:"role"`
+	Reason string     `json:"reason"`
+}
@@ -0,0 +1,20 @@
+package request
+
+import (
+	"server/app/model"
+)
+
+// ReqAuditLog lists the audit log of an entity, newest first. EntityID and Action narrow it
+// further and ActorID keeps what one staff member or user did.
+type ReqAuditLog struct {
+	Entity   string `form:"entity"`
+	EntityID int64  `form:"entity_id"`
+	Action   string `form:"action"`
+	ActorID  int64  `form:"actor_id"`
+	ReqPage
+}
+
+type ResAuditLog struct {
+	List    []*model.AuditRecord `json:"list"`
+	HasMore bool                 `json:"has_more"`
+}
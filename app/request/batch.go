@@ -0,0 +1,26 @@
+package request
+
+import (
+	"github.com/shopspring/decimal"
+
+	"server/app/model"
+)
+
+// ReqBatchTransfer pays Items out of one wallet. Mode defaults to atomic.
+type ReqBatchTransfer struct {
+	Mode  model.BatchMode `json:"mode"` // 1-atomic, 2-best-effort
+	Items []ReqBatchItem  `json:"items"`
+}
+
+// ReqBatchItem is one transfer of a batch. A Reference is paid at most once from the funding
+// wallet, so a batch can be submitted again after an interruption.
+type ReqBatchItem struct {
+	ToUID     int64           `json:"to_uid"`
+	Amount    decimal.Decimal `json:"amount"`
+	Reference string          `json:"reference"` // optional
+}
+
+type ReqBatchID struct {
+	UID int64 `uri:"uid"`
+	ID  int64 `uri:"id"`
+}
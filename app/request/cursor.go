@@ -0,0 +1,47 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	DirectionNext = "next"
+	DirectionPrev = "prev"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the decoded form of an opaque pagination token.
+// It points at a single transaction by its (created_at, id) sort key.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+}
+
+// EncodeCursor returns the opaque token for the given sort key.
+func EncodeCursor(createdAt time.Time, id int64) string {
+	bytes, _ := json.Marshal(Cursor{CreatedAt: createdAt, ID: id})
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// DecodeCursor parses a token produced by EncodeCursor.
+func DecodeCursor(token string) (*Cursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	if err = json.Unmarshal(bytes, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.ID <= 0 || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,69 @@
+package request
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestCursor(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		createdAt := time.Date(2024, 5, 1, 10, 30, 0, 123456000, time.UTC)
+
+		cursor, err := DecodeCursor(EncodeCursor(createdAt, 42))
+		require.NoError(t, err)
+		assert.True(t, createdAt.Equal(cursor.CreatedAt))
+		assert.Equal(t, int64(42), cursor.ID)
+	})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"NotBase64", "%%%"},
+		{"NotJSON", "bm90IGpzb24"},
+		{"MissingID", EncodeCursor(time.Now(), 0)},
+		{"MissingTime", EncodeCursor(time.Time{}, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeCursor(tt.token)
+			require.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
+
+func TestReqTransactions_ValidateCursor(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		name    string
+		req     ReqTransactions
+		wantErr bool
+	}{
+		{"OffsetOnly", ReqTransactions{}, false},
+		{"Next", ReqTransactions{Cursor: EncodeCursor(time.Now(), 1), Direction: DirectionNext}, false},
+		{"Prev", ReqTransactions{Cursor: EncodeCursor(time.Now(), 1), Direction: DirectionPrev}, false},
+		{"DefaultDirection", ReqTransactions{Cursor: EncodeCursor(time.Now(), 1)}, false},
+		{"UnknownDirection", ReqTransactions{Direction: "sideways"}, true},
+		{"BrokenCursor", ReqTransactions{Cursor: "broken"}, true},
+		{"AmountSort", ReqTransactions{Cursor: EncodeCursor(time.Now(), 1), Sort: "amount_desc"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.ValidateCursor()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
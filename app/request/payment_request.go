@@ -0,0 +1,60 @@
+package request
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"server/app/model"
+)
+
+// ReqPaymentRequest asks for Amount from the wallet of PayerUID, or from anyone holding the token
+// of the request when PayerUID is 0. ExpiresAt defaults to a week from now.
+type ReqPaymentRequest struct {
+	PayerUID  int64           `json:"payer_uid"`
+	Amount    decimal.Decimal `json:"amount"`
+	Memo      string          `json:"memo"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// ReqPaymentRequests lists the payment requests of a wallet. Flow is "outgoing" for the requests
+// it made, "incoming" for the ones it was asked to pay, or empty for both.
+type ReqPaymentRequests struct {
+	UID    int64                      `json:"-"`
+	Flow   string                     `form:"flow"`
+	Status model.PaymentRequestStatus `form:"status"`
+	ReqPage
+}
+
+// ValidateFilter checks the flow and the status filters.
+func (r *ReqPaymentRequests) ValidateFilter() error {
+	if r.Flow != "" && r.Flow != model.FlowIncoming && r.Flow != model.FlowOutgoing {
+		return fmt.Errorf("%w: unknown flow %q", ErrInvalidFilter, r.Flow)
+	}
+
+	if r.Status != 0 && !r.Status.IsValid() {
+		return fmt.Errorf("%w: unknown status %d", ErrInvalidFilter, r.Status)
+	}
+
+	return nil
+}
+
+type ResPaymentRequests struct {
+	List    []*model.PaymentRequest `json:"list"`
+	HasMore bool                    `json:"has_more"`
+}
+
+type ReqPaymentRequestID struct {
+	UID int64 `uri:"uid"`
+	ID  int64 `uri:"id"`
+}
+
+// ReqAcceptPaymentRequest pays a payment request. The token is needed for an open request.
+type ReqAcceptPaymentRequest struct {
+	Token string `json:"token"`
+}
+
+type ReqPaymentToken struct {
+	Token string `uri:"token"`
+}
@@ -0,0 +1,16 @@
+package request
+
+import (
+	"server/app/model"
+)
+
+// ReqDiscrepancies lists reconciliation discrepancies. Status defaults to open.
+type ReqDiscrepancies struct {
+	ReqPage
+	Status model.DiscrepancyStatus `form:"status"`
+}
+
+type ResDiscrepancies struct {
+	List    []*model.Discrepancy `json:"list"`
+	HasMore bool                 `json:"has_more"`
+}
@@ -13,3 +13,9 @@ type ReqUsername struct {
 type ReqEmail struct {
 	Email string `uri:"email" form:"email" json:"email"`
 }
+
+// ReqCloseAccount closes an account. Settle withdraws whatever balance is left first; without it
+// only an empty wallet is closed.
+type ReqCloseAccount struct {
+	Settle bool `json:"settle"`
+}
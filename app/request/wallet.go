@@ -1,13 +1,23 @@
 package request
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
 	"github.com/shopspring/decimal"
 
 	"server/app/model"
+	"server/pkg/statement"
 )
 
+// ReqAmount is a deposit or a withdrawal. Its memo, external_reference and metadata are optional
+// and stored with the transaction.
 type ReqAmount struct {
-	Amount decimal.Decimal `json:"amount"`
+	Amount  decimal.Decimal `json:"amount"`
+	QuoteID string          `json:"quote_id"` // quote of a dry run whose fee is charged, optional
+	model.TransactionDetails
 }
 
 type ReqDeposit struct {
@@ -19,21 +29,225 @@ type ReqWithdraw struct {
 }
 
 type ReqTransfer struct {
-	ToUID  int64           `json:"to_uid"`
-	Amount decimal.Decimal `json:"amount"`
+	ToUID   int64           `json:"to_uid"`
+	To      string          `json:"to"` // username, email or @handle of the recipient, instead of to_uid
+	Amount  decimal.Decimal `json:"amount"`
+	QuoteID string          `json:"quote_id"` // quote of a dry run whose fee is charged, optional
+	model.TransactionDetails
+}
+
+// ReqRecipient addresses the recipient of a transfer by username, email or @handle.
+type ReqRecipient struct {
+	To string `form:"to"`
+}
+
+// ReqHandle is the handle to give a wallet.
+type ReqHandle struct {
+	Handle string `json:"handle"`
+}
+
+// ReqDryRun asks for the outcome of a money movement instead of making it. With Quote, the fee
+// of a withdrawal or a transfer is locked in under a quote ID.
+type ReqDryRun struct {
+	DryRun bool `form:"dry_run"`
+	Quote  bool `form:"quote"`
+}
+
+// ReqFeeQuote asks for the fee of a withdrawal or a transfer of Amount.
+type ReqFeeQuote struct {
+	Operation string          `form:"operation"`
+	Amount    decimal.Decimal `form:"amount"`
 }
 
 type ResBalance struct {
 	Balance decimal.Decimal `json:"balance"`
 }
 
+// ReqTransactions lists a wallet's transactions.
+// All filters are optional and combined with AND; Type is kept for older clients and
+// is merged into Types. Flow is "incoming" or "outgoing" relative to the wallet,
+// Counterparty matches the other party's username and ExternalReference the reference a
+// client gave the transaction.
 type ReqTransactions struct {
-	UID  int64                 `json:"-"`
-	Type model.TransactionType `form:"type" `
+	UID               int64                   `json:"-"`
+	Type              model.TransactionType   `form:"type" `
+	Types             []model.TransactionType `form:"types"`
+	From              time.Time               `form:"from"`
+	To                time.Time               `form:"to"`
+	MinAmount         decimal.NullDecimal     `form:"min_amount"`
+	MaxAmount         decimal.NullDecimal     `form:"max_amount"`
+	Flow              string                  `form:"flow"`
+	CounterpartyUID   int64                   `form:"counterparty_uid"`
+	Counterparty      string                  `form:"counterparty"`
+	ExternalReference string                  `form:"external_reference"`
+	Sort              string                  `form:"sort"`
+	Cursor            string                  `form:"cursor"`
+	Direction         string                  `form:"direction"`
 	ReqPage
 }
 
+var (
+	ErrInvalidFilter = errors.New("invalid filter")
+	// ErrInvalidTransactionType is returned, along with ErrInvalidFilter, for a type filter that
+	// names no transaction type.
+	ErrInvalidTransactionType = errors.New("invalid transaction type")
+)
+
+// TransactionTypes returns the requested types with the legacy Type folded in.
+func (r *ReqTransactions) TransactionTypes() []model.TransactionType {
+	types := slices.Clone(r.Types)
+	if model.GetTransactionTypeString(r.Type) != "" && !slices.Contains(types, r.Type) {
+		types = append(types, r.Type)
+	}
+
+	return types
+}
+
+// ValidateFilter checks that the filters are well-formed and consistent with each other.
+func (r *ReqTransactions) ValidateFilter() error {
+	// A zero Type leaves the legacy filter out; every one of Types must be known.
+	if r.Type != 0 && model.GetTransactionTypeString(r.Type) == "" {
+		return fmt.Errorf("%w: %w %d", ErrInvalidFilter, ErrInvalidTransactionType, r.Type)
+	}
+
+	for _, tType := range r.Types {
+		if model.GetTransactionTypeString(tType) == "" {
+			return fmt.Errorf("%w: %w %d", ErrInvalidFilter, ErrInvalidTransactionType, tType)
+		}
+	}
+
+	if !r.From.IsZero() && !r.To.IsZero() && r.From.After(r.To) {
+		return fmt.Errorf("%w: from must not be after to", ErrInvalidFilter)
+	}
+
+	if (r.MinAmount.Valid && r.MinAmount.Decimal.IsNegative()) || (r.MaxAmount.Valid && r.MaxAmount.Decimal.IsNegative()) {
+		return fmt.Errorf("%w: amounts must not be negative", ErrInvalidFilter)
+	}
+
+	if r.MinAmount.Valid && r.MaxAmount.Valid && r.MinAmount.Decimal.GreaterThan(r.MaxAmount.Decimal) {
+		return fmt.Errorf("%w: min_amount must not be greater than max_amount", ErrInvalidFilter)
+	}
+
+	if r.Flow != "" && r.Flow != model.FlowIncoming && r.Flow != model.FlowOutgoing {
+		return fmt.Errorf("%w: unknown flow %q", ErrInvalidFilter, r.Flow)
+	}
+
+	if r.CounterpartyUID < 0 {
+		return fmt.Errorf("%w: invalid counterparty_uid", ErrInvalidFilter)
+	}
+
+	if model.GetTransactionSortOrder(r.Sort) == "" {
+		return fmt.Errorf("%w: unknown sort %q", ErrInvalidFilter, r.Sort)
+	}
+
+	return nil
+}
+
+// IsCursor reports whether the request pages by cursor instead of by offset.
+func (r *ReqTransactions) IsCursor() bool {
+	return r.Cursor != ""
+}
+
+// IsPrev reports whether the request walks towards newer transactions.
+// An empty direction is treated as DirectionNext.
+func (r *ReqTransactions) IsPrev() bool {
+	return r.Direction == DirectionPrev
+}
+
+// ValidateCursor checks the direction and that the cursor, if any, can be decoded.
+func (r *ReqTransactions) ValidateCursor() error {
+	if r.Direction != "" && r.Direction != DirectionNext && r.Direction != DirectionPrev {
+		return ErrInvalidCursor
+	}
+
+	if !r.IsCursor() {
+		return nil
+	}
+
+	if !model.IsCursorSort(r.Sort) {
+		return ErrInvalidCursor
+	}
+
+	_, err := DecodeCursor(r.Cursor)
+
+	return err
+}
+
 type ResTransactions struct {
-	List    []*model.TransactionWithUsername `json:"list"`
-	HasMore bool                             `json:"has_more"`
+	List       []*model.TransactionWithUsername `json:"list"`
+	HasMore    bool                             `json:"has_more"`
+	NextCursor string                           `json:"next_cursor,omitempty"`
+	PrevCursor string                           `json:"prev_cursor,omitempty"`
+}
+
+// ReqPeriod is a range of whole days, both inclusive.
+// Both ends default to the current month so far.
+type ReqPeriod struct {
+	From time.Time `form:"from" time_format:"2006-01-02"`
+	To   time.Time `form:"to" time_format:"2006-01-02"`
+}
+
+const periodMaxDays = 366
+
+// Validate fills in the defaults relative to now and checks the period.
+func (r *ReqPeriod) Validate(now time.Time) error {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if r.To.IsZero() {
+		r.To = today
+	}
+
+	if r.From.IsZero() {
+		r.From = time.Date(r.To.Year(), r.To.Month(), 1, 0, 0, 0, 0, r.To.Location())
+	}
+
+	if r.From.After(r.To) {
+		return fmt.Errorf("%w: from must not be after to", ErrInvalidFilter)
+	}
+
+	if r.To.Sub(r.From) > periodMaxDays*24*time.Hour {
+		return fmt.Errorf("%w: period must not exceed %d days", ErrInvalidFilter, periodMaxDays)
+	}
+
+	return nil
+}
+
+// End returns the exclusive upper bound of the period.
+func (r *ReqPeriod) End() time.Time {
+	return r.To.AddDate(0, 0, 1)
+}
+
+// ReqStatement exports a wallet's transactions for a period. Format defaults to csv.
+type ReqStatement struct {
+	UID int64 `json:"-"`
+	ReqPeriod
+	Format string `form:"format"`
+}
+
+// Validate fills in the defaults relative to now and checks the format and period.
+func (r *ReqStatement) Validate(now time.Time) error {
+	if r.Format == "" {
+		r.Format = statement.FormatCSV
+	}
+
+	if statement.ContentType(r.Format) == "" {
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidFilter, r.Format)
+	}
+
+	return r.ReqPeriod.Validate(now)
+}
+
+// ReqBalance asks for the current balance, or the balance at a point in time when At is set.
+type ReqBalance struct {
+	At time.Time `form:"at"`
+}
+
+// ReqBalanceHistory asks for the closing balance of every day in a period.
+type ReqBalanceHistory struct {
+	UID int64 `json:"-"`
+	ReqPeriod
+}
+
+type ResBalanceHistory struct {
+	List []*model.DailyBalance `json:"list"`
 }
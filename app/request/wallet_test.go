@@ -0,0 +1,139 @@
+package request
+
+import (
+	"testing"
+	"time"
+
+	"server/app/model"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestReqTransactions_TransactionTypes(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		name     string
+		req      ReqTransactions
+		expected []model.TransactionType
+	}{
+		{"None", ReqTransactions{}, nil},
+		{"LegacyType", ReqTransactions{Type: model.TransactionTypeDeposit},
+			[]model.TransactionType{model.TransactionTypeDeposit}},
+		{"Types", ReqTransactions{Types: []model.TransactionType{model.TransactionTypeWithdraw}},
+			[]model.TransactionType{model.TransactionTypeWithdraw}},
+		{"Merged", ReqTransactions{
+			Type:  model.TransactionTypeDeposit,
+			Types: []model.TransactionType{model.TransactionTypeWithdraw, model.TransactionTypeDeposit},
+		}, []model.TransactionType{model.TransactionTypeWithdraw, model.TransactionTypeDeposit}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.req.TransactionTypes())
+		})
+	}
+}
+
+func TestReqTransactions_ValidateFilter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		req     ReqTransactions
+		wantErr bool
+	}{
+		{"Empty", ReqTransactions{}, false},
+		{"Valid", ReqTransactions{
+			Types:     []model.TransactionType{model.TransactionTypeTransfer},
+			From:      now.Add(-time.Hour),
+			To:        now,
+			MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(1)),
+			MaxAmount: decimal.NewNullDecimal(decimal.NewFromInt(2)),
+			Flow:      model.FlowIncoming,
+			Sort:      model.SortAmountAsc,
+		}, false},
+		{"UnknownType", ReqTransactions{Types: []model.TransactionType{9}}, true},
+		{"UnknownLegacyType", ReqTransactions{Type: 9}, true},
+		{"FeeType", ReqTransactions{Type: model.TransactionTypeFee,
+			Types: []model.TransactionType{model.TransactionTypeFee}}, false},
+		{"FromAfterTo", ReqTransactions{From: now, To: now.Add(-time.Hour)}, true},
+		{"NegativeAmount", ReqTransactions{MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(-1))}, true},
+		{"MinAboveMax", ReqTransactions{
+			MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(5)),
+			MaxAmount: decimal.NewNullDecimal(decimal.NewFromInt(2)),
+		}, true},
+		{"UnknownFlow", ReqTransactions{Flow: "sideways"}, true},
+		{"NegativeCounterparty", ReqTransactions{CounterpartyUID: -1}, true},
+		{"UnknownSort", ReqTransactions{Sort: "random"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.ValidateFilter()
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidFilter)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestReqStatement_Validate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	now := time.Date(2024, 5, 17, 15, 4, 5, 0, time.UTC)
+
+	t.Run("Defaults", func(t *testing.T) {
+		req := &ReqStatement{}
+		require.NoError(t, req.Validate(now))
+		assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), req.From)
+		assert.Equal(t, time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC), req.To)
+		assert.Equal(t, time.Date(2024, 5, 18, 0, 0, 0, 0, time.UTC), req.End())
+		assert.Equal(t, "csv", req.Format)
+	})
+
+	tests := []struct {
+		name string
+		req  ReqStatement
+	}{
+		{"UnknownFormat", ReqStatement{Format: "xml"}},
+		{"FromAfterTo", ReqStatement{ReqPeriod: ReqPeriod{From: now, To: now.AddDate(0, 0, -1)}}},
+		{"TooLong", ReqStatement{ReqPeriod: ReqPeriod{From: now.AddDate(-2, 0, 0), To: now}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, tt.req.Validate(now), ErrInvalidFilter)
+		})
+	}
+}
+
+func TestReqPeriod_Validate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	now := time.Date(2024, 5, 17, 15, 4, 5, 0, time.UTC)
+
+	t.Run("Keeps explicit period", func(t *testing.T) {
+		req := &ReqPeriod{From: now.AddDate(0, 0, -3), To: now}
+		require.NoError(t, req.Validate(now))
+		assert.Equal(t, now.AddDate(0, 0, -3), req.From)
+		assert.Equal(t, now, req.To)
+	})
+
+	t.Run("SameDay", func(t *testing.T) {
+		req := &ReqPeriod{From: now, To: now}
+		require.NoError(t, req.Validate(now))
+	})
+
+	t.Run("FromAfterTo", func(t *testing.T) {
+		req := &ReqPeriod{From: now, To: now.AddDate(0, 0, -1)}
+		require.ErrorIs(t, req.Validate(now), ErrInvalidFilter)
+	})
+}
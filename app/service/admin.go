@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/request"
+	"server/pkg/lock"
+	"server/pkg/metrics"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrNotJustified        = errors.New("reason and ticket are required")
+	ErrZeroAmount          = errors.New("amount must not be zero")
+	ErrInvalidWalletStatus = errors.New("wallet status can't be set")
+)
+
+const (
+	maxReasonLength = 500
+	maxTicketLength = 64
+)
+
+// dummyHash is compared against when the username is unknown, so that a failed login takes as
+// long whether or not the user exists.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// NewAdmin creates the service behind the admin API. Every change it makes is recorded in audit.
+func NewAdmin(repo repository.AdminInter, walletRepo repository.WalletInter, audit repository.AuditInter,
+	locker lock.Locker, logger *zap.SugaredLogger) AdminInter {
+	return &AdminServ{
+		repo:       repo,
+		walletRepo: walletRepo,
+		audit:      audit,
+		locker:     locker,
+		logger:     logger,
+	}
+}
+
+// AdminInter defines the interface for the actions of support staff.
+type AdminInter interface {
+	Authenticate(ctx context.Context, username, password string) (*model.User, error)
+	SearchUsers(ctx *gin.Context, req *request.ReqUserSearch) (*request.ResUsers, error)
+	Wallet(ctx *gin.Context, uid int64) (*model.Wallet, error)
+	SetWalletStatus(ctx *gin.Context, actor *model.Actor, uid int64, status model.WalletStatus,
+		req *request.ReqAdminAction) (*model.Wallet, error)
+	Adjust(ctx *gin.Context, actor *model.Actor, uid int64, req *request.ReqAdjustment) (*model.Wallet, error)
+	SetRole(ctx context.Context, actor *model.Actor, uid int64, req *request.ReqRole) error
+}
+
+// AdminServ implements the AdminInter interface.
+type AdminServ struct {
+	repo       repository.AdminInter
+	walletRepo repository.WalletInter
+	audit      repository.AuditInter
+	locker     lock.Locker
+	logger     *zap.SugaredLogger
+}
+
+// Authenticate returns the user called username if password is theirs and the account is valid.
+// Whether the user is staff is left to the caller.
+func (a *AdminServ) Authenticate(ctx context.Context, username, password string) (*model.User, error) {
+	user, err := a.repo.Credentials(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)) != nil ||
+		user.Status != model.UserStatusValid {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// SearchUsers lists the users matching req.
+func (a *AdminServ) SearchUsers(ctx *gin.Context, req *request.ReqUserSearch) (res *request.ResUsers, err error) {
+	end := tracing.StartGin(ctx, "AdminServ.SearchUsers")
+	defer func() { end(err) }()
+
+	return a.repo.SearchUsers(ctx, req)
+}
+
+// Wallet returns the wallet of uid as stored, bypassing any cache.
+func (a *AdminServ) Wallet(ctx *gin.Context, uid int64) (res *model.Wallet, err error) {
+	end := tracing.StartGin(ctx, "AdminServ.Wallet")
+	defer func() { end(err) }()
+
+	return a.walletRepo.GetWalletByUID(ctx, uid)
+}
+
+// walletStatusActions are the statuses staff may give a wallet, with the action recording it.
+// A wallet is closed through its own procedure, never by a status change.
+var walletStatusActions = map[model.WalletStatus]string{
+	model.WalletStatusActive:       model.AuditWalletUnfreeze,
+	model.WalletStatusFrozen:       model.AuditWalletFreeze,
+	model.WalletStatusDebitBlocked: model.AuditWalletDebitBlock,
+}
+
+// SetWalletStatus freezes the wallet of uid, blocks its debits or makes it active again.
+func (a *AdminServ) SetWalletStatus(ctx *gin.Context, actor *model.Actor, uid int64, status model.WalletStatus,
+	req *request.ReqAdminAction) (res *model.Wallet, err error) {
+	end := tracing.StartGin(ctx, "AdminServ.SetWalletStatus")
+	defer func() { end(err) }()
+
+	if err = checkJustified(req); err != nil {
+		return nil, err
+	}
+
+	action, ok := walletStatusActions[status]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidWalletStatus, status)
+	}
+
+	entry := newAuditEntry(actor, action, model.AuditEntityWallet, uid, req)
+	defer func() { recordAudit(ctx, a.audit, a.logger, entry, err) }()
+
+	before, err := a.walletRepo.GetWalletByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	entry.Before = before
+
+	if err = a.walletRepo.SetStatus(ctx, uid, status); err != nil {
+		return nil, err
+	}
+
+	res, err = a.walletRepo.GetWalletByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	entry.After = res
+
+	return res, nil
+}
+
+// Adjust credits or debits the wallet of uid by req.Amount, even when it is frozen or blocked
+// for debits, holding the wallet lock like any other money movement. Closed wallets are refused.
+func (a *AdminServ) Adjust(ctx *gin.Context, actor *model.Actor, uid int64,
+	req *request.ReqAdjustment) (res *model.Wallet, err error) {
+	end := tracing.StartGin(ctx, "AdminServ.Adjust")
+	defer func() {
+		metrics.ObserveMoney(model.Adjustment, req.Amount.Abs(), failureReason(err))
+		end(err)
+	}()
+
+	if req.Amount.IsZero() {
+		return nil, fmt.Errorf("adjustment %w", ErrZeroAmount)
+	}
+
+	if err = checkJustified(&req.ReqAdminAction); err != nil {
+		return nil, err
+	}
+
+	entry := newAuditEntry(actor, model.AuditWalletAdjust, model.AuditEntityWallet, uid, &req.ReqAdminAction)
+	defer func() { recordAudit(ctx, a.audit, a.logger, entry, err) }()
+
+	unlock, err := lockWallets(ctx, a.locker, a.logger, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	before, err := a.walletRepo.GetWalletByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	entry.Before = before
+
+	if err = a.walletRepo.Adjust(ctx, uid, req.Amount); err != nil {
+		return nil, err
+	}
+
+	res, err = a.walletRepo.GetWalletByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	entry.After = res
+
+	return res, nil
+}
+
+// SetRole gives uid the role req.Role, RoleNone taking away access to the admin API.
+func (a *AdminServ) SetRole(ctx context.Context, actor *model.Actor, uid int64, req *request.ReqRole) (err error) {
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxReasonLength {
+		return ErrNotJustified
+	}
+
+	entry := newAuditEntry(actor, model.AuditUserRole, model.AuditEntityUser, uid, &request.ReqAdminAction{Reason: req.Reason})
+	defer func() { recordAudit(ctx, a.audit, a.logger, entry, err) }()
+
+	old, err := a.repo.SetRole(ctx, uid, req.Role)
+	if err != nil {
+		return err
+	}
+
+	entry.Before = map[string]model.Role{"role": old}
+	entry.After = map[string]model.Role{"role": req.Role}
+
+	return nil
+}
+
+func newAuditEntry(actor *model.Actor, action, entity string, id int64, req *request.ReqAdminAction) *model.AuditEntry {
+	return &model.AuditEntry{
+		Actor:    *actor,
+		Action:   action,
+		Entity:   entity,
+		EntityID: id,
+		Reason:   req.Reason,
+		Ticket:   req.Ticket,
+	}
+}
+
+// checkJustified requires a reason and a ticket for an action that changes an account.
+func checkJustified(req *request.ReqAdminAction) error {
+	if req.Reason == "" || req.Ticket == "" ||
+		utf8.RuneCountInString(req.Reason) > maxReasonLength || utf8.RuneCountInString(req.Ticket) > maxTicketLength {
+		return fmt.Errorf("%w, of at most %d and %d characters", ErrNotJustified, maxReasonLength, maxTicketLength)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAdminRepo is a mock implementation of the repository.AdminInter interface
+type MockAdminRepo struct {
+	mock.Mock
+}
+
+func (m *MockAdminRepo) Credentials(ctx context.Context, username string) (*model.User, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockAdminRepo) SearchUsers(ctx *gin.Context, req *request.ReqUserSearch) (*request.ResUsers, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResUsers), args.Error(1)
+}
+
+func (m *MockAdminRepo) SetRole(ctx context.Context, uid int64, role model.Role) (model.Role, error) {
+	args := m.Called(ctx, uid, role)
+	return args.Get(0).(model.Role), args.Error(1)
+}
+
+// MockAuditRepo is a mock implementation of the repository.AuditLogInter interface
+type MockAuditRepo struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepo) Record(ctx context.Context, entry *model.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepo) ListAudit(ctx *gin.Context, req *request.ReqAuditLog) (*request.ResAuditLog, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResAuditLog), args.Error(1)
+}
+
+func (m *MockAuditRepo) VerifyAudit(ctx context.Context) (*model.AuditVerification, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*model.AuditVerification), args.Error(1)
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"server/app/model"
+	"server/app/request"
+	"server/pkg/lock"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAdminServ_Authenticate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		user        *model.User
+		repoErr     error
+		password    string
+		expectedErr error
+	}{
+		{
+			name:     "Success",
+			user:     &model.User{ID: 1, PasswordHash: hash, Status: model.UserStatusValid, Role: model.RoleSupport},
+			password: "secret",
+		},
+		{
+			name:        "Wrong password",
+			user:        &model.User{ID: 1, PasswordHash: hash, Status: model.UserStatusValid},
+			password:    "guess",
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:        "Disabled user",
+			user:        &model.User{ID: 1, PasswordHash: hash, Status: model.UserStatusDisabled},
+			password:    "secret",
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:        "Unknown user",
+			user:        &model.User{},
+			repoErr:     sql.ErrNoRows,
+			password:    "secret",
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:        "Repository error",
+			user:        &model.User{},
+			repoErr:     errors.New("db down"),
+			password:    "secret",
+			expectedErr: errors.New("db down"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockAdminRepo)
+			serv := NewAdmin(repo, nil, nil, lock.Nop(), zap.NewNop().Sugar())
+
+			repo.On("Credentials", ctx, "alice").Return(tt.user, tt.repoErr)
+
+			user, err := serv.Authenticate(ctx, "alice", tt.password)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				assert.Nil(t, user)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.user, user)
+			}
+
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminServ_SetWalletStatus(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	actor := &model.Actor{ID: 9, Role: model.RoleSupport}
+	req := &request.ReqAdminAction{Reason: "chargeback", Ticket: "SUP-1"}
+	active := &model.Wallet{UID: 1, Status: model.WalletStatusActive}
+	frozen := &model.Wallet{UID: 1, Status: model.WalletStatusFrozen}
+
+	t.Run("Freeze", func(t *testing.T) {
+		walletRepo := new(MockWalletRepo)
+		audit := new(MockAuditRepo)
+		serv := NewAdmin(new(MockAdminRepo), walletRepo, audit, lock.Nop(), zap.NewNop().Sugar())
+
+		walletRepo.On("GetWalletByUID", ctx, int64(1)).Return(active, nil).Once()
+		walletRepo.On("SetStatus", ctx, int64(1), model.WalletStatusFrozen).Return(nil)
+		walletRepo.On("GetWalletByUID", ctx, int64(1)).Return(frozen, nil).Once()
+		audit.On("Record", ctx, &model.AuditEntry{
+			Actor: *actor, Action: model.AuditWalletFreeze, Entity: model.AuditEntityWallet, EntityID: 1,
+			Before: active, After: frozen, Reason: "chargeback", Ticket: "SUP-1",
+		}).Return(nil)
+
+		res, err := serv.SetWalletStatus(ctx, actor, 1, model.WalletStatusFrozen, req)
+		require.NoError(t, err)
+		assert.Equal(t, frozen, res)
+
+		walletRepo.AssertExpectations(t)
+		audit.AssertExpectations(t)
+	})
+
+	t.Run("Closing is not a status change", func(t *testing.T) {
+		audit := new(MockAuditRepo)
+		serv := NewAdmin(new(MockAdminRepo), new(MockWalletRepo), audit, lock.Nop(), zap.NewNop().Sugar())
+
+		_, err := serv.SetWalletStatus(ctx, actor, 1, model.WalletStatusClosed, req)
+		require.ErrorIs(t, err, ErrInvalidWalletStatus)
+		audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not justified", func(t *testing.T) {
+		audit := new(MockAuditRepo)
+		serv := NewAdmin(new(MockAdminRepo), new(MockWalletRepo), audit, lock.Nop(), zap.NewNop().Sugar())
+
+		_, err := serv.SetWalletStatus(ctx, actor, 1, model.WalletStatusFrozen, &request.ReqAdminAction{Reason: "x"})
+		require.ErrorIs(t, err, ErrNotJustified)
+		audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failure is audited", func(t *testing.T) {
+		walletRepo := new(MockWalletRepo)
+		audit := new(MockAuditRepo)
+		serv := NewAdmin(new(MockAdminRepo), walletRepo, audit, lock.Nop(), zap.NewNop().Sugar())
+
+		walletRepo.On("GetWalletByUID", ctx, int64(1)).Return(&model.Wallet{}, sql.ErrNoRows)
+		audit.On("Record", ctx, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+			return entry.Action == model.AuditWalletUnfreeze && entry.Error == sql.ErrNoRows.Error()
+		})).Return(errors.New("audit down"))
+
+		_, err := serv.SetWalletStatus(ctx, actor, 1, model.WalletStatusActive, req)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		audit.AssertExpectations(t)
+	})
+}
+
+func TestAdminServ_Adjust(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	actor := &model.Actor{ID: 9, Role: model.RoleFinance}
+	action := request.ReqAdminAction{Reason: "refund", Ticket: "FIN-7"}
+
+	t.Run("Success", func(t *testing.T) {
+		walletRepo := new(MockWalletRepo)
+		audit := new(MockAuditRepo)
+		serv := NewAdmin(new(MockAdminRepo), walletRepo, audit, lock.Nop(), zap.NewNop().Sugar())
+
+		amount := decimal.NewFromInt(-5)
+		before := &model.Wallet{UID: 1, Balance: decimal.NewFromInt(10)}
+		after := &model.Wallet{UID: 1, Balance: decimal.NewFromInt(5)}
+
+		walletRepo.On("GetWalletByUID", ctx, int64(1)).Return(before, nil).Once()
+		walletRepo.On("Adjust", ctx, int64(1), amount).Return(nil)
+		walletRepo.On("GetWalletByUID", ctx, int64(1)).Return(after, nil).Once()
+		audit.On("Record", ctx, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+			return entry.Action == model.AuditWalletAdjust && entry.Before == before && entry.After == after &&
+				entry.Ticket == "FIN-7" && entry.Error == ""
+		})).Return(nil)
+
+		res, err := serv.Adjust(ctx, actor, 1, &request.ReqAdjustment{Amount: amount, ReqAdminAction: action})
+		require.NoError(t, err)
+		assert.Equal(t, after, res)
+
+		walletRepo.AssertExpectations(t)
+		audit.AssertExpectations(t)
+	})
+
+	t.Run("Zero amount", func(t *testing.T) {
+		serv := NewAdmin(new(MockAdminRepo), new(MockWalletRepo), new(MockAuditRepo), lock.Nop(), zap.NewNop().Sugar())
+
+		_, err := serv.Adjust(ctx, actor, 1, &request.ReqAdjustment{ReqAdminAction: action})
+		require.ErrorIs(t, err, ErrZeroAmount)
+	})
+
+	t.Run("Lock timeout", func(t *testing.T) {
+		locker := new(MockLocker)
+		audit := new(MockAuditRepo)
+		serv := NewAdmin(new(MockAdminRepo), new(MockWalletRepo), audit, locker, zap.NewNop().Sugar())
+
+		locker.On("Lock", mock.Anything, []string{"wallet:1"}).Return((*lock.Lease)(nil), lock.ErrTimeout)
+		audit.On("Record", ctx, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+			return entry.Error != ""
+		})).Return(nil)
+
+		_, err := serv.Adjust(ctx, actor, 1, &request.ReqAdjustment{Amount: decimal.NewFromInt(1), ReqAdminAction: action})
+		require.ErrorIs(t, err, lock.ErrTimeout)
+
+		locker.AssertExpectations(t)
+		audit.AssertExpectations(t)
+	})
+}
+
+func TestAdminServ_SetRole(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+	actor := &model.Actor{ID: 9, Role: model.RoleSuperadmin}
+
+	t.Run("Success", func(t *testing.T) {
+		repo := new(MockAdminRepo)
+		audit := new(MockAuditRepo)
+		serv := NewAdmin(repo, nil, audit, lock.Nop(), zap.NewNop().Sugar())
+
+		repo.On("SetRole", ctx, int64(2), model.RoleFinance).Return(model.RoleSupport, nil)
+		audit.On("Record", ctx, &model.AuditEntry{
+			Actor: *actor, Action: model.AuditUserRole, Entity: model.AuditEntityUser, EntityID: 2,
+			Before: map[string]model.Role{"role": model.RoleSupport},
+			After:  map[string]model.Role{"role": model.RoleFinance},
+			Reason: "joined finance",
+		}).Return(nil)
+
+		err := serv.SetRole(ctx, actor, 2, &request.ReqRole{Role: model.RoleFinance, Reason: "joined finance"})
+		require.NoError(t, err)
+
+		repo.AssertExpectations(t)
+		audit.AssertExpectations(t)
+	})
+
+	t.Run("No reason", func(t *testing.T) {
+		serv := NewAdmin(new(MockAdminRepo), nil, new(MockAuditRepo), lock.Nop(), zap.NewNop().Sugar())
+
+		err := serv.SetRole(ctx, actor, 2, &request.ReqRole{Role: model.RoleFinance})
+		require.ErrorIs(t, err, ErrNotJustified)
+	})
+}
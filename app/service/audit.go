@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/request"
+	"server/pkg/logger"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func NewAudit(repo repository.AuditLogInter) AuditInter {
+	return &AuditServ{
+		repo: repo,
+	}
+}
+
+// AuditInter reads the audit log.
+type AuditInter interface {
+	List(ctx *gin.Context, req *request.ReqAuditLog) (*request.ResAuditLog, error)
+	Verify(ctx context.Context) (*model.AuditVerification, error)
+}
+
+type AuditServ struct {
+	repo repository.AuditLogInter
+}
+
+// List lists the audit log matching req, newest first.
+func (a *AuditServ) List(ctx *gin.Context, req *request.ReqAuditLog) (res *request.ResAuditLog, err error) {
+	end := tracing.StartGin(ctx, "AuditServ.List")
+	defer func() { end(err) }()
+
+	return a.repo.ListAudit(ctx, req)
+}
+
+// Verify checks that no record of the audit log was changed, removed or inserted.
+func (a *AuditServ) Verify(ctx context.Context) (*model.AuditVerification, error) {
+	return a.repo.VerifyAudit(ctx)
+}
+
+// auditUser is a user as recorded in the audit log. The audit log can never be erased, so it
+// keeps no username or email: only the status of the user and which of them a change replaced.
+type auditUser struct {
+	Status          model.UserStatus `json:"status"`
+	UsernameChanged bool             `json:"username_changed,omitempty"`
+	EmailChanged    bool             `json:"email_changed,omitempty"`
+}
+
+func newAuditUser(user *model.User) *auditUser {
+	return &auditUser{Status: user.Status}
+}
+
+// auditUserChange returns the user before and after a change, as recorded in the audit log.
+func auditUserChange(before, after *model.User) (*auditUser, *auditUser) {
+	return newAuditUser(before), &auditUser{
+		Status:          after.Status,
+		UsernameChanged: after.Username != before.Username,
+		EmailChanged:    after.Email != before.Email,
+	}
+}
+
+// auditBalance is the balance of a wallet as recorded around a money movement, with the other
+// wallet of a transfer.
+type auditBalance struct {
+	Balance      decimal.Decimal `json:"balance"`
+	Counterparty int64           `json:"counterparty_uid,omitempty"`
+}
+
+// recordAudit writes entry to audit, noting err when the action failed. The action has already
+// taken place, so a failure to record it is logged rather than returned.
+func recordAudit(ctx context.Context, audit repository.AuditInter, log *zap.SugaredLogger, entry *model.AuditEntry,
+	err error) {
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if err := audit.Record(ctx, entry); err != nil {
+		tracing.WithTrace(ctx, logger.FromContext(ctx, log)).
+			Errorw("record audit entry failed", "action", entry.Action, "entity_id", entry.EntityID, "error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAuditServ_List(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	repo := new(MockAuditRepo)
+	serv := NewAudit(repo)
+
+	req := &request.ReqAuditLog{Entity: model.AuditEntityUser, EntityID: 1}
+	expected := &request.ResAuditLog{List: []*model.AuditRecord{{ID: 1}}}
+	repo.On("ListAudit", ctx, req).Return(expected, nil)
+
+	res, err := serv.List(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, expected, res)
+	repo.AssertExpectations(t)
+}
+
+func TestAuditServ_Verify(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+
+	repo := new(MockAuditRepo)
+	serv := NewAudit(repo)
+
+	expected := &model.AuditVerification{Checked: 2, Valid: false, BrokenID: 2}
+	repo.On("VerifyAudit", ctx).Return(expected, nil)
+
+	res, err := serv.Verify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, expected, res)
+	repo.AssertExpectations(t)
+}
+
+func TestRecordAudit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+	core, logs := observer.New(zapcore.ErrorLevel)
+
+	audit := new(MockAuditRepo)
+	entry := &model.AuditEntry{Action: model.AuditWalletFreeze, EntityID: 1}
+	audit.On("Record", ctx, entry).Return(errors.New("db down"))
+
+	// A failed action is recorded with its error, and a failure to record is only logged.
+	recordAudit(ctx, audit, zap.New(core).Sugar(), entry, errors.New("wallet not found"))
+
+	assert.Equal(t, "wallet not found", entry.Error)
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "record audit entry failed", logs.All()[0].Message)
+	audit.AssertExpectations(t)
+}
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/pkg/lock"
+	"server/pkg/logger"
+	"server/pkg/metrics"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// batchRuns counts the batches running in the background of this process, which shutdown waits
+// for.
+var batchRuns sync.WaitGroup
+
+// maxReferenceLength is the longest reference a batch item may have.
+const maxReferenceLength = 64
+
+// NewBatch creates the service that pays out batches of transfers. Batches are checked when they
+// are submitted and run in the background; each transfer holds the wallet locks and is recorded
+// in audit like a single one.
+func NewBatch(repo repository.BatchInter, walletRepo repository.WalletInter, locker lock.Locker,
+	audit repository.AuditInter, logger *zap.SugaredLogger) BatchInter {
+	return &BatchServ{
+		repo: repo,
+		wallets: &WalletServ{
+			repo:   walletRepo,
+			locker: locker,
+			audit:  audit,
+			logger: logger,
+		},
+		logger: logger,
+		spawn:  spawnBatch,
+	}
+}
+
+// spawnBatch runs a batch in the background, counted in batchRuns.
+func spawnBatch(run func()) {
+	batchRuns.Add(1)
+	go func() {
+		defer batchRuns.Done()
+		run()
+	}()
+}
+
+// WaitBatches waits for the batches running in the background to finish, or for ctx to be done.
+// A batch cut short is failed later by the batch sweep.
+func WaitBatches(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		batchRuns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// BatchInter defines the interface for batch transfers.
+type BatchInter interface {
+	Create(ctx *gin.Context, uid int64, mode model.BatchMode, items []*model.BatchItem) (*model.Batch, error)
+	Get(ctx *gin.Context, uid, id int64) (*model.Batch, error)
+}
+
+// BatchServ implements the BatchInter interface.
+type BatchServ struct {
+	repo    repository.BatchInter
+	wallets *WalletServ
+	logger  *zap.SugaredLogger
+	spawn   func(run func()) // runs a batch in the background
+}
+
+// Create checks a batch of transfers from the wallet of uid as a whole, stores it and starts it.
+// It returns the batch while still pending; Get follows its progress. A zero mode is atomic.
+func (b *BatchServ) Create(ctx *gin.Context, uid int64, mode model.BatchMode, items []*model.BatchItem) (
+	res *model.Batch, err error) {
+	end := tracing.StartGin(ctx, "BatchServ.Create")
+	defer func() { end(err) }()
+
+	if mode == 0 {
+		mode = model.BatchModeAtomic
+	}
+
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("%w: unknown mode %d", ErrInvalidBatch, mode)
+	}
+
+	total, err := b.validate(ctx, uid, items)
+	if err != nil {
+		return nil, err
+	}
+
+	res = &model.Batch{UID: uid, Mode: mode, ItemCount: len(items), Total: total, Items: items}
+	if err = b.repo.CreateBatch(ctx, res); err != nil {
+		return nil, err
+	}
+
+	job, bg := cloneBatch(res), detach(ctx)
+	b.spawn(func() { b.run(bg, job) })
+
+	return res, nil
+}
+
+// validate checks every item of a batch from the wallet of uid, numbers them and returns their
+// total. The wallets of the batch must all exist.
+func (b *BatchServ) validate(ctx *gin.Context, uid int64, items []*model.BatchItem) (decimal.Decimal, error) {
+	if len(items) == 0 || len(items) > model.MaxBatchItems {
+		return decimal.Zero, fmt.Errorf("%w: must hold 1 to %d transfers, got %d", ErrInvalidBatch,
+			model.MaxBatchItems, len(items))
+	}
+
+	total := decimal.Zero
+	uids := []int64{uid}
+	references := make(map[string]int, len(items))
+	for i, item := range items {
+		item.Seq = i
+
+		switch {
+		case item.ToUID <= 0:
+			return decimal.Zero, fmt.Errorf("%w: item %d: invalid to_uid %d", ErrInvalidBatch, i, item.ToUID)
+		case item.ToUID == uid:
+			return decimal.Zero, fmt.Errorf("%w: item %d: can't transfer to the funding wallet", ErrInvalidBatch, i)
+		case !item.Amount.IsPositive():
+			return decimal.Zero, fmt.Errorf("%w: item %d: amount must be positive", ErrInvalidBatch, i)
+		case len(item.Reference) > maxReferenceLength:
+			return decimal.Zero, fmt.Errorf("%w: item %d: reference longer than %d", ErrInvalidBatch, i,
+				maxReferenceLength)
+		}
+
+		if item.Reference != "" {
+			if first, ok := references[item.Reference]; ok {
+				return decimal.Zero, fmt.Errorf("%w: item %d: reference %q already used by item %d", ErrInvalidBatch, i,
+					item.Reference, first)
+			}
+			references[item.Reference] = i
+		}
+
+		if err := checkRuntime(model.Transfer, item.Amount); err != nil {
+			return decimal.Zero, fmt.Errorf("item %d: %w", i, err)
+		}
+
+		total = total.Add(item.Amount)
+		uids = append(uids, item.ToUID)
+	}
+
+	missing, err := b.repo.MissingWallets(ctx, uids)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	for _, missingUID := range missing {
+		if missingUID == uid {
+			return decimal.Zero, fmt.Errorf("funding wallet of uid %d: %w", uid, sql.ErrNoRows)
+		}
+	}
+
+	if len(missing) > 0 {
+		return decimal.Zero, fmt.Errorf("%w: no wallet for uids %v", ErrInvalidBatch, missing)
+	}
+
+	return total, nil
+}
+
+// Get returns the batch of id funded by the wallet of uid, with the outcome of each transfer.
+func (b *BatchServ) Get(ctx *gin.Context, uid, id int64) (res *model.Batch, err error) {
+	end := tracing.StartGin(ctx, "BatchServ.Get")
+	defer func() { end(err) }()
+
+	return b.repo.GetBatch(ctx, uid, id)
+}
+
+// run makes the transfers of batch and stores the outcome. A batch that fails to start stays
+// pending.
+func (b *BatchServ) run(ctx *gin.Context, batch *model.Batch) {
+	var err error
+	end := tracing.StartGin(ctx, "BatchServ.run")
+	defer func() { end(err) }()
+
+	if err = b.repo.StartBatch(ctx, batch.ID); err != nil {
+		return
+	}
+
+	if batch.Mode == model.BatchModeAtomic {
+		err = b.runAtomic(ctx, batch)
+	} else {
+		b.runBestEffort(ctx, batch)
+	}
+
+	switch {
+	case err != nil:
+		batch.Status, batch.Error = model.BatchStatusFailed, err.Error()
+		for _, item := range batch.Items {
+			if item.Status != model.BatchItemStatusDuplicate {
+				batch.Failed++
+			}
+		}
+	case batch.Failed == 0:
+		batch.Status = model.BatchStatusCompleted
+	case batch.Succeeded == 0:
+		batch.Status = model.BatchStatusFailed
+	default:
+		batch.Status = model.BatchStatusPartial
+	}
+
+	if finishErr := b.repo.FinishBatch(ctx, batch); errors.Is(finishErr, repository.ErrBatchClosed) {
+		b.log(ctx).Warnw("batch was failed as stale before it finished", "batch_id", batch.ID)
+	} else if finishErr != nil {
+		b.log(ctx).Errorw("store batch outcome failed", "batch_id", batch.ID, "status", batch.Status,
+			"error", finishErr)
+	}
+}
+
+// runBestEffort makes each transfer of batch on its own, and stores the outcome of each.
+func (b *BatchServ) runBestEffort(ctx *gin.Context, batch *model.Batch) {
+	for _, item := range batch.Items {
+		err := b.transfer(ctx, batch.UID, item)
+		switch {
+		case err == nil:
+			batch.Succeeded++
+			continue
+		case errors.Is(err, repository.ErrBatchClosed):
+			return
+		case errors.Is(err, repository.ErrDuplicateReference):
+			item.Status = model.BatchItemStatusDuplicate
+		default:
+			item.Status, item.Error = model.BatchItemStatusFailed, err.Error()
+			batch.Failed++
+		}
+
+		if err = b.repo.SetItemStatus(ctx, item); err != nil {
+			b.log(ctx).Errorw("store batch item outcome failed", "batch_id", batch.ID, "seq", item.Seq, "error", err)
+		}
+	}
+}
+
+// transfer makes the transfer of item from the wallet of uid, like WalletServ.Transfer.
+func (b *BatchServ) transfer(ctx *gin.Context, uid int64, item *model.BatchItem) (err error) {
+	defer func() { metrics.ObserveMoney(model.Transfer, item.Amount, failureReason(err)) }()
+
+	// The runtime settings may have changed since the batch was submitted.
+	if err = checkRuntime(model.Transfer, item.Amount); err != nil {
+		return err
+	}
+
+	unlock, err := lockWallets(ctx, b.wallets.locker, b.logger, uid, item.ToUID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	repository.Fresh(ctx)
+
+	m, err := b.wallets.check(ctx, model.Transfer, uid, item.ToUID, item.Amount, "")
+	if err != nil {
+		return err
+	}
+
+	if err = b.repo.TransferItem(ctx, uid, item, m.fee); err != nil {
+		return err
+	}
+
+	item.Status = model.BatchItemStatusSucceeded
+	observeFee(m.fee)
+	b.wallets.record(ctx, model.AuditWalletTransfer, uid, item.ToUID, m.fromBalance, m.fromAfter())
+	b.wallets.record(ctx, model.AuditWalletTransfer, item.ToUID, uid, m.toBalance, m.toAfter())
+
+	return nil
+}
+
+// runAtomic makes all the transfers of batch in one transaction, or none of them. Transfers whose
+// reference was already paid are skipped up front.
+func (b *BatchServ) runAtomic(ctx *gin.Context, batch *model.Batch) (err error) {
+	todo, err := b.skipPaid(ctx, batch)
+	if err != nil || len(todo) == 0 {
+		return err
+	}
+
+	defer func() {
+		for _, item := range todo {
+			metrics.ObserveMoney(model.Transfer, item.Amount, failureReason(err))
+		}
+	}()
+
+	for _, item := range todo {
+		if err = checkRuntime(model.Transfer, item.Amount); err != nil {
+			return fmt.Errorf("item %d: %w", item.Seq, err)
+		}
+	}
+
+	// A batch can reach up to model.MaxBatchItems receivers, more locks than a lock backend should
+	// hold at once: the postgres one keeps a connection per lock. Only the funding wallet is locked
+	// here, and TransferAll locks the rows of the receivers in its transaction, whose guarded
+	// writes still enforce their status and balance limit. The receiver balances checked below
+	// are read without their locks, so the audit is written from the locked rows instead.
+	unlock, err := lockWallets(ctx, b.wallets.locker, b.logger, batch.UID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	repository.Fresh(ctx)
+
+	movements, err := b.checkAll(ctx, batch.UID, todo)
+	if err != nil {
+		return err
+	}
+
+	fees := make([]model.FeeCharge, len(movements))
+	for i, m := range movements {
+		fees[i] = m.fee
+	}
+
+	balances, err := b.repo.TransferAll(ctx, batch.UID, todo, fees)
+	if err != nil {
+		return err
+	}
+
+	for i, item := range todo {
+		m := movements[i]
+		m.fromBalance, m.toBalance = balances[batch.UID], balances[item.ToUID]
+		balances[batch.UID], balances[item.ToUID] = m.fromAfter(), m.toAfter()
+		if m.fee.IsCharged() {
+			balances[m.fee.RevenueUID] = balances[m.fee.RevenueUID].Add(m.fee.Amount)
+		}
+
+		item.Status = model.BatchItemStatusSucceeded
+		batch.Succeeded++
+		observeFee(m.fee)
+		b.wallets.record(ctx, model.AuditWalletTransfer, batch.UID, item.ToUID, m.fromBalance, m.fromAfter())
+		b.wallets.record(ctx, model.AuditWalletTransfer, item.ToUID, batch.UID, m.toBalance, m.toAfter())
+	}
+
+	return nil
+}
+
+// skipPaid marks the items of batch whose reference was already paid as duplicates and returns
+// the others.
+func (b *BatchServ) skipPaid(ctx *gin.Context, batch *model.Batch) ([]*model.BatchItem, error) {
+	var references []string
+	for _, item := range batch.Items {
+		if item.Reference != "" {
+			references = append(references, item.Reference)
+		}
+	}
+
+	paid := map[string]bool{}
+	if len(references) > 0 {
+		list, err := b.repo.PaidReferences(ctx, batch.UID, references)
+		if err != nil {
+			return nil, err
+		}
+		for _, reference := range list {
+			paid[reference] = true
+		}
+	}
+
+	todo := make([]*model.BatchItem, 0, len(batch.Items))
+	for _, item := range batch.Items {
+		if !paid[item.Reference] {
+			todo = append(todo, item)
+			continue
+		}
+
+		item.Status = model.BatchItemStatusDuplicate
+		if err := b.repo.SetItemStatus(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return todo, nil
+}
+
+// checkAll checks the transfers of items from the wallet of uid as if they were made one after
+// the other, and returns them as movements, each with the balances it starts from.
+func (b *BatchServ) checkAll(ctx *gin.Context, uid int64, items []*model.BatchItem) ([]*movement, error) {
+	fromBalance, err := b.wallets.repo.Balance(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBalance := decimal.NewFromInt(model.BalanceLimit())
+	balances := map[int64]decimal.Decimal{}
+	movements := make([]*movement, len(items))
+	for i, item := range items {
+		m := &movement{fromUID: uid, toUID: item.ToUID, amount: item.Amount, fromBalance: fromBalance}
+
+		if m.fee, err = b.wallets.fee(ctx, model.Transfer, uid, item.Amount); err != nil {
+			return nil, err
+		}
+
+		if fromBalance.LessThan(m.total()) {
+			return nil, fmt.Errorf("item %d: %w for transfer", item.Seq, ErrInsufficientBalance)
+		}
+
+		toBalance, ok := balances[item.ToUID]
+		if !ok {
+			if toBalance, err = b.wallets.repo.Balance(ctx, item.ToUID); err != nil {
+				return nil, fmt.Errorf("item %d: %w", item.Seq, err)
+			}
+		}
+
+		if toBalance.Add(item.Amount).GreaterThan(maxBalance) {
+			return nil, fmt.Errorf("item %d: transfer %w of %s for the receiver", item.Seq, ErrBalanceLimitExceeded,
+				maxBalance.String())
+		}
+
+		m.toBalance = toBalance
+		movements[i] = m
+		fromBalance, balances[item.ToUID] = m.fromAfter(), m.toAfter()
+	}
+
+	return movements, nil
+}
+
+func (b *BatchServ) log(ctx context.Context) *zap.SugaredLogger {
+	return tracing.WithTrace(ctx, logger.FromContext(ctx, b.logger))
+}
+
+// cloneBatch copies batch and its items, for a background run that must not share them with the
+// response.
+func cloneBatch(batch *model.Batch) *model.Batch {
+	clone := *batch
+	clone.Items = make([]*model.BatchItem, len(batch.Items))
+	for i, item := range batch.Items {
+		itemClone := *item
+		clone.Items[i] = &itemClone
+	}
+
+	return &clone
+}
+
+// detach returns a copy of ctx for work that outlives the request, which is not cancelled when
+// the request ends.
+func detach(ctx *gin.Context) *gin.Context {
+	bg := ctx.Copy()
+	if ctx.Request != nil {
+		bg.Request = ctx.Request.WithContext(context.WithoutCancel(ctx.Request.Context()))
+	}
+
+	return bg
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"server/app/model"
+)
+
+// MockBatchRepo is a mock implementation of the repository.BatchInter interface
+type MockBatchRepo struct {
+	mock.Mock
+}
+
+func (m *MockBatchRepo) CreateBatch(ctx *gin.Context, mod *model.Batch) error {
+	args := m.Called(ctx, mod)
+	return args.Error(0)
+}
+
+func (m *MockBatchRepo) GetBatch(ctx *gin.Context, uid, id int64) (*model.Batch, error) {
+	args := m.Called(ctx, uid, id)
+	return args.Get(0).(*model.Batch), args.Error(1)
+}
+
+func (m *MockBatchRepo) MissingWallets(ctx *gin.Context, uids []int64) ([]int64, error) {
+	args := m.Called(ctx, uids)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockBatchRepo) PaidReferences(ctx *gin.Context, uid int64, references []string) ([]string, error) {
+	args := m.Called(ctx, uid, references)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockBatchRepo) StartBatch(ctx *gin.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockBatchRepo) TransferItem(ctx *gin.Context, uid int64, item *model.BatchItem, fee model.FeeCharge) error {
+	args := m.Called(ctx, uid, item, fee)
+	return args.Error(0)
+}
+
+func (m *MockBatchRepo) TransferAll(ctx *gin.Context, uid int64, items []*model.BatchItem,
+	fees []model.FeeCharge) (map[int64]decimal.Decimal, error) {
+	args := m.Called(ctx, uid, items, fees)
+	balances, _ := args.Get(0).(map[int64]decimal.Decimal)
+	return balances, args.Error(1)
+}
+
+func (m *MockBatchRepo) SetItemStatus(ctx *gin.Context, item *model.BatchItem) error {
+	args := m.Called(ctx, item)
+	return args.Error(0)
+}
+
+func (m *MockBatchRepo) FinishBatch(ctx *gin.Context, mod *model.Batch) error {
+	args := m.Called(ctx, mod)
+	return args.Error(0)
+}
+
+// MockBatchSweepRepo is a mock implementation of the repository.BatchSweepInter interface
+type MockBatchSweepRepo struct {
+	mock.Mock
+}
+
+func (m *MockBatchSweepRepo) FailStaleBatches(ctx context.Context, staleAfter time.Duration, reason string) (int64,
+	error) {
+	args := m.Called(ctx, staleAfter, reason)
+	return args.Get(0).(int64), args.Error(1)
+}
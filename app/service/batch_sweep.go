@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"server/app/repository"
+)
+
+const (
+	// staleBatchAfter is how long a pending or running batch may go without progress before its
+	// run is taken as interrupted, by a restart or a crash.
+	staleBatchAfter = 10 * time.Minute
+	// staleBatchReason is the error of the transfers of a stale batch that were never made.
+	staleBatchReason = "interrupted before it finished"
+)
+
+func NewBatchSweep(repo repository.BatchSweepInter, logger *zap.SugaredLogger) BatchSweepInter {
+	return &BatchSweepServ{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// BatchSweepInter closes the batches whose background run was lost.
+type BatchSweepInter interface {
+	Run(ctx context.Context) error
+}
+
+type BatchSweepServ struct {
+	repo   repository.BatchSweepInter
+	logger *zap.SugaredLogger
+}
+
+// Run fails the transfers that stale batches never made and closes the batches with the outcome
+// of the others. Their references stay unpaid, so they can be submitted again.
+func (s *BatchSweepServ) Run(ctx context.Context) error {
+	n, err := s.repo.FailStaleBatches(ctx, staleBatchAfter, staleBatchReason)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		s.logger.Warnw("failed stale batches", "count", n)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestBatchSweepServ_Run(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+
+	t.Run("Stale batches", func(t *testing.T) {
+		repo := new(MockBatchSweepRepo)
+		repo.On("FailStaleBatches", ctx, staleBatchAfter, staleBatchReason).Return(int64(2), nil)
+
+		require.NoError(t, NewBatchSweep(repo, zap.NewNop().Sugar()).Run(ctx))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		repo := new(MockBatchSweepRepo)
+		repo.On("FailStaleBatches", ctx, staleBatchAfter, staleBatchReason).Return(int64(0), errors.New("db down"))
+
+		require.EqualError(t, NewBatchSweep(repo, zap.NewNop().Sugar()).Run(ctx), "db down")
+	})
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/pkg/lock"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+// newTestBatch returns a batch service that runs batches before Create returns.
+func newTestBatch(repo *MockBatchRepo, walletRepo *MockWalletRepo) *BatchServ {
+	audit := new(MockAuditRepo)
+	audit.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+	batchServ := NewBatch(repo, walletRepo, lock.Nop(), audit, zap.NewNop().Sugar()).(*BatchServ)
+	batchServ.spawn = func(run func()) { run() }
+
+	return batchServ
+}
+
+func batchItems(amounts ...int64) []*model.BatchItem {
+	items := make([]*model.BatchItem, len(amounts))
+	for i, amount := range amounts {
+		items[i] = &model.BatchItem{ToUID: int64(i + 2), Amount: decimal.NewFromInt(amount)}
+	}
+
+	return items
+}
+
+func TestBatchServ_Create(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	tests := []struct {
+		name     string
+		mode     model.BatchMode
+		items    []*model.BatchItem
+		missing  []int64
+		expected error
+	}{
+		{"Unknown mode", 3, batchItems(1), nil, ErrInvalidBatch},
+		{"No items", model.BatchModeAtomic, nil, nil, ErrInvalidBatch},
+		{"Transfer to the funding wallet", model.BatchModeAtomic,
+			[]*model.BatchItem{{ToUID: 1, Amount: decimal.NewFromInt(1)}}, nil, ErrInvalidBatch},
+		{"Zero amount", model.BatchModeAtomic, batchItems(1, 0), nil, ErrInvalidBatch},
+		{"Reference used twice", model.BatchModeBestEffort, []*model.BatchItem{
+			{ToUID: 2, Amount: decimal.NewFromInt(1), Reference: "inv-1"},
+			{ToUID: 3, Amount: decimal.NewFromInt(1), Reference: "inv-1"},
+		}, nil, ErrInvalidBatch},
+		{"Missing receiver", model.BatchModeAtomic, batchItems(1, 2), []int64{3}, ErrInvalidBatch},
+		{"Missing funding wallet", model.BatchModeAtomic, batchItems(1), []int64{1}, sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockBatchRepo)
+			batchServ := newTestBatch(mockRepo, new(MockWalletRepo))
+
+			if tt.missing != nil {
+				mockRepo.On("MissingWallets", ctx, mock.Anything).Return(tt.missing, nil)
+			}
+
+			_, err := batchServ.Create(ctx, 1, tt.mode, tt.items)
+			require.ErrorIs(t, err, tt.expected)
+
+			mockRepo.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBatchServ_Atomic(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	t.Run("Paid references are skipped", func(t *testing.T) {
+		mockRepo, walletRepo := new(MockBatchRepo), new(MockWalletRepo)
+		batchServ := newTestBatch(mockRepo, walletRepo)
+
+		items := batchItems(3, 4)
+		items[0].Reference, items[1].Reference = "inv-1", "inv-2"
+
+		mockRepo.On("MissingWallets", ctx, []int64{1, 2, 3}).Return([]int64{}, nil)
+		mockRepo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			batch := args.Get(1).(*model.Batch)
+			batch.ID, batch.Status = 7, model.BatchStatusPending
+		}).Return(nil)
+		mockRepo.On("StartBatch", mock.Anything, int64(7)).Return(nil)
+		mockRepo.On("PaidReferences", mock.Anything, int64(1), []string{"inv-1", "inv-2"}).
+			Return([]string{"inv-1"}, nil)
+		mockRepo.On("SetItemStatus", mock.Anything, mock.MatchedBy(func(item *model.BatchItem) bool {
+			return item.Seq == 0 && item.Status == model.BatchItemStatusDuplicate
+		})).Return(nil)
+		walletRepo.On("Balance", mock.Anything, int64(1)).Return(decimal.NewFromInt(10), nil)
+		walletRepo.On("Balance", mock.Anything, int64(3)).Return(decimal.Zero, nil)
+		mockRepo.On("TransferAll", mock.Anything, int64(1), mock.MatchedBy(func(items []*model.BatchItem) bool {
+			return len(items) == 1 && items[0].Seq == 1
+		}), []model.FeeCharge{{}}).Return(map[int64]decimal.Decimal{1: decimal.NewFromInt(10), 3: decimal.Zero}, nil)
+		mockRepo.On("FinishBatch", mock.Anything, mock.MatchedBy(func(batch *model.Batch) bool {
+			return batch.Status == model.BatchStatusCompleted && batch.Succeeded == 1 && batch.Failed == 0
+		})).Return(nil)
+
+		res, err := batchServ.Create(ctx, 1, 0, items)
+		require.NoError(t, err)
+		assert.Equal(t, model.BatchModeAtomic, res.Mode)
+		assert.Equal(t, model.BatchStatusPending, res.Status, "the response is not touched by the run")
+		assert.True(t, decimal.NewFromInt(7).Equal(res.Total))
+
+		mockRepo.AssertExpectations(t)
+		walletRepo.AssertExpectations(t)
+	})
+
+	t.Run("All or nothing", func(t *testing.T) {
+		mockRepo, walletRepo := new(MockBatchRepo), new(MockWalletRepo)
+		batchServ := newTestBatch(mockRepo, walletRepo)
+
+		mockRepo.On("MissingWallets", ctx, []int64{1, 2, 3}).Return([]int64{}, nil)
+		mockRepo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Batch).ID = 7
+		}).Return(nil)
+		mockRepo.On("StartBatch", mock.Anything, int64(7)).Return(nil)
+		walletRepo.On("Balance", mock.Anything, int64(1)).Return(decimal.NewFromInt(5), nil)
+		walletRepo.On("Balance", mock.Anything, int64(2)).Return(decimal.Zero, nil)
+		mockRepo.On("FinishBatch", mock.Anything, mock.MatchedBy(func(batch *model.Batch) bool {
+			return batch.Status == model.BatchStatusFailed && batch.Failed == 2 &&
+				batch.Error == "item 1: "+ErrInsufficientBalance.Error()+" for transfer"
+		})).Return(nil)
+
+		_, err := batchServ.Create(ctx, 1, model.BatchModeAtomic, batchItems(3, 4))
+		require.NoError(t, err)
+
+		mockRepo.AssertNotCalled(t, "TransferAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+		walletRepo.AssertExpectations(t)
+	})
+
+	t.Run("Only the funding wallet is locked", func(t *testing.T) {
+		mockRepo, walletRepo, locker := new(MockBatchRepo), new(MockWalletRepo), new(MockLocker)
+		batchServ := newTestBatch(mockRepo, walletRepo)
+		batchServ.wallets.locker = locker
+
+		locker.On("Lock", mock.Anything, []string{"wallet:1"}).Return(&lock.Lease{Token: 9}, nil)
+		mockRepo.On("MissingWallets", ctx, []int64{1, 2, 3, 4}).Return([]int64{}, nil)
+		mockRepo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Batch).ID = 7
+		}).Return(nil)
+		mockRepo.On("StartBatch", mock.Anything, int64(7)).Return(nil)
+		walletRepo.On("Balance", mock.Anything, mock.Anything).Return(decimal.NewFromInt(10), nil)
+		mockRepo.On("TransferAll", mock.Anything, int64(1), mock.Anything, mock.Anything).
+			Return(map[int64]decimal.Decimal{1: decimal.NewFromInt(10)}, nil)
+		mockRepo.On("FinishBatch", mock.Anything, mock.MatchedBy(func(batch *model.Batch) bool {
+			return batch.Status == model.BatchStatusCompleted && batch.Succeeded == 3
+		})).Return(nil)
+
+		_, err := batchServ.Create(ctx, 1, model.BatchModeAtomic, batchItems(1, 1, 1))
+		require.NoError(t, err)
+
+		locker.AssertExpectations(t)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Transfer into a receiver while the batch runs", func(t *testing.T) {
+		mockRepo, walletRepo, audit := new(MockBatchRepo), new(MockWalletRepo), new(MockAuditRepo)
+		batchServ := newTestBatch(mockRepo, walletRepo)
+		batchServ.wallets.audit = audit
+
+		var entries []*model.AuditEntry
+		audit.On("Record", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			entries = append(entries, args.Get(1).(*model.AuditEntry))
+		}).Return(nil)
+
+		items := []*model.BatchItem{{ToUID: 2, Amount: decimal.NewFromInt(3)}, {ToUID: 2, Amount: decimal.NewFromInt(4)}}
+
+		mockRepo.On("MissingWallets", ctx, mock.Anything).Return([]int64{}, nil)
+		mockRepo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Batch).ID = 7
+		}).Return(nil)
+		mockRepo.On("StartBatch", mock.Anything, int64(7)).Return(nil)
+		// The receiver is checked while empty; a transfer of 5 into it lands before its row is locked.
+		walletRepo.On("Balance", mock.Anything, int64(1)).Return(decimal.NewFromInt(10), nil)
+		walletRepo.On("Balance", mock.Anything, int64(2)).Return(decimal.Zero, nil)
+		mockRepo.On("TransferAll", mock.Anything, int64(1), mock.Anything, mock.Anything).
+			Return(map[int64]decimal.Decimal{1: decimal.NewFromInt(10), 2: decimal.NewFromInt(5)}, nil)
+		mockRepo.On("FinishBatch", mock.Anything, mock.Anything).Return(nil)
+
+		_, err := batchServ.Create(ctx, 1, model.BatchModeAtomic, items)
+		require.NoError(t, err)
+
+		expected := []struct{ uid, before, after int64 }{{1, 10, 7}, {2, 5, 8}, {1, 7, 3}, {2, 8, 12}}
+		require.Len(t, entries, len(expected))
+		for i, e := range expected {
+			assert.Equal(t, e.uid, entries[i].EntityID)
+			assert.True(t, decimal.NewFromInt(e.before).Equal(entries[i].Before.(*auditBalance).Balance), "entry %d", i)
+			assert.True(t, decimal.NewFromInt(e.after).Equal(entries[i].After.(*auditBalance).Balance), "entry %d", i)
+		}
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestBatchServ_BestEffort(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mockRepo, walletRepo := new(MockBatchRepo), new(MockWalletRepo)
+	batchServ := newTestBatch(mockRepo, walletRepo)
+
+	items := batchItems(3, 4, 1)
+	items[2].Reference = "inv-3"
+
+	mockRepo.On("MissingWallets", ctx, []int64{1, 2, 3, 4}).Return([]int64{}, nil)
+	mockRepo.On("CreateBatch", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.Batch).ID = 7
+	}).Return(nil)
+	mockRepo.On("StartBatch", mock.Anything, int64(7)).Return(nil)
+
+	// The first transfer leaves 2 of 5, too little for the second one; the third one was already paid.
+	walletRepo.On("Balance", mock.Anything, int64(1)).Return(decimal.NewFromInt(5), nil).Once()
+	walletRepo.On("Balance", mock.Anything, int64(1)).Return(decimal.NewFromInt(2), nil)
+	walletRepo.On("Balance", mock.Anything, mock.Anything).Return(decimal.Zero, nil)
+	mockRepo.On("TransferItem", mock.Anything, int64(1), mock.MatchedBy(func(item *model.BatchItem) bool {
+		return item.Seq == 0
+	}), model.FeeCharge{}).Return(nil)
+	mockRepo.On("TransferItem", mock.Anything, int64(1), mock.MatchedBy(func(item *model.BatchItem) bool {
+		return item.Seq == 2
+	}), model.FeeCharge{}).Return(repository.ErrDuplicateReference)
+	mockRepo.On("SetItemStatus", mock.Anything, mock.MatchedBy(func(item *model.BatchItem) bool {
+		return item.Seq == 1 && item.Status == model.BatchItemStatusFailed && item.Error != ""
+	})).Return(nil)
+	mockRepo.On("SetItemStatus", mock.Anything, mock.MatchedBy(func(item *model.BatchItem) bool {
+		return item.Seq == 2 && item.Status == model.BatchItemStatusDuplicate
+	})).Return(nil)
+	mockRepo.On("FinishBatch", mock.Anything, mock.MatchedBy(func(batch *model.Batch) bool {
+		return batch.Status == model.BatchStatusPartial && batch.Succeeded == 1 && batch.Failed == 1
+	})).Return(nil)
+
+	_, err := batchServ.Create(ctx, 1, model.BatchModeBestEffort, items)
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+	walletRepo.AssertExpectations(t)
+}
+
+func TestWaitBatches(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	release := make(chan struct{})
+	spawnBatch(func() { <-release })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, WaitBatches(ctx), context.Canceled, "a run is still going")
+
+	close(release)
+	require.NoError(t, WaitBatches(context.Background()))
+}
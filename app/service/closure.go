@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/pkg/lock"
+	"server/pkg/metrics"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+var ErrBalanceNotSettled = errors.New("balance must be withdrawn before closing")
+
+// NewClosure creates the service that closes accounts. A closure holds the lock of the wallet
+// like any money movement and is recorded in audit once done.
+func NewClosure(repo repository.ClosureInter, walletRepo repository.WalletInter, locker lock.Locker,
+	audit repository.AuditInter, logger *zap.SugaredLogger) ClosureInter {
+	return &ClosureServ{
+		repo:       repo,
+		walletRepo: walletRepo,
+		locker:     locker,
+		audit:      audit,
+		logger:     logger,
+	}
+}
+
+// ClosureInter defines the interface for closing accounts.
+type ClosureInter interface {
+	Close(ctx *gin.Context, uid int64, settle bool) (*model.Closure, error)
+}
+
+// ClosureServ implements the ClosureInter interface.
+type ClosureServ struct {
+	repo       repository.ClosureInter
+	walletRepo repository.WalletInter
+	locker     lock.Locker
+	audit      repository.AuditInter
+	logger     *zap.SugaredLogger
+}
+
+// Close closes the account of uid for good. A wallet with money left is only closed when settle
+// is set, after withdrawing all of it as a settlement. Only active wallets can be closed.
+func (c *ClosureServ) Close(ctx *gin.Context, uid int64, settle bool) (res *model.Closure, err error) {
+	end := tracing.StartGin(ctx, "ClosureServ.Close")
+
+	settlement := decimal.Zero
+	defer func() {
+		if settlement.IsPositive() {
+			metrics.ObserveMoney(model.Withdraw, settlement, failureReason(err))
+		}
+		end(err)
+	}()
+
+	unlock, err := lockWallets(ctx, c.locker, c.logger, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	repository.Fresh(ctx)
+
+	balance, err := c.walletRepo.Balance(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if balance.IsPositive() {
+		if !settle {
+			return nil, fmt.Errorf("%w: %s left", ErrBalanceNotSettled, balance.String())
+		}
+
+		settlement = balance
+		if err = checkRuntime(model.Withdraw, settlement); err != nil {
+			return nil, err
+		}
+	}
+
+	before, err := c.repo.Close(ctx, uid, settlement)
+	if err != nil {
+		return nil, err
+	}
+
+	c.record(ctx, model.AuditWalletClose, model.AuditEntityWallet, uid, &auditBalance{Balance: balance},
+		&auditBalance{Balance: decimal.Zero})
+	beforeAudit, afterAudit := auditUserChange(before, model.AnonymousUser(uid))
+	c.record(ctx, model.AuditUserClose, model.AuditEntityUser, uid, beforeAudit, afterAudit)
+
+	return &model.Closure{UID: uid, Settled: settlement, ClosedAt: time.Now()}, nil
+}
+
+// record writes a part of a closure to the audit log, on behalf of the actor of the request.
+func (c *ClosureServ) record(ctx *gin.Context, action, entity string, uid int64, before, after any) {
+	recordAudit(ctx, c.audit, c.logger, &model.AuditEntry{
+		Actor:    repository.ActorOf(ctx),
+		Action:   action,
+		Entity:   entity,
+		EntityID: uid,
+		Before:   before,
+		After:    after,
+	}, nil)
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"server/app/model"
+)
+
+// MockClosureRepo is a mock implementation of the repository.ClosureInter interface
+type MockClosureRepo struct {
+	mock.Mock
+}
+
+func (m *MockClosureRepo) Close(ctx *gin.Context, uid int64, settlement decimal.Decimal) (*model.User, error) {
+	args := m.Called(ctx, uid, settlement)
+	return args.Get(0).(*model.User), args.Error(1)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/pkg/lock"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestClosureServ_Close(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+
+	uid := int64(7)
+	alice := &model.User{ID: uid, Username: "alice", Email: "alice@example.com", Status: model.UserStatusValid}
+
+	tests := []struct {
+		name     string
+		balance  decimal.Decimal
+		settle   bool
+		closeErr error
+		expected error
+	}{
+		{"Empty wallet", decimal.Zero, false, nil, nil},
+		{"Settle the balance", decimal.NewFromInt(40), true, nil, nil},
+		{"Balance left", decimal.NewFromInt(40), false, nil, ErrBalanceNotSettled},
+		{"Frozen wallet", decimal.Zero, false, repository.ErrWalletFrozen, repository.ErrWalletFrozen},
+		{"Already closed", decimal.Zero, true, repository.ErrWalletClosed, repository.ErrWalletClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+			repo := new(MockClosureRepo)
+			walletRepo := new(MockWalletRepo)
+			audit := new(MockAuditRepo)
+			serv := NewClosure(repo, walletRepo, lock.Nop(), audit, zap.NewNop().Sugar())
+
+			walletRepo.On("Balance", ctx, uid).Return(tt.balance, nil)
+			if !tt.balance.IsPositive() || tt.settle {
+				repo.On("Close", ctx, uid, mock.Anything).Return(alice, tt.closeErr)
+			}
+			if tt.expected == nil {
+				audit.On("Record", ctx, &model.AuditEntry{
+					Action: model.AuditWalletClose, Entity: model.AuditEntityWallet, EntityID: uid,
+					Before: &auditBalance{Balance: tt.balance}, After: &auditBalance{Balance: decimal.Zero},
+				}).Return(nil)
+				audit.On("Record", ctx, &model.AuditEntry{
+					Action: model.AuditUserClose, Entity: model.AuditEntityUser, EntityID: uid,
+					Before: &auditUser{Status: alice.Status},
+					After:  &auditUser{Status: model.UserStatusDisabled, UsernameChanged: true, EmailChanged: true},
+				}).Return(nil)
+			}
+
+			res, err := serv.Close(ctx, uid, tt.settle)
+			if tt.expected != nil {
+				require.ErrorIs(t, err, tt.expected)
+				assert.Nil(t, res)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, uid, res.UID)
+				assert.True(t, tt.balance.Equal(res.Settled))
+				repo.AssertCalled(t, "Close", ctx, uid, tt.balance)
+			}
+
+			repo.AssertExpectations(t)
+			walletRepo.AssertExpectations(t)
+			audit.AssertExpectations(t)
+		})
+	}
+
+	t.Run("No wallet", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+		walletRepo := new(MockWalletRepo)
+		serv := NewClosure(new(MockClosureRepo), walletRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+		walletRepo.On("Balance", ctx, uid).Return(decimal.Zero, sql.ErrNoRows)
+
+		_, err := serv.Close(ctx, uid, true)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
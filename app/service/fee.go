@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"server/app/model"
+	"server/config"
+	"server/pkg/metrics"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+var ErrUnknownOperation = errors.New("unknown operation")
+
+var hundred = decimal.NewFromInt(100)
+
+// calculateFee works out the fee that conf charges users of tier on an operation of amount,
+// rounded to the cent. It is zero when no rule applies.
+func calculateFee(conf *config.FeesConf, operation, tier string, amount decimal.Decimal) decimal.Decimal {
+	rule := conf.Rule(operation, tier)
+	if rule == nil {
+		return decimal.Zero
+	}
+
+	formula := rule.FeeFormula
+	for _, band := range rule.Bands {
+		if amount.LessThan(band.From) {
+			break
+		}
+		formula = band.FeeFormula
+	}
+
+	fee := formula.Flat.Add(amount.Mul(formula.Percent).Div(hundred))
+	if fee.LessThan(formula.Min) {
+		fee = formula.Min
+	}
+	if formula.Max.IsPositive() && fee.GreaterThan(formula.Max) {
+		fee = formula.Max
+	}
+
+	return fee.Round(2)
+}
+
+// fee returns the fee the owner of the wallet of uid pays on an operation of amount, under the
+// current runtime settings. The tier of the owner is only looked up when fees are configured.
+func (w *WalletServ) fee(ctx *gin.Context, operation string, uid int64, amount decimal.Decimal) (model.FeeCharge, error) {
+	fees := config.Runtime().Fees
+	if len(fees.Rules) == 0 {
+		return model.FeeCharge{}, nil
+	}
+
+	tier, err := w.repo.Tier(ctx, uid)
+	if err != nil {
+		return model.FeeCharge{}, err
+	}
+
+	return model.FeeCharge{Amount: calculateFee(&fees, operation, tier, amount), RevenueUID: fees.RevenueUID}, nil
+}
+
+// Quote returns the fee of a withdrawal or a transfer of amount from the wallet of uid, and what
+// it takes from the wallet in total, without making it.
+func (w *WalletServ) Quote(ctx *gin.Context, uid int64, operation string, amount decimal.Decimal) (
+	res *model.FeeQuote, err error) {
+	end := tracing.StartGin(ctx, "WalletServ.Quote")
+	defer func() { end(err) }()
+
+	if operation != model.Withdraw && operation != model.Transfer {
+		return nil, fmt.Errorf("%w %q", ErrUnknownOperation, operation)
+	}
+
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%s %w", operation, ErrNonPositiveAmount)
+	}
+
+	fee, err := w.fee(ctx, operation, uid, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.FeeQuote{Operation: operation, Amount: amount, Fee: fee.Amount, Total: amount.Add(fee.Amount)}, nil
+}
+
+// observeFee counts a fee that was charged in the money metrics, as an operation of its own.
+func observeFee(fee model.FeeCharge) {
+	if fee.IsCharged() {
+		metrics.ObserveMoney(model.Fee, fee.Amount, "")
+	}
+}
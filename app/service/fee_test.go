@@ -0,0 +1,128 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"server/app/model"
+	"server/config"
+	"server/pkg/lock"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestCalculateFee(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	dec := decimal.RequireFromString
+	conf := &config.FeesConf{RevenueUID: 9, Rules: []config.FeeRule{
+		{Operation: model.Withdraw, Tier: "premium"},
+		{Operation: model.Withdraw, FeeFormula: config.FeeFormula{Flat: dec("0.5"), Percent: dec("1"), Min: dec("1"), Max: dec("20")}},
+		{Operation: model.Transfer, FeeFormula: config.FeeFormula{Flat: dec("0.25")}, Bands: []config.FeeBand{
+			{From: dec("100"), FeeFormula: config.FeeFormula{Percent: dec("0.5")}},
+			{From: dec("1000"), FeeFormula: config.FeeFormula{Percent: dec("0.25"), Max: dec("4")}},
+		}},
+	}}
+
+	tests := []struct {
+		name      string
+		operation string
+		tier      string
+		amount    string
+		expected  string
+	}{
+		{"Flat and percent", model.Withdraw, "", "100", "1.5"},
+		{"Raised to the minimum", model.Withdraw, "", "10", "1"},
+		{"Capped at the maximum", model.Withdraw, "", "5000", "20"},
+		{"Rounded to the cent", model.Withdraw, "", "123.45", "1.73"},
+		{"Tier rule comes first", model.Withdraw, "premium", "100", "0"},
+		{"Below the first band", model.Transfer, "", "99.99", "0.25"},
+		{"First band", model.Transfer, "", "100", "0.5"},
+		{"Last band", model.Transfer, "", "2000", "4"},
+		{"No rule", model.Deposit, "", "100", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee := calculateFee(conf, tt.operation, tt.tier, dec(tt.amount))
+			assert.True(t, dec(tt.expected).Equal(fee), "got %s", fee)
+		})
+	}
+}
+
+func TestWalletServ_Fees(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	uid := int64(1)
+	revenueUID := int64(9)
+	fee := model.FeeCharge{Amount: decimal.New(200, -2), RevenueUID: revenueUID}
+
+	config.SetRuntime(&config.RuntimeConf{Fees: config.FeesConf{RevenueUID: revenueUID, Rules: []config.FeeRule{
+		{Operation: model.Withdraw, FeeFormula: config.FeeFormula{Flat: decimal.NewFromInt(2)}},
+		{Operation: model.Transfer, FeeFormula: config.FeeFormula{Flat: decimal.NewFromInt(2)}},
+	}}})
+	t.Cleanup(func() { config.SetRuntime(nil) })
+
+	t.Run("Quote", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+		mockRepo.On("Tier", ctx, uid).Return("", nil)
+
+		quote, err := walletServ.Quote(ctx, uid, model.Withdraw, decimal.NewFromInt(10))
+		require.NoError(t, err)
+		assert.Equal(t, model.Withdraw, quote.Operation)
+		assert.True(t, decimal.NewFromInt(2).Equal(quote.Fee))
+		assert.True(t, decimal.NewFromInt(12).Equal(quote.Total))
+
+		_, err = walletServ.Quote(ctx, uid, model.Deposit, decimal.NewFromInt(10))
+		require.ErrorIs(t, err, ErrUnknownOperation)
+
+		_, err = walletServ.Quote(ctx, uid, model.Transfer, decimal.Zero)
+		require.ErrorIs(t, err, ErrNonPositiveAmount)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Withdraw charges the fee", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		audit := new(MockAuditRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), audit, zap.NewNop().Sugar())
+
+		amount := decimal.NewFromInt(10)
+		mockRepo.On("Balance", ctx, uid).Return(decimal.NewFromInt(50), nil)
+		mockRepo.On("Tier", ctx, uid).Return("", nil)
+		mockRepo.On("Withdraw", ctx, uid, amount, fee, model.TransactionDetails{}).Return(nil)
+		audit.On("Record", ctx, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+			after, ok := entry.After.(*auditBalance)
+			return entry.Action == model.AuditWalletWithdraw && ok && after.Balance.Equal(decimal.NewFromInt(38))
+		})).Return(nil)
+
+		require.NoError(t, walletServ.Withdraw(ctx, uid, amount, "", model.TransactionDetails{}))
+
+		mockRepo.AssertExpectations(t)
+		audit.AssertExpectations(t)
+	})
+
+	t.Run("The fee must be covered too", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+		mockRepo.On("Balance", ctx, uid).Return(decimal.NewFromInt(11), nil)
+		mockRepo.On("Tier", ctx, uid).Return("", nil)
+
+		err := walletServ.Transfer(ctx, uid, 2, decimal.NewFromInt(10), "", model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrInsufficientBalance)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
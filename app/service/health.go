@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"server/app/model"
+	"server/app/repository"
+)
+
+var ErrSchemaOutdated = errors.New("schema is not current")
+
+func NewHealth(repo repository.SchemaInter) HealthInter {
+	return &HealthServ{
+		repo: repo,
+	}
+}
+
+// HealthInter checks the parts of the service's readiness that depend on application data.
+type HealthInter interface {
+	CheckSchema(ctx context.Context) error
+}
+
+type HealthServ struct {
+	repo repository.SchemaInter
+}
+
+// CheckSchema fails unless the database has every migration this build expects.
+func (h *HealthServ) CheckSchema(ctx context.Context) error {
+	version, err := h.repo.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version < model.SchemaVersion {
+		return fmt.Errorf("%w: have version %d, want %d", ErrSchemaOutdated, version, model.SchemaVersion)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSchemaRepo is a mock implementation of the repository.SchemaInter interface
+type MockSchemaRepo struct {
+	mock.Mock
+}
+
+func (m *MockSchemaRepo) Version(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"server/app/model"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestHealthServ_CheckSchema(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		version int64
+		err     error
+		wantErr error
+	}{
+		{"Current", model.SchemaVersion, nil, nil},
+		{"Newer", model.SchemaVersion + 1, nil, nil},
+		{"Outdated", model.SchemaVersion - 1, nil, ErrSchemaOutdated},
+		{"Query error", 0, errors.New("db down"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockSchemaRepo)
+			serv := NewHealth(repo)
+
+			repo.On("Version", ctx).Return(tt.version, tt.err)
+
+			err := serv.CheckSchema(ctx)
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			case tt.err != nil:
+				require.ErrorIs(t, err, tt.err)
+			default:
+				require.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/request"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidPaymentRequest = errors.New("invalid payment request")
+	ErrNotPayer              = errors.New("not the payer of the payment request")
+	ErrNotRequester          = errors.New("not the requester of the payment request")
+)
+
+const (
+	// defaultPaymentRequestTTL is how long a payment request is valid without an expires_at.
+	defaultPaymentRequestTTL = 7 * 24 * time.Hour
+	// maxPaymentRequestTTL is the furthest expires_at of a payment request.
+	maxPaymentRequestTTL = 30 * 24 * time.Hour
+	maxMemoLength        = 140
+)
+
+// NewPaymentRequest creates the service of payment requests. A request is paid by a transfer
+// made through wallets, with its locks, limits, fees and audit.
+func NewPaymentRequest(repo repository.PaymentRequestInter, wallets WalletInter, walletRepo repository.WalletInter,
+	logger *zap.SugaredLogger) PaymentRequestInter {
+	return &PaymentRequestServ{
+		repo:       repo,
+		wallets:    wallets,
+		walletRepo: walletRepo,
+		logger:     logger,
+	}
+}
+
+// PaymentRequestInter defines the interface for payment requests.
+type PaymentRequestInter interface {
+	Create(ctx *gin.Context, mod *model.PaymentRequest) (*model.PaymentRequest, error)
+	List(ctx *gin.Context, req *request.ReqPaymentRequests) (*request.ResPaymentRequests, error)
+	GetByToken(ctx *gin.Context, token string) (*model.PaymentRequestView, error)
+	Accept(ctx *gin.Context, uid, id int64, token string) (*model.PaymentRequest, error)
+	Decline(ctx *gin.Context, uid, id int64) (*model.PaymentRequest, error)
+	Cancel(ctx *gin.Context, uid, id int64) (*model.PaymentRequest, error)
+}
+
+// PaymentRequestServ implements the PaymentRequestInter interface.
+type PaymentRequestServ struct {
+	repo       repository.PaymentRequestInter
+	wallets    WalletInter
+	walletRepo repository.WalletInter
+	logger     *zap.SugaredLogger
+}
+
+// Create stores a payment request from the wallet of mod.RequesterUID and returns it with its
+// token, which is shown only this once. The wallets of the requester and of the payer, if any,
+// must exist.
+func (p *PaymentRequestServ) Create(ctx *gin.Context, mod *model.PaymentRequest) (res *model.PaymentRequest,
+	err error) {
+	end := tracing.StartGin(ctx, "PaymentRequestServ.Create")
+	defer func() { end(err) }()
+
+	now := time.Now()
+	if mod.ExpiresAt.IsZero() {
+		mod.ExpiresAt = now.Add(defaultPaymentRequestTTL)
+	}
+
+	switch {
+	case !mod.Amount.IsPositive():
+		return nil, fmt.Errorf("payment request %w", ErrNonPositiveAmount)
+	case mod.PayerUID < 0:
+		return nil, fmt.Errorf("%w: invalid payer_uid %d", ErrInvalidPaymentRequest, mod.PayerUID)
+	case mod.PayerUID == mod.RequesterUID:
+		return nil, fmt.Errorf("%w: can't request money from oneself", ErrInvalidPaymentRequest)
+	case utf8.RuneCountInString(mod.Memo) > maxMemoLength:
+		return nil, fmt.Errorf("%w: memo longer than %d", ErrInvalidPaymentRequest, maxMemoLength)
+	case !mod.ExpiresAt.After(now) || mod.ExpiresAt.After(now.Add(maxPaymentRequestTTL)):
+		return nil, fmt.Errorf("%w: expires_at must be within %s from now", ErrInvalidPaymentRequest,
+			maxPaymentRequestTTL)
+	}
+
+	if err = checkRuntime(model.Transfer, mod.Amount); err != nil {
+		return nil, err
+	}
+
+	for _, uid := range []int64{mod.RequesterUID, mod.PayerUID} {
+		if uid == 0 {
+			continue
+		}
+		if _, err = p.walletRepo.GetWalletByUID(ctx, uid); err != nil {
+			return nil, err
+		}
+	}
+
+	if mod.Token, err = newPaymentToken(); err != nil {
+		return nil, err
+	}
+	mod.TokenHash = hashPaymentToken(mod.Token)
+
+	if err = p.repo.CreatePaymentRequest(ctx, mod); err != nil {
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+// List returns the payment requests made by or to the wallet of req.UID.
+func (p *PaymentRequestServ) List(ctx *gin.Context, req *request.ReqPaymentRequests) (
+	res *request.ResPaymentRequests, err error) {
+	end := tracing.StartGin(ctx, "PaymentRequestServ.List")
+	defer func() { end(err) }()
+
+	return p.repo.ListPaymentRequests(ctx, req)
+}
+
+// GetByToken returns the payment request of a shared token, so that a payer can see what it is
+// asked to pay before accepting it.
+func (p *PaymentRequestServ) GetByToken(ctx *gin.Context, token string) (res *model.PaymentRequestView, err error) {
+	end := tracing.StartGin(ctx, "PaymentRequestServ.GetByToken")
+	defer func() { end(err) }()
+
+	mod, err := p.repo.GetPaymentRequestByToken(ctx, hashPaymentToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	return mod.View(), nil
+}
+
+// Accept pays the payment request of id from the wallet of uid with a transfer to its requester.
+// An open request needs its token. The request is marked as paid in the DB transaction of the
+// transfer, so that it is paid at most once and never without the money moving.
+func (p *PaymentRequestServ) Accept(ctx *gin.Context, uid, id int64, token string) (res *model.PaymentRequest,
+	err error) {
+	end := tracing.StartGin(ctx, "PaymentRequestServ.Accept")
+	defer func() { end(err) }()
+
+	res, err = p.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.RequesterUID == uid:
+		return nil, fmt.Errorf("%w: can't pay one's own request", ErrInvalidPaymentRequest)
+	case res.PayerUID == uid:
+	case res.PayerUID == 0 && token != "" &&
+		subtle.ConstantTimeCompare([]byte(hashPaymentToken(token)), []byte(res.TokenHash)) == 1:
+	default:
+		return nil, fmt.Errorf("%w %d", ErrNotPayer, id)
+	}
+
+	err = p.wallets.TransferAndClaim(ctx, uid, res.RequesterUID, res.Amount, model.TransactionDetails{Memo: res.Memo},
+		model.PaymentClaim{ID: id, PayerUID: uid})
+	if err != nil {
+		return nil, err
+	}
+
+	res.PayerUID, res.Status = uid, model.PaymentRequestStatusPaid
+
+	return res, nil
+}
+
+// Decline turns down the payment request of id made to the wallet of uid.
+func (p *PaymentRequestServ) Decline(ctx *gin.Context, uid, id int64) (res *model.PaymentRequest, err error) {
+	end := tracing.StartGin(ctx, "PaymentRequestServ.Decline")
+	defer func() { end(err) }()
+
+	res, err = p.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.PayerUID != uid {
+		return nil, fmt.Errorf("%w %d", ErrNotPayer, id)
+	}
+
+	return p.close(ctx, res, model.PaymentRequestStatusDeclined)
+}
+
+// Cancel withdraws the payment request of id made by the wallet of uid.
+func (p *PaymentRequestServ) Cancel(ctx *gin.Context, uid, id int64) (res *model.PaymentRequest, err error) {
+	end := tracing.StartGin(ctx, "PaymentRequestServ.Cancel")
+	defer func() { end(err) }()
+
+	res, err = p.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.RequesterUID != uid {
+		return nil, fmt.Errorf("%w %d", ErrNotRequester, id)
+	}
+
+	return p.close(ctx, res, model.PaymentRequestStatusCancelled)
+}
+
+// pending returns the payment request of id, failing with ErrPaymentRequestClosed when it is no
+// longer pending.
+func (p *PaymentRequestServ) pending(ctx *gin.Context, id int64) (*model.PaymentRequest, error) {
+	res, err := p.repo.GetPaymentRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Status != model.PaymentRequestStatusPending {
+		return nil, fmt.Errorf("%w: %d has status %d", repository.ErrPaymentRequestClosed, id, res.Status)
+	}
+
+	return res, nil
+}
+
+func (p *PaymentRequestServ) close(ctx *gin.Context, res *model.PaymentRequest,
+	status model.PaymentRequestStatus) (*model.PaymentRequest, error) {
+	if err := p.repo.ClosePaymentRequest(ctx, res.ID, status); err != nil {
+		return nil, err
+	}
+
+	res.Status = status
+
+	return res, nil
+}
+
+// newPaymentToken returns a random token that lets whoever holds it see and pay a payment
+// request.
+func newPaymentToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashPaymentToken returns what is stored of a token: its sha256, in hex.
+func hashPaymentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
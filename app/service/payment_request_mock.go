@@ -0,0 +1,42 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+
+	"server/app/model"
+	"server/app/request"
+)
+
+// MockPaymentRequestRepo is a mock implementation of the repository.PaymentRequestInter interface
+type MockPaymentRequestRepo struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRequestRepo) CreatePaymentRequest(ctx *gin.Context, mod *model.PaymentRequest) error {
+	args := m.Called(ctx, mod)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRequestRepo) GetPaymentRequest(ctx *gin.Context, id int64) (*model.PaymentRequest, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestRepo) GetPaymentRequestByToken(ctx *gin.Context, tokenHash string) (
+	*model.PaymentRequest, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*model.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestRepo) ListPaymentRequests(ctx *gin.Context,
+	req *request.ReqPaymentRequests) (*request.ResPaymentRequests, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResPaymentRequests), args.Error(1)
+}
+
+func (m *MockPaymentRequestRepo) ClosePaymentRequest(ctx *gin.Context, id int64,
+	status model.PaymentRequestStatus) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
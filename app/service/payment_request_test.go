@@ -0,0 +1,268 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/pkg/lock"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func newTestPaymentRequest(repo *MockPaymentRequestRepo, walletRepo *MockWalletRepo) PaymentRequestInter {
+	audit := new(MockAuditRepo)
+	audit.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+	logger := zap.NewNop().Sugar()
+	return NewPaymentRequest(repo, NewWallet(walletRepo, lock.Nop(), audit, logger), walletRepo, logger)
+}
+
+func TestPaymentRequestServ_Create(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	t.Run("Open request", func(t *testing.T) {
+		mockRepo, walletRepo := new(MockPaymentRequestRepo), new(MockWalletRepo)
+		serv := newTestPaymentRequest(mockRepo, walletRepo)
+
+		walletRepo.On("GetWalletByUID", ctx, int64(1)).Return(&model.Wallet{UID: 1}, nil)
+		mockRepo.On("CreatePaymentRequest", ctx, mock.MatchedBy(func(mod *model.PaymentRequest) bool {
+			return mod.RequesterUID == 1 && mod.PayerUID == 0 && mod.TokenHash == hashPaymentToken(mod.Token) &&
+				mod.ExpiresAt.After(time.Now().Add(defaultPaymentRequestTTL-time.Minute))
+		})).Return(nil)
+
+		res, err := serv.Create(ctx, &model.PaymentRequest{RequesterUID: 1, Amount: decimal.NewFromInt(10),
+			Memo: "dinner"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, res.Token)
+		assert.NotEqual(t, res.Token, res.TokenHash, "only the hash of the token is stored")
+
+		mockRepo.AssertExpectations(t)
+		walletRepo.AssertExpectations(t)
+	})
+
+	t.Run("Payer without a wallet", func(t *testing.T) {
+		mockRepo, walletRepo := new(MockPaymentRequestRepo), new(MockWalletRepo)
+		serv := newTestPaymentRequest(mockRepo, walletRepo)
+
+		walletRepo.On("GetWalletByUID", ctx, int64(1)).Return(&model.Wallet{UID: 1}, nil)
+		walletRepo.On("GetWalletByUID", ctx, int64(2)).Return(&model.Wallet{}, sql.ErrNoRows)
+
+		_, err := serv.Create(ctx, &model.PaymentRequest{RequesterUID: 1, PayerUID: 2, Amount: decimal.NewFromInt(10)})
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		mockRepo.AssertNotCalled(t, "CreatePaymentRequest", mock.Anything, mock.Anything)
+	})
+
+	tests := []struct {
+		name     string
+		mod      *model.PaymentRequest
+		expected error
+	}{
+		{"Zero amount", &model.PaymentRequest{RequesterUID: 1}, ErrNonPositiveAmount},
+		{"From oneself", &model.PaymentRequest{RequesterUID: 1, PayerUID: 1, Amount: decimal.NewFromInt(1)},
+			ErrInvalidPaymentRequest},
+		{"Expired", &model.PaymentRequest{RequesterUID: 1, Amount: decimal.NewFromInt(1),
+			ExpiresAt: time.Now().Add(-time.Minute)}, ErrInvalidPaymentRequest},
+		{"Too far out", &model.PaymentRequest{RequesterUID: 1, Amount: decimal.NewFromInt(1),
+			ExpiresAt: time.Now().Add(maxPaymentRequestTTL + time.Hour)}, ErrInvalidPaymentRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, walletRepo := new(MockPaymentRequestRepo), new(MockWalletRepo)
+			serv := newTestPaymentRequest(mockRepo, walletRepo)
+
+			_, err := serv.Create(ctx, tt.mod)
+			require.ErrorIs(t, err, tt.expected)
+
+			mockRepo.AssertNotCalled(t, "CreatePaymentRequest", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentRequestServ_GetByToken(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mockRepo := new(MockPaymentRequestRepo)
+	serv := newTestPaymentRequest(mockRepo, new(MockWalletRepo))
+
+	mockRepo.On("GetPaymentRequestByToken", ctx, hashPaymentToken("secret")).Return(&model.PaymentRequest{
+		ID: 5, RequesterUID: 1, RequesterName: "alice", PayerUID: 3, Amount: decimal.NewFromInt(10),
+		Status: model.PaymentRequestStatusPending, TokenHash: hashPaymentToken("secret"),
+	}, nil)
+
+	res, err := serv.GetByToken(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, &model.PaymentRequestView{ID: 5, RequesterName: "alice", Amount: decimal.NewFromInt(10),
+		Status: model.PaymentRequestStatusPending}, res)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPaymentRequestServ_Accept(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	amount := decimal.NewFromInt(10)
+	pending := func(payerUID int64) *model.PaymentRequest {
+		return &model.PaymentRequest{ID: 5, RequesterUID: 1, PayerUID: payerUID, Amount: amount, Memo: "dinner",
+			Status: model.PaymentRequestStatusPending, TokenHash: hashPaymentToken("secret")}
+	}
+
+	t.Run("Open request with its token", func(t *testing.T) {
+		mockRepo, walletRepo := new(MockPaymentRequestRepo), new(MockWalletRepo)
+		serv := newTestPaymentRequest(mockRepo, walletRepo)
+
+		mockRepo.On("GetPaymentRequest", ctx, int64(5)).Return(pending(0), nil)
+		walletRepo.On("Balance", ctx, int64(3)).Return(decimal.NewFromInt(50), nil)
+		walletRepo.On("Balance", ctx, int64(1)).Return(decimal.Zero, nil)
+		walletRepo.On("TransferAndClaim", ctx, int64(3), int64(1), amount, model.FeeCharge{},
+			model.TransactionDetails{Memo: "dinner"}, model.PaymentClaim{ID: 5, PayerUID: 3}).Return(nil)
+
+		res, err := serv.Accept(ctx, 3, 5, "secret")
+		require.NoError(t, err)
+		assert.Equal(t, model.PaymentRequestStatusPaid, res.Status)
+		assert.Equal(t, int64(3), res.PayerUID)
+
+		mockRepo.AssertExpectations(t)
+		walletRepo.AssertExpectations(t)
+	})
+
+	t.Run("Failed transfer", func(t *testing.T) {
+		mockRepo, walletRepo := new(MockPaymentRequestRepo), new(MockWalletRepo)
+		serv := newTestPaymentRequest(mockRepo, walletRepo)
+
+		mockRepo.On("GetPaymentRequest", ctx, int64(5)).Return(pending(0), nil)
+		walletRepo.On("Balance", ctx, int64(3)).Return(decimal.NewFromInt(5), nil)
+
+		_, err := serv.Accept(ctx, 3, 5, "secret")
+		require.ErrorIs(t, err, ErrInsufficientBalance)
+
+		mockRepo.AssertExpectations(t)
+		walletRepo.AssertNotCalled(t, "TransferAndClaim", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	tests := []struct {
+		name     string
+		request  *model.PaymentRequest
+		uid      int64
+		token    string
+		expected error
+	}{
+		{"Open request without its token", pending(0), 3, "", ErrNotPayer},
+		{"Open request with another token", pending(0), 3, "guess", ErrNotPayer},
+		{"Someone else's request", pending(2), 3, "secret", ErrNotPayer},
+		{"Own request", pending(0), 1, "secret", ErrInvalidPaymentRequest},
+		{"Already paid", &model.PaymentRequest{ID: 5, RequesterUID: 1, PayerUID: 3,
+			Status: model.PaymentRequestStatusPaid}, 3, "", repository.ErrPaymentRequestClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, walletRepo := new(MockPaymentRequestRepo), new(MockWalletRepo)
+			serv := newTestPaymentRequest(mockRepo, walletRepo)
+
+			mockRepo.On("GetPaymentRequest", ctx, int64(5)).Return(tt.request, nil)
+
+			_, err := serv.Accept(ctx, tt.uid, 5, tt.token)
+			require.ErrorIs(t, err, tt.expected)
+
+			walletRepo.AssertNotCalled(t, "TransferAndClaim", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("Claimed concurrently", func(t *testing.T) {
+		mockRepo, walletRepo := new(MockPaymentRequestRepo), new(MockWalletRepo)
+		serv := newTestPaymentRequest(mockRepo, walletRepo)
+
+		mockRepo.On("GetPaymentRequest", ctx, int64(5)).Return(pending(3), nil)
+		walletRepo.On("Balance", ctx, int64(3)).Return(decimal.NewFromInt(50), nil)
+		walletRepo.On("Balance", ctx, int64(1)).Return(decimal.Zero, nil)
+		walletRepo.On("TransferAndClaim", ctx, int64(3), int64(1), amount, model.FeeCharge{},
+			model.TransactionDetails{Memo: "dinner"}, model.PaymentClaim{ID: 5, PayerUID: 3}).Return(fmt.Errorf("%w: 5", repository.ErrPaymentRequestClosed))
+
+		_, err := serv.Accept(ctx, 3, 5, "")
+		require.ErrorIs(t, err, repository.ErrPaymentRequestClosed)
+
+		walletRepo.AssertExpectations(t)
+	})
+}
+
+func TestPaymentRequestServ_DeclineCancel(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	request := func() *model.PaymentRequest {
+		return &model.PaymentRequest{ID: 5, RequesterUID: 1, PayerUID: 2, Amount: decimal.NewFromInt(10),
+			Status: model.PaymentRequestStatusPending}
+	}
+
+	t.Run("Payer declines", func(t *testing.T) {
+		mockRepo := new(MockPaymentRequestRepo)
+		serv := newTestPaymentRequest(mockRepo, new(MockWalletRepo))
+
+		mockRepo.On("GetPaymentRequest", ctx, int64(5)).Return(request(), nil)
+		mockRepo.On("ClosePaymentRequest", ctx, int64(5), model.PaymentRequestStatusDeclined).Return(nil)
+
+		res, err := serv.Decline(ctx, 2, 5)
+		require.NoError(t, err)
+		assert.Equal(t, model.PaymentRequestStatusDeclined, res.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Requester can't decline", func(t *testing.T) {
+		mockRepo := new(MockPaymentRequestRepo)
+		serv := newTestPaymentRequest(mockRepo, new(MockWalletRepo))
+
+		mockRepo.On("GetPaymentRequest", ctx, int64(5)).Return(request(), nil)
+
+		_, err := serv.Decline(ctx, 1, 5)
+		require.ErrorIs(t, err, ErrNotPayer)
+	})
+
+	t.Run("Requester cancels", func(t *testing.T) {
+		mockRepo := new(MockPaymentRequestRepo)
+		serv := newTestPaymentRequest(mockRepo, new(MockWalletRepo))
+
+		mockRepo.On("GetPaymentRequest", ctx, int64(5)).Return(request(), nil)
+		mockRepo.On("ClosePaymentRequest", ctx, int64(5), model.PaymentRequestStatusCancelled).Return(nil)
+
+		res, err := serv.Cancel(ctx, 1, 5)
+		require.NoError(t, err)
+		assert.Equal(t, model.PaymentRequestStatusCancelled, res.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Payer can't cancel", func(t *testing.T) {
+		mockRepo := new(MockPaymentRequestRepo)
+		serv := newTestPaymentRequest(mockRepo, new(MockWalletRepo))
+
+		mockRepo.On("GetPaymentRequest", ctx, int64(5)).Return(request(), nil)
+
+		_, err := serv.Cancel(ctx, 2, 5)
+		require.ErrorIs(t, err, ErrNotRequester)
+	})
+}
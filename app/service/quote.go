@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/config"
+	"server/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrQuoteMismatch is returned when a quote is used for another movement than it was issued for.
+var ErrQuoteMismatch = errors.New("quote does not match the request")
+
+// defaultQuoteTTL is how long a quote is valid when runtime.fees.quote_ttl is not set.
+const defaultQuoteTTL = time.Minute
+
+// DryRun runs every check of a deposit, a withdrawal or a transfer of amount from or to the
+// wallet of uid, works out its fee and the balances it would leave, and makes nothing. With
+// quote, the fee of a withdrawal or a transfer is locked in for runtime.fees.quote_ttl under
+// the returned quote ID. toUID is only used by transfers.
+func (w *WalletServ) DryRun(ctx *gin.Context, operation string, uid, toUID int64, amount decimal.Decimal,
+	quote bool) (res *model.DryRun, err error) {
+	end := tracing.StartGin(ctx, "WalletServ.DryRun")
+	defer func() { end(err) }()
+
+	fromUID := uid
+	switch operation {
+	case model.Deposit:
+		fromUID, toUID = 0, uid
+	case model.Withdraw:
+		toUID = 0
+	case model.Transfer:
+		if toUID == uid {
+			return nil, fmt.Errorf("%w: uid %d", ErrSelfTransfer, uid)
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownOperation, operation)
+	}
+
+	if err = precheck(operation, amount); err != nil {
+		return nil, err
+	}
+
+	// A dry run takes no locks, but still reads the latest balances.
+	repository.Fresh(ctx)
+
+	if err = w.checkStatus(ctx, fromUID, toUID); err != nil {
+		return nil, err
+	}
+
+	m, err := w.check(ctx, operation, fromUID, toUID, amount, "")
+	if err != nil {
+		return nil, err
+	}
+
+	res = &model.DryRun{
+		FeeQuote: model.FeeQuote{Operation: operation, Amount: amount, Fee: m.fee.Amount, Total: m.total()},
+		Balance:  m.toAfter(),
+	}
+
+	if fromUID != 0 {
+		res.Balance = m.fromAfter()
+	}
+
+	if fromUID != 0 && toUID != 0 {
+		receiver := m.toAfter()
+		res.ReceiverBalance = &receiver
+	}
+
+	if !quote || fromUID == 0 {
+		return res, nil
+	}
+
+	ttl := config.Runtime().Fees.QuoteTTL
+	if ttl <= 0 {
+		ttl = defaultQuoteTTL
+	}
+
+	q := &model.Quote{ID: uuid.NewString(), Operation: operation, UID: fromUID, ToUID: toUID, Amount: amount,
+		Fee: m.fee.Amount, RevenueUID: m.fee.RevenueUID}
+	if err = w.repo.CreateQuote(ctx, q, ttl); err != nil {
+		return nil, err
+	}
+
+	res.QuoteID, res.ExpiresAt = q.ID, &q.ExpiresAt
+
+	return res, nil
+}
+
+// checkStatus refuses a movement that the status of its wallets would refuse. Real movements
+// leave this to their guarded writes, which a dry run doesn't make.
+func (w *WalletServ) checkStatus(ctx *gin.Context, fromUID, toUID int64) error {
+	if fromUID != 0 {
+		wallet, err := w.repo.GetWalletByUID(ctx, fromUID)
+		if err != nil {
+			return err
+		}
+		if !wallet.Status.CanDebit() {
+			return repository.WalletStatusError(wallet.Status, fromUID)
+		}
+	}
+
+	if toUID != 0 && toUID != fromUID {
+		wallet, err := w.repo.GetWalletByUID(ctx, toUID)
+		if err != nil {
+			return err
+		}
+		if !wallet.Status.CanCredit() {
+			return repository.WalletStatusError(wallet.Status, toUID)
+		}
+	}
+
+	return nil
+}
+
+// charge returns the fee of a movement: the configured one, or the one locked in by the quote of
+// quoteID when it is given. The quote must have been issued for this very movement.
+func (w *WalletServ) charge(ctx *gin.Context, operation string, fromUID, toUID int64, amount decimal.Decimal,
+	quoteID string) (model.FeeCharge, error) {
+	if quoteID == "" {
+		return w.fee(ctx, operation, fromUID, amount)
+	}
+
+	q, err := w.repo.GetQuote(ctx, quoteID)
+	if err != nil {
+		return model.FeeCharge{}, err
+	}
+
+	if q.Operation != operation || q.UID != fromUID || q.ToUID != toUID || !q.Amount.Equal(amount) {
+		return model.FeeCharge{}, fmt.Errorf("%w: %s", ErrQuoteMismatch, quoteID)
+	}
+
+	return model.FeeCharge{Amount: q.Fee, RevenueUID: q.RevenueUID, QuoteID: q.ID}, nil
+}
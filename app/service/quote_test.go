@@ -0,0 +1,180 @@
+package service
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/config"
+	"server/pkg/lock"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestWalletServ_DryRun(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	config.SetRuntime(&config.RuntimeConf{Fees: config.FeesConf{RevenueUID: 9, QuoteTTL: 30 * time.Second,
+		Rules: []config.FeeRule{{Operation: model.Transfer, FeeFormula: config.FeeFormula{Flat: decimal.NewFromInt(1)}}}}})
+	t.Cleanup(func() { config.SetRuntime(nil) })
+
+	active := &model.Wallet{Status: model.WalletStatusActive}
+
+	t.Run("Deposit", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+		mockRepo.On("GetWalletByUID", ctx, int64(1)).Return(active, nil)
+		mockRepo.On("Balance", ctx, int64(1)).Return(decimal.NewFromInt(10), nil)
+
+		res, err := walletServ.DryRun(ctx, model.Deposit, 1, 0, decimal.NewFromInt(5), true)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(15).Equal(res.Balance))
+		assert.True(t, res.Fee.IsZero())
+		assert.Nil(t, res.ReceiverBalance)
+		assert.Empty(t, res.QuoteID, "deposits are free, there is nothing to quote")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Transfer with a quote", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+		amount := decimal.NewFromInt(5)
+		expiresAt := time.Now().Add(30 * time.Second)
+		mockRepo.On("GetWalletByUID", ctx, int64(1)).Return(active, nil)
+		mockRepo.On("GetWalletByUID", ctx, int64(2)).Return(active, nil)
+		mockRepo.On("Balance", ctx, int64(1)).Return(decimal.NewFromInt(10), nil)
+		mockRepo.On("Balance", ctx, int64(2)).Return(decimal.NewFromInt(3), nil)
+		mockRepo.On("Tier", ctx, int64(1)).Return("", nil)
+		mockRepo.On("CreateQuote", ctx, mock.MatchedBy(func(q *model.Quote) bool {
+			return q.ID != "" && q.Operation == model.Transfer && q.UID == 1 && q.ToUID == 2 &&
+				q.Amount.Equal(amount) && q.Fee.Equal(decimal.NewFromInt(1)) && q.RevenueUID == 9
+		}), 30*time.Second).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Quote).ExpiresAt = expiresAt
+		}).Return(nil)
+
+		res, err := walletServ.DryRun(ctx, model.Transfer, 1, 2, amount, true)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(6).Equal(res.Total))
+		assert.True(t, decimal.NewFromInt(4).Equal(res.Balance))
+		require.NotNil(t, res.ReceiverBalance)
+		assert.True(t, decimal.NewFromInt(8).Equal(*res.ReceiverBalance))
+		assert.NotEmpty(t, res.QuoteID)
+		assert.Equal(t, expiresAt, *res.ExpiresAt)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Failing checks", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			operation string
+			wallet    *model.Wallet
+			walletErr error
+			balance   decimal.Decimal
+			expected  error
+		}{
+			{"Unknown operation", "refund", nil, nil, decimal.Zero, ErrUnknownOperation},
+			{"Wallet not found", model.Withdraw, &model.Wallet{}, sql.ErrNoRows, decimal.Zero, sql.ErrNoRows},
+			{"Frozen wallet", model.Withdraw, &model.Wallet{Status: model.WalletStatusFrozen}, nil, decimal.Zero,
+				repository.ErrWalletFrozen},
+			{"Insufficient balance", model.Withdraw, active, nil, decimal.NewFromInt(1), ErrInsufficientBalance},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockRepo := new(MockWalletRepo)
+				walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+				if tt.wallet != nil {
+					mockRepo.On("GetWalletByUID", ctx, int64(1)).Return(tt.wallet, tt.walletErr)
+				}
+				if tt.expected == ErrInsufficientBalance {
+					mockRepo.On("Balance", ctx, int64(1)).Return(tt.balance, nil)
+					mockRepo.On("Tier", ctx, int64(1)).Return("", nil)
+				}
+
+				_, err := walletServ.DryRun(ctx, tt.operation, 1, 0, decimal.NewFromInt(5), true)
+				require.ErrorIs(t, err, tt.expected)
+
+				mockRepo.AssertNotCalled(t, "CreateQuote", mock.Anything, mock.Anything, mock.Anything)
+				mockRepo.AssertExpectations(t)
+			})
+		}
+	})
+}
+
+func TestWalletServ_QuotedFee(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	// The configured fee has gone up since the quote was issued.
+	config.SetRuntime(&config.RuntimeConf{Fees: config.FeesConf{RevenueUID: 9,
+		Rules: []config.FeeRule{{Operation: model.Withdraw, FeeFormula: config.FeeFormula{Flat: decimal.NewFromInt(3)}}}}})
+	t.Cleanup(func() { config.SetRuntime(nil) })
+
+	amount := decimal.NewFromInt(10)
+	quote := &model.Quote{ID: "q1", Operation: model.Withdraw, UID: 1, Amount: amount, Fee: decimal.NewFromInt(1),
+		RevenueUID: 9}
+
+	t.Run("The quoted fee is charged", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		audit := new(MockAuditRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), audit, zap.NewNop().Sugar())
+
+		mockRepo.On("Balance", ctx, int64(1)).Return(decimal.NewFromInt(11), nil)
+		mockRepo.On("GetQuote", ctx, "q1").Return(quote, nil)
+		mockRepo.On("Withdraw", ctx, int64(1), amount,
+			model.FeeCharge{Amount: decimal.NewFromInt(1), RevenueUID: 9, QuoteID: "q1"}, model.TransactionDetails{}).
+			Return(nil)
+		audit.On("Record", ctx, mock.Anything).Return(nil)
+
+		require.NoError(t, walletServ.Withdraw(ctx, 1, amount, "q1", model.TransactionDetails{}))
+
+		mockRepo.AssertNotCalled(t, "Tier", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("A quote is only good for its own movement", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+		mockRepo.On("Balance", ctx, int64(1)).Return(decimal.NewFromInt(50), nil)
+		mockRepo.On("GetQuote", ctx, "q1").Return(quote, nil)
+
+		err := walletServ.Withdraw(ctx, 1, decimal.NewFromInt(20), "q1", model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrQuoteMismatch)
+		assert.Equal(t, "invalid_quote", failureReason(err))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Expired quote", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+		mockRepo.On("Balance", ctx, int64(1)).Return(decimal.NewFromInt(50), nil)
+		mockRepo.On("GetQuote", ctx, "q2").Return((*model.Quote)(nil), repository.ErrQuoteExpired)
+
+		err := walletServ.Withdraw(ctx, 1, amount, "q2", model.TransactionDetails{})
+		require.ErrorIs(t, err, repository.ErrQuoteExpired)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
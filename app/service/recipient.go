@@ -0,0 +1,119 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/pkg/tracing"
+)
+
+var (
+	ErrInvalidRecipient = errors.New("invalid recipient")
+	ErrInvalidHandle    = errors.New("invalid handle")
+)
+
+// NewRecipient creates the service that finds the wallet a transfer is addressed to.
+func NewRecipient(userRepo repository.UserInter, walletRepo repository.WalletInter,
+	logger *zap.SugaredLogger) RecipientInter {
+	return &RecipientServ{
+		userRepo:   userRepo,
+		walletRepo: walletRepo,
+		logger:     logger,
+	}
+}
+
+// RecipientInter defines the interface for the recipients of transfers.
+type RecipientInter interface {
+	Resolve(ctx *gin.Context, fromUID int64, to string) (*model.Recipient, error)
+	SetHandle(ctx *gin.Context, uid int64, handle string) error
+}
+
+// RecipientServ implements the RecipientInter interface.
+type RecipientServ struct {
+	userRepo   repository.UserInter
+	walletRepo repository.WalletInter
+	logger     *zap.SugaredLogger
+}
+
+// Resolve finds the wallet that the wallet of fromUID would transfer to when addressing to: a
+// wallet handle after model.HandlePrefix, an email or a username. A closed wallet is not found,
+// and the wallet of fromUID itself fails with ErrSelfTransfer.
+func (r *RecipientServ) Resolve(ctx *gin.Context, fromUID int64, to string) (res *model.Recipient, err error) {
+	end := tracing.StartGin(ctx, "RecipientServ.Resolve")
+	defer func() { end(err) }()
+
+	to = strings.TrimSpace(to)
+
+	var user *model.User
+	var wallet *model.Wallet
+
+	switch {
+	case to == "" || to == model.HandlePrefix:
+		return nil, fmt.Errorf("%w: empty address", ErrInvalidRecipient)
+	case strings.HasPrefix(to, model.HandlePrefix):
+		handle := strings.ToLower(strings.TrimPrefix(to, model.HandlePrefix))
+		if !model.IsValidHandle(handle) {
+			return nil, fmt.Errorf("%w: malformed handle", ErrInvalidRecipient)
+		}
+		if wallet, err = r.walletRepo.GetWalletByHandle(ctx, handle); err != nil {
+			return nil, err
+		}
+		user, err = r.userRepo.GetUserByID(ctx, wallet.UID)
+	case strings.Contains(to, "@"):
+		user, err = r.userRepo.GetUserByEmail(ctx, to)
+	default:
+		user, err = r.userRepo.GetUserByUsername(ctx, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if wallet == nil {
+		if wallet, err = r.walletRepo.GetWalletByUID(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case wallet.Status == model.WalletStatusClosed:
+		return nil, sql.ErrNoRows
+	case wallet.UID == fromUID:
+		return nil, fmt.Errorf("%w: uid %d", ErrSelfTransfer, fromUID)
+	}
+
+	return &model.Recipient{UID: wallet.UID, Name: maskName(user.Username), Handle: wallet.Handle}, nil
+}
+
+// SetHandle gives the wallet of uid a handle that others can transfer to.
+func (r *RecipientServ) SetHandle(ctx *gin.Context, uid int64, handle string) (err error) {
+	end := tracing.StartGin(ctx, "RecipientServ.SetHandle")
+	defer func() { end(err) }()
+
+	handle = strings.ToLower(strings.TrimPrefix(handle, model.HandlePrefix))
+	if !model.IsValidHandle(handle) {
+		return fmt.Errorf("%w: %q must be 3 to 32 letters, digits or underscores", ErrInvalidHandle, handle)
+	}
+
+	return r.walletRepo.SetHandle(ctx, uid, handle)
+}
+
+// maskName hides all but the first and last characters of name, enough for a sender to
+// recognise the recipient without learning its username.
+func maskName(name string) string {
+	runes := []rune(name)
+	switch len(runes) {
+	case 0:
+		return ""
+	case 1, 2:
+		return string(runes[0]) + "***"
+	default:
+		return string(runes[0]) + "***" + string(runes[len(runes)-1])
+	}
+}
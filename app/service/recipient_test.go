@@ -0,0 +1,153 @@
+package service
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	"server/app/model"
+	"server/app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestRecipientServ_Resolve(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	alice := &model.User{ID: 2, Username: "alice", Email: "alice@example.com"}
+	active := &model.Wallet{UID: 2, Status: model.WalletStatusActive, Handle: "alice_shop"}
+
+	tests := []struct {
+		name     string
+		to       string
+		mockFunc func(userRepo *MockUserRepo, walletRepo *MockWalletRepo)
+		expected *model.Recipient
+		err      error
+	}{
+		{
+			name: "Username",
+			to:   "alice",
+			mockFunc: func(userRepo *MockUserRepo, walletRepo *MockWalletRepo) {
+				userRepo.On("GetUserByUsername", ctx, "alice").Return(alice, nil)
+				walletRepo.On("GetWalletByUID", ctx, int64(2)).Return(active, nil)
+			},
+			expected: &model.Recipient{UID: 2, Name: "a***e", Handle: "alice_shop"},
+		},
+		{
+			name: "Email",
+			to:   "alice@example.com",
+			mockFunc: func(userRepo *MockUserRepo, walletRepo *MockWalletRepo) {
+				userRepo.On("GetUserByEmail", ctx, "alice@example.com").Return(alice, nil)
+				walletRepo.On("GetWalletByUID", ctx, int64(2)).Return(active, nil)
+			},
+			expected: &model.Recipient{UID: 2, Name: "a***e", Handle: "alice_shop"},
+		},
+		{
+			name: "Handle",
+			to:   "@Alice_Shop",
+			mockFunc: func(userRepo *MockUserRepo, walletRepo *MockWalletRepo) {
+				walletRepo.On("GetWalletByHandle", ctx, "alice_shop").Return(active, nil)
+				userRepo.On("GetUserByID", ctx, int64(2)).Return(alice, nil)
+			},
+			expected: &model.Recipient{UID: 2, Name: "a***e", Handle: "alice_shop"},
+		},
+		{
+			name: "Unknown username",
+			to:   "bob",
+			mockFunc: func(userRepo *MockUserRepo, walletRepo *MockWalletRepo) {
+				userRepo.On("GetUserByUsername", ctx, "bob").Return(&model.User{}, sql.ErrNoRows)
+			},
+			err: sql.ErrNoRows,
+		},
+		{
+			name: "Closed wallet",
+			to:   "alice",
+			mockFunc: func(userRepo *MockUserRepo, walletRepo *MockWalletRepo) {
+				userRepo.On("GetUserByUsername", ctx, "alice").Return(alice, nil)
+				walletRepo.On("GetWalletByUID", ctx, int64(2)).
+					Return(&model.Wallet{UID: 2, Status: model.WalletStatusClosed}, nil)
+			},
+			err: sql.ErrNoRows,
+		},
+		{
+			name: "Oneself",
+			to:   "carol",
+			mockFunc: func(userRepo *MockUserRepo, walletRepo *MockWalletRepo) {
+				userRepo.On("GetUserByUsername", ctx, "carol").Return(&model.User{ID: 1, Username: "carol"}, nil)
+				walletRepo.On("GetWalletByUID", ctx, int64(1)).
+					Return(&model.Wallet{UID: 1, Status: model.WalletStatusActive}, nil)
+			},
+			err: ErrSelfTransfer,
+		},
+		{name: "Empty", to: " ", err: ErrInvalidRecipient},
+		{name: "Malformed handle", to: "@a!", err: ErrInvalidRecipient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo, walletRepo := new(MockUserRepo), new(MockWalletRepo)
+			if tt.mockFunc != nil {
+				tt.mockFunc(userRepo, walletRepo)
+			}
+
+			recipientServ := NewRecipient(userRepo, walletRepo, zap.NewNop().Sugar())
+
+			res, err := recipientServ.Resolve(ctx, 1, tt.to)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, res)
+			}
+
+			userRepo.AssertExpectations(t)
+			walletRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecipientServ_SetHandle(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	t.Run("Normalized", func(t *testing.T) {
+		walletRepo := new(MockWalletRepo)
+		walletRepo.On("SetHandle", ctx, int64(1), "carol").Return(nil)
+
+		recipientServ := NewRecipient(new(MockUserRepo), walletRepo, zap.NewNop().Sugar())
+		require.NoError(t, recipientServ.SetHandle(ctx, 1, "@Carol"))
+		walletRepo.AssertExpectations(t)
+	})
+
+	t.Run("Taken", func(t *testing.T) {
+		walletRepo := new(MockWalletRepo)
+		walletRepo.On("SetHandle", ctx, int64(1), "alice_shop").Return(repository.ErrHandleTaken)
+
+		recipientServ := NewRecipient(new(MockUserRepo), walletRepo, zap.NewNop().Sugar())
+		require.ErrorIs(t, recipientServ.SetHandle(ctx, 1, "alice_shop"), repository.ErrHandleTaken)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		walletRepo := new(MockWalletRepo)
+
+		recipientServ := NewRecipient(new(MockUserRepo), walletRepo, zap.NewNop().Sugar())
+		require.ErrorIs(t, recipientServ.SetHandle(ctx, 1, "no spaces"), ErrInvalidHandle)
+		walletRepo.AssertNotCalled(t, "SetHandle", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestMaskName(t *testing.T) {
+	for name, expected := range map[string]string{"": "", "a": "a***", "al": "a***", "alice": "a***e", "éloïse": "é***e"} {
+		assert.Equal(t, expected, maskName(name), name)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/request"
+
+	"go.uber.org/zap"
+)
+
+func NewReconcile(repo repository.ReconcileInter, logger *zap.SugaredLogger) ReconcileInter {
+	return &ReconcileServ{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// ReconcileInter checks wallet balances against their transaction history.
+type ReconcileInter interface {
+	Run(ctx context.Context) error
+	Reconcile(ctx context.Context) ([]*model.Discrepancy, error)
+	ListDiscrepancies(ctx context.Context, req *request.ReqDiscrepancies) (*request.ResDiscrepancies, error)
+}
+
+type ReconcileServ struct {
+	repo   repository.ReconcileInter
+	logger *zap.SugaredLogger
+}
+
+// Run reconciles every wallet. Discrepancies are recorded and logged, not treated as a failure of the job.
+func (r *ReconcileServ) Run(ctx context.Context) error {
+	_, err := r.Reconcile(ctx)
+	return err
+}
+
+// Reconcile recomputes every wallet's balance from its history and logs each open discrepancy at error level.
+func (r *ReconcileServ) Reconcile(ctx context.Context) ([]*model.Discrepancy, error) {
+	list, err := r.repo.Reconcile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range list {
+		r.logger.Errorw("balance discrepancy",
+			"uid", d.UID,
+			"wallet_balance", d.WalletBalance.String(),
+			"ledger_balance", d.LedgerBalance.String(),
+			"difference", d.Difference.String(),
+			"detected_at", d.DetectedAt,
+		)
+	}
+
+	r.logger.Infof("reconciliation finished, %d open discrepancies", len(list))
+
+	return list, nil
+}
+
+func (r *ReconcileServ) ListDiscrepancies(ctx context.Context,
+	req *request.ReqDiscrepancies) (*request.ResDiscrepancies, error) {
+	return r.repo.ListDiscrepancies(ctx, req)
+}
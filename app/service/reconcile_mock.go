@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReconcileRepo is a mock implementation of the repository.ReconcileInter interface
+type MockReconcileRepo struct {
+	mock.Mock
+}
+
+func (m *MockReconcileRepo) Reconcile(ctx context.Context) ([]*model.Discrepancy, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.Discrepancy), args.Error(1)
+}
+
+func (m *MockReconcileRepo) ListDiscrepancies(ctx context.Context,
+	req *request.ReqDiscrepancies) (*request.ResDiscrepancies, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*request.ResDiscrepancies), args.Error(1)
+}
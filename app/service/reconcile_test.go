@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"server/app/model"
+	"server/app/request"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestReconcileServ_NewReconcile(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	repo := new(MockReconcileRepo)
+	logger := zap.NewExample().Sugar()
+
+	inter := NewReconcile(repo, logger)
+	serv, ok := inter.(*ReconcileServ)
+	assert.True(t, ok)
+	assert.Equal(t, repo, serv.repo)
+	assert.Equal(t, logger, serv.logger)
+}
+
+func TestReconcileServ_Reconcile(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+
+	t.Run("Logs discrepancies", func(t *testing.T) {
+		core, logs := observer.New(zap.InfoLevel)
+		repo := new(MockReconcileRepo)
+		serv := NewReconcile(repo, zap.New(core).Sugar())
+
+		list := []*model.Discrepancy{{
+			UID:           7,
+			WalletBalance: decimal.NewFromInt(100),
+			LedgerBalance: decimal.NewFromInt(90),
+			Difference:    decimal.NewFromInt(10),
+			Status:        model.DiscrepancyStatusOpen,
+		}}
+		repo.On("Reconcile", ctx).Return(list, nil)
+
+		res, err := serv.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, list, res)
+
+		errs := logs.FilterMessage("balance discrepancy").All()
+		require.Len(t, errs, 1)
+		assert.Equal(t, zap.ErrorLevel, errs[0].Level)
+		assert.Equal(t, int64(7), errs[0].ContextMap()["uid"])
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Run ignores discrepancies", func(t *testing.T) {
+		repo := new(MockReconcileRepo)
+		serv := NewReconcile(repo, zap.NewNop().Sugar())
+
+		repo.On("Reconcile", ctx).Return([]*model.Discrepancy{{UID: 1}}, nil)
+
+		require.NoError(t, serv.Run(ctx))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		repo := new(MockReconcileRepo)
+		serv := NewReconcile(repo, zap.NewNop().Sugar())
+
+		repo.On("Reconcile", ctx).Return([]*model.Discrepancy(nil), errors.New("db down"))
+
+		require.Error(t, serv.Run(ctx))
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestReconcileServ_ListDiscrepancies(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+	repo := new(MockReconcileRepo)
+	serv := NewReconcile(repo, zap.NewNop().Sugar())
+
+	req := &request.ReqDiscrepancies{Status: model.DiscrepancyStatusOpen}
+	expected := &request.ResDiscrepancies{List: []*model.Discrepancy{{UID: 1}}}
+	repo.On("ListDiscrepancies", ctx, req).Return(expected, nil)
+
+	res, err := serv.ListDiscrepancies(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, expected, res)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"server/app/repository"
+)
+
+func NewSnapshot(repo repository.SnapshotInter) SnapshotInter {
+	return &SnapshotServ{
+		repo: repo,
+	}
+}
+
+// SnapshotInter keeps the daily balance snapshots up to date.
+type SnapshotInter interface {
+	Run(ctx context.Context) error
+	SnapshotDay(ctx context.Context, day time.Time) (int64, error)
+}
+
+type SnapshotServ struct {
+	repo repository.SnapshotInter
+}
+
+// Run snapshots yesterday, the latest day that is already closed.
+// It is safe to run repeatedly; days that are already snapshotted are skipped.
+func (s *SnapshotServ) Run(ctx context.Context) error {
+	_, err := s.SnapshotDay(ctx, time.Now().AddDate(0, 0, -1))
+	return err
+}
+
+// SnapshotDay stores the closing balance of every wallet for day and returns how many were added.
+func (s *SnapshotServ) SnapshotDay(ctx context.Context, day time.Time) (int64, error) {
+	return s.repo.CreateDailySnapshots(ctx, day)
+}
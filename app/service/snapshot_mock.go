@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSnapshotRepo is a mock implementation of the repository.SnapshotInter interface
+type MockSnapshotRepo struct {
+	mock.Mock
+}
+
+func (m *MockSnapshotRepo) CreateDailySnapshots(ctx context.Context, day time.Time) (int64, error) {
+	args := m.Called(ctx, day)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestSnapshotServ_NewSnapshot(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	repo := new(MockSnapshotRepo)
+
+	inter := NewSnapshot(repo)
+	serv, ok := inter.(*SnapshotServ)
+	assert.True(t, ok)
+	assert.Equal(t, repo, serv.repo)
+}
+
+func TestSnapshotServ_Run(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+
+	t.Run("Snapshots yesterday", func(t *testing.T) {
+		repo := new(MockSnapshotRepo)
+		serv := NewSnapshot(repo)
+
+		yesterday := time.Now().AddDate(0, 0, -1).Format(time.DateOnly)
+		repo.On("CreateDailySnapshots", ctx, mock.MatchedBy(func(day time.Time) bool {
+			return day.Format(time.DateOnly) == yesterday
+		})).Return(int64(3), nil)
+
+		require.NoError(t, serv.Run(ctx))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		repo := new(MockSnapshotRepo)
+		serv := NewSnapshot(repo)
+
+		repo.On("CreateDailySnapshots", ctx, mock.Anything).Return(int64(0), errors.New("db down"))
+
+		require.Error(t, serv.Run(ctx))
+		repo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"server/app/repository"
+	"server/pkg/metrics"
+)
+
+func NewStats(repo repository.StatsInter) StatsInter {
+	return &StatsServ{
+		repo: repo,
+	}
+}
+
+// StatsInter refreshes the business gauges that are too expensive to compute per scrape.
+type StatsInter interface {
+	Run(ctx context.Context) error
+}
+
+type StatsServ struct {
+	repo repository.StatsInter
+}
+
+// Run updates the balance under management and the wallet count gauges.
+func (s *StatsServ) Run(ctx context.Context) error {
+	total, count, err := s.repo.WalletTotals(ctx)
+	if err != nil {
+		return err
+	}
+
+	metrics.BalanceTotal.Set(total.InexactFloat64())
+	metrics.Wallets.Set(float64(count))
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockStatsRepo is a mock implementation of the repository.StatsInter interface
+type MockStatsRepo struct {
+	mock.Mock
+}
+
+func (m *MockStatsRepo) WalletTotals(ctx context.Context) (decimal.Decimal, int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(decimal.Decimal), args.Get(1).(int64), args.Error(2)
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"server/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestStatsServ_Run(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+
+	t.Run("Updates gauges", func(t *testing.T) {
+		repo := new(MockStatsRepo)
+		serv := NewStats(repo)
+
+		repo.On("WalletTotals", ctx).Return(decimal.RequireFromString("1234.5"), int64(3), nil)
+
+		require.NoError(t, serv.Run(ctx))
+		assert.Equal(t, 1234.5, testutil.ToFloat64(metrics.BalanceTotal))
+		assert.Equal(t, float64(3), testutil.ToFloat64(metrics.Wallets))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Error keeps previous values", func(t *testing.T) {
+		repo := new(MockStatsRepo)
+		serv := NewStats(repo)
+
+		repo.On("WalletTotals", ctx).Return(decimal.Zero, int64(0), errors.New("db down"))
+
+		require.Error(t, serv.Run(ctx))
+		assert.Equal(t, 1234.5, testutil.ToFloat64(metrics.BalanceTotal))
+		repo.AssertExpectations(t)
+	})
+}
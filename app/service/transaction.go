@@ -1,10 +1,16 @@
 package service
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 
+	"server/app/model"
 	"server/app/repository"
 	"server/app/request"
+	"server/pkg/statement"
+	"server/pkg/tracing"
 )
 
 func NewTransaction(repo repository.TransactionInter) TransactionInter {
@@ -15,6 +21,9 @@ func NewTransaction(repo repository.TransactionInter) TransactionInter {
 
 type TransactionInter interface {
 	GetTransactionsByUID(ctx *gin.Context, req *request.ReqTransactions) (*request.ResTransactions, error)
+	Statement(ctx *gin.Context, req *request.ReqStatement, w statement.Writer) error
+	BalanceAt(ctx *gin.Context, uid int64, at time.Time) (decimal.Decimal, error)
+	BalanceHistory(ctx *gin.Context, req *request.ReqBalanceHistory) (*request.ResBalanceHistory, error)
 }
 
 type TransactionServ struct {
@@ -22,6 +31,72 @@ type TransactionServ struct {
 }
 
 func (t *TransactionServ) GetTransactionsByUID(ctx *gin.Context,
-	req *request.ReqTransactions) (*request.ResTransactions, error) {
+	req *request.ReqTransactions) (res *request.ResTransactions, err error) {
+	end := tracing.StartGin(ctx, "TransactionServ.GetTransactionsByUID")
+	defer func() { end(err) }()
+
 	return t.repo.GetTransactionsByUID(ctx, req)
 }
+
+// BalanceAt returns the balance uid had at the given point in time.
+func (t *TransactionServ) BalanceAt(ctx *gin.Context, uid int64, at time.Time) (res decimal.Decimal, err error) {
+	end := tracing.StartGin(ctx, "TransactionServ.BalanceAt")
+	defer func() { end(err) }()
+
+	return t.repo.BalanceAt(ctx, uid, at)
+}
+
+// BalanceHistory returns the closing balance of every day in the requested period.
+func (t *TransactionServ) BalanceHistory(ctx *gin.Context,
+	req *request.ReqBalanceHistory) (res *request.ResBalanceHistory, err error) {
+	end := tracing.StartGin(ctx, "TransactionServ.BalanceHistory")
+	defer func() { end(err) }()
+
+	list, err := t.repo.DailyBalances(ctx, req.UID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return &request.ResBalanceHistory{List: list}, nil
+}
+
+// Statement streams a wallet's transactions for the requested period to w, oldest first,
+// together with the opening balance, a running balance per row and the closing balance.
+func (t *TransactionServ) Statement(ctx *gin.Context, req *request.ReqStatement, w statement.Writer) (err error) {
+	end := tracing.StartGin(ctx, "TransactionServ.Statement")
+	defer func() { end(err) }()
+
+	balance, err := t.repo.BalanceAt(ctx, req.UID, req.From)
+	if err != nil {
+		return err
+	}
+
+	err = w.Begin(&statement.Header{UID: req.UID, From: req.From, To: req.To, OpeningBalance: balance})
+	if err != nil {
+		return err
+	}
+
+	filter := &request.ReqTransactions{UID: req.UID, From: req.From, To: req.End(), Sort: model.SortCreatedAtAsc}
+
+	count := 0
+	err = t.repo.EachTransactionByUID(ctx, filter, func(mod *model.TransactionWithUsername) error {
+		amount := mod.SignedAmount(req.UID)
+		balance = balance.Add(amount)
+		count++
+
+		return w.Row(&statement.Row{
+			ID:        mod.ID,
+			CreatedAt: mod.CreatedAt,
+			Type:      mod.TransactionTypeName,
+			Sender:    mod.SenderUsername,
+			Receiver:  mod.ReceiverUsername,
+			Amount:    amount,
+			Balance:   balance,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.End(&statement.Footer{ClosingBalance: balance, Count: count})
+}
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"time"
+
+	"server/app/model"
 	"server/app/request"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -16,3 +20,26 @@ func (m *MockTransactionInter) GetTransactionsByUID(ctx *gin.Context, req *reque
 	args := m.Called(ctx, req)
 	return args.Get(0).(*request.ResTransactions), args.Error(1)
 }
+
+func (m *MockTransactionInter) EachTransactionByUID(ctx *gin.Context, req *request.ReqTransactions,
+	fn func(mod *model.TransactionWithUsername) error) error {
+	args := m.Called(ctx, req, fn)
+	if rows, ok := args.Get(0).([]*model.TransactionWithUsername); ok {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockTransactionInter) BalanceAt(ctx *gin.Context, uid int64, at time.Time) (decimal.Decimal, error) {
+	args := m.Called(ctx, uid, at)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockTransactionInter) DailyBalances(ctx *gin.Context, uid int64, from, to time.Time) ([]*model.DailyBalance, error) {
+	args := m.Called(ctx, uid, from, to)
+	return args.Get(0).([]*model.DailyBalance), args.Error(1)
+}
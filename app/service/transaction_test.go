@@ -1,13 +1,17 @@
 package service
 
 import (
+	"errors"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"server/app/model"
 	"server/app/request"
+	"server/pkg/statement"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -74,3 +78,120 @@ func TestTransactionServ_GetTransactionsByUID(t *testing.T) {
 	// Assert that the mock was called as expected
 	mockRepo.AssertExpectations(t)
 }
+
+// recordingWriter is a statement.Writer that keeps everything it is given.
+type recordingWriter struct {
+	header *statement.Header
+	rows   []*statement.Row
+	footer *statement.Footer
+}
+
+func (r *recordingWriter) Begin(header *statement.Header) error {
+	r.header = header
+	return nil
+}
+
+func (r *recordingWriter) Row(row *statement.Row) error {
+	r.rows = append(r.rows, row)
+	return nil
+}
+
+func (r *recordingWriter) End(footer *statement.Footer) error {
+	r.footer = footer
+	return nil
+}
+
+func TestTransactionServ_Statement(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	req := &request.ReqStatement{
+		UID:       1,
+		ReqPeriod: request.ReqPeriod{From: from, To: from.AddDate(0, 0, 30)},
+		Format:    statement.FormatCSV,
+	}
+
+	rows := []*model.TransactionWithUsername{
+		{Transaction: model.Transaction{ID: 1, ReceiverWalletID: 1, Amount: decimal.NewFromInt(50)}},
+		{Transaction: model.Transaction{ID: 2, SenderWalletID: 1, ReceiverWalletID: 2, Amount: decimal.NewFromInt(20)}},
+	}
+
+	t.Run("Running balance", func(t *testing.T) {
+		mockRepo := new(MockTransactionInter)
+		serv := NewTransaction(mockRepo)
+
+		mockRepo.On("BalanceAt", ctx, req.UID, from).Return(decimal.NewFromInt(100), nil)
+		mockRepo.On("EachTransactionByUID", ctx, &request.ReqTransactions{
+			UID: 1, From: from, To: req.End(), Sort: model.SortCreatedAtAsc,
+		}, mock.Anything).Return(rows, nil)
+
+		w := &recordingWriter{}
+		require.NoError(t, serv.Statement(ctx, req, w))
+
+		assert.True(t, decimal.NewFromInt(100).Equal(w.header.OpeningBalance))
+		require.Len(t, w.rows, 2)
+		assert.True(t, decimal.NewFromInt(150).Equal(w.rows[0].Balance))
+		assert.True(t, decimal.NewFromInt(-20).Equal(w.rows[1].Amount))
+		assert.True(t, decimal.NewFromInt(130).Equal(w.rows[1].Balance))
+		assert.True(t, decimal.NewFromInt(130).Equal(w.footer.ClosingBalance))
+		assert.Equal(t, 2, w.footer.Count)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Opening balance error", func(t *testing.T) {
+		mockRepo := new(MockTransactionInter)
+		serv := NewTransaction(mockRepo)
+
+		mockRepo.On("BalanceAt", ctx, req.UID, from).Return(decimal.Zero, errors.New("db down"))
+
+		w := &recordingWriter{}
+		require.Error(t, serv.Statement(ctx, req, w))
+		assert.Nil(t, w.header)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTransactionServ_BalanceHistory(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	req := &request.ReqBalanceHistory{UID: 1, ReqPeriod: request.ReqPeriod{From: from, To: to}}
+
+	t.Run("Success", func(t *testing.T) {
+		repo := new(MockTransactionInter)
+		serv := NewTransaction(repo)
+
+		list := []*model.DailyBalance{
+			{Day: from, Balance: decimal.NewFromInt(10)},
+			{Day: to, Balance: decimal.NewFromInt(12)},
+		}
+		repo.On("DailyBalances", ctx, int64(1), from, to).Return(list, nil)
+
+		res, err := serv.BalanceHistory(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, list, res.List)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		repo := new(MockTransactionInter)
+		serv := NewTransaction(repo)
+
+		repo.On("DailyBalances", ctx, int64(1), from, to).
+			Return([]*model.DailyBalance(nil), errors.New("db error"))
+
+		res, err := serv.BalanceHistory(ctx, req)
+		require.Error(t, err)
+		assert.Nil(t, res)
+		repo.AssertExpectations(t)
+	})
+}
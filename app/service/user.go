@@ -5,17 +5,23 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 
 	"server/app/model"
 	"server/app/repository"
 	"server/app/request"
+	"server/pkg/tracing"
 )
 
-func NewUser(repo repository.UserInter, repoWallet repository.WalletInter) UserInter {
+// NewUser creates the user service. Registrations and updates are recorded in audit.
+func NewUser(repo repository.UserInter, repoWallet repository.WalletInter, audit repository.AuditInter,
+	logger *zap.SugaredLogger) UserInter {
 	return &UserServ{
 		repo:       repo,
 		repoWallet: repoWallet,
+		audit:      audit,
+		logger:     logger,
 	}
 }
 
@@ -30,9 +36,14 @@ type UserInter interface {
 type UserServ struct {
 	repo       repository.UserInter
 	repoWallet repository.WalletInter
+	audit      repository.AuditInter
+	logger     *zap.SugaredLogger
 }
 
-func (s *UserServ) RegisterUser(ctx *gin.Context, req *request.ReqRegisterUser) (*model.User, error) {
+func (s *UserServ) RegisterUser(ctx *gin.Context, req *request.ReqRegisterUser) (res *model.User, err error) {
+	end := tracing.StartGin(ctx, "UserServ.RegisterUser")
+	defer func() { end(err) }()
+
 	mod := &model.User{}
 
 	if req.Password == "" {
@@ -70,21 +81,65 @@ func (s *UserServ) RegisterUser(ctx *gin.Context, req *request.ReqRegisterUser)
 		return nil, err
 	}
 
+	s.record(ctx, model.AuditUserRegister, mod.ID, nil, newAuditUser(mod))
+
 	return mod, nil
 }
 
-func (s *UserServ) UpdateUser(ctx *gin.Context, mod *model.User) error {
-	return s.repo.UpdateUser(ctx, mod)
+func (s *UserServ) UpdateUser(ctx *gin.Context, mod *model.User) (err error) {
+	end := tracing.StartGin(ctx, "UserServ.UpdateUser")
+	defer func() { end(err) }()
+
+	before, err := s.repo.GetUserByID(ctx, mod.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.UpdateUser(ctx, mod); err != nil {
+		return err
+	}
+
+	beforeAudit, afterAudit := auditUserChange(before, mod)
+	afterAudit.Status = before.Status
+	s.record(ctx, model.AuditUserUpdate, mod.ID, beforeAudit, afterAudit)
+
+	return nil
 }
 
-func (s *UserServ) GetUserByID(ctx *gin.Context, id int64) (*model.User, error) {
+// record writes a change of the user uid to the audit log, on behalf of the actor of the request.
+func (s *UserServ) record(ctx *gin.Context, action string, uid int64, before, after *auditUser) {
+	entry := &model.AuditEntry{
+		Actor:    repository.ActorOf(ctx),
+		Action:   action,
+		Entity:   model.AuditEntityUser,
+		EntityID: uid,
+		After:    after,
+	}
+	// A nil *auditUser would be recorded as null rather than left out.
+	if before != nil {
+		entry.Before = before
+	}
+
+	recordAudit(ctx, s.audit, s.logger, entry, nil)
+}
+
+func (s *UserServ) GetUserByID(ctx *gin.Context, id int64) (res *model.User, err error) {
+	end := tracing.StartGin(ctx, "UserServ.GetUserByID")
+	defer func() { end(err) }()
+
 	return s.repo.GetUserByID(ctx, id)
 }
 
-func (s *UserServ) GetUserByUsername(ctx *gin.Context, username string) (*model.User, error) {
+func (s *UserServ) GetUserByUsername(ctx *gin.Context, username string) (res *model.User, err error) {
+	end := tracing.StartGin(ctx, "UserServ.GetUserByUsername")
+	defer func() { end(err) }()
+
 	return s.repo.GetUserByUsername(ctx, username)
 }
 
-func (s *UserServ) GetUserByEmail(ctx *gin.Context, email string) (*model.User, error) {
+func (s *UserServ) GetUserByEmail(ctx *gin.Context, email string) (res *model.User, err error) {
+	end := tracing.StartGin(ctx, "UserServ.GetUserByEmail")
+	defer func() { end(err) }()
+
 	return s.repo.GetUserByEmail(ctx, email)
 }
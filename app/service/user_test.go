@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
+	"go.uber.org/zap"
 )
 
 func TestUserServ_NewUser(t *testing.T) {
@@ -23,7 +24,7 @@ func TestUserServ_NewUser(t *testing.T) {
 		repo := new(MockUserRepo)
 		repoWallet := new(MockWalletRepo)
 
-		inter := NewUser(repo, repoWallet)
+		inter := NewUser(repo, repoWallet, new(MockAuditRepo), zap.NewNop().Sugar())
 		assert.NotNil(t, inter)
 
 		serv, ok := inter.(*UserServ)
@@ -32,7 +33,7 @@ func TestUserServ_NewUser(t *testing.T) {
 	})
 
 	t.Run("TestNewUser_NilRepo", func(t *testing.T) {
-		inter := NewUser(nil, nil)
+		inter := NewUser(nil, nil, nil, nil)
 		expectedInter := &UserServ{repo: nil, repoWallet: nil}
 		assert.Equal(t, expectedInter, inter)
 	})
@@ -74,10 +75,17 @@ func TestUserServ_RegisterUser(t *testing.T) {
 			mockRepo := new(MockUserRepo)
 			mockWalletRepo := new(MockWalletRepo)
 
-			userServ := NewUser(mockRepo, mockWalletRepo)
+			audit := new(MockAuditRepo)
+
+			userServ := NewUser(mockRepo, mockWalletRepo, audit, zap.NewNop().Sugar())
 
 			mockRepo.On("CreateUser", ctx, mock.Anything).Return(tt.expectedUser, tt.expectedError)
 			mockWalletRepo.On("CreateWallet", ctx, mock.Anything).Return(&model.Wallet{UID: tt.expectedUser.ID, Balance: decimal.NewFromFloat(0)}, nil)
+			audit.On("Record", ctx, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+				after, ok := entry.After.(*auditUser)
+				return entry.Action == model.AuditUserRegister && entry.EntityID == tt.expectedUser.ID &&
+					entry.Before == nil && ok && *after == auditUser{Status: tt.expectedUser.Status}
+			})).Return(nil)
 
 			user, err := userServ.RegisterUser(ctx, tt.req)
 			assert.Equal(t, tt.expectedError, err)
@@ -85,6 +93,7 @@ func TestUserServ_RegisterUser(t *testing.T) {
 
 			mockRepo.AssertExpectations(t)
 			mockWalletRepo.AssertExpectations(t)
+			audit.AssertExpectations(t)
 		})
 	}
 }
@@ -97,8 +106,9 @@ func TestUserServ_UpdateUser(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockUserRepo)
+	audit := new(MockAuditRepo)
 
-	userServ := NewUser(mockRepo, nil)
+	userServ := NewUser(mockRepo, nil, audit, zap.NewNop().Sugar())
 
 	user := &model.User{
 		ID:       1,
@@ -106,12 +116,22 @@ func TestUserServ_UpdateUser(t *testing.T) {
 		Email:    "testuser@example.com",
 	}
 
+	mockRepo.On("GetUserByID", ctx, int64(1)).
+		Return(&model.User{ID: 1, Username: "olduser", Email: "old@example.com", Status: model.UserStatusValid}, nil)
 	mockRepo.On("UpdateUser", ctx, user).Return(nil)
+	audit.On("Record", ctx, &model.AuditEntry{
+		Action:   model.AuditUserUpdate,
+		Entity:   model.AuditEntityUser,
+		EntityID: 1,
+		Before:   &auditUser{Status: model.UserStatusValid},
+		After:    &auditUser{Status: model.UserStatusValid, UsernameChanged: true, EmailChanged: true},
+	}).Return(nil)
 
 	err := userServ.UpdateUser(ctx, user)
 	require.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
+	audit.AssertExpectations(t)
 }
 
 func TestUserServ_GetUserByID(t *testing.T) {
@@ -123,7 +143,7 @@ func TestUserServ_GetUserByID(t *testing.T) {
 
 	mockRepo := new(MockUserRepo)
 
-	userServ := NewUser(mockRepo, nil)
+	userServ := NewUser(mockRepo, nil, nil, nil)
 
 	expectedUser := &model.User{
 		ID:       1,
@@ -148,7 +168,7 @@ func TestUserServ_GetUserByUsername(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockUserRepo)
-	userServ := NewUser(mockRepo, nil)
+	userServ := NewUser(mockRepo, nil, nil, nil)
 
 	expectedUser := &model.User{
 		ID:       1,
@@ -173,7 +193,7 @@ func TestUserServ_GetUserByEmail(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockUserRepo)
-	userServ := NewUser(mockRepo, nil)
+	userServ := NewUser(mockRepo, nil, nil, nil)
 
 	expectedUser := &model.User{
 		ID:       1,
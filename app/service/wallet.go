@@ -1,115 +1,459 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"unicode/utf8"
 
 	"server/app/model"
 	"server/app/repository"
+	"server/config"
+	"server/pkg/lock"
+	"server/pkg/logger"
+	"server/pkg/metrics"
+	"server/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
 )
 
-// NewWallet creates a new Wallet service instance.
-func NewWallet(repo repository.WalletInter) WalletInter {
+var (
+	ErrNonPositiveAmount    = errors.New("amount must be positive")
+	ErrInsufficientBalance  = errors.New("insufficient balance")
+	ErrBalanceLimitExceeded = errors.New("would exceed the maximum allowed balance")
+	ErrAmountLimitExceeded  = errors.New("amount exceeds the maximum allowed per operation")
+	ErrFeatureDisabled      = errors.New("is disabled")
+	ErrInvalidDetails       = errors.New("invalid transaction details")
+	ErrSelfTransfer         = errors.New("can't transfer to oneself")
+)
+
+const (
+	// maxMetadataSize is the largest metadata of a transaction, in bytes of JSON.
+	maxMetadataSize = 1024
+	// maxMetadataKeys is the most keys the metadata of a transaction can have.
+	maxMetadataKeys = 16
+)
+
+// NewWallet creates a new Wallet service instance. Every money movement holds the locks of the
+// wallets it touches, taken from locker, from its balance checks to its write, and is recorded
+// in audit once done.
+func NewWallet(repo repository.WalletInter, locker lock.Locker, audit repository.AuditInter,
+	logger *zap.SugaredLogger) WalletInter {
 	return &WalletServ{
-		repo: repo,
+		repo:   repo,
+		locker: locker,
+		audit:  audit,
+		logger: logger,
 	}
 }
 
 // WalletInter defines the interface for wallet operations.
 type WalletInter interface {
-	Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal) error
-	Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal) error
-	Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal) error
+	Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal, details model.TransactionDetails) error
+	Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal, quoteID string, details model.TransactionDetails) error
+	Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal, quoteID string,
+		details model.TransactionDetails) error
+	TransferAndClaim(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal, details model.TransactionDetails,
+		claim model.PaymentClaim) error
 	Balance(ctx *gin.Context, uid int64) (decimal.Decimal, error)
+	Quote(ctx *gin.Context, uid int64, operation string, amount decimal.Decimal) (*model.FeeQuote, error)
+	DryRun(ctx *gin.Context, operation string, uid, toUID int64, amount decimal.Decimal, quote bool) (
+		*model.DryRun, error)
 }
 
 // WalletServ implements the WalletInter interface.
 type WalletServ struct {
-	repo repository.WalletInter
+	repo   repository.WalletInter
+	locker lock.Locker
+	audit  repository.AuditInter
+	logger *zap.SugaredLogger
 }
 
-// Deposit adds the specified amount to the user's balance.
-func (w *WalletServ) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
-	// Check if the deposit amount is positive
-	if amount.LessThan(decimal.Zero) {
-		return fmt.Errorf("deposit amount must be positive")
+// Deposit adds the specified amount to the user's balance and records it with its details.
+func (w *WalletServ) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal, details model.TransactionDetails) (
+	err error) {
+	end := tracing.StartGin(ctx, "WalletServ.Deposit")
+	defer func() {
+		metrics.ObserveMoney(model.Deposit, amount, failureReason(err))
+		end(err)
+	}()
+
+	if err = precheck(model.Deposit, amount); err != nil {
+		return err
 	}
 
-	// Get the current balance of the user
-	balance, err := w.repo.Balance(ctx, uid)
+	if err = checkDetails(&details); err != nil {
+		return err
+	}
+
+	unlock, err := lockWallets(ctx, w.locker, w.logger, uid)
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	repository.Fresh(ctx)
 
-	// Check if the deposit would exceed the maximum allowed balance
-	maxBalance := decimal.NewFromInt(model.MaxBalance)
-	if balance.Add(amount).GreaterThan(maxBalance) {
-		return fmt.Errorf("deposit would exceed the maximum allowed balance of %s", maxBalance.String())
+	m, err := w.check(ctx, model.Deposit, 0, uid, amount, "")
+	if err != nil {
+		return err
 	}
 
 	// Perform the deposit operation
-	return w.repo.Deposit(ctx, uid, amount)
+	if err = w.repo.Deposit(ctx, uid, amount, details); err != nil {
+		return err
+	}
+
+	w.record(ctx, model.AuditWalletDeposit, uid, 0, m.toBalance, m.toAfter())
+
+	return nil
 }
 
-// Withdraw subtracts the specified amount from the user's balance.
-func (w *WalletServ) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
-	// Check if the withdraw amount is positive
-	if amount.LessThan(decimal.Zero) {
-		return fmt.Errorf("withdraw amount must be positive")
+// Withdraw subtracts the specified amount from the user's balance and records it with its
+// details. With a quoteID, the fee locked in by that quote is charged instead of the configured
+// one.
+func (w *WalletServ) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal, quoteID string,
+	details model.TransactionDetails) (err error) {
+	end := tracing.StartGin(ctx, "WalletServ.Withdraw")
+	defer func() {
+		metrics.ObserveMoney(model.Withdraw, amount, failureReason(err))
+		end(err)
+	}()
+
+	if err = precheck(model.Withdraw, amount); err != nil {
+		return err
+	}
+
+	if err = checkDetails(&details); err != nil {
+		return err
 	}
 
-	// Get the current balance of the user
-	balance, err := w.repo.Balance(ctx, uid)
+	unlock, err := lockWallets(ctx, w.locker, w.logger, uid)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	// Check if the user has sufficient balance for the withdrawal
-	if balance.LessThan(amount) {
-		return fmt.Errorf("insufficient balance for withdrawal")
+	repository.Fresh(ctx)
+
+	m, err := w.check(ctx, model.Withdraw, uid, 0, amount, quoteID)
+	if err != nil {
+		return err
 	}
 
 	// Perform the withdrawal operation
-	return w.repo.Withdraw(ctx, uid, amount)
+	if err = w.repo.Withdraw(ctx, uid, amount, m.fee, details); err != nil {
+		return err
+	}
+
+	observeFee(m.fee)
+	w.record(ctx, model.AuditWalletWithdraw, uid, 0, m.fromBalance, m.fromAfter())
+
+	return nil
 }
 
-// Transfer moves the specified amount from the sender's balance to the receiver's balance.
-func (w *WalletServ) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal) error {
-	// Check if the transfer amount is positive
-	if amount.LessThan(decimal.Zero) {
-		return fmt.Errorf("transfer amount must be positive")
+// Transfer moves the specified amount from the sender's balance to the receiver's balance and
+// records it with its details. With a quoteID, the fee locked in by that quote is charged instead
+// of the configured one.
+func (w *WalletServ) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal, quoteID string,
+	details model.TransactionDetails) (err error) {
+	end := tracing.StartGin(ctx, "WalletServ.Transfer")
+	defer func() {
+		metrics.ObserveMoney(model.Transfer, amount, failureReason(err))
+		end(err)
+	}()
+
+	return w.transfer(ctx, fromUID, toUID, amount, quoteID, details, nil)
+}
+
+// TransferAndClaim makes the transfer that pays a payment request, like Transfer with the
+// configured fee, and marks the request as paid in the same DB transaction.
+func (w *WalletServ) TransferAndClaim(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	details model.TransactionDetails, claim model.PaymentClaim) (err error) {
+	end := tracing.StartGin(ctx, "WalletServ.TransferAndClaim")
+	defer func() {
+		metrics.ObserveMoney(model.Transfer, amount, failureReason(err))
+		end(err)
+	}()
+
+	return w.transfer(ctx, fromUID, toUID, amount, "", details, &claim)
+}
+
+// transfer checks and makes a transfer, marking the payment request of claim as paid with it
+// when claim is not nil.
+func (w *WalletServ) transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal, quoteID string,
+	details model.TransactionDetails, claim *model.PaymentClaim) (err error) {
+	if err = precheck(model.Transfer, amount); err != nil {
+		return err
 	}
 
-	// Get the current balance of the sender
-	fromBalance, err := w.repo.Balance(ctx, fromUID)
+	if fromUID == toUID {
+		return fmt.Errorf("%w: uid %d", ErrSelfTransfer, fromUID)
+	}
+
+	if err = checkDetails(&details); err != nil {
+		return err
+	}
+
+	unlock, err := lockWallets(ctx, w.locker, w.logger, fromUID, toUID)
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	// The balance checks below must see the latest balances, never cached ones.
+	repository.Fresh(ctx)
 
-	// Check if the sender has sufficient balance for the transfer
-	if fromBalance.LessThan(amount) {
-		return fmt.Errorf("insufficient balance for transfer")
+	m, err := w.check(ctx, model.Transfer, fromUID, toUID, amount, quoteID)
+	if err != nil {
+		return err
 	}
 
-	// Get the current balance of the receiver
-	toBalance, err := w.repo.Balance(ctx, toUID)
+	// Perform the transfer operation
+	if claim != nil {
+		err = w.repo.TransferAndClaim(ctx, fromUID, toUID, amount, m.fee, details, *claim)
+	} else {
+		err = w.repo.Transfer(ctx, fromUID, toUID, amount, m.fee, details)
+	}
 	if err != nil {
 		return err
 	}
 
-	// Check if the transfer would exceed the maximum allowed balance for the receiver
-	maxBalance := decimal.NewFromInt(model.MaxBalance)
-	if toBalance.Add(amount).GreaterThan(maxBalance) {
-		return fmt.Errorf("transfer would exceed the maximum allowed balance of %s for the receiver", maxBalance.String())
+	observeFee(m.fee)
+
+	w.record(ctx, model.AuditWalletTransfer, fromUID, toUID, m.fromBalance, m.fromAfter())
+	w.record(ctx, model.AuditWalletTransfer, toUID, fromUID, m.toBalance, m.toAfter())
+
+	return nil
+}
+
+// movement is a money movement checked against the current balances, ready to be made. fromUID
+// is 0 for a deposit and toUID is 0 for a withdrawal.
+type movement struct {
+	fromUID, toUID         int64
+	amount                 decimal.Decimal
+	fee                    model.FeeCharge
+	fromBalance, toBalance decimal.Decimal
+}
+
+// total is what the movement takes from the sender: its amount plus its fee.
+func (m *movement) total() decimal.Decimal {
+	return m.amount.Add(m.fee.Amount)
+}
+
+// fromAfter is the balance of the sender once the movement is made.
+func (m *movement) fromAfter() decimal.Decimal {
+	return m.fromBalance.Sub(m.total())
+}
+
+// toAfter is the balance of the receiver once the movement is made.
+func (m *movement) toAfter() decimal.Decimal {
+	return m.toBalance.Add(m.amount)
+}
+
+// precheck runs the checks of a money movement that don't need its wallets.
+func precheck(operation string, amount decimal.Decimal) error {
+	// Check if the amount is positive
+	if amount.LessThan(decimal.Zero) {
+		return fmt.Errorf("%s %w", operation, ErrNonPositiveAmount)
+	}
+
+	return checkRuntime(operation, amount)
+}
+
+// checkDetails checks the details of a money movement against their bounds: a memo of at most
+// maxMemoLength characters, an external reference of at most maxReferenceLength and metadata that
+// is a JSON object of at most maxMetadataKeys keys and maxMetadataSize bytes. A null metadata is
+// dropped.
+func checkDetails(details *model.TransactionDetails) error {
+	if utf8.RuneCountInString(details.Memo) > maxMemoLength {
+		return fmt.Errorf("%w: memo longer than %d", ErrInvalidDetails, maxMemoLength)
 	}
 
-	// Perform the transfer operation
-	return w.repo.Transfer(ctx, fromUID, toUID, amount)
+	if utf8.RuneCountInString(details.ExternalReference) > maxReferenceLength {
+		return fmt.Errorf("%w: external_reference longer than %d", ErrInvalidDetails, maxReferenceLength)
+	}
+
+	if len(details.Metadata) == 0 || bytes.Equal(details.Metadata, []byte("null")) {
+		details.Metadata = nil
+		return nil
+	}
+
+	if len(details.Metadata) > maxMetadataSize {
+		return fmt.Errorf("%w: metadata larger than %d bytes", ErrInvalidDetails, maxMetadataSize)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(details.Metadata, &fields); err != nil {
+		return fmt.Errorf("%w: metadata must be a JSON object", ErrInvalidDetails)
+	}
+
+	if len(fields) > maxMetadataKeys {
+		return fmt.Errorf("%w: metadata has more than %d keys", ErrInvalidDetails, maxMetadataKeys)
+	}
+
+	return nil
+}
+
+// check reads the balances a money movement depends on, works out its fee, or takes it from
+// the quote of quoteID, and checks that the sender can pay for it and that the receiver can
+// take it.
+func (w *WalletServ) check(ctx *gin.Context, operation string, fromUID, toUID int64, amount decimal.Decimal,
+	quoteID string) (m *movement, err error) {
+	m = &movement{fromUID: fromUID, toUID: toUID, amount: amount}
+
+	if fromUID != 0 {
+		// Get the current balance of the sender
+		if m.fromBalance, err = w.repo.Balance(ctx, fromUID); err != nil {
+			return nil, err
+		}
+
+		if m.fee, err = w.charge(ctx, operation, fromUID, toUID, amount, quoteID); err != nil {
+			return nil, err
+		}
+
+		// Check if the sender has sufficient balance for the movement and its fee
+		if m.fromBalance.LessThan(m.total()) {
+			return nil, fmt.Errorf("%w for %s", ErrInsufficientBalance, operationNoun(operation))
+		}
+	}
+
+	if toUID != 0 {
+		// Get the current balance of the receiver
+		if m.toBalance, err = w.repo.Balance(ctx, toUID); err != nil {
+			return nil, err
+		}
+
+		// Check if the movement would exceed the maximum allowed balance for the receiver
+		maxBalance := decimal.NewFromInt(model.BalanceLimit())
+		if m.toBalance.Add(amount).GreaterThan(maxBalance) {
+			if fromUID != 0 {
+				return nil, fmt.Errorf("%s %w of %s for the receiver", operation, ErrBalanceLimitExceeded,
+					maxBalance.String())
+			}
+			return nil, fmt.Errorf("%s %w of %s", operation, ErrBalanceLimitExceeded, maxBalance.String())
+		}
+	}
+
+	return m, nil
+}
+
+// operationNoun names an operation in an error message.
+func operationNoun(operation string) string {
+	if operation == model.Withdraw {
+		return "withdrawal"
+	}
+
+	return operation
+}
+
+// record writes the balance change of the wallet of uid to the audit log, on behalf of the actor
+// of the request.
+func (w *WalletServ) record(ctx *gin.Context, action string, uid, counterparty int64, before, after decimal.Decimal) {
+	recordAudit(ctx, w.audit, w.logger, &model.AuditEntry{
+		Actor:    repository.ActorOf(ctx),
+		Action:   action,
+		Entity:   model.AuditEntityWallet,
+		EntityID: uid,
+		Before:   &auditBalance{Balance: before, Counterparty: counterparty},
+		After:    &auditBalance{Balance: after, Counterparty: counterparty},
+	}, nil)
 }
 
 // Balance returns the current balance of the user.
-func (w *WalletServ) Balance(ctx *gin.Context, uid int64) (decimal.Decimal, error) {
+func (w *WalletServ) Balance(ctx *gin.Context, uid int64) (res decimal.Decimal, err error) {
+	end := tracing.StartGin(ctx, "WalletServ.Balance")
+	defer func() { end(err) }()
+
 	return w.repo.Balance(ctx, uid)
 }
+
+// lockWallets takes the locks of the wallets of uids and hands their fencing token to the
+// repository for the writes of this request. The returned func releases the locks.
+func lockWallets(ctx *gin.Context, locker lock.Locker, log *zap.SugaredLogger, uids ...int64) (func(), error) {
+	keys := make([]string, len(uids))
+	for i, uid := range uids {
+		keys[i] = "wallet:" + strconv.FormatInt(uid, 10)
+	}
+
+	lease, err := locker.Lock(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	repository.WithFenceToken(ctx, lease.Token)
+
+	return func() {
+		// Release even when the client is gone; a lock left behind would stall the wallet.
+		if err := lease.Unlock(context.WithoutCancel(ctx)); err != nil {
+			tracing.WithTrace(ctx, logger.FromContext(ctx, log)).
+				Warnw("release wallet locks failed", "keys", keys, "error", err)
+		}
+	}, nil
+}
+
+// checkRuntime applies the hot-reloadable settings to a money movement: the operation must be
+// switched on and the amount must be within runtime.limits.max_amount.
+func checkRuntime(operation string, amount decimal.Decimal) error {
+	runtime := config.Runtime()
+	if !runtime.Feature(operation) {
+		return fmt.Errorf("%s %w", operation, ErrFeatureDisabled)
+	}
+
+	if limit := runtime.Limits.MaxAmount; limit > 0 && amount.GreaterThan(decimal.NewFromInt(limit)) {
+		return fmt.Errorf("%s %w of %d", operation, ErrAmountLimitExceeded, limit)
+	}
+
+	return nil
+}
+
+// failureReason classifies a money movement error into a low-cardinality metrics label.
+// It returns an empty string when err is nil.
+func failureReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNonPositiveAmount), errors.Is(err, ErrZeroAmount):
+		return "invalid_amount"
+	case errors.Is(err, ErrNotJustified):
+		return "not_justified"
+	case errors.Is(err, ErrInvalidDetails):
+		return "invalid_details"
+	case errors.Is(err, ErrSelfTransfer):
+		return "self_transfer"
+	case errors.Is(err, ErrInsufficientBalance):
+		return "insufficient_balance"
+	case errors.Is(err, ErrBalanceNotSettled):
+		return "not_settled"
+	case errors.Is(err, repository.ErrDuplicateReference):
+		return "duplicate"
+	case errors.Is(err, repository.ErrQuoteExpired), errors.Is(err, ErrQuoteMismatch):
+		return "invalid_quote"
+	case errors.Is(err, ErrBalanceLimitExceeded):
+		return "balance_limit"
+	case errors.Is(err, ErrAmountLimitExceeded):
+		return "amount_limit"
+	case errors.Is(err, ErrFeatureDisabled):
+		return "disabled"
+	case errors.Is(err, lock.ErrTimeout):
+		return "lock_timeout"
+	case errors.Is(err, repository.ErrWalletFrozen):
+		return "frozen"
+	case errors.Is(err, repository.ErrWalletDebitBlocked):
+		return "debit_blocked"
+	case errors.Is(err, repository.ErrWalletClosed):
+		return "closed"
+	case errors.Is(err, repository.ErrWriteRejected):
+		return "rejected"
+	case errors.Is(err, sql.ErrNoRows):
+		return "wallet_not_found"
+	default:
+		return "internal"
+	}
+}
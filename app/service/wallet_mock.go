@@ -1,11 +1,15 @@
 package service
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 
 	"server/app/model"
+	"server/pkg/lock"
 )
 
 // MockWalletRepo is a mock implementation of the repository.WalletInter interface
@@ -23,22 +27,76 @@ func (m *MockWalletRepo) GetWalletByUID(ctx *gin.Context, uid int64) (*model.Wal
 	return args.Get(0).(*model.Wallet), args.Error(1)
 }
 
-func (m *MockWalletRepo) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
-	args := m.Called(ctx, uid, amount)
+func (m *MockWalletRepo) Deposit(ctx *gin.Context, uid int64, amount decimal.Decimal,
+	details model.TransactionDetails) error {
+	args := m.Called(ctx, uid, amount, details)
 	return args.Error(0)
 }
 
-func (m *MockWalletRepo) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
-	args := m.Called(ctx, uid, amount)
+func (m *MockWalletRepo) Withdraw(ctx *gin.Context, uid int64, amount decimal.Decimal, fee model.FeeCharge,
+	details model.TransactionDetails) error {
+	args := m.Called(ctx, uid, amount, fee, details)
+	return args.Error(0)
+}
+
+func (m *MockWalletRepo) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	fee model.FeeCharge, details model.TransactionDetails) error {
+	args := m.Called(ctx, fromUID, toUID, amount, fee, details)
+	return args.Error(0)
+}
+
+func (m *MockWalletRepo) TransferAndClaim(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal,
+	fee model.FeeCharge, details model.TransactionDetails, claim model.PaymentClaim) error {
+	args := m.Called(ctx, fromUID, toUID, amount, fee, details, claim)
+	return args.Error(0)
+}
+
+func (m *MockWalletRepo) GetWalletByHandle(ctx *gin.Context, handle string) (*model.Wallet, error) {
+	args := m.Called(ctx, handle)
+	return args.Get(0).(*model.Wallet), args.Error(1)
+}
+
+func (m *MockWalletRepo) SetHandle(ctx *gin.Context, uid int64, handle string) error {
+	args := m.Called(ctx, uid, handle)
 	return args.Error(0)
 }
 
-func (m *MockWalletRepo) Transfer(ctx *gin.Context, fromUID, toUID int64, amount decimal.Decimal) error {
-	args := m.Called(ctx, fromUID, toUID, amount)
+func (m *MockWalletRepo) Tier(ctx *gin.Context, uid int64) (string, error) {
+	args := m.Called(ctx, uid)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockWalletRepo) CreateQuote(ctx *gin.Context, mod *model.Quote, ttl time.Duration) error {
+	args := m.Called(ctx, mod, ttl)
 	return args.Error(0)
 }
 
+func (m *MockWalletRepo) GetQuote(ctx *gin.Context, id string) (*model.Quote, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.Quote), args.Error(1)
+}
+
 func (m *MockWalletRepo) Balance(ctx *gin.Context, uid int64) (decimal.Decimal, error) {
 	args := m.Called(ctx, uid)
 	return args.Get(0).(decimal.Decimal), args.Error(1)
 }
+
+func (m *MockWalletRepo) Adjust(ctx *gin.Context, uid int64, amount decimal.Decimal) error {
+	args := m.Called(ctx, uid, amount)
+	return args.Error(0)
+}
+
+func (m *MockWalletRepo) SetStatus(ctx *gin.Context, uid int64, status model.WalletStatus) error {
+	args := m.Called(ctx, uid, status)
+	return args.Error(0)
+}
+
+// MockLocker is a mock implementation of the lock.Locker interface
+type MockLocker struct {
+	mock.Mock
+}
+
+func (m *MockLocker) Lock(ctx context.Context, keys ...string) (*lock.Lease, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).(*lock.Lease), args.Error(1)
+}
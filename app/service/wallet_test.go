@@ -1,17 +1,26 @@
 package service
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"server/app/model"
+	"server/app/repository"
+	"server/config"
+	"server/pkg/lock"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
+	"go.uber.org/zap"
 )
 
 func TestWalletServ_NewWallet(t *testing.T) {
@@ -21,7 +30,7 @@ func TestWalletServ_NewWallet(t *testing.T) {
 		// Create a mock instance
 		repo := new(MockWalletRepo)
 
-		inter := NewWallet(repo)
+		inter := NewWallet(repo, lock.Nop(), repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
 		assert.NotNil(t, inter)
 
 		serv, ok := inter.(*WalletServ)
@@ -30,7 +39,7 @@ func TestWalletServ_NewWallet(t *testing.T) {
 	})
 
 	t.Run("TestNewWallet_NilRepo", func(t *testing.T) {
-		inter := NewWallet(nil)
+		inter := NewWallet(nil, nil, nil, nil)
 		expectedInter := &WalletServ{repo: nil}
 		assert.Equal(t, expectedInter, inter)
 	})
@@ -44,7 +53,8 @@ func TestWalletServ_Deposit(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockWalletRepo)
-	walletServ := NewWallet(mockRepo)
+	audit := new(MockAuditRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), audit, zap.NewNop().Sugar())
 
 	uid := int64(1)
 	amount := decimal.NewFromInt(100)
@@ -52,12 +62,17 @@ func TestWalletServ_Deposit(t *testing.T) {
 	// Mock the Balance method
 	mockRepo.On("Balance", ctx, uid).Return(decimal.Zero, nil)
 
-	mockRepo.On("Deposit", ctx, uid, amount).Return(nil)
+	mockRepo.On("Deposit", ctx, uid, amount, model.TransactionDetails{}).Return(nil)
+	audit.On("Record", ctx, &model.AuditEntry{
+		Action: model.AuditWalletDeposit, Entity: model.AuditEntityWallet, EntityID: uid,
+		Before: &auditBalance{Balance: decimal.Zero}, After: &auditBalance{Balance: amount},
+	}).Return(nil)
 
-	err := walletServ.Deposit(ctx, uid, amount)
+	err := walletServ.Deposit(ctx, uid, amount, model.TransactionDetails{})
 	require.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
+	audit.AssertExpectations(t)
 }
 
 func TestWalletServ_Withdraw(t *testing.T) {
@@ -68,7 +83,8 @@ func TestWalletServ_Withdraw(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockWalletRepo)
-	walletServ := NewWallet(mockRepo)
+	audit := new(MockAuditRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), audit, zap.NewNop().Sugar())
 
 	uid := int64(1)
 	amount := decimal.NewFromInt(100)
@@ -76,12 +92,17 @@ func TestWalletServ_Withdraw(t *testing.T) {
 	// Mock the Balance method
 	mockRepo.On("Balance", ctx, uid).Return(decimal.NewFromInt(500), nil)
 
-	mockRepo.On("Withdraw", ctx, uid, amount).Return(nil)
+	mockRepo.On("Withdraw", ctx, uid, amount, model.FeeCharge{}, model.TransactionDetails{}).Return(nil)
+	audit.On("Record", ctx, &model.AuditEntry{
+		Action: model.AuditWalletWithdraw, Entity: model.AuditEntityWallet, EntityID: uid,
+		Before: &auditBalance{Balance: decimal.NewFromInt(500)}, After: &auditBalance{Balance: decimal.NewFromInt(400)},
+	}).Return(nil)
 
-	err := walletServ.Withdraw(ctx, uid, amount)
+	err := walletServ.Withdraw(ctx, uid, amount, "", model.TransactionDetails{})
 	require.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
+	audit.AssertExpectations(t)
 }
 
 func TestWalletServ_Transfer(t *testing.T) {
@@ -92,7 +113,8 @@ func TestWalletServ_Transfer(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockWalletRepo)
-	walletServ := NewWallet(mockRepo)
+	audit := new(MockAuditRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), audit, zap.NewNop().Sugar())
 
 	fromUID := int64(1)
 	toUID := int64(2)
@@ -103,12 +125,25 @@ func TestWalletServ_Transfer(t *testing.T) {
 	mockRepo.On("Balance", ctx, toUID).Return(decimal.NewFromInt(200), nil)
 
 	// Mock the Transfer method
-	mockRepo.On("Transfer", ctx, fromUID, toUID, amount).Return(nil)
-
-	err := walletServ.Transfer(ctx, fromUID, toUID, amount)
+	mockRepo.On("Transfer", ctx, fromUID, toUID, amount, model.FeeCharge{}, model.TransactionDetails{}).Return(nil)
+
+	// Both wallets are recorded, each with the other as counterparty.
+	audit.On("Record", ctx, &model.AuditEntry{
+		Action: model.AuditWalletTransfer, Entity: model.AuditEntityWallet, EntityID: fromUID,
+		Before: &auditBalance{Balance: decimal.NewFromInt(500), Counterparty: toUID},
+		After:  &auditBalance{Balance: decimal.NewFromInt(400), Counterparty: toUID},
+	}).Return(nil)
+	audit.On("Record", ctx, &model.AuditEntry{
+		Action: model.AuditWalletTransfer, Entity: model.AuditEntityWallet, EntityID: toUID,
+		Before: &auditBalance{Balance: decimal.NewFromInt(200), Counterparty: fromUID},
+		After:  &auditBalance{Balance: decimal.NewFromInt(300), Counterparty: fromUID},
+	}).Return(nil)
+
+	err := walletServ.Transfer(ctx, fromUID, toUID, amount, "", model.TransactionDetails{})
 	require.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
+	audit.AssertExpectations(t)
 }
 
 func TestWalletServ_Balance(t *testing.T) {
@@ -119,7 +154,7 @@ func TestWalletServ_Balance(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockWalletRepo)
-	walletServ := NewWallet(mockRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
 
 	uid := int64(1)
 
@@ -140,7 +175,7 @@ func TestWalletServ_Deposit_Overflow(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockWalletRepo)
-	walletServ := NewWallet(mockRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
 
 	uid := int64(1)
 	// Use a large amount that, when added to the near-max balance, will exceed the limit
@@ -151,9 +186,10 @@ func TestWalletServ_Deposit_Overflow(t *testing.T) {
 	mockRepo.On("Balance", ctx, uid).Return(decimal.NewFromInt(model.MaxBalance-1), nil)
 
 	// The Deposit method should not be called because the check in Deposit function should prevent it
-	err := walletServ.Deposit(ctx, uid, amount)
+	err := walletServ.Deposit(ctx, uid, amount, model.TransactionDetails{})
 	require.Error(t, err)
-	assert.Equal(t, expectedErr, err)
+	assert.EqualError(t, err, expectedErr.Error())
+	assert.ErrorIs(t, err, ErrBalanceLimitExceeded)
 
 	mockRepo.AssertExpectations(t)
 }
@@ -166,7 +202,7 @@ func TestWalletServ_Withdraw_Overflow(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockWalletRepo)
-	walletServ := NewWallet(mockRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
 
 	uid := int64(1)
 	amount := decimal.NewFromInt(1000000000000000000) // Large amount to cause overflow
@@ -175,9 +211,10 @@ func TestWalletServ_Withdraw_Overflow(t *testing.T) {
 	// Mock the Balance method to return a value close to the maximum balance
 	mockRepo.On("Balance", ctx, uid).Return(decimal.NewFromInt(model.MaxBalance-1), nil)
 
-	err := walletServ.Withdraw(ctx, uid, amount)
+	err := walletServ.Withdraw(ctx, uid, amount, "", model.TransactionDetails{})
 	require.Error(t, err)
-	assert.Equal(t, expectedErr, err)
+	assert.EqualError(t, err, expectedErr.Error())
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
 
 	mockRepo.AssertExpectations(t)
 }
@@ -190,7 +227,8 @@ func TestWalletServ_Transfer_OverflowReceiver(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockWalletRepo)
-	walletServ := NewWallet(mockRepo)
+	audit := new(MockAuditRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), audit, zap.NewNop().Sugar())
 
 	fromUID := int64(1)
 	toUID := int64(2)
@@ -200,13 +238,33 @@ func TestWalletServ_Transfer_OverflowReceiver(t *testing.T) {
 	// Mock the Balance method for sender and receiver
 	mockRepo.On("Balance", ctx, fromUID).Return(decimal.NewFromInt(500), nil)
 
-	err := walletServ.Transfer(ctx, fromUID, toUID, amount)
+	err := walletServ.Transfer(ctx, fromUID, toUID, amount, "", model.TransactionDetails{})
 	require.Error(t, err)
-	assert.Equal(t, expectedErr, err)
+	assert.EqualError(t, err, expectedErr.Error())
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
 
 	mockRepo.AssertExpectations(t)
 }
 
+func TestWalletServ_Transfer_Self(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	mockRepo := new(MockWalletRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+	err := walletServ.Transfer(ctx, 1, 1, decimal.NewFromInt(10), "", model.TransactionDetails{})
+	require.ErrorIs(t, err, ErrSelfTransfer)
+
+	_, err = walletServ.DryRun(ctx, model.Transfer, 1, 1, decimal.NewFromInt(10), false)
+	require.ErrorIs(t, err, ErrSelfTransfer)
+
+	mockRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything)
+}
+
 func TestWalletServ_Balance_Error(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
@@ -215,7 +273,7 @@ func TestWalletServ_Balance_Error(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
 	mockRepo := new(MockWalletRepo)
-	walletServ := NewWallet(mockRepo)
+	walletServ := NewWallet(mockRepo, lock.Nop(), repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
 
 	uid := int64(1)
 
@@ -228,3 +286,192 @@ func TestWalletServ_Balance_Error(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestFailureReason(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"Success", nil, ""},
+		{"InvalidAmount", fmt.Errorf("deposit %w", ErrNonPositiveAmount), "invalid_amount"},
+		{"InvalidDetails", fmt.Errorf("%w: memo longer than 140", ErrInvalidDetails), "invalid_details"},
+		{"SelfTransfer", fmt.Errorf("%w: uid 1", ErrSelfTransfer), "self_transfer"},
+		{"InsufficientBalance", fmt.Errorf("%w for transfer", ErrInsufficientBalance), "insufficient_balance"},
+		{"NotSettled", fmt.Errorf("%w: 1 left", ErrBalanceNotSettled), "not_settled"},
+		{"BalanceLimit", fmt.Errorf("deposit %w of 1", ErrBalanceLimitExceeded), "balance_limit"},
+		{"AmountLimit", fmt.Errorf("deposit %w of 1", ErrAmountLimitExceeded), "amount_limit"},
+		{"Disabled", fmt.Errorf("deposit %w", ErrFeatureDisabled), "disabled"},
+		{"LockTimeout", fmt.Errorf("%w wallet:1", lock.ErrTimeout), "lock_timeout"},
+		{"Rejected", fmt.Errorf("%w for uid 1", repository.ErrWriteRejected), "rejected"},
+		{"Frozen", fmt.Errorf("%w for uid 1", repository.ErrWalletFrozen), "frozen"},
+		{"Debit blocked", fmt.Errorf("%w for uid 1", repository.ErrWalletDebitBlocked), "debit_blocked"},
+		{"Closed", fmt.Errorf("%w for uid 1", repository.ErrWalletClosed), "closed"},
+		{"WalletNotFound", sql.ErrNoRows, "wallet_not_found"},
+		{"Internal", errors.New("connection refused"), "internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, failureReason(tt.err))
+		})
+	}
+}
+
+func TestCheckDetails(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	manyKeys := make(map[string]int, maxMetadataKeys+1)
+	for i := 0; i <= maxMetadataKeys; i++ {
+		manyKeys[fmt.Sprintf("k%d", i)] = i
+	}
+	manyKeysJSON, err := json.Marshal(manyKeys)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		details  model.TransactionDetails
+		expected error
+	}{
+		{"Empty", model.TransactionDetails{}, nil},
+		{"All set", model.TransactionDetails{Memo: "rent", ExternalReference: "inv-42",
+			Metadata: json.RawMessage(`{"order": 7}`)}, nil},
+		{"Null metadata", model.TransactionDetails{Metadata: json.RawMessage(`null`)}, nil},
+		{"Memo too long", model.TransactionDetails{Memo: strings.Repeat("é", maxMemoLength+1)}, ErrInvalidDetails},
+		{"Reference too long", model.TransactionDetails{ExternalReference: strings.Repeat("r", maxReferenceLength+1)},
+			ErrInvalidDetails},
+		{"Metadata not an object", model.TransactionDetails{Metadata: json.RawMessage(`[1, 2]`)}, ErrInvalidDetails},
+		{"Metadata too large", model.TransactionDetails{
+			Metadata: json.RawMessage(`{"note": "` + strings.Repeat("x", maxMetadataSize) + `"}`)}, ErrInvalidDetails},
+		{"Too many keys", model.TransactionDetails{Metadata: manyKeysJSON}, ErrInvalidDetails},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := tt.details
+			err := checkDetails(&details)
+			if tt.expected != nil {
+				require.ErrorIs(t, err, tt.expected)
+				return
+			}
+
+			require.NoError(t, err)
+			if string(tt.details.Metadata) == "null" {
+				assert.Nil(t, details.Metadata)
+			}
+		})
+	}
+
+	t.Run("Rejected before any write", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), new(MockAuditRepo), zap.NewNop().Sugar())
+
+		err := walletServ.Transfer(ctx, 1, 2, decimal.NewFromInt(1), "",
+			model.TransactionDetails{Metadata: json.RawMessage(`"text"`)})
+		require.ErrorIs(t, err, ErrInvalidDetails)
+
+		mockRepo.AssertNotCalled(t, "Balance", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWalletServ_RuntimeSettings(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	uid := int64(1)
+	t.Cleanup(func() { config.SetRuntime(nil) })
+
+	t.Run("Operation disabled", func(t *testing.T) {
+		config.SetRuntime(&config.RuntimeConf{Features: map[string]bool{model.Transfer: false}})
+
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
+
+		err := walletServ.Transfer(ctx, uid, 2, decimal.NewFromInt(1), "", model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrFeatureDisabled)
+		assert.EqualError(t, err, "transfer is disabled")
+
+		// Other operations stay on.
+		mockRepo.On("Balance", ctx, uid).Return(decimal.Zero, nil)
+		mockRepo.On("Deposit", ctx, uid, decimal.NewFromInt(1), model.TransactionDetails{}).Return(nil)
+		require.NoError(t, walletServ.Deposit(ctx, uid, decimal.NewFromInt(1), model.TransactionDetails{}))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Amount limit", func(t *testing.T) {
+		config.SetRuntime(&config.RuntimeConf{Limits: config.LimitsConf{MaxAmount: 50}})
+
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
+
+		err := walletServ.Withdraw(ctx, uid, decimal.NewFromInt(51), "", model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrAmountLimitExceeded)
+		assert.EqualError(t, err, "withdraw amount exceeds the maximum allowed per operation of 50")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Balance limit", func(t *testing.T) {
+		config.SetRuntime(&config.RuntimeConf{Limits: config.LimitsConf{MaxBalance: 100}})
+
+		mockRepo := new(MockWalletRepo)
+		walletServ := NewWallet(mockRepo, lock.Nop(), repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
+
+		mockRepo.On("Balance", ctx, uid).Return(decimal.NewFromInt(90), nil)
+
+		err := walletServ.Deposit(ctx, uid, decimal.NewFromInt(11), model.TransactionDetails{})
+		require.ErrorIs(t, err, ErrBalanceLimitExceeded)
+		assert.EqualError(t, err, "deposit would exceed the maximum allowed balance of 100")
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestWalletServ_Locking(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	amount := decimal.NewFromInt(10)
+
+	t.Run("Transfer holds both wallets", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		locker := new(MockLocker)
+		walletServ := NewWallet(mockRepo, locker, repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
+
+		locker.On("Lock", ctx, []string{"wallet:1", "wallet:2"}).Return(&lock.Lease{Token: 9}, nil)
+		mockRepo.On("Balance", ctx, int64(1)).Return(decimal.NewFromInt(50), nil)
+		mockRepo.On("Balance", ctx, int64(2)).Return(decimal.Zero, nil)
+		mockRepo.On("Transfer", ctx, int64(1), int64(2), amount, model.FeeCharge{}, model.TransactionDetails{}).Return(nil)
+
+		require.NoError(t, walletServ.Transfer(ctx, 1, 2, amount, "", model.TransactionDetails{}))
+
+		locker.AssertExpectations(t)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Lock timeout", func(t *testing.T) {
+		mockRepo := new(MockWalletRepo)
+		locker := new(MockLocker)
+		walletServ := NewWallet(mockRepo, locker, repository.NewAuditLogger(zap.NewNop().Sugar()), zap.NewNop().Sugar())
+
+		locker.On("Lock", ctx, []string{"wallet:1"}).
+			Return((*lock.Lease)(nil), fmt.Errorf("%w wallet:1", lock.ErrTimeout))
+
+		err := walletServ.Withdraw(ctx, 1, amount, "", model.TransactionDetails{})
+		require.ErrorIs(t, err, lock.ErrTimeout)
+
+		// Nothing is read or written without the lock.
+		mockRepo.AssertNotCalled(t, "Balance", ctx, int64(1))
+		mockRepo.AssertNotCalled(t, "Withdraw", ctx, int64(1), amount, model.FeeCharge{}, model.TransactionDetails{})
+	})
+}
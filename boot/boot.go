@@ -1,7 +1,15 @@
 package boot
 
-func Boot() error {
-	if err := initConfig(); err != nil {
+import (
+	"context"
+	"errors"
+
+	"server/config"
+)
+
+// Boot starts the api server and the background jobs. args are the command-line flags.
+func Boot(args []string) error {
+	if err := initConfig(args); err != nil {
 		return err
 	}
 
@@ -9,13 +17,51 @@ func Boot() error {
 		return err
 	}
 
+	if err := initTracing(); err != nil {
+		return err
+	}
+
 	if err := initDB(); err != nil {
 		return err
 	}
 
-	if err := initHTTP(); err != nil {
+	if err := initHealth(); err != nil {
+		return err
+	}
+
+	if err := initWorker(); err != nil {
+		return err
+	}
+
+	if err := initReload(); err != nil {
+		return err
+	}
+
+	serveErr, err := initHTTP()
+	if err != nil {
+		return errors.Join(err, shutdown())
+	}
+
+	return wait(serveErr)
+}
+
+// Reconcile runs a single reconciliation of every wallet and returns an error
+// when open discrepancies remain, so it can be used from cron or CI.
+func Reconcile(args []string) error {
+	if err := initConfig(args); err != nil {
+		return err
+	}
+
+	if err := initLog(); err != nil {
+		return err
+	}
+
+	// The ddl drops every table, so it must never run from a one-off command.
+	config.Config.DB.InitTable = false
+
+	if err := initDB(); err != nil {
 		return err
 	}
 
-	return nil
+	return errors.Join(runReconcile(context.Background()), closeDB())
 }
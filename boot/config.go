@@ -2,31 +2,91 @@ package boot
 
 import (
 	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"server/config"
+	"server/pkg/override"
 
 	"gopkg.in/yaml.v3"
 )
 
+// envPrefix prefixes the environment variables that override the config file,
+// e.g. WALLET_DB_PASSWORD overrides db.password.
+const envPrefix = "WALLET_"
+
 var (
 	envCnfPath      = "/usr/local/config/config.yaml"
 	envCnfLocalPath = "config/config.local.yaml"
 )
 
-func initConfig() error {
-	env := os.Getenv("ENV")
-	if env == "" {
-		envCnfPath = envCnfLocalPath
-	}
+// cnfArgs and cnfPath are what the config was loaded from, so it can be reloaded the same way.
+var (
+	cnfArgs []string
+	cnfPath string
+)
 
-	err := unmarshalConfig(&config.Config, envCnfPath)
+func initConfig(args []string) error {
+	path, err := loadConfig(&config.Config, args)
 	if err != nil {
 		return err
 	}
 
+	cnfArgs, cnfPath = args, path
+
+	runtime := config.Config.Runtime
+	config.SetRuntime(&runtime)
+
 	return nil
 }
 
+// loadConfig reads the config file into conf, then applies the environment and finally the
+// flags in args, and validates the result. The file is given by --config or WALLET_CONFIG,
+// or else chosen by ENV. It returns the path of the file.
+func loadConfig(conf interface{ Validate() error }, args []string) (string, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", "", "path of the config file, also "+envPrefix+"CONFIG")
+
+	applyFlags, err := override.Flags(fs, conf, envPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	if err = fs.Parse(args); err != nil {
+		return "", err
+	}
+
+	if *path == "" {
+		*path = os.Getenv(envPrefix + "CONFIG")
+	}
+
+	if *path == "" {
+		*path = envCnfPath
+		if os.Getenv("ENV") == "" {
+			*path = envCnfLocalPath
+		}
+	}
+
+	err = unmarshalConfig(conf, *path)
+	if err != nil {
+		return "", err
+	}
+
+	if err = override.Env(conf, envPrefix, os.LookupEnv); err != nil {
+		return "", err
+	}
+
+	if err = applyFlags(); err != nil {
+		return "", err
+	}
+
+	if err = conf.Validate(); err != nil {
+		return "", fmt.Errorf("invalid config %s:\n%w", *path, err)
+	}
+
+	return *path, nil
+}
+
 func unmarshalConfig(conf any, filePath string) error {
 	bytes, err := os.ReadFile(filePath)
 	if err != nil {
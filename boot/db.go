@@ -2,21 +2,25 @@ package boot
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
 	"os"
 
 	"server/config"
 	"server/pkg/dal"
+	"server/pkg/metrics"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 var (
 	ddlPath      = "/usr/local/config/ddl.sql"
 	ddlLocalPath = "config/ddl.sql"
+
+	defaultRedisPoolSize = 100
 )
 
 func initDB() error {
@@ -31,11 +35,20 @@ func initDB() error {
 	dataSourceName := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		dbConf.Host, dbConf.Port, dbConf.User, dbConf.Password, dbConf.DBName)
 
-	db, err := sql.Open(dbConf.Driver, dataSourceName)
+	db, err := otelsql.Open(dbConf.Driver, dataSourceName,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{DisableErrSkip: true}))
 	if err != nil {
 		return err
 	}
 
+	db.SetMaxOpenConns(dbConf.MaxOpenConns)
+	if dbConf.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(dbConf.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(dbConf.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(dbConf.ConnMaxIdleTime)
+
 	// for test
 	_, _ = db.Exec("DROP DATABASE IF EXISTS test_postgres")
 	_, _ = db.Exec("CREATE DATABASE test_postgres")
@@ -58,11 +71,18 @@ func initDB() error {
 	}
 
 	rdbConf := config.Config.Redis
+	poolSize := rdbConf.PoolSize
+	if poolSize <= 0 {
+		poolSize = defaultRedisPoolSize
+	}
+
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     rdbConf.Addr,
-		Password: rdbConf.Password,
-		DB:       rdbConf.DB,
-		PoolSize: 100,
+		Addr:            rdbConf.Addr,
+		Password:        rdbConf.Password,
+		DB:              rdbConf.DB,
+		PoolSize:        poolSize,
+		MinIdleConns:    rdbConf.MinIdleConns,
+		ConnMaxIdleTime: rdbConf.ConnMaxIdleTime,
 	})
 
 	_, err = rdb.Ping(context.Background()).Result()
@@ -70,6 +90,10 @@ func initDB() error {
 		return err
 	}
 
+	if err = metrics.RegisterDB(db, dbConf.DBName); err != nil {
+		return err
+	}
+
 	logger := log.Default()
 	dal.CustomDal, err = dal.New(db, rdb, logger)
 	if err != nil {
@@ -0,0 +1,36 @@
+package boot
+
+import (
+	"context"
+	"time"
+
+	"server/app/repository"
+	"server/app/service"
+	"server/config"
+	"server/pkg/dal"
+	"server/pkg/health"
+	"server/pkg/logger"
+)
+
+var defaultHealthTimeout = 2 * time.Second
+
+// probe backs /readyz; it is drained first on shutdown so traffic moves away before the server stops.
+var probe *health.Probe
+
+func initHealth() error {
+	timeout := config.Config.Health.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	servHealth := service.NewHealth(repository.NewSchema(dal.CustomDal.DB, logger.Logger))
+
+	probe = health.New(timeout)
+	probe.Add("postgres", dal.CustomDal.DB.PingContext)
+	probe.Add("redis", func(ctx context.Context) error {
+		return dal.CustomDal.RDB.Ping(ctx).Err()
+	})
+	probe.Add("schema", servHealth.CheckSchema)
+
+	return nil
+}
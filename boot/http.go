@@ -1,6 +1,10 @@
 package boot
 
 import (
+	"errors"
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"log"
@@ -11,19 +15,62 @@ import (
 	"server/router"
 )
 
-func initHTTP() error {
+var (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 15 * time.Second
+	defaultWriteTimeout      = time.Minute
+	defaultIdleTimeout       = 2 * time.Minute
+)
+
+// server is the api server, kept so it can be shut down gracefully.
+var server *http.Server
+
+// initHTTP starts the api server in the background. Errors from serving, other than the
+// server being shut down, are sent on the returned channel.
+func initHTTP() (<-chan error, error) {
 	gin.SetMode(config.Config.AppMode)
 
-	engine := gin.Default()
+	// Requests are logged by middleware.Logger, so gin's own logger is left out.
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+
+	// Only trusted proxies may set the client IP, which rate limiting is keyed by.
+	if err := engine.SetTrustedProxies(config.Config.HTTP.TrustedProxies); err != nil {
+		return nil, err
+	}
 
-	router.Router(engine, dal.CustomDal.DB, logger.Logger)
+	router.Health(engine, probe)
+	router.Router(engine, dal.CustomDal.DB, dal.CustomDal.RDB, logger.Logger)
+
+	httpConf := config.Config.HTTP
+	server = &http.Server{
+		Addr:              config.Config.APIAddr,
+		Handler:           engine,
+		ReadHeaderTimeout: orDefault(httpConf.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		ReadTimeout:       orDefault(httpConf.ReadTimeout, defaultReadTimeout),
+		WriteTimeout:      orDefault(httpConf.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       orDefault(httpConf.IdleTimeout, defaultIdleTimeout),
+	}
 
 	log.Printf("start api server, address: %s, version: %s \n", config.Config.APIAddr, config.Config.AppVersion)
 
-	err := engine.Run(config.Config.APIAddr)
-	if err != nil {
-		log.Fatalln("api run failed", config.Config.APIAddr, err.Error())
+	serveErr := make(chan error, 1)
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	return serveErr, nil
+}
+
+// orDefault returns d, or def when d is not set.
+func orDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
 	}
 
-	return nil
+	return d
 }
@@ -3,6 +3,8 @@ package boot
 import (
 	"server/config"
 	"server/pkg/logger"
+
+	"go.uber.org/zap/zapcore"
 )
 
 func initLog() error {
@@ -21,5 +23,22 @@ func initLog() error {
 
 	logger.Logger = log
 
+	return setLogLevel(config.Runtime().LogLevel)
+}
+
+// setLogLevel changes the level of logger.Logger in place; an empty level means debug.
+func setLogLevel(level string) error {
+	if level == "" {
+		logger.Level.SetLevel(zapcore.DebugLevel)
+		return nil
+	}
+
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	logger.Level.SetLevel(lvl)
+
 	return nil
 }
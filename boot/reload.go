@@ -0,0 +1,175 @@
+package boot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"syscall"
+	"time"
+
+	"server/config"
+	"server/pkg/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stopReload stops watching the config for changes.
+var stopReload = func() {}
+
+// initReload reloads the runtime settings on SIGHUP and, every reload.interval, when the
+// content of the config file has changed.
+func initReload() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval := config.Config.Reload.Interval; interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+
+	sum, _ := fileSum(cnfPath)
+
+	go func() {
+		defer close(done)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				sum, _ = fileSum(cnfPath)
+				reloadConfig("sighup")
+			case <-tick:
+				next, err := fileSum(cnfPath)
+				if err != nil || bytes.Equal(next, sum) {
+					continue
+				}
+				sum = next
+				reloadConfig("file")
+			}
+		}
+	}()
+
+	stopReload = func() {
+		signal.Stop(hup)
+		cancel()
+		<-done
+	}
+
+	return nil
+}
+
+// reloadConfig loads the config again and swaps in its runtime settings. Anything else that
+// changed needs a restart and is only reported. An invalid config is rejected as a whole.
+func reloadConfig(trigger string) {
+	log := logger.Logger.With("trigger", trigger, "path", cnfPath)
+
+	next := config.New()
+	if _, err := loadConfig(next, cnfArgs); err != nil {
+		log.Errorw("config reload rejected", "error", err)
+		return
+	}
+
+	runtime := next.Runtime
+	changes := diffRuntime(config.Runtime(), &runtime)
+
+	if len(changes) > 0 {
+		if err := setLogLevel(runtime.LogLevel); err != nil {
+			log.Errorw("config reload rejected", "error", err)
+			return
+		}
+
+		config.SetRuntime(&runtime)
+		log.Infow("config reloaded", "changes", changes)
+	}
+
+	next.Runtime = config.Config.Runtime
+	if !reflect.DeepEqual(*next, config.Config) {
+		log.Warnw("config changes outside runtime were ignored, they need a restart")
+	}
+}
+
+// diffRuntime lists the runtime settings that differ, as "key: old -> new".
+func diffRuntime(old, next *config.RuntimeConf) []string {
+	before, after := flatten(old), flatten(next)
+
+	var changes []string
+	for key, value := range after {
+		prev, ok := before[key]
+		if !ok {
+			prev = "<unset>"
+		}
+
+		if !ok || prev != value {
+			changes = append(changes, fmt.Sprintf("runtime.%s: %s -> %s", key, prev, value))
+		}
+	}
+
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, fmt.Sprintf("runtime.%s: %s -> <unset>", key, value))
+		}
+	}
+
+	sort.Strings(changes)
+
+	return changes
+}
+
+// flatten returns the leaves of v, keyed by their dotted yaml path.
+func flatten(v any) map[string]string {
+	out := make(map[string]string)
+
+	bytes, err := yaml.Marshal(v)
+	if err != nil {
+		return out
+	}
+
+	var tree map[string]any
+	if err = yaml.Unmarshal(bytes, &tree); err != nil {
+		return out
+	}
+
+	var walk func(prefix string, node any)
+	walk = func(prefix string, node any) {
+		m, ok := node.(map[string]any)
+		if !ok {
+			out[prefix] = fmt.Sprint(node)
+			return
+		}
+
+		for key, child := range m {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			walk(key, child)
+		}
+	}
+	walk("", tree)
+
+	return out
+}
+
+func fileSum(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+
+	return sum[:], nil
+}
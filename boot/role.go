@@ -0,0 +1,71 @@
+package boot
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"server/app/model"
+	"server/app/repository"
+	"server/app/request"
+	"server/app/service"
+	"server/config"
+	"server/pkg/dal"
+	"server/pkg/lock"
+	"server/pkg/logger"
+)
+
+// Role gives a user a role of the admin API, which is how the first superadmin is appointed.
+// args are the username, the role and then the command-line flags.
+func Role(args []string) error {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		return errors.New("usage: server role <username> <none|support|finance|superadmin> [flags]")
+	}
+
+	role, err := model.ParseRole(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err = initConfig(args[2:]); err != nil {
+		return err
+	}
+
+	if err = initLog(); err != nil {
+		return err
+	}
+
+	// The ddl drops every table, so it must never run from a one-off command.
+	config.Config.DB.InitTable = false
+
+	if err = initDB(); err != nil {
+		return err
+	}
+
+	return errors.Join(runRole(context.Background(), args[0], role), closeDB())
+}
+
+func runRole(ctx context.Context, username string, role model.Role) error {
+	repo := repository.NewAdmin(dal.CustomDal.DB, logger.Logger)
+
+	user, err := repo.Credentials(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user called %q", username)
+	}
+	if err != nil {
+		return err
+	}
+
+	serv := service.NewAdmin(repo, nil, repository.NewAudit(dal.CustomDal.DB, logger.Logger), lock.Nop(), logger.Logger)
+	err = serv.SetRole(ctx, &model.Actor{}, user.ID, &request.ReqRole{Role: role, Reason: "set from the command line"})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("------ %s (uid %d) is now %s\n", username, user.ID, role)
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package boot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"server/app/service"
+	"server/config"
+	"server/pkg/dal"
+)
+
+var defaultShutdownTimeout = 30 * time.Second
+
+// wait blocks until the api server fails or SIGINT/SIGTERM is received, then shuts down.
+func wait(serveErr <-chan error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		if err != nil {
+			err = fmt.Errorf("api server: %w", err)
+		}
+	case <-ctx.Done():
+		log.Printf("------ received shutdown signal \n")
+	}
+
+	// A second signal kills the process right away.
+	stop()
+
+	return errors.Join(err, shutdown())
+}
+
+// shutdown stops the service in order: readiness goes down first so traffic moves away,
+// then the api server drains in-flight requests and the batches they started, then config reloading and
+// the background jobs stop and finally Redis, the DB pool and the tracer are closed.
+func shutdown() error {
+	httpConf := config.Config.HTTP
+
+	ctx, cancel := context.WithTimeout(context.Background(), orDefault(httpConf.ShutdownTimeout, defaultShutdownTimeout))
+	defer cancel()
+
+	var errs []error
+
+	if probe != nil {
+		probe.Drain()
+
+		select {
+		case <-time.After(httpConf.DrainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	if server != nil {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("api server: %w", err))
+		}
+	}
+
+	// A batch still running when the timeout is up is cut short, and failed later by the batch sweep.
+	if err := service.WaitBatches(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("batches: %w", err))
+	}
+
+	stopReload()
+
+	if worker != nil {
+		worker.Stop()
+	}
+
+	errs = append(errs, closeDB())
+
+	if err := shutdownTracing(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+
+	err := errors.Join(errs...)
+	if err == nil {
+		log.Printf("------ Shutdown Success \n")
+	}
+
+	return err
+}
+
+// closeDB closes the Redis client and the DB pool.
+func closeDB() error {
+	if dal.CustomDal == nil {
+		return nil
+	}
+
+	var errs []error
+	if dal.CustomDal.RDB != nil {
+		if err := dal.CustomDal.RDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis: %w", err))
+		}
+	}
+
+	if dal.CustomDal.DB != nil {
+		if err := dal.CustomDal.DB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("postgres: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,31 @@
+package boot
+
+import (
+	"context"
+
+	"server/config"
+	"server/pkg/tracing"
+)
+
+// shutdownTracing flushes the spans that are still buffered.
+var shutdownTracing = func(context.Context) error { return nil }
+
+func initTracing() error {
+	tracingConf := config.Config.Tracing
+
+	shutdown, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName:    config.Config.AppName,
+		ServiceVersion: config.Config.AppVersion,
+		Exporter:       tracingConf.Exporter,
+		Endpoint:       tracingConf.Endpoint,
+		Insecure:       tracingConf.Insecure,
+		SampleRatio:    tracingConf.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+
+	shutdownTracing = shutdown
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package boot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"server/app/repository"
+	"server/app/service"
+	"server/config"
+	"server/pkg/dal"
+	"server/pkg/logger"
+	"server/pkg/scheduler"
+)
+
+var (
+	defaultSnapshotInterval   = time.Hour
+	defaultReconcileInterval  = 24 * time.Hour
+	defaultStatsInterval      = time.Minute
+	defaultBatchSweepInterval = time.Minute
+)
+
+// worker holds the background jobs so they can be stopped on shutdown.
+var worker *scheduler.Scheduler
+
+func initWorker() error {
+	workerConf := config.Config.Worker
+
+	snapshotInterval := workerConf.SnapshotInterval
+	if snapshotInterval <= 0 {
+		snapshotInterval = defaultSnapshotInterval
+	}
+
+	reconcileInterval := workerConf.ReconcileInterval
+	if reconcileInterval <= 0 {
+		reconcileInterval = defaultReconcileInterval
+	}
+
+	statsInterval := workerConf.StatsInterval
+	if statsInterval <= 0 {
+		statsInterval = defaultStatsInterval
+	}
+
+	batchSweepInterval := workerConf.BatchSweepInterval
+	if batchSweepInterval <= 0 {
+		batchSweepInterval = defaultBatchSweepInterval
+	}
+
+	db := dal.CustomDal.DB
+	servSnapshot := service.NewSnapshot(repository.NewSnapshot(db, logger.Logger))
+	servReconcile := service.NewReconcile(repository.NewReconcile(db, logger.Logger), logger.Logger)
+	servStats := service.NewStats(repository.NewStats(db, logger.Logger))
+	servBatchSweep := service.NewBatchSweep(repository.NewBatchSweep(db, logger.Logger), logger.Logger)
+
+	worker = scheduler.New(logger.Logger)
+	worker.Every("balance_snapshot", snapshotInterval, servSnapshot.Run)
+	worker.Every("reconcile", reconcileInterval, servReconcile.Run)
+	worker.Every("stats", statsInterval, servStats.Run)
+	worker.Every("batch_sweep", batchSweepInterval, servBatchSweep.Run)
+	worker.Start()
+
+	return nil
+}
+
+func runReconcile(ctx context.Context) error {
+	servReconcile := service.NewReconcile(repository.NewReconcile(dal.CustomDal.DB, logger.Logger), logger.Logger)
+
+	list, err := servReconcile.Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range list {
+		log.Printf("------ discrepancy uid:%d wallet_balance:%s ledger_balance:%s difference:%s\n",
+			d.UID, d.WalletBalance, d.LedgerBalance, d.Difference)
+	}
+
+	if len(list) > 0 {
+		return fmt.Errorf("%d open balance discrepancies", len(list))
+	}
+
+	log.Printf("------ Reconcile Success \n")
+
+	return nil
+}
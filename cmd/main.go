@@ -6,17 +6,43 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 )
 
 func main() {
 	setupEnvironment()
 
-	if err := boot.Boot(); err != nil {
+	var err error
+	name, args := command(os.Args)
+	switch name {
+	case "reconcile":
+		err = boot.Reconcile(args)
+	case "role":
+		err = boot.Role(args)
+	default:
+		err = boot.Boot(args)
+	}
+
+	if err != nil {
 		log.Fatalln("start failure: ", err.Error())
 	}
 }
 
+// command returns the subcommand given on the command line, or "" to start the server,
+// followed by the flags that come after it.
+func command(args []string) (string, []string) {
+	if len(args) < 2 {
+		return "", nil
+	}
+
+	if strings.HasPrefix(args[1], "-") {
+		return "", args[1:]
+	}
+
+	return args[1], args[2:]
+}
+
 func setupEnvironment() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
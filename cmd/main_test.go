@@ -45,3 +45,24 @@ func captureLogOutput(f func()) string {
 	log.SetOutput(os.Stdout)
 	return buf.String()
 }
+
+func TestCommand(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		args         []string
+		expectedName string
+		expectedArgs []string
+	}{
+		{[]string{"server"}, "", nil},
+		{[]string{"server", "reconcile"}, "reconcile", []string{}},
+		{[]string{"server", "--config", "a.yaml"}, "", []string{"--config", "a.yaml"}},
+		{[]string{"server", "reconcile", "--db.host=db"}, "reconcile", []string{"--db.host=db"}},
+	}
+
+	for _, tt := range tests {
+		name, args := command(tt.args)
+		assert.Equal(t, tt.expectedName, name)
+		assert.Equal(t, tt.expectedArgs, args)
+	}
+}
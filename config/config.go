@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 var Config config
 
 type config struct {
@@ -11,6 +13,20 @@ type config struct {
 	DBTest     postgresqlConf `yaml:"db_test"`
 	Redis      redisConf      `yaml:"redis"`
 	Log        logConf        `yaml:"log"`
+	Worker     workerConf     `yaml:"worker"`
+	Admin      adminConf      `yaml:"admin"`
+	Tracing    tracingConf    `yaml:"tracing"`
+	Health     healthConf     `yaml:"health"`
+	HTTP       httpConf       `yaml:"http"`
+	Cache      cacheConf      `yaml:"cache"`
+	Lock       lockConf       `yaml:"lock"`
+	Reload     reloadConf     `yaml:"reload"`
+	Runtime    RuntimeConf    `yaml:"runtime"`
+}
+
+// New returns an empty config, for loading a fresh copy without touching Config.
+func New() *config {
+	return new(config)
 }
 
 type postgresqlConf struct {
@@ -21,12 +37,21 @@ type postgresqlConf struct {
 	Password  string `yaml:"password"`
 	DBName    string `yaml:"db_name"`
 	InitTable bool   `yaml:"init_table"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`     // 连接池最大连接数, 0 表示不限制
+	MaxIdleConns    int           `yaml:"max_idle_conns"`     // 连接池最大空闲连接数, 0 表示使用默认值
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`  // 连接的最长存活时间, 0 表示不限制
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"` // 连接的最长空闲时间, 0 表示不限制
 }
 
 type redisConf struct {
 	Addr     string `yaml:"addr"`
 	Password string `yaml:"password"`
 	DB       int    `yaml:"db"`
+
+	PoolSize        int           `yaml:"pool_size"`          // 连接池大小, 0 表示使用默认值
+	MinIdleConns    int           `yaml:"min_idle_conns"`     // 连接池最少空闲连接数
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"` // 连接的最长空闲时间, 0 表示使用默认值
 }
 
 type logConf struct {
@@ -37,3 +62,58 @@ type logConf struct {
 	ErrFilename  string `yaml:"err_filename"`  // err 级日志文件的名字
 	IgnoreHeader string `yaml:"ignore_header"` // 忽略header的key
 }
+
+type workerConf struct {
+	SnapshotInterval   time.Duration `yaml:"snapshot_interval"`    // 余额快照任务的执行间隔
+	ReconcileInterval  time.Duration `yaml:"reconcile_interval"`   // 余额对账任务的执行间隔
+	StatsInterval      time.Duration `yaml:"stats_interval"`       // 业务指标采集任务的执行间隔
+	BatchSweepInterval time.Duration `yaml:"batch_sweep_interval"` // 关闭中断的批量转账任务的执行间隔
+}
+
+type adminConf struct {
+	Enabled bool `yaml:"enabled"` // 是否开启管理接口; 员工用自己的用户名密码登录, 权限由用户的 role 决定
+}
+
+type tracingConf struct {
+	Exporter    string  `yaml:"exporter"`     // 链路追踪导出方式: otlp, stdout, 为空时不导出
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/HTTP 收集器地址, 如 otel-collector:4318
+	Insecure    bool    `yaml:"insecure"`     // OTLP 使用 HTTP 而非 HTTPS
+	SampleRatio float64 `yaml:"sample_ratio"` // 新链路的采样比例, 0 表示全部采样
+}
+
+type healthConf struct {
+	Timeout time.Duration `yaml:"timeout"` // 就绪检查中每个依赖的超时时间
+}
+
+type httpConf struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"` // 读取请求头的超时时间
+	ReadTimeout       time.Duration `yaml:"read_timeout"`        // 读取整个请求的超时时间
+	WriteTimeout      time.Duration `yaml:"write_timeout"`       // 写响应的超时时间
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`        // keep-alive 连接的空闲超时时间
+	DrainDelay        time.Duration `yaml:"drain_delay"`         // 停机时就绪检查失败后, 等待负载均衡摘除流量的时间
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`    // 停机时等待处理中请求完成的最长时间
+	TrustedProxies    []string      `yaml:"trusted_proxies"`     // 可信代理的 IP 或网段, 只有它们设置的 X-Forwarded-For 会被采用
+}
+
+type cacheConf struct {
+	Enabled    bool          `yaml:"enabled"`     // 是否开启 Redis 读缓存
+	BalanceTTL time.Duration `yaml:"balance_ttl"` // 余额缓存的有效期, 也是其他实例修改余额后可能读到旧值的最长时间
+	UserTTL    time.Duration `yaml:"user_ttl"`    // 用户信息缓存的有效期
+}
+
+// Backends of lock.backend.
+const (
+	LockRedis    = "redis"
+	LockPostgres = "postgres"
+	LockNone     = "none"
+)
+
+type lockConf struct {
+	Backend string        `yaml:"backend"` // 钱包锁的实现: redis, postgres 或 none(单实例部署, 留空同 none)
+	Wait    time.Duration `yaml:"wait"`    // 等待被占用的钱包锁的最长时间, 超时返回钱包繁忙
+	TTL     time.Duration `yaml:"ttl"`     // Redis 锁的过期时间, 持有者异常退出后锁自动释放
+}
+
+type reloadConf struct {
+	Interval time.Duration `yaml:"interval"` // 检查配置文件变化的间隔, 0 表示只在收到 SIGHUP 时重新加载
+}
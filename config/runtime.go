@@ -0,0 +1,98 @@
+package config
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RuntimeConf is the part of the config that is reloaded without a restart, on SIGHUP or when
+// the config file changes. Readers must go through Runtime, never Config.Runtime.
+type RuntimeConf struct {
+	LogLevel  string          `yaml:"log_level"`  // 日志级别: debug, info, warn, error
+	Limits    LimitsConf      `yaml:"limits"`     // 金额限制
+	Features  map[string]bool `yaml:"features"`   // 功能开关, 未配置的功能默认开启
+	RateLimit RateLimitConf   `yaml:"rate_limit"` // 限流设置
+	Fees      FeesConf        `yaml:"fees"`       // 手续费设置
+}
+
+type LimitsConf struct {
+	MaxBalance int64 `yaml:"max_balance"` // 钱包余额上限, 0 表示使用默认值
+	MaxAmount  int64 `yaml:"max_amount"`  // 单笔存款/取款/转账金额上限, 0 表示不限制
+}
+
+type RateLimitConf struct {
+	Enabled bool                `yaml:"enabled"` // 是否开启限流
+	IP      RateConf            `yaml:"ip"`      // 每个客户端 IP 的限额
+	UID     RateConf            `yaml:"uid"`     // 每个用户的限额
+	Routes  map[string]RateConf `yaml:"routes"`  // 每个路由的限额, 键为 "METHOD /path", 如 "POST /api/wallets/:uid/transfer"
+}
+
+// RateConf allows Limit requests per Window; a zero Limit means unlimited.
+type RateConf struct {
+	Limit  int           `yaml:"limit"`
+	Window time.Duration `yaml:"window"`
+}
+
+type FeesConf struct {
+	RevenueUID int64         `yaml:"revenue_uid"` // 收取手续费的钱包所属的用户, 配置了规则时必填
+	Rules      []FeeRule     `yaml:"rules"`       // 手续费规则, 取第一条匹配交易类型和用户等级的规则, 没有匹配的规则则不收费
+	QuoteTTL   time.Duration `yaml:"quote_ttl"`   // 试运行报价锁定手续费的有效期, 0 表示使用默认值 (1m)
+}
+
+// FeeRule charges the fee of its formula, or of the last of its bands whose From the amount
+// reaches when it has bands.
+type FeeRule struct {
+	Operation  string `yaml:"operation"` // 交易类型: withdraw 或 transfer
+	Tier       string `yaml:"tier"`      // 用户等级, 为空时匹配所有等级
+	FeeFormula `yaml:",inline"`
+	Bands      []FeeBand `yaml:"bands"` // 按金额分档, from 从小到大排列, 金额低于第一档时使用规则本身的公式
+}
+
+type FeeBand struct {
+	From       decimal.Decimal `yaml:"from"` // 本档的起始金额 (含)
+	FeeFormula `yaml:",inline"`
+}
+
+// FeeFormula charges Flat plus Percent of the amount, raised to Min and capped at Max.
+type FeeFormula struct {
+	Flat    decimal.Decimal `yaml:"flat"`    // 固定金额
+	Percent decimal.Decimal `yaml:"percent"` // 金额的百分比, 如 1.5 表示 1.5%
+	Min     decimal.Decimal `yaml:"min"`     // 最低收费
+	Max     decimal.Decimal `yaml:"max"`     // 最高收费, 0 表示不封顶
+}
+
+var runtime atomic.Pointer[RuntimeConf]
+
+// Runtime returns the current runtime settings. The result must not be modified.
+func Runtime() *RuntimeConf {
+	if r := runtime.Load(); r != nil {
+		return r
+	}
+
+	return &RuntimeConf{}
+}
+
+// SetRuntime swaps in new runtime settings.
+func SetRuntime(r *RuntimeConf) {
+	runtime.Store(r)
+}
+
+// Feature reports whether a feature is switched on. Features are on unless switched off.
+func (r *RuntimeConf) Feature(name string) bool {
+	on, ok := r.Features[name]
+	return !ok || on
+}
+
+// Rule returns the first fee rule for operation that applies to users of tier, or nil when
+// the operation is free for them.
+func (f *FeesConf) Rule(operation, tier string) *FeeRule {
+	for i := range f.Rules {
+		if rule := &f.Rules[i]; rule.Operation == operation && (rule.Tier == "" || rule.Tier == tier) {
+			return rule
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap/zapcore"
+)
+
+// Validate checks the loaded config and reports every problem found, each named after its yaml key.
+func (c *config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+	}
+
+	switch c.AppMode {
+	case "debug", "release", "test":
+	default:
+		fail("app_mode", "must be one of debug, release, test, got %q", c.AppMode)
+	}
+
+	if err := validateAddr(c.APIAddr); err != nil {
+		fail("api_addr", "%v", err)
+	}
+
+	c.DB.validate("db", fail)
+
+	if err := validateAddr(c.Redis.Addr); err != nil {
+		fail("redis.addr", "%v", err)
+	}
+	if c.Redis.DB < 0 {
+		fail("redis.db", "must not be negative")
+	}
+	if c.Redis.PoolSize < 0 {
+		fail("redis.pool_size", "must not be negative")
+	}
+	if c.Redis.MinIdleConns < 0 {
+		fail("redis.min_idle_conns", "must not be negative")
+	}
+	if c.Redis.PoolSize > 0 && c.Redis.MinIdleConns > c.Redis.PoolSize {
+		fail("redis.min_idle_conns", "must not exceed redis.pool_size")
+	}
+	if c.Redis.ConnMaxIdleTime < 0 {
+		fail("redis.conn_max_idle_time", "must not be negative")
+	}
+
+	if c.Cache.Enabled {
+		if c.Cache.BalanceTTL <= 0 {
+			fail("cache.balance_ttl", "must be positive when the cache is enabled")
+		}
+		if c.Cache.UserTTL <= 0 {
+			fail("cache.user_ttl", "must be positive when the cache is enabled")
+		}
+	}
+
+	switch c.Lock.Backend {
+	case LockRedis:
+		if c.Lock.TTL <= 0 {
+			fail("lock.ttl", "must be positive for the redis backend")
+		}
+		fallthrough
+	case LockPostgres:
+		if c.Lock.Wait <= 0 {
+			fail("lock.wait", "must be positive")
+		}
+	case "", LockNone:
+	default:
+		fail("lock.backend", fmt.Sprintf("unknown backend %q", c.Lock.Backend))
+	}
+
+	if c.Log.FilePath == "" {
+		fail("log.file_path", "is required")
+	}
+
+	switch c.Tracing.Exporter {
+	case "", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			fail("tracing.endpoint", "is required by the otlp exporter")
+		}
+	default:
+		fail("tracing.exporter", "must be empty, otlp or stdout, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				fail("http.trusted_proxies", "%q is neither an IP nor a CIDR", proxy)
+			}
+		}
+	}
+
+	c.Runtime.validate("runtime", fail)
+
+	durations := []struct {
+		key string
+		d   time.Duration
+	}{
+		{"worker.snapshot_interval", c.Worker.SnapshotInterval},
+		{"worker.reconcile_interval", c.Worker.ReconcileInterval},
+		{"worker.stats_interval", c.Worker.StatsInterval},
+		{"worker.batch_sweep_interval", c.Worker.BatchSweepInterval},
+		{"health.timeout", c.Health.Timeout},
+		{"http.read_header_timeout", c.HTTP.ReadHeaderTimeout},
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.drain_delay", c.HTTP.DrainDelay},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+		{"reload.interval", c.Reload.Interval},
+	}
+	for _, d := range durations {
+		if d.d < 0 {
+			fail(d.key, "must not be negative")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *postgresqlConf) validate(key string, fail func(key, format string, args ...any)) {
+	if p.Driver == "" {
+		fail(key+".driver", "is required")
+	}
+	if p.Host == "" {
+		fail(key+".host", "is required")
+	}
+	if p.Port <= 0 || p.Port > 65535 {
+		fail(key+".port", "must be between 1 and 65535, got %d", p.Port)
+	}
+	if p.User == "" {
+		fail(key+".user", "is required")
+	}
+	if p.DBName == "" {
+		fail(key+".db_name", "is required")
+	}
+	if p.MaxOpenConns < 0 {
+		fail(key+".max_open_conns", "must not be negative")
+	}
+	if p.MaxIdleConns < 0 {
+		fail(key+".max_idle_conns", "must not be negative")
+	}
+	if p.MaxOpenConns > 0 && p.MaxIdleConns > p.MaxOpenConns {
+		fail(key+".max_idle_conns", "must not exceed %s.max_open_conns", key)
+	}
+	if p.ConnMaxLifetime < 0 {
+		fail(key+".conn_max_lifetime", "must not be negative")
+	}
+	if p.ConnMaxIdleTime < 0 {
+		fail(key+".conn_max_idle_time", "must not be negative")
+	}
+}
+
+func validateAddr(addr string) error {
+	if addr == "" {
+		return errors.New("is required")
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+func (r *RuntimeConf) validate(key string, fail func(key, format string, args ...any)) {
+	if r.LogLevel != "" {
+		if _, err := zapcore.ParseLevel(r.LogLevel); err != nil {
+			fail(key+".log_level", "%v", err)
+		}
+	}
+
+	if r.Limits.MaxBalance < 0 {
+		fail(key+".limits.max_balance", "must not be negative")
+	}
+	if r.Limits.MaxAmount < 0 {
+		fail(key+".limits.max_amount", "must not be negative")
+	}
+
+	r.RateLimit.IP.validate(key+".rate_limit.ip", fail)
+	r.RateLimit.UID.validate(key+".rate_limit.uid", fail)
+	for route, rate := range r.RateLimit.Routes {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method == "" || !strings.HasPrefix(path, "/") {
+			fail(key+".rate_limit.routes", "key %q must look like \"POST /api/wallets/:uid/transfer\"", route)
+		}
+		rate.validate(fmt.Sprintf("%s.rate_limit.routes[%s]", key, route), fail)
+	}
+
+	r.Fees.validate(key+".fees", fail)
+}
+
+func (f *FeesConf) validate(key string, fail func(key, format string, args ...any)) {
+	if len(f.Rules) > 0 && f.RevenueUID <= 0 {
+		fail(key+".revenue_uid", "must be positive when fees are charged")
+	}
+
+	if f.QuoteTTL < 0 {
+		fail(key+".quote_ttl", "must not be negative")
+	}
+
+	for i, rule := range f.Rules {
+		ruleKey := fmt.Sprintf("%s.rules[%d]", key, i)
+		if rule.Operation != "withdraw" && rule.Operation != "transfer" {
+			fail(ruleKey+".operation", "must be withdraw or transfer, got %q", rule.Operation)
+		}
+		rule.FeeFormula.validate(ruleKey, fail)
+
+		for j, band := range rule.Bands {
+			bandKey := fmt.Sprintf("%s.bands[%d]", ruleKey, j)
+			if band.From.IsNegative() {
+				fail(bandKey+".from", "must not be negative")
+			}
+			if j > 0 && !band.From.GreaterThan(rule.Bands[j-1].From) {
+				fail(bandKey+".from", "must be greater than the from of the band before")
+			}
+			band.FeeFormula.validate(bandKey, fail)
+		}
+	}
+}
+
+func (f FeeFormula) validate(key string, fail func(key, format string, args ...any)) {
+	if f.Flat.IsNegative() {
+		fail(key+".flat", "must not be negative")
+	}
+	if f.Min.IsNegative() {
+		fail(key+".min", "must not be negative")
+	}
+	if f.Max.IsNegative() {
+		fail(key+".max", "must not be negative")
+	}
+	if f.Percent.IsNegative() || f.Percent.GreaterThan(decimal.NewFromInt(100)) {
+		fail(key+".percent", "must be between 0 and 100")
+	}
+	if f.Max.IsPositive() && f.Min.GreaterThan(f.Max) {
+		fail(key+".min", "must not exceed max")
+	}
+}
+
+func (r RateConf) validate(key string, fail func(key, format string, args ...any)) {
+	if r.Limit < 0 {
+		fail(key+".limit", "must not be negative")
+	}
+	if r.Limit > 0 && r.Window <= 0 {
+		fail(key+".window", "must be positive when a limit is set")
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"gopkg.in/yaml.v3"
+)
+
+func loadConfig(t *testing.T, path string) config {
+	t.Helper()
+
+	bytes, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var conf config
+	require.NoError(t, yaml.Unmarshal(bytes, &conf))
+
+	return conf
+}
+
+func TestConfig_Validate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("Shipped configs", func(t *testing.T) {
+		for _, path := range []string{"config.yaml", "config.local.yaml"} {
+			conf := loadConfig(t, path)
+			assert.NoError(t, conf.Validate(), path)
+			assert.Equal(t, "test_postgres", conf.DBTest.DBName, path)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		modify  func(c *config)
+		wantErr string
+	}{
+		{"App mode", func(c *config) { c.AppMode = "prod" }, `app_mode: must be one of debug, release, test, got "prod"`},
+		{"Api addr", func(c *config) { c.APIAddr = "8080" }, "api_addr: address 8080: missing port in address"},
+		{"DB host", func(c *config) { c.DB.Host = "" }, "db.host: is required"},
+		{"DB port", func(c *config) { c.DB.Port = 70000 }, "db.port: must be between 1 and 65535, got 70000"},
+		{"DB pool", func(c *config) { c.DB.MaxIdleConns = c.DB.MaxOpenConns + 1 }, "db.max_idle_conns: must not exceed db.max_open_conns"},
+		{"Redis addr", func(c *config) { c.Redis.Addr = "" }, "redis.addr: is required"},
+		{"Redis pool", func(c *config) { c.Redis.PoolSize = -1 }, "redis.pool_size: must not be negative"},
+		{"Tracing exporter", func(c *config) { c.Tracing.Exporter = "jaeger" }, `tracing.exporter: must be empty, otlp or stdout, got "jaeger"`},
+		{"Tracing endpoint", func(c *config) { c.Tracing.Exporter, c.Tracing.Endpoint = "otlp", "" }, "tracing.endpoint: is required by the otlp exporter"},
+		{"Cache TTL", func(c *config) { c.Cache.Enabled, c.Cache.BalanceTTL = true, 0 }, "cache.balance_ttl: must be positive when the cache is enabled"},
+		{"Lock backend", func(c *config) { c.Lock.Backend = "etcd" }, `lock.backend: unknown backend "etcd"`},
+		{"Lock TTL", func(c *config) { c.Lock.Backend, c.Lock.TTL = LockRedis, 0 }, "lock.ttl: must be positive for the redis backend"},
+		{"Lock wait", func(c *config) { c.Lock.Backend, c.Lock.Wait = LockPostgres, 0 }, "lock.wait: must be positive"},
+		{"Trusted proxy", func(c *config) { c.HTTP.TrustedProxies = []string{"10.0.0.0/8", "proxy"} }, `http.trusted_proxies: "proxy" is neither an IP nor a CIDR`},
+		{"Log level", func(c *config) { c.Runtime.LogLevel = "loud" }, `runtime.log_level: unrecognized level: "loud"`},
+		{"Max amount", func(c *config) { c.Runtime.Limits.MaxAmount = -1 }, "runtime.limits.max_amount: must not be negative"},
+		{"Rate window", func(c *config) { c.Runtime.RateLimit.IP = RateConf{Limit: 10} }, "runtime.rate_limit.ip.window: must be positive when a limit is set"},
+		{"Rate route", func(c *config) {
+			c.Runtime.RateLimit.Routes = map[string]RateConf{"transfer": {Limit: 1, Window: time.Second}}
+		}, `runtime.rate_limit.routes: key "transfer" must look like`},
+		{"Fee revenue wallet", func(c *config) {
+			c.Runtime.Fees = FeesConf{Rules: []FeeRule{{Operation: "withdraw"}}}
+		}, "runtime.fees.revenue_uid: must be positive when fees are charged"},
+		{"Negative quote TTL", func(c *config) {
+			c.Runtime.Fees = FeesConf{QuoteTTL: -time.Second}
+		}, "runtime.fees.quote_ttl: must not be negative"},
+		{"Fee operation", func(c *config) {
+			c.Runtime.Fees = FeesConf{RevenueUID: 1, Rules: []FeeRule{{Operation: "deposit"}}}
+		}, `runtime.fees.rules[0].operation: must be withdraw or transfer, got "deposit"`},
+		{"Fee percent", func(c *config) {
+			c.Runtime.Fees = FeesConf{RevenueUID: 1, Rules: []FeeRule{
+				{Operation: "withdraw", FeeFormula: FeeFormula{Percent: decimal.NewFromInt(101)}},
+			}}
+		}, "runtime.fees.rules[0].percent: must be between 0 and 100"},
+		{"Fee min above max", func(c *config) {
+			c.Runtime.Fees = FeesConf{RevenueUID: 1, Rules: []FeeRule{
+				{Operation: "withdraw", FeeFormula: FeeFormula{Min: decimal.NewFromInt(5), Max: decimal.NewFromInt(1)}},
+			}}
+		}, "runtime.fees.rules[0].min: must not exceed max"},
+		{"Fee bands out of order", func(c *config) {
+			c.Runtime.Fees = FeesConf{RevenueUID: 1, Rules: []FeeRule{{Operation: "transfer", Bands: []FeeBand{
+				{From: decimal.NewFromInt(100)}, {From: decimal.NewFromInt(100)},
+			}}}}
+		}, "runtime.fees.rules[0].bands[1].from: must be greater than the from of the band before"},
+		{"Negative duration", func(c *config) { c.HTTP.ShutdownTimeout = -1 }, "http.shutdown_timeout: must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := loadConfig(t, "config.local.yaml")
+			tt.modify(&conf)
+
+			err := conf.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("Reports every problem", func(t *testing.T) {
+		conf := loadConfig(t, "config.local.yaml")
+		conf.DB.User = ""
+		conf.Redis.Addr = ""
+
+		err := conf.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db.user: is required")
+		assert.Contains(t, err.Error(), "redis.addr: is required")
+	})
+}
+
+func TestRuntime(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Cleanup(func() { SetRuntime(nil) })
+
+	assert.NotNil(t, Runtime(), "defaults before the config is loaded")
+	assert.True(t, Runtime().Feature("transfer"))
+
+	SetRuntime(&RuntimeConf{Features: map[string]bool{"transfer": false, "deposit": true}})
+	assert.False(t, Runtime().Feature("transfer"))
+	assert.True(t, Runtime().Feature("deposit"))
+	assert.True(t, Runtime().Feature("withdraw"), "features are on unless switched off")
+}
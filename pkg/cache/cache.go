@@ -0,0 +1,93 @@
+// Package cache stores JSON values in Redis with a TTL and counts hits and misses.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"server/pkg/metrics"
+)
+
+const keyPrefix = "cache:"
+
+// Results of a lookup, used as the metrics label.
+const (
+	resultHit   = "hit"
+	resultMiss  = "miss"
+	resultError = "error"
+)
+
+// Cache is a named set of cached values sharing a TTL.
+type Cache struct {
+	rdb  redis.UniversalClient
+	name string
+	ttl  time.Duration
+}
+
+func New(rdb redis.UniversalClient, name string, ttl time.Duration) *Cache {
+	return &Cache{rdb: rdb, name: name, ttl: ttl}
+}
+
+// Key joins the parts of a key. Parts that are personal data should go through Hash.
+func (c *Cache) Key(parts ...string) string {
+	key := keyPrefix + c.name
+	for _, p := range parts {
+		key += ":" + p
+	}
+
+	return key
+}
+
+// Hash hides a value that must not appear in a key in clear, such as an email.
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get decodes the value of key into dst and reports whether it was found. A Redis or decoding
+// error is returned and counts as a miss, so callers can fall back to the source.
+func (c *Cache) Get(ctx context.Context, key string, dst any) (bool, error) {
+	bytes, err := c.rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		metrics.CacheRequests.WithLabelValues(c.name, resultMiss).Inc()
+		return false, nil
+	}
+
+	if err == nil {
+		err = json.Unmarshal(bytes, dst)
+	}
+
+	if err != nil {
+		metrics.CacheRequests.WithLabelValues(c.name, resultError).Inc()
+		return false, err
+	}
+
+	metrics.CacheRequests.WithLabelValues(c.name, resultHit).Inc()
+
+	return true, nil
+}
+
+// Set stores value under key for the TTL of the cache.
+func (c *Cache) Set(ctx context.Context, key string, value any) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return c.rdb.Set(ctx, key, bytes, c.ttl).Err()
+}
+
+// Delete removes keys, whether they exist or not.
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return c.rdb.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"server/pkg/metrics"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestCache(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	c := New(rdb, "test", time.Minute)
+	ctx := context.Background()
+	key := c.Key("user", Hash("alice@example.com"))
+
+	assert.Equal(t, "cache:test:user:"+Hash("alice@example.com"), key)
+	assert.Len(t, Hash("alice@example.com"), 64)
+
+	var value map[string]int
+	hit, err := c.Get(ctx, key, &value)
+	require.NoError(t, err)
+	assert.False(t, hit)
+
+	require.NoError(t, c.Set(ctx, key, map[string]int{"a": 1}))
+	assert.Equal(t, time.Minute, mr.TTL(key))
+
+	hit, err = c.Get(ctx, key, &value)
+	require.NoError(t, err)
+	assert.True(t, hit)
+	assert.Equal(t, map[string]int{"a": 1}, value)
+
+	require.NoError(t, c.Delete(ctx, key))
+	require.NoError(t, c.Delete(ctx))
+	assert.False(t, mr.Exists(key))
+
+	require.NoError(t, mr.Set(key, "not json"))
+	hit, err = c.Get(ctx, key, &value)
+	require.Error(t, err)
+	assert.False(t, hit)
+
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("test", resultHit)), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("test", resultMiss)), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("test", resultError)), 0)
+}
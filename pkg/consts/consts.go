@@ -25,4 +25,29 @@ const (
 	ErrInvalidAmount          = "Invalid Amount"
 	ErrTransferFailed         = "Transfer failed"
 	ErrInvalidTransactionType = "Invalid transaction type"
+	ErrInvalidCursor          = "Invalid cursor"
+	ErrInvalidFilter          = "Invalid filter"
+	ErrForbidden              = "Forbidden"
+	ErrOperationDisabled      = "Operation temporarily disabled"
+	ErrTooManyRequests        = "Too many requests"
+	ErrWalletBusy             = "Wallet is busy, please retry"
+	ErrUnauthorized           = "Unauthorized"
+	ErrWalletFrozen           = "Wallet is frozen"
+	ErrWalletDebitBlocked     = "Wallet is blocked for debits"
+	ErrWalletClosed           = "Wallet is closed"
+	ErrWalletNotFound         = "wallet not found"
+	ErrNotJustified           = "A reason and a ticket are required"
+	ErrBalanceNotSettled      = "The balance must be withdrawn before closing the account"
+	ErrInvalidQuote           = "The quote has expired, was used or does not match the request"
+	ErrInvalidBatch           = "Invalid batch"
+	ErrBatchNotFound          = "batch not found"
+	ErrInvalidPaymentRequest  = "Invalid payment request"
+	ErrPaymentRequestNotFound = "payment request not found"
+	ErrPaymentRequestClosed   = "The payment request is no longer pending"
+	ErrInvalidDetails         = "Invalid transaction details"
+	ErrInvalidRecipient       = "Invalid recipient"
+	ErrRecipientNotFound      = "recipient not found"
+	ErrSelfTransfer           = "Can't transfer to oneself"
+	ErrInvalidHandle          = "Invalid handle"
+	ErrHandleTaken            = "The handle is taken, please choose another one"
 )
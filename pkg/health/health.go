@@ -0,0 +1,111 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+var ErrDraining = errors.New("shutting down")
+
+// CheckFunc reports whether a dependency is usable. It must return once ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the outcome of a readiness probe.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Probe runs the readiness checks of the service's dependencies.
+type Probe struct {
+	timeout  time.Duration
+	checks   []check
+	draining atomic.Bool
+}
+
+// New returns a probe whose checks each get at most timeout to complete.
+func New(timeout time.Duration) *Probe {
+	return &Probe{timeout: timeout}
+}
+
+// Add registers a named check. It must be called before the probe is used.
+func (p *Probe) Add(name string, fn CheckFunc) {
+	p.checks = append(p.checks, check{name: name, fn: fn})
+}
+
+// Drain marks the service as shutting down; from then on it is never ready.
+func (p *Probe) Drain() {
+	p.draining.Store(true)
+}
+
+func (p *Probe) Draining() bool {
+	return p.draining.Load()
+}
+
+// Ready runs every check concurrently and reports whether all of them passed.
+func (p *Probe) Ready(ctx context.Context) (*Report, bool) {
+	report := &Report{Status: StatusUp, Checks: make(map[string]Result, len(p.checks))}
+
+	if p.Draining() {
+		report.Status = StatusDown
+		report.Checks["shutdown"] = Result{Status: StatusDown, Error: ErrDraining.Error()}
+		return report, false
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range p.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+
+			res := p.run(ctx, c)
+
+			mu.Lock()
+			report.Checks[c.name] = res
+			if res.Status != StatusUp {
+				report.Status = StatusDown
+			}
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	return report, report.Status == StatusUp
+}
+
+func (p *Probe) run(ctx context.Context, c check) Result {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	res := Result{Status: StatusUp, LatencyMS: time.Since(start).Milliseconds()}
+
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+
+	return res
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestProbe_Ready(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("All up", func(t *testing.T) {
+		p := New(time.Second)
+		p.Add("postgres", up)
+		p.Add("redis", up)
+
+		report, ok := p.Ready(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, StatusUp, report.Status)
+		assert.Equal(t, StatusUp, report.Checks["postgres"].Status)
+		assert.Equal(t, StatusUp, report.Checks["redis"].Status)
+	})
+
+	t.Run("One down", func(t *testing.T) {
+		p := New(time.Second)
+		p.Add("postgres", up)
+		p.Add("redis", down)
+
+		report, ok := p.Ready(ctx)
+		assert.False(t, ok)
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, StatusUp, report.Checks["postgres"].Status)
+		assert.Equal(t, "connection refused", report.Checks["redis"].Error)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		p := New(10 * time.Millisecond)
+		p.Add("postgres", slow)
+
+		report, ok := p.Ready(ctx)
+		assert.False(t, ok)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["postgres"].Error)
+	})
+
+	t.Run("Draining", func(t *testing.T) {
+		p := New(time.Second)
+		p.Add("postgres", up)
+		p.Drain()
+
+		report, ok := p.Ready(ctx)
+		assert.False(t, ok)
+		assert.True(t, p.Draining())
+		assert.Equal(t, StatusDown, report.Checks["shutdown"].Status)
+		assert.NotContains(t, report.Checks, "postgres")
+	})
+}
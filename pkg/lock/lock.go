@@ -0,0 +1,188 @@
+// Package lock provides exclusive locks shared by every instance of the service, held in Redis
+// or as Postgres advisory locks. Each acquisition gets a fencing token, larger than any token
+// handed out before, so that the store can reject writes from a holder whose lock has expired.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"server/pkg/metrics"
+)
+
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+const (
+	minBackoff = 5 * time.Millisecond
+	maxBackoff = 100 * time.Millisecond
+)
+
+// Results of an acquisition, used as the metrics label.
+const (
+	resultAcquired = "acquired"
+	resultTimeout  = "timeout"
+	resultError    = "error"
+)
+
+// Fence returns the next fencing token.
+type Fence func(ctx context.Context) (int64, error)
+
+// Options configure a Locker.
+type Options struct {
+	Wait time.Duration // how long Lock waits for busy locks
+	TTL  time.Duration // how long a Redis lock lives if it is never released
+}
+
+// Locker takes exclusive locks on keys.
+type Locker interface {
+	// Lock takes the locks of keys, in a fixed order so that overlapping sets can't deadlock,
+	// and waits at most Options.Wait for them.
+	Lock(ctx context.Context, keys ...string) (*Lease, error)
+}
+
+// Lease is a set of locks held together.
+type Lease struct {
+	Token int64 // fencing token of this acquisition, 0 when fencing is off
+
+	backend    string
+	acquiredAt time.Time
+	releases   []release
+	once       sync.Once
+	err        error
+}
+
+// Unlock releases the locks. Only the first call does anything.
+func (l *Lease) Unlock(ctx context.Context) error {
+	l.once.Do(func() {
+		l.err = releaseAll(ctx, l.releases)
+		if l.backend != "" {
+			metrics.LockHeldSeconds.WithLabelValues(l.backend).Observe(time.Since(l.acquiredAt).Seconds())
+		}
+	})
+
+	return l.err
+}
+
+type release func(ctx context.Context) error
+
+func releaseAll(ctx context.Context, releases []release) error {
+	var errs []error
+	for i := len(releases) - 1; i >= 0; i-- {
+		errs = append(errs, releases[i](ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// backend takes a single lock without waiting.
+type backend interface {
+	name() string
+	try(ctx context.Context, key string) (release, bool, error)
+}
+
+type locker struct {
+	backend backend
+	fence   Fence
+	opts    Options
+}
+
+func (l *locker) Lock(ctx context.Context, keys ...string) (lease *Lease, err error) {
+	start := time.Now()
+	defer func() {
+		result := resultAcquired
+		switch {
+		case errors.Is(err, ErrTimeout):
+			result = resultTimeout
+		case err != nil:
+			result = resultError
+		}
+		metrics.LockWaitSeconds.WithLabelValues(l.backend.name(), result).Observe(time.Since(start).Seconds())
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, l.opts.Wait)
+	defer cancel()
+
+	var releases []release
+	for _, key := range sortedUnique(keys) {
+		rel, err := l.acquire(waitCtx, key)
+		if err != nil {
+			_ = releaseAll(context.WithoutCancel(ctx), releases)
+			return nil, err
+		}
+		releases = append(releases, rel)
+	}
+
+	token, err := l.fence(ctx)
+	if err != nil {
+		_ = releaseAll(context.WithoutCancel(ctx), releases)
+		return nil, fmt.Errorf("fencing token: %w", err)
+	}
+
+	return &Lease{Token: token, backend: l.backend.name(), acquiredAt: time.Now(), releases: releases}, nil
+}
+
+// acquire retries a busy lock with a jittered exponential backoff until ctx is done.
+func (l *locker) acquire(ctx context.Context, key string) (release, error) {
+	backoff := minBackoff
+	contended := false
+
+	for {
+		rel, ok, err := l.backend.try(ctx, key)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w %s", ErrTimeout, key)
+			}
+			return nil, err
+		}
+
+		if ok {
+			return rel, nil
+		}
+
+		if !contended {
+			contended = true
+			metrics.LockContended.WithLabelValues(l.backend.name()).Inc()
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w %s", ErrTimeout, key)
+			}
+			return nil, ctx.Err()
+		case <-time.After(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func sortedUnique(keys []string) []string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+
+	unique := sorted[:0]
+	for i, key := range sorted {
+		if i == 0 || key != sorted[i-1] {
+			unique = append(unique, key)
+		}
+	}
+
+	return unique
+}
+
+type nopLocker struct{}
+
+// Nop returns a locker that never blocks and hands out no fencing token, for a single instance.
+func Nop() Locker {
+	return nopLocker{}
+}
+
+func (nopLocker) Lock(context.Context, ...string) (*Lease, error) {
+	return &Lease{}, nil
+}
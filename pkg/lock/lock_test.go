@@ -0,0 +1,184 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// counter is a Fence counting up from 1.
+func counter() Fence {
+	var n int64
+	return func(context.Context) (int64, error) {
+		n++
+		return n, nil
+	}
+}
+
+func TestRedisLocker(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	locker := NewRedis(rdb, counter(), Options{Wait: 50 * time.Millisecond, TTL: time.Minute})
+	ctx := context.Background()
+
+	lease, err := locker.Lock(ctx, "wallet:2", "wallet:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), lease.Token)
+	assert.True(t, mr.Exists("lock:wallet:1"))
+	assert.True(t, mr.Exists("lock:wallet:2"))
+
+	t.Run("Busy", func(t *testing.T) {
+		_, err := locker.Lock(ctx, "wallet:1")
+		require.ErrorIs(t, err, ErrTimeout)
+
+		// A partial acquisition is rolled back.
+		_, err = locker.Lock(ctx, "wallet:3", "wallet:2")
+		require.ErrorIs(t, err, ErrTimeout)
+		assert.False(t, mr.Exists("lock:wallet:3"))
+	})
+
+	t.Run("Unlock", func(t *testing.T) {
+		require.NoError(t, lease.Unlock(ctx))
+		require.NoError(t, lease.Unlock(ctx))
+		assert.False(t, mr.Exists("lock:wallet:1"))
+
+		next, err := locker.Lock(ctx, "wallet:1")
+		require.NoError(t, err)
+		assert.Greater(t, next.Token, lease.Token)
+		require.NoError(t, next.Unlock(ctx))
+	})
+
+	t.Run("Expired lock is not released by its old holder", func(t *testing.T) {
+		old, err := locker.Lock(ctx, "wallet:1")
+		require.NoError(t, err)
+
+		mr.FastForward(time.Minute)
+		current, err := locker.Lock(ctx, "wallet:1")
+		require.NoError(t, err)
+		assert.Greater(t, current.Token, old.Token)
+
+		require.NoError(t, old.Unlock(ctx))
+		assert.True(t, mr.Exists("lock:wallet:1"))
+		require.NoError(t, current.Unlock(ctx))
+	})
+
+	t.Run("Waits for release", func(t *testing.T) {
+		held, err := locker.Lock(ctx, "wallet:1")
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			time.Sleep(10 * time.Millisecond)
+			_ = held.Unlock(ctx)
+		}()
+
+		waiter := NewRedis(rdb, counter(), Options{Wait: time.Second, TTL: time.Minute})
+		lease, err := waiter.Lock(ctx, "wallet:1")
+		<-done
+		require.NoError(t, err)
+		require.NoError(t, lease.Unlock(ctx))
+	})
+}
+
+func TestPostgresLocker(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+	key := advisoryKey("wallet:1")
+
+	newLocker := func(t *testing.T) (Locker, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+
+		return NewPostgres(db, counter(), Options{Wait: 50 * time.Millisecond}), mock
+	}
+
+	t.Run("Lock and unlock", func(t *testing.T) {
+		locker, mock := newLocker(t)
+		mock.ExpectQuery(regexp.QuoteMeta(queryTryAdvisoryLock)).WithArgs(key).
+			WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(queryAdvisoryUnlock)).WithArgs(key).
+			WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+
+		lease, err := locker.Lock(ctx, "wallet:1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), lease.Token)
+		require.NoError(t, lease.Unlock(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Busy", func(t *testing.T) {
+		locker, mock := newLocker(t)
+		for range 20 {
+			mock.ExpectQuery(regexp.QuoteMeta(queryTryAdvisoryLock)).WithArgs(key).
+				WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(false))
+		}
+
+		_, err := locker.Lock(ctx, "wallet:1")
+		require.ErrorIs(t, err, ErrTimeout)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		locker, mock := newLocker(t)
+		mock.ExpectQuery(regexp.QuoteMeta(queryTryAdvisoryLock)).WithArgs(key).
+			WillReturnError(errors.New("db down"))
+
+		_, err := locker.Lock(ctx, "wallet:1")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrTimeout)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLocker_FenceError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	fence := func(context.Context) (int64, error) { return 0, errors.New("db down") }
+	locker := NewRedis(rdb, fence, Options{Wait: time.Second, TTL: time.Minute})
+
+	_, err = locker.Lock(context.Background(), "wallet:1")
+	require.Error(t, err)
+	assert.False(t, mr.Exists("lock:wallet:1"))
+}
+
+func TestNop(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	lease, err := Nop().Lock(context.Background(), "wallet:1")
+	require.NoError(t, err)
+	assert.Zero(t, lease.Token)
+	assert.NoError(t, lease.Unlock(context.Background()))
+}
+
+func TestSortedUnique(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	keys := []string{"wallet:2", "wallet:1", "wallet:2"}
+	assert.Equal(t, []string{"wallet:1", "wallet:2"}, sortedUnique(keys))
+	assert.Equal(t, []string{"wallet:2", "wallet:1", "wallet:2"}, keys)
+}
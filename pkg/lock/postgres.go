@@ -0,0 +1,61 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"hash/fnv"
+)
+
+const (
+	queryTryAdvisoryLock = `SELECT pg_try_advisory_lock($1)`
+	queryAdvisoryUnlock  = `SELECT pg_advisory_unlock($1)`
+)
+
+type postgresBackend struct {
+	db *sql.DB
+}
+
+// NewPostgres returns a locker holding its locks as Postgres session advisory locks. Each lock
+// keeps a connection of db until it is released, and goes away with it if the holder dies.
+func NewPostgres(db *sql.DB, fence Fence, opts Options) Locker {
+	return &locker{backend: &postgresBackend{db: db}, fence: fence, opts: opts}
+}
+
+func (p *postgresBackend) name() string {
+	return "postgres"
+}
+
+func (p *postgresBackend) try(ctx context.Context, key string) (release, bool, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	id := advisoryKey(key)
+
+	var ok bool
+	if err = conn.QueryRowContext(ctx, queryTryAdvisoryLock, id).Scan(&ok); err != nil || !ok {
+		return nil, false, errors.Join(err, conn.Close())
+	}
+
+	return func(ctx context.Context) error {
+		var unlocked bool
+		err := conn.QueryRowContext(ctx, queryAdvisoryUnlock, id).Scan(&unlocked)
+		if err != nil || !unlocked {
+			// The session may still hold the lock, so it must not go back to the pool.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+
+		return errors.Join(err, conn.Close())
+	}, true, nil
+}
+
+// advisoryKey maps a key onto the bigint space of advisory locks.
+func advisoryKey(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return int64(h.Sum64())
+}
@@ -0,0 +1,47 @@
+package lock
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "lock:"
+
+// unlockScript deletes a lock only if it is still held by the caller.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type redisBackend struct {
+	rdb  redis.UniversalClient
+	opts Options
+}
+
+// NewRedis returns a locker holding its locks in Redis. A lock expires after Options.TTL if its
+// holder dies, which is when the fencing token matters.
+func NewRedis(rdb redis.UniversalClient, fence Fence, opts Options) Locker {
+	return &locker{backend: &redisBackend{rdb: rdb, opts: opts}, fence: fence, opts: opts}
+}
+
+func (r *redisBackend) name() string {
+	return "redis"
+}
+
+func (r *redisBackend) try(ctx context.Context, key string) (release, bool, error) {
+	key = redisKeyPrefix + key
+	owner := uuid.NewString()
+
+	ok, err := r.rdb.SetNX(ctx, key, owner, r.opts.TTL).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	return func(ctx context.Context) error {
+		return unlockScript.Run(ctx, r.rdb, []string{key}, owner).Err()
+	}, true, nil
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx that carries l, typically a logger scoped to one request.
+func WithContext(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or fallback when there is none.
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+
+	return fallback
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestFromContext(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	fallback := zap.NewNop().Sugar()
+	scoped := zap.NewExample().Sugar()
+
+	assert.Same(t, fallback, FromContext(context.Background(), fallback))
+	assert.Same(t, scoped, FromContext(WithContext(context.Background(), scoped), fallback))
+}
+
+func TestRedactEmail(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		email    string
+		expected string
+	}{
+		{"alice@example.com", "a***@example.com"},
+		{"a@b.c", "a***@b.c"},
+		{"@example.com", "[REDACTED]"},
+		{"not-an-email", "[REDACTED]"},
+		{"", "[REDACTED]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RedactEmail(tt.email))
+		})
+	}
+
+	assert.Equal(t, "[REDACTED]", Redact("$2a$10$hash"))
+}
@@ -14,9 +14,12 @@ import (
 
 var Logger *zap.SugaredLogger
 
+// Level is the minimum level written by Logger. It can be changed while the service runs.
+var Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+
 func initConf(filepath, callerLoc string) *zap.Config {
 	return &zap.Config{
-		Level:       zap.NewAtomicLevelAt(zap.DebugLevel),
+		Level:       Level,
 		Development: true,
 		Encoding:    "json",
 		EncoderConfig: zapcore.EncoderConfig{
@@ -52,15 +55,15 @@ func newZapLogger(logFilepath, infoFilename, warnFilename, errFilename, fileExt
 	encoder := getEncoder(cfg)
 
 	infoLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl < zapcore.WarnLevel
+		return Level.Enabled(lvl) && lvl < zapcore.WarnLevel
 	})
 
 	warnLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl == zapcore.WarnLevel
+		return Level.Enabled(lvl) && lvl == zapcore.WarnLevel
 	})
 
 	errLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl > zapcore.WarnLevel
+		return Level.Enabled(lvl) && lvl > zapcore.WarnLevel
 	})
 
 	infoWriter, err := getLogWriter(logFilepath+"/"+infoFilename, fileExt)
@@ -0,0 +1,21 @@
+package logger
+
+import "strings"
+
+const redacted = "[REDACTED]"
+
+// Redact hides a secret such as a password hash or a token entirely.
+func Redact(string) string {
+	return redacted
+}
+
+// RedactEmail keeps the first character of the local part and the domain,
+// which is enough to tell addresses apart in logs without exposing them.
+func RedactEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return redacted
+	}
+
+	return email[:1] + "***" + email[at:]
+}
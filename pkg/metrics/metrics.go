@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shopspring/decimal"
+)
+
+const namespace = "wallet"
+
+// Registry holds every metric of the service. It is separate from the prometheus default
+// registry so that only what is registered here is exposed on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	MoneyOperations = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "money_operations_total",
+		Help:      "Successful money movements by operation.",
+	}, []string{"operation"})
+
+	MoneyAmount = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "money_amount_total",
+		Help:      "Amount moved by successful money movements, by operation.",
+	}, []string{"operation"})
+
+	MoneyFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "money_failures_total",
+		Help:      "Failed money movements by operation and reason.",
+	}, []string{"operation", "reason"})
+
+	BalanceTotal = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "balance_total",
+		Help:      "Sum of all wallet balances under management.",
+	})
+
+	Wallets = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wallets",
+		Help:      "Number of wallets.",
+	})
+
+	RateLimited = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by the rate limiter, by the limit they hit: ip, uid or route.",
+	}, []string{"scope"})
+
+	CacheRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups by cache and result: hit, miss or error.",
+	}, []string{"cache", "result"})
+
+	LockWaitSeconds = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lock_wait_seconds",
+		Help:      "Time spent taking wallet locks by backend and result: acquired, timeout or error.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"backend", "result"})
+
+	LockContended = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lock_contended_total",
+		Help:      "Wallet locks that were busy on the first attempt, by backend.",
+	}, []string{"backend"})
+
+	LockHeldSeconds = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lock_held_seconds",
+		Help:      "Time wallet locks were held, by backend.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"backend"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics in the prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveMoney records the outcome of a money movement. An empty reason means it succeeded.
+func ObserveMoney(operation string, amount decimal.Decimal, reason string) {
+	if reason != "" {
+		MoneyFailures.WithLabelValues(operation, reason).Inc()
+		return
+	}
+
+	MoneyOperations.WithLabelValues(operation).Inc()
+	MoneyAmount.WithLabelValues(operation).Add(amount.InexactFloat64())
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestObserveMoney(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ObserveMoney("deposit", decimal.RequireFromString("12.5"), "")
+	ObserveMoney("deposit", decimal.RequireFromString("0.5"), "")
+	ObserveMoney("deposit", decimal.RequireFromString("7"), "balance_limit")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(MoneyOperations.WithLabelValues("deposit")))
+	assert.Equal(t, float64(13), testutil.ToFloat64(MoneyAmount.WithLabelValues("deposit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(MoneyFailures.WithLabelValues("deposit", "balance_limit")))
+}
+
+func TestHandler(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, RegisterDB(db, "postgres"))
+
+	BalanceTotal.Set(42)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/metrics", http.NoBody)
+	require.NoError(t, err)
+
+	Handler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "wallet_balance_total 42")
+	assert.Contains(t, w.Body.String(), `go_sql_open_connections{db_name="postgres"}`)
+}
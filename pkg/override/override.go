@@ -0,0 +1,212 @@
+// Package override sets the fields of a yaml configuration struct from environment variables
+// and command-line flags. A field is named after its yaml keys: db.max_open_conns is read from
+// the env var PREFIX_DB_MAX_OPEN_CONNS and from the flag --db.max_open_conns. A list of strings is
+// comma separated; other lists and maps are given whole as yaml or json, with the keys of the
+// config file, as in [{"operation": "withdraw", "rate": "0.01"}].
+package override
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type field struct {
+	path  []string
+	value reflect.Value
+}
+
+// EnvName returns the environment variable of the field at path.
+func EnvName(prefix string, path []string) string {
+	return prefix + strings.ToUpper(strings.Join(path, "_"))
+}
+
+// FlagName returns the command-line flag of the field at path.
+func FlagName(path []string) string {
+	return strings.Join(path, ".")
+}
+
+// Env sets every field of conf, a pointer to a struct, whose environment variable is set.
+func Env(conf any, prefix string, lookupEnv func(string) (string, bool)) error {
+	fields, err := collect(conf)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		name := EnvName(prefix, f.path)
+		s, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err = set(f.value, s); err != nil {
+			return fmt.Errorf("env %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Flags registers a flag on fs for every field of conf, a pointer to a struct. The values given
+// on the command line are kept aside until the returned func is called, so they can be applied
+// after the config file and the environment have been read.
+func Flags(fs *flag.FlagSet, conf any, prefix string) (func() error, error) {
+	fields, err := collect(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []func() error
+	for _, f := range fields {
+		name := FlagName(f.path)
+		fs.Func(name, "overrides "+name+", also "+EnvName(prefix, f.path), func(s string) error {
+			// Check the value now so a typo fails while parsing, not after the file is loaded.
+			if err := set(reflect.New(f.value.Type()).Elem(), s); err != nil {
+				return err
+			}
+
+			pending = append(pending, func() error { return set(f.value, s) })
+			return nil
+		})
+	}
+
+	apply := func() error {
+		for _, fn := range pending {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return apply, nil
+}
+
+func collect(conf any) ([]field, error) {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("override: %T is not a pointer to a struct", conf)
+	}
+
+	var fields []field
+	walk(v.Elem(), nil, &fields)
+
+	return fields, nil
+}
+
+func walk(v reflect.Value, path []string, fields *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		fieldPath := append(append([]string{}, path...), key)
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			walk(fv, fieldPath, fields)
+			continue
+		}
+
+		if settable(fv.Type()) {
+			*fields = append(*fields, field{path: fieldPath, value: fv})
+		}
+	}
+}
+
+func settable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+func set(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return setYAML(v, s)
+		}
+
+		// Lists of strings are comma separated; an empty value is an empty list.
+		list := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = reflect.Append(list, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(list)
+	case reflect.Map:
+		return setYAML(v, s)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// setYAML sets v, a list or a map, from s decoded as yaml, of which json is a subset. The whole
+// value is replaced; an empty s clears it.
+func setYAML(v reflect.Value, s string) error {
+	value := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(s), value.Interface()); err != nil {
+		return err
+	}
+
+	v.Set(value.Elem())
+	return nil
+}
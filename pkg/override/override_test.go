@@ -0,0 +1,156 @@
+package override
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+type testConf struct {
+	Name string `yaml:"name"`
+	DB   struct {
+		Port     int64         `yaml:"port"`
+		Timeout  time.Duration `yaml:"conn_timeout"`
+		Ratio    float64       `yaml:"ratio"`
+		Init     bool          `yaml:"init_table"`
+		Password string        `yaml:"password"`
+	} `yaml:"db"`
+	Hosts []string `yaml:"hosts"`
+	Ports []int    `yaml:"ports"`
+	Rules []struct {
+		Operation string        `yaml:"operation"`
+		Window    time.Duration `yaml:"window"`
+	} `yaml:"rules"`
+	Routes map[string]struct {
+		Limit int `yaml:"limit"`
+	} `yaml:"routes"`
+	Ignore string `yaml:"-"`
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		s, ok := vars[name]
+		return s, ok
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	assert.Equal(t, "WALLET_DB_MAX_OPEN_CONNS", EnvName("WALLET_", []string{"db", "max_open_conns"}))
+	assert.Equal(t, "db.max_open_conns", FlagName([]string{"db", "max_open_conns"}))
+}
+
+func TestEnv(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("Sets fields", func(t *testing.T) {
+		conf := &testConf{Name: "file"}
+		conf.DB.Password = "file"
+
+		err := Env(conf, "APP_", env(map[string]string{
+			"APP_DB_PORT":         "6543",
+			"APP_DB_CONN_TIMEOUT": "3s",
+			"APP_DB_RATIO":        "0.5",
+			"APP_DB_INIT_TABLE":   "true",
+			"APP_DB_PASSWORD":     "secret",
+			"APP_HOSTS":           "a, b,,c",
+		}))
+		require.NoError(t, err)
+
+		assert.Equal(t, "file", conf.Name)
+		assert.Equal(t, int64(6543), conf.DB.Port)
+		assert.Equal(t, 3*time.Second, conf.DB.Timeout)
+		assert.Equal(t, 0.5, conf.DB.Ratio)
+		assert.True(t, conf.DB.Init)
+		assert.Equal(t, "secret", conf.DB.Password)
+		assert.Equal(t, []string{"a", "b", "c"}, conf.Hosts)
+	})
+
+	t.Run("Sets lists and maps from yaml or json", func(t *testing.T) {
+		conf := &testConf{}
+
+		err := Env(conf, "APP_", env(map[string]string{
+			"APP_PORTS":  "[80, 443]",
+			"APP_RULES":  `[{"operation": "withdraw", "window": "1m"}]`,
+			"APP_ROUTES": "{POST /transfer: {limit: 5}}",
+		}))
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{80, 443}, conf.Ports)
+		require.Len(t, conf.Rules, 1)
+		assert.Equal(t, "withdraw", conf.Rules[0].Operation)
+		assert.Equal(t, time.Minute, conf.Rules[0].Window)
+		assert.Equal(t, 5, conf.Routes["POST /transfer"].Limit)
+	})
+
+	t.Run("Empty value clears", func(t *testing.T) {
+		conf := &testConf{Name: "file"}
+
+		require.NoError(t, Env(conf, "APP_", env(map[string]string{"APP_NAME": ""})))
+		assert.Equal(t, "", conf.Name)
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		conf := &testConf{}
+
+		err := Env(conf, "APP_", env(map[string]string{"APP_DB_PORT": "abc"}))
+		require.ErrorContains(t, err, "APP_DB_PORT")
+
+		err = Env(conf, "APP_", env(map[string]string{"APP_RULES": `[{"operation": `}))
+		require.ErrorContains(t, err, "APP_RULES")
+	})
+
+	t.Run("Not a struct pointer", func(t *testing.T) {
+		require.Error(t, Env(testConf{}, "APP_", env(nil)))
+	})
+}
+
+func TestFlags(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	newFlagSet := func() *flag.FlagSet {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		return fs
+	}
+
+	t.Run("Applied after the file", func(t *testing.T) {
+		conf := &testConf{}
+		fs := newFlagSet()
+		apply, err := Flags(fs, conf, "APP_")
+		require.NoError(t, err)
+
+		require.NoError(t, fs.Parse([]string{"--db.port=7000", "--name", "flag"}))
+		assert.Equal(t, int64(0), conf.DB.Port, "flags wait for apply")
+
+		conf.Name = "file"
+		require.NoError(t, apply())
+		assert.Equal(t, int64(7000), conf.DB.Port)
+		assert.Equal(t, "flag", conf.Name)
+	})
+
+	t.Run("Invalid value fails parsing", func(t *testing.T) {
+		fs := newFlagSet()
+		_, err := Flags(fs, &testConf{}, "APP_")
+		require.NoError(t, err)
+
+		require.Error(t, fs.Parse([]string{"--db.conn_timeout=soon"}))
+	})
+
+	t.Run("Ignored fields have no flag", func(t *testing.T) {
+		fs := newFlagSet()
+		_, err := Flags(fs, &testConf{}, "APP_")
+		require.NoError(t, err)
+
+		assert.Nil(t, fs.Lookup("ignore"))
+		assert.NotNil(t, fs.Lookup("db.password"))
+		assert.NotNil(t, fs.Lookup("ports"))
+		assert.NotNil(t, fs.Lookup("routes"))
+	})
+}
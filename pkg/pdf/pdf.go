@@ -0,0 +1,166 @@
+package pdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 portrait in points, with a fixed monospaced-looking text layout.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	margin       = 40
+	fontSize     = 8
+	leading      = 11
+	linesPerPage = (pageHeight - 2*margin) / leading
+)
+
+// Reserved object numbers; everything after them is allocated as pages are written.
+const (
+	objCatalog = 1
+	objPages   = 2
+	objFont    = 3
+)
+
+// Writer streams a text-only PDF document.
+// Each page is written out as soon as it is full, so memory use does not grow with
+// the document. Only the built-in Courier font is referenced, so nothing is fetched
+// or embedded and the output can be produced fully offline.
+type Writer struct {
+	w       *bufio.Writer
+	written int64
+	offsets map[int]int64
+	nextObj int
+	pages   []int
+	lines   []string
+	err     error
+}
+
+// NewWriter writes the PDF header and returns a Writer ready for lines.
+func NewWriter(w io.Writer) *Writer {
+	p := &Writer{
+		w:       bufio.NewWriter(w),
+		offsets: map[int]int64{},
+		nextObj: objFont + 1,
+	}
+
+	p.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	p.object(objFont, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	return p
+}
+
+// Line adds a line of text, starting a new page when the current one is full.
+func (p *Writer) Line(text string) error {
+	p.lines = append(p.lines, text)
+	if len(p.lines) == linesPerPage {
+		p.flushPage()
+	}
+
+	return p.err
+}
+
+// PageBreak starts a new page unless the current one is empty.
+func (p *Writer) PageBreak() error {
+	if len(p.lines) > 0 {
+		p.flushPage()
+	}
+
+	return p.err
+}
+
+// Close writes the last page, the page tree and the cross-reference table.
+// It does not close the underlying writer.
+func (p *Writer) Close() error {
+	if len(p.lines) > 0 || len(p.pages) == 0 {
+		p.flushPage()
+	}
+
+	kids := make([]string, 0, len(p.pages))
+	for _, id := range p.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+
+	p.object(objPages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	p.object(objCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", objPages))
+
+	xref := p.written
+	p.printf("xref\n0 %d\n0000000000 65535 f \n", p.nextObj)
+	for id := 1; id < p.nextObj; id++ {
+		p.printf("%010d 00000 n \n", p.offsets[id])
+	}
+	p.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", p.nextObj, objCatalog, xref)
+
+	if p.err != nil {
+		return p.err
+	}
+
+	return p.w.Flush()
+}
+
+func (p *Writer) flushPage() {
+	content := strings.Builder{}
+	fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, leading, margin, pageHeight-margin)
+	for _, line := range p.lines {
+		content.WriteString("(")
+		content.WriteString(escape(line))
+		content.WriteString(") Tj T*\n")
+	}
+	content.WriteString("ET")
+
+	contentID := p.alloc()
+	p.object(contentID, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+
+	pageID := p.alloc()
+	p.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] "+
+		"/Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+		objPages, pageWidth, pageHeight, objFont, contentID))
+
+	p.pages = append(p.pages, pageID)
+	p.lines = p.lines[:0]
+}
+
+func (p *Writer) alloc() int {
+	id := p.nextObj
+	p.nextObj++
+
+	return id
+}
+
+func (p *Writer) object(id int, body string) {
+	p.offsets[id] = p.written
+	p.printf("%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (p *Writer) printf(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(p.w, format, args...)
+	p.written += int64(n)
+	p.err = err
+}
+
+// escape makes text safe inside a PDF string literal. Characters outside
+// WinAnsi's Latin-1 range cannot be shown by a base font and become "?".
+func escape(text string) string {
+	sb := strings.Builder{}
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r >= ' ' && r <= '~':
+			sb.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&sb, "\\%03o", r)
+		default:
+			sb.WriteByte('?')
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestWriter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("Empty document has one page", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, NewWriter(buf).Close())
+
+		assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.4")))
+		assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("%%EOF\n")))
+		assert.Contains(t, buf.String(), "/Count 1")
+	})
+
+	t.Run("Lines spill onto new pages", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w := NewWriter(buf)
+		for i := 0; i < linesPerPage*2+1; i++ {
+			require.NoError(t, w.Line(fmt.Sprintf("line %d", i)))
+		}
+		require.NoError(t, w.Close())
+
+		assert.Contains(t, buf.String(), "/Count 3")
+	})
+
+	t.Run("Cross-reference offsets point at objects", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w := NewWriter(buf)
+		require.NoError(t, w.Line("hello"))
+		require.NoError(t, w.PageBreak())
+		require.NoError(t, w.Line("world"))
+		require.NoError(t, w.Close())
+
+		out := buf.Bytes()
+		entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out, -1)
+		require.Len(t, entries, 7)
+
+		for i, entry := range entries {
+			offset, err := strconv.Atoi(string(entry[1]))
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(out[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))))
+		}
+	})
+}
+
+func TestEscape(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	assert.Equal(t, `a\(b\)\\c`, escape(`a(b)\c`))
+	assert.Equal(t, `caf\351`, escape("café"))
+	assert.Equal(t, "?", escape("张"))
+}
@@ -0,0 +1,146 @@
+// Package ratelimit counts requests in fixed windows, in Redis so every instance of the
+// service shares the same counters, or in memory when Redis cannot be reached.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const keyPrefix = "ratelimit:"
+
+// Result is the outcome of counting one request.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // time until the window resets
+}
+
+// Limiter allows at most limit requests per window for a key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+func result(count int64, limit int, ttl time.Duration) Result {
+	remaining := limit - int(count)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{Allowed: count <= int64(limit), Remaining: remaining, RetryAfter: ttl}
+}
+
+// allowScript increments the counter of a key and starts its window on the first request.
+// It returns the count and the milliseconds left in the window.
+var allowScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+type redisLimiter struct {
+	rdb redis.UniversalClient
+}
+
+// NewRedis returns a limiter whose counters live in Redis.
+func NewRedis(rdb redis.UniversalClient) Limiter {
+	return &redisLimiter{rdb: rdb}
+}
+
+func (r *redisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	res, err := allowScript.Run(ctx, r.rdb, []string{keyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(res[0], limit, time.Duration(res[1])*time.Millisecond), nil
+}
+
+type counter struct {
+	count   int64
+	resetAt time.Time
+}
+
+type memoryLimiter struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	now      func() time.Time
+	swept    time.Time
+}
+
+// NewMemory returns a limiter whose counters live in this process only.
+func NewMemory() Limiter {
+	return &memoryLimiter{counters: make(map[string]*counter), now: time.Now}
+}
+
+func (m *memoryLimiter) Allow(_ context.Context, key string, limit int, window time.Duration) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now, window)
+
+	c, ok := m.counters[key]
+	if !ok || !now.Before(c.resetAt) {
+		c = &counter{resetAt: now.Add(window)}
+		m.counters[key] = c
+	}
+	c.count++
+
+	return result(c.count, limit, c.resetAt.Sub(now)), nil
+}
+
+// sweep drops the expired counters, at most once per window, so idle keys don't pile up.
+func (m *memoryLimiter) sweep(now time.Time, window time.Duration) {
+	if now.Sub(m.swept) < window {
+		return
+	}
+	m.swept = now
+
+	for key, c := range m.counters {
+		if !now.Before(c.resetAt) {
+			delete(m.counters, key)
+		}
+	}
+}
+
+type fallbackLimiter struct {
+	primary   Limiter
+	secondary Limiter
+	logger    *zap.SugaredLogger
+	failing   atomic.Bool
+}
+
+// NewFallback returns a limiter that uses primary and switches to secondary for as long as
+// primary fails, logging when that starts and stops.
+func NewFallback(primary, secondary Limiter, logger *zap.SugaredLogger) Limiter {
+	return &fallbackLimiter{primary: primary, secondary: secondary, logger: logger}
+}
+
+func (f *fallbackLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	res, err := f.primary.Allow(ctx, key, limit, window)
+	if err == nil {
+		if f.failing.CompareAndSwap(true, false) {
+			f.logger.Infow("rate limiter recovered")
+		}
+		return res, nil
+	}
+
+	if f.failing.CompareAndSwap(false, true) {
+		f.logger.Warnw("rate limiter failed, falling back", "error", err)
+	}
+
+	return f.secondary.Allow(ctx, key, limit, window)
+}
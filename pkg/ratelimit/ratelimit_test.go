@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestRedisLimiter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	limiter := NewRedis(rdb)
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		res, err := limiter.Allow(ctx, "ip:1.2.3.4", 2, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res, err := limiter.Allow(ctx, "ip:1.2.3.4", 2, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.InDelta(t, time.Minute, res.RetryAfter, float64(time.Second))
+
+	// Other keys have their own counter.
+	res, err = limiter.Allow(ctx, "ip:5.6.7.8", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// A new window starts once the old one expires.
+	mr.FastForward(time.Minute)
+	res, err = limiter.Allow(ctx, "ip:1.2.3.4", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemory().(*memoryLimiter)
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	res, err := limiter.Allow(ctx, "uid:1", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	now = now.Add(20 * time.Second)
+	res, err = limiter.Allow(ctx, "uid:1", 1, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 40*time.Second, res.RetryAfter)
+
+	now = now.Add(40 * time.Second)
+	res, err = limiter.Allow(ctx, "uid:1", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// Expired counters are dropped.
+	now = now.Add(2 * time.Minute)
+	_, err = limiter.Allow(ctx, "uid:2", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, limiter.counters, 1)
+}
+
+type failingLimiter struct{ err error }
+
+func (f *failingLimiter) Allow(context.Context, string, int, time.Duration) (Result, error) {
+	return Result{Allowed: f.err == nil}, f.err
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	primary := &failingLimiter{err: errors.New("redis: connection refused")}
+	limiter := NewFallback(primary, NewMemory(), zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	res, err := limiter.Allow(ctx, "ip:1.2.3.4", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, err = limiter.Allow(ctx, "ip:1.2.3.4", 1, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed, "the fallback still limits")
+
+	primary.err = nil
+	res, err = limiter.Allow(ctx, "ip:1.2.3.4", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "back on the primary")
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"server/pkg/tracing"
+)
+
+// Func is a unit of background work. It should return promptly once ctx is done.
+type Func func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Func
+}
+
+// Scheduler runs jobs on fixed intervals in their own goroutines.
+// A job never overlaps with itself: the next run starts an interval after the previous one began,
+// or right after it finished if it took longer than that.
+type Scheduler struct {
+	logger *zap.SugaredLogger
+	jobs   []job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(logger *zap.SugaredLogger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Every registers fn to run once at start and then every interval.
+// It must be called before Start.
+func (s *Scheduler) Every(name string, interval time.Duration, fn Func) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, fn: fn})
+}
+
+// Start launches every registered job.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+}
+
+// Stop cancels the running jobs and waits for them to return.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, j)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j job) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.logger.Errorf("job %s panicked: %v", j.name, p)
+		}
+	}()
+
+	ctx, span := tracing.Start(ctx, "job "+j.name)
+	logger := tracing.WithTrace(ctx, s.logger)
+
+	start := time.Now()
+	err := j.fn(ctx)
+	tracing.End(span, err)
+
+	if err != nil {
+		logger.Errorf("job %s failed after %s: %v", j.name, time.Since(start), err)
+		return
+	}
+
+	logger.Infof("job %s finished in %s", j.name, time.Since(start))
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestScheduler(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("Runs jobs until stopped", func(t *testing.T) {
+		s := New(zap.NewNop().Sugar())
+
+		var ok, failed, panicked atomic.Int32
+		s.Every("ok", time.Millisecond, func(context.Context) error {
+			ok.Add(1)
+			return nil
+		})
+		s.Every("failed", time.Millisecond, func(context.Context) error {
+			failed.Add(1)
+			return errors.New("boom")
+		})
+		s.Every("panicked", time.Millisecond, func(context.Context) error {
+			panicked.Add(1)
+			panic("boom")
+		})
+
+		s.Start()
+		assert.Eventually(t, func() bool {
+			return ok.Load() > 2 && failed.Load() > 2 && panicked.Load() > 2
+		}, time.Second, time.Millisecond)
+		s.Stop()
+
+		runs := ok.Load()
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(t, runs, ok.Load())
+	})
+
+	t.Run("Stop cancels the job context", func(t *testing.T) {
+		s := New(zap.NewNop().Sugar())
+
+		started := make(chan struct{})
+		s.Every("blocking", time.Hour, func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		s.Start()
+		<-started
+		s.Stop()
+	})
+
+	t.Run("Stop without Start", func(_ *testing.T) {
+		New(zap.NewNop().Sugar()).Stop()
+	})
+}
@@ -0,0 +1,107 @@
+package sqlbuilder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Builder assembles a SELECT statement out of fixed SQL fragments and bound arguments.
+// Callers only ever pass SQL written in code; every value goes through a placeholder,
+// so request input never ends up in the statement text.
+//
+// Fragments use "?" for placeholders, which Build rewrites to PostgreSQL's $n form.
+type Builder struct {
+	base    string
+	where   []string
+	orderBy []string
+	tail    []string
+	args    []any
+}
+
+// New starts a statement from a SELECT ... FROM ... fragment.
+func New(base string) *Builder {
+	return &Builder{base: base}
+}
+
+// Where adds a condition joined to the others with AND.
+func (b *Builder) Where(cond string, args ...any) *Builder {
+	b.where = append(b.where, "("+cond+")")
+	b.args = append(b.args, args...)
+
+	return b
+}
+
+// WhereIn adds "column IN (...)" with one placeholder per value.
+// An empty list matches nothing, the same as an empty IN list would.
+func (b *Builder) WhereIn(column string, values ...any) *Builder {
+	if len(values) == 0 {
+		return b.Where("FALSE")
+	}
+
+	return b.Where(column+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")+")", values...)
+}
+
+// OrderBy appends ORDER BY terms.
+func (b *Builder) OrderBy(terms ...string) *Builder {
+	b.orderBy = append(b.orderBy, terms...)
+
+	return b
+}
+
+// Limit bounds the number of returned rows.
+func (b *Builder) Limit(n int) *Builder {
+	b.tail = append(b.tail, "LIMIT ?")
+	b.args = append(b.args, n)
+
+	return b
+}
+
+// Offset skips the first n rows.
+func (b *Builder) Offset(n int) *Builder {
+	b.tail = append(b.tail, "OFFSET ?")
+	b.args = append(b.args, n)
+
+	return b
+}
+
+// Build returns the statement and its arguments in placeholder order.
+func (b *Builder) Build() (string, []any) {
+	sb := strings.Builder{}
+	sb.WriteString(b.base)
+
+	if len(b.where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(b.where, " AND "))
+	}
+
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+
+	for _, t := range b.tail {
+		sb.WriteString(" ")
+		sb.WriteString(t)
+	}
+
+	return numberPlaceholders(sb.String()), b.args
+}
+
+// numberPlaceholders rewrites each "?" to $1, $2, ... in order.
+func numberPlaceholders(query string) string {
+	sb := strings.Builder{}
+	n := 0
+
+	for _, r := range query {
+		if r != '?' {
+			sb.WriteRune(r)
+			continue
+		}
+
+		n++
+		sb.WriteString("$")
+		sb.WriteString(strconv.Itoa(n))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,39 @@
+package sqlbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestBuilder(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("BaseOnly", func(t *testing.T) {
+		query, args := New("SELECT id FROM t").Build()
+		assert.Equal(t, "SELECT id FROM t", query)
+		assert.Empty(t, args)
+	})
+
+	t.Run("AllClauses", func(t *testing.T) {
+		query, args := New("SELECT id FROM t").
+			Where("a = ? OR b = ?", 1, 1).
+			WhereIn("c", 2, 3).
+			Where("d >= ?", "x").
+			OrderBy("id DESC", "c ASC").
+			Limit(10).
+			Offset(20).
+			Build()
+
+		assert.Equal(t, "SELECT id FROM t WHERE (a = $1 OR b = $2) AND (c IN ($3, $4)) AND (d >= $5) "+
+			"ORDER BY id DESC, c ASC LIMIT $6 OFFSET $7", query)
+		assert.Equal(t, []any{1, 1, 2, 3, "x", 10, 20}, args)
+	})
+
+	t.Run("EmptyIn", func(t *testing.T) {
+		query, args := New("SELECT id FROM t").WhereIn("c").Build()
+		assert.Equal(t, "SELECT id FROM t WHERE (FALSE)", query)
+		assert.Empty(t, args)
+	})
+}
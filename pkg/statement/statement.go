@@ -0,0 +1,249 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"server/pkg/pdf"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatPDF   = "pdf"
+)
+
+const dateFormat = "2006-01-02"
+
+// flushEvery bounds how many rows are buffered before they are pushed to the client.
+const flushEvery = 100
+
+var ErrUnknownFormat = errors.New("unknown statement format")
+
+var contentTypeMap = map[string]string{
+	FormatCSV:   "text/csv; charset=utf-8",
+	FormatJSONL: "application/x-ndjson",
+	FormatPDF:   "application/pdf",
+}
+
+// ContentType returns the MIME type of a format, or an empty string if the format does not exist.
+func ContentType(format string) string {
+	return contentTypeMap[format]
+}
+
+// Header opens a statement.
+type Header struct {
+	UID            int64
+	From           time.Time
+	To             time.Time
+	OpeningBalance decimal.Decimal
+}
+
+// Row is one transaction line. Amount is signed from the wallet's point of view
+// and Balance is the running balance after the transaction.
+type Row struct {
+	ID        int64
+	CreatedAt time.Time
+	Type      string
+	Sender    string
+	Receiver  string
+	Amount    decimal.Decimal
+	Balance   decimal.Decimal
+}
+
+// Footer closes a statement.
+type Footer struct {
+	ClosingBalance decimal.Decimal
+	Count          int
+}
+
+// Writer renders a statement as it is streamed: Begin once, Row per transaction, End once.
+type Writer interface {
+	Begin(header *Header) error
+	Row(row *Row) error
+	End(footer *Footer) error
+}
+
+// NewWriter returns a Writer for the given format.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSONL:
+		buf := bufio.NewWriter(w)
+		return &jsonlWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case FormatPDF:
+		return &pdfWriter{w: pdf.NewWriter(w)}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvWriter struct {
+	w     *csv.Writer
+	count int
+}
+
+func (c *csvWriter) Begin(header *Header) error {
+	_ = c.w.Write([]string{"id", "created_at", "type", "sender", "receiver", "amount", "balance"})
+	_ = c.w.Write([]string{"", header.From.Format(time.RFC3339), "opening_balance", "", "", "",
+		header.OpeningBalance.StringFixed(2)})
+
+	return c.w.Error()
+}
+
+func (c *csvWriter) Row(row *Row) error {
+	_ = c.w.Write([]string{strconv.FormatInt(row.ID, 10), row.CreatedAt.Format(time.RFC3339), row.Type,
+		row.Sender, row.Receiver, row.Amount.StringFixed(2), row.Balance.StringFixed(2)})
+
+	c.count++
+	if c.count%flushEvery == 0 {
+		c.w.Flush()
+	}
+
+	return c.w.Error()
+}
+
+func (c *csvWriter) End(footer *Footer) error {
+	_ = c.w.Write([]string{"", "", "closing_balance", "", "", "", footer.ClosingBalance.StringFixed(2)})
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+type jsonlWriter struct {
+	buf   *bufio.Writer
+	enc   *json.Encoder
+	count int
+}
+
+type jsonlHeader struct {
+	Record  string          `json:"record"`
+	UID     int64           `json:"uid"`
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+type jsonlRow struct {
+	Record    string          `json:"record"`
+	ID        int64           `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Type      string          `json:"type"`
+	Sender    string          `json:"sender"`
+	Receiver  string          `json:"receiver"`
+	Amount    decimal.Decimal `json:"amount"`
+	Balance   decimal.Decimal `json:"balance"`
+}
+
+type jsonlFooter struct {
+	Record  string          `json:"record"`
+	Count   int             `json:"count"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+func (j *jsonlWriter) Begin(header *Header) error {
+	return j.enc.Encode(jsonlHeader{
+		Record:  "opening",
+		UID:     header.UID,
+		From:    header.From,
+		To:      header.To,
+		Balance: header.OpeningBalance,
+	})
+}
+
+func (j *jsonlWriter) Row(row *Row) error {
+	err := j.enc.Encode(jsonlRow{
+		Record:    "transaction",
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		Type:      row.Type,
+		Sender:    row.Sender,
+		Receiver:  row.Receiver,
+		Amount:    row.Amount,
+		Balance:   row.Balance,
+	})
+	if err != nil {
+		return err
+	}
+
+	j.count++
+	if j.count%flushEvery == 0 {
+		return j.buf.Flush()
+	}
+
+	return nil
+}
+
+func (j *jsonlWriter) End(footer *Footer) error {
+	err := j.enc.Encode(jsonlFooter{Record: "closing", Count: footer.Count, Balance: footer.ClosingBalance})
+	if err != nil {
+		return err
+	}
+
+	return j.buf.Flush()
+}
+
+type pdfWriter struct {
+	w *pdf.Writer
+}
+
+const pdfRowFormat = "%-8s %-19s %-9s %-16s %-16s %13s %13s"
+
+func (p *pdfWriter) Begin(header *Header) error {
+	lines := []string{
+		"Wallet statement",
+		"",
+		fmt.Sprintf("UID:              %d", header.UID),
+		fmt.Sprintf("Period:           %s - %s", header.From.Format(dateFormat), header.To.Format(dateFormat)),
+		fmt.Sprintf("Opening balance:  %s", header.OpeningBalance.StringFixed(2)),
+		"",
+		fmt.Sprintf(pdfRowFormat, "ID", "Date", "Type", "Sender", "Receiver", "Amount", "Balance"),
+	}
+
+	for _, line := range lines {
+		if err := p.w.Line(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *pdfWriter) Row(row *Row) error {
+	return p.w.Line(fmt.Sprintf(pdfRowFormat, strconv.FormatInt(row.ID, 10), row.CreatedAt.Format(time.DateTime),
+		row.Type, truncate(row.Sender, 16), truncate(row.Receiver, 16), row.Amount.StringFixed(2), row.Balance.StringFixed(2)))
+}
+
+func (p *pdfWriter) End(footer *Footer) error {
+	lines := []string{
+		"",
+		fmt.Sprintf("Transactions:     %d", footer.Count),
+		fmt.Sprintf("Closing balance:  %s", footer.ClosingBalance.StringFixed(2)),
+	}
+
+	for _, line := range lines {
+		if err := p.w.Line(line); err != nil {
+			return err
+		}
+	}
+
+	return p.w.Close()
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n-1]) + "~"
+}
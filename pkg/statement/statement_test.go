@@ -0,0 +1,94 @@
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func writeStatement(t *testing.T, format string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+
+	w, err := NewWriter(format, buf)
+	require.NoError(t, err)
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, w.Begin(&Header{UID: 1, From: from, To: from.AddDate(0, 1, -1), OpeningBalance: decimal.NewFromInt(10)}))
+	require.NoError(t, w.Row(&Row{
+		ID: 7, CreatedAt: from.Add(time.Hour), Type: "transfer", Sender: "alice", Receiver: "bob",
+		Amount: decimal.NewFromInt(-3), Balance: decimal.NewFromInt(7),
+	}))
+	require.NoError(t, w.End(&Footer{ClosingBalance: decimal.NewFromInt(7), Count: 1}))
+
+	return buf
+}
+
+func TestNewWriter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	_, err := NewWriter("xml", &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrUnknownFormat)
+
+	assert.Equal(t, "application/pdf", ContentType(FormatPDF))
+	assert.Empty(t, ContentType("xml"))
+}
+
+func TestCSVWriter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	records, err := csv.NewReader(writeStatement(t, FormatCSV)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	assert.Equal(t, "opening_balance", records[1][2])
+	assert.Equal(t, "10.00", records[1][6])
+	assert.Equal(t, []string{"7", "2024-05-01T01:00:00Z", "transfer", "alice", "bob", "-3.00", "7.00"}, records[2])
+	assert.Equal(t, "closing_balance", records[3][2])
+	assert.Equal(t, "7.00", records[3][6])
+}
+
+func TestJSONLWriter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var records []map[string]any
+
+	scanner := bufio.NewScanner(writeStatement(t, FormatJSONL))
+	for scanner.Scan() {
+		record := map[string]any{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+
+	require.Len(t, records, 3)
+	assert.Equal(t, "opening", records[0]["record"])
+	assert.Equal(t, "transaction", records[1]["record"])
+	assert.Equal(t, "-3", records[1]["amount"])
+	assert.Equal(t, "closing", records[2]["record"])
+	assert.InDelta(t, 1, records[2]["count"], 0)
+}
+
+func TestPDFWriter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	out := writeStatement(t, FormatPDF).String()
+
+	assert.Contains(t, out, "%PDF-1.4")
+	assert.Contains(t, out, "Opening balance:  10.00")
+	assert.Contains(t, out, "Closing balance:  7.00")
+	assert.Contains(t, out, "%%EOF")
+}
+
+func TestTruncate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	assert.Equal(t, "short", truncate("short", 16))
+	assert.Equal(t, "abc~", truncate("abcdef", 4))
+}
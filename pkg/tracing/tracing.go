@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	ExporterNone   = ""
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+const instrumentationName = "server"
+
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+type Config struct {
+	ServiceName    string
+	ServiceVersion string
+	Exporter       string  // otlp, stdout, or empty to disable exporting
+	Endpoint       string  // host:port of the OTLP/HTTP collector, defaults to localhost:4318
+	Insecure       bool    // use plain HTTP for OTLP
+	SampleRatio    float64 // fraction of new traces to sample, 0 samples every trace
+}
+
+// Init installs the global tracer provider and the W3C trace context propagator.
+// The returned function flushes and stops the exporter.
+func Init(ctx context.Context, conf Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, err := newExporter(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(conf.ServiceName),
+		semconv.ServiceVersion(conf.ServiceVersion),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if conf.SampleRatio > 0 && conf.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(conf.SampleRatio)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, conf Config) (sdktrace.SpanExporter, error) {
+	switch conf.Exporter {
+	case ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		opts := make([]otlptracehttp.Option, 0, 2)
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownExporter, conf.Exporter)
+	}
+}
+
+// Start starts a span as a child of the span in ctx.
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name)
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// StartGin starts a span as a child of the request's span and makes it the request's current span,
+// so that everything called with ctx, including SQL statements, is nested under it.
+// The returned function ends the span with the given error and restores the request's span.
+func StartGin(ctx *gin.Context, name string) func(err error) {
+	if ctx.Request == nil {
+		return func(error) {}
+	}
+
+	parent := ctx.Request
+	spanCtx, span := Start(parent.Context(), name)
+	ctx.Request = parent.WithContext(spanCtx)
+
+	return func(err error) {
+		End(span, err)
+		ctx.Request = parent
+	}
+}
+
+// WithTrace returns logger with the trace and span IDs of ctx attached, or logger itself
+// when ctx carries no span.
+func WithTrace(ctx context.Context, logger *zap.SugaredLogger) *zap.SugaredLogger {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return logger
+	}
+
+	return logger.With("trace_id", spanCtx.TraceID().String(), "span_id", spanCtx.SpanID().String())
+}
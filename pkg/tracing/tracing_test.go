@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestInit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx := context.Background()
+
+	t.Run("Disabled", func(t *testing.T) {
+		shutdown, err := Init(ctx, Config{})
+		require.NoError(t, err)
+		require.NoError(t, shutdown(ctx))
+	})
+
+	t.Run("Unknown exporter", func(t *testing.T) {
+		_, err := Init(ctx, Config{Exporter: "zipkin"})
+		require.ErrorIs(t, err, ErrUnknownExporter)
+	})
+}
+
+func TestStartGin(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	defer provider.Shutdown(context.Background())
+
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Nests under the request span", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+		rootCtx, root := Start(context.Background(), "request")
+		req, err := http.NewRequestWithContext(rootCtx, "GET", "/", http.NoBody)
+		require.NoError(t, err)
+		ctx.Request = req
+
+		end := StartGin(ctx, "WalletServ.Transfer")
+		child := trace.SpanContextFromContext(ctx.Request.Context())
+		assert.NotEqual(t, root.SpanContext().SpanID(), child.SpanID())
+		assert.Equal(t, root.SpanContext().TraceID(), child.TraceID())
+
+		end(errors.New("insufficient balance"))
+		root.End()
+
+		assert.Same(t, req, ctx.Request)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, "WalletServ.Transfer", spans[0].Name())
+		assert.Equal(t, root.SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+
+	t.Run("Without request", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+		end := StartGin(ctx, "WalletServ.Balance")
+		end(nil)
+
+		assert.Nil(t, ctx.Request)
+	})
+}
+
+func TestWithTrace(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core).Sugar()
+
+	WithTrace(context.Background(), logger).Info("no span")
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	WithTrace(ctx, logger).Info("with span")
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Empty(t, entries[0].ContextMap())
+	assert.Equal(t, map[string]any{
+		"trace_id": "0102030405060708090a0b0c0d0e0f10",
+		"span_id":  "0102030405060708",
+	}, entries[1].ContextMap())
+}
@@ -7,14 +7,45 @@ import (
 	"go.uber.org/zap"
 
 	"server/app/controller"
+	"server/app/middleware"
+	"server/app/model"
 	"server/app/repository"
 	"server/app/request"
 	"server/app/service"
+	"server/config"
+	"server/pkg/cache"
+	"server/pkg/health"
+	"server/pkg/lock"
+	"server/pkg/metrics"
+	"server/pkg/ratelimit"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func Router(router *gin.Engine, db *sql.DB, logger *zap.SugaredLogger) {
+// Health registers the liveness and readiness probes. It must be called before Router so the
+// probes skip the request middleware and don't flood the access log, traces and metrics.
+func Health(router *gin.Engine, probe *health.Probe) {
+	healthCtrl := controller.NewHealth(probe)
+
+	router.GET("/healthz", healthCtrl.Healthz)
+	router.GET("/readyz", healthCtrl.Readyz)
+}
+
+// Router registers the api. Rate limits are counted in rdb, or in memory when rdb is nil or failing.
+// Users and balances are cached in rdb when the cache is enabled. Wallets are locked with the
+// backend of lock.backend; without rdb the redis backend is unavailable and wallets are not locked.
+func Router(router *gin.Engine, db *sql.DB, rdb redis.UniversalClient, logger *zap.SugaredLogger) {
+	router.ContextWithFallback = true
+	router.Use(otelgin.Middleware(config.Config.AppName, otelgin.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/metrics"
+	})))
+	router.Use(middleware.RequestID(), middleware.Logger(logger), middleware.AuditActor())
+	router.Use(middleware.Metrics())
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	router.GET("", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, request.ResponseEntity{
 			ErrCode: 0,
@@ -23,25 +54,106 @@ func Router(router *gin.Engine, db *sql.DB, logger *zap.SugaredLogger) {
 		})
 	})
 
+	var limiter ratelimit.Limiter = ratelimit.NewMemory()
+	if rdb != nil {
+		limiter = ratelimit.NewFallback(ratelimit.NewRedis(rdb), limiter, logger)
+	}
+	rateLimit := middleware.RateLimit(limiter, logger)
+
 	userRepo := repository.NewUser(db, logger)
 	walletRepo := repository.NewWallet(db, logger)
+	closureRepo := repository.NewClosure(db, logger)
+	batchRepo := repository.NewBatch(db, logger)
+	if cacheConf := config.Config.Cache; cacheConf.Enabled && rdb != nil {
+		userCache := cache.New(rdb, "user", cacheConf.UserTTL)
+		balanceCache := cache.New(rdb, "balance", cacheConf.BalanceTTL)
+		userRepo = repository.NewUserCache(userRepo, userCache, logger)
+		walletRepo = repository.NewWalletCache(walletRepo, balanceCache, logger)
+		closureRepo = repository.NewClosureCache(closureRepo, userCache, balanceCache, logger)
+		batchRepo = repository.NewBatchCache(batchRepo, balanceCache, logger)
+	}
 	transactionRepo := repository.NewTransaction(db, logger)
+	reconcileRepo := repository.NewReconcile(db, logger)
+	auditRepo := repository.NewAudit(db, logger)
 
-	userServ := service.NewUser(userRepo, walletRepo)
+	userServ := service.NewUser(userRepo, walletRepo, auditRepo, logger)
 	userCtrl := controller.NewUser(userServ)
 
-	userRout := router.Group("/api/users")
+	locker := newLocker(db, rdb)
+	closureCtrl := controller.NewClosure(service.NewClosure(closureRepo, walletRepo, locker, auditRepo, logger))
+
+	userRout := router.Group("/api/users", rateLimit)
 	userRout.POST("", userCtrl.RegisterUser)
 	userRout.GET("/:uid", userCtrl.GetUserByUID)
+	userRout.POST("/:uid/close", closureCtrl.Close)
 
 	transactionServ := service.NewTransaction(transactionRepo)
-	walletServ := service.NewWallet(walletRepo)
-	walletCtrl := controller.NewWallet(walletServ, transactionServ)
+	walletServ := service.NewWallet(walletRepo, locker, auditRepo, logger)
+	recipientServ := service.NewRecipient(userRepo, walletRepo, logger)
+	walletCtrl := controller.NewWallet(walletServ, transactionServ, recipientServ)
+	recipientCtrl := controller.NewRecipient(recipientServ)
+	batchCtrl := controller.NewBatch(service.NewBatch(batchRepo, walletRepo, locker, auditRepo, logger))
+	paymentRequestCtrl := controller.NewPaymentRequest(service.NewPaymentRequest(
+		repository.NewPaymentRequest(db, logger), walletServ, walletRepo, logger))
 
-	walletRout := router.Group("/api/wallets")
+	walletRout := router.Group("/api/wallets", rateLimit)
 	walletRout.POST("/:uid/deposit", walletCtrl.Deposit)
 	walletRout.POST("/:uid/withdraw", walletCtrl.Withdraw)
 	walletRout.POST("/:uid/transfer", walletCtrl.Transfer)
+	walletRout.GET("/:uid/recipient", recipientCtrl.Preview)
+	walletRout.PUT("/:uid/handle", recipientCtrl.SetHandle)
+	walletRout.POST("/:uid/batch-transfers", batchCtrl.Create)
+	walletRout.GET("/:uid/batch-transfers/:id", batchCtrl.Get)
+	walletRout.POST("/:uid/payment-requests", paymentRequestCtrl.Create)
+	walletRout.GET("/:uid/payment-requests", paymentRequestCtrl.List)
+	walletRout.POST("/:uid/payment-requests/:id/accept", paymentRequestCtrl.Accept)
+	walletRout.POST("/:uid/payment-requests/:id/decline", paymentRequestCtrl.Decline)
+	walletRout.POST("/:uid/payment-requests/:id/cancel", paymentRequestCtrl.Cancel)
+	walletRout.GET("/:uid/fees", walletCtrl.Quote)
 	walletRout.GET("/:uid/balance", walletCtrl.Balance)
+	walletRout.GET("/:uid/balance/history", walletCtrl.BalanceHistory)
 	walletRout.GET("/:uid/transactions", walletCtrl.Transactions)
+	walletRout.GET("/:uid/statement", walletCtrl.Statement)
+
+	paymentRout := router.Group("/api/payment-requests", rateLimit)
+	paymentRout.GET("/:token", paymentRequestCtrl.GetByToken)
+
+	reconcileServ := service.NewReconcile(reconcileRepo, logger)
+	reconcileCtrl := controller.NewReconcile(reconcileServ)
+
+	adminServ := service.NewAdmin(repository.NewAdmin(db, logger), walletRepo, auditRepo, locker, logger)
+	adminCtrl := controller.NewAdmin(adminServ)
+	auditCtrl := controller.NewAudit(service.NewAudit(auditRepo))
+
+	staff := middleware.RequireRole(model.RoleSupport, model.RoleFinance, model.RoleSuperadmin)
+	finance := middleware.RequireRole(model.RoleFinance, model.RoleSuperadmin)
+	superadmin := middleware.RequireRole(model.RoleSuperadmin)
+
+	adminRout := router.Group("/api/admin", middleware.AdminAuth(config.Config.Admin.Enabled, adminServ.Authenticate),
+		middleware.AdminAudit(repository.NewAuditLogger(logger), logger))
+	adminRout.GET("/users", staff, adminCtrl.SearchUsers)
+	adminRout.PUT("/users/:uid/role", superadmin, adminCtrl.SetRole)
+	adminRout.GET("/wallets/:uid", staff, adminCtrl.Wallet)
+	adminRout.GET("/wallets/:uid/transactions", staff, walletCtrl.Transactions)
+	adminRout.POST("/wallets/:uid/freeze", staff, adminCtrl.Freeze)
+	adminRout.POST("/wallets/:uid/debit-block", staff, adminCtrl.DebitBlock)
+	adminRout.POST("/wallets/:uid/unfreeze", superadmin, adminCtrl.Unfreeze)
+	adminRout.POST("/wallets/:uid/adjustments", finance, adminCtrl.Adjust)
+	adminRout.GET("/discrepancies", finance, reconcileCtrl.Discrepancies)
+	adminRout.GET("/audit", superadmin, auditCtrl.List)
+	adminRout.GET("/audit/verify", superadmin, auditCtrl.Verify)
+}
+
+func newLocker(db *sql.DB, rdb redis.UniversalClient) lock.Locker {
+	conf := config.Config.Lock
+	opts := lock.Options{Wait: conf.Wait, TTL: conf.TTL}
+
+	switch {
+	case conf.Backend == config.LockRedis && rdb != nil:
+		return lock.NewRedis(rdb, repository.NewFence(db), opts)
+	case conf.Backend == config.LockPostgres:
+		return lock.NewPostgres(db, repository.NewFence(db), opts)
+	default:
+		return lock.Nop()
+	}
 }
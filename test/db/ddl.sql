@@ -9,6 +9,10 @@ CREATE TABLE "public"."t_transaction"
     "receiver_wallet_id" integer        DEFAULT '0',
     "amount"             numeric(15, 2) DEFAULT '0.00'                        NOT NULL,
     "transaction_type"   smallint       DEFAULT '0'                           NOT NULL,
+    "parent_id"          integer        DEFAULT '0'                           NOT NULL,
+    "memo"               character varying(140) DEFAULT ''                    NOT NULL,
+    "external_reference" character varying(64)  DEFAULT ''                    NOT NULL,
+    "metadata"           jsonb,
     "created_at"         timestamp      DEFAULT CURRENT_TIMESTAMP             NOT NULL,
     CONSTRAINT "transaction_pkey" PRIMARY KEY ("id")
 ) WITH (oids = false);
@@ -17,8 +21,20 @@ CREATE INDEX "transaction_receiver_wallet_id" ON "public"."t_transaction" USING
 
 CREATE INDEX "transaction_sender_wallet_id" ON "public"."t_transaction" USING btree ("sender_wallet_id");
 
+CREATE INDEX "transaction_created_at_id" ON "public"."t_transaction" USING btree ("created_at", "id");
+
+CREATE INDEX "transaction_parent_id" ON "public"."t_transaction" USING btree ("parent_id") WHERE "parent_id" > 0;
+
+CREATE INDEX "transaction_external_reference" ON "public"."t_transaction" USING btree ("external_reference") WHERE "external_reference" <> '';
+
+COMMENT
+ON COLUMN "public"."t_transaction"."transaction_type" IS '1-deposit, 2-withdraw, 3-transfer, 4-adjustment, 5-fee';
+
+COMMENT
+ON COLUMN "public"."t_transaction"."parent_id" IS 'the transaction a fee was charged on, 0 for none';
+
 COMMENT
-ON COLUMN "public"."t_transaction"."transaction_type" IS '1-deposit, 2-withdraw, 3-transfer';
+ON COLUMN "public"."t_transaction"."external_reference" IS 'reference of the movement in the client''s own system, searchable';
 
 
 DROP TABLE IF EXISTS "t_user";
@@ -32,6 +48,8 @@ CREATE TABLE "public"."t_user"
     "email"         character varying(255)                   NOT NULL,
     "password_hash" character varying(255)                   NOT NULL,
     "status"        smallint  DEFAULT '1'                    NOT NULL,
+    "role"          smallint  DEFAULT '0'                    NOT NULL,
+    "tier"          character varying(32) DEFAULT ''         NOT NULL,
     "created_at"    timestamp DEFAULT CURRENT_TIMESTAMP      NOT NULL,
     "updated_at"    timestamp DEFAULT CURRENT_TIMESTAMP      NOT NULL,
     CONSTRAINT "user_email" UNIQUE ("email"),
@@ -42,6 +60,12 @@ CREATE TABLE "public"."t_user"
 COMMENT
 ON COLUMN "public"."t_user"."status" IS '1-Valid, 2-Invalid, 3-Disabled';
 
+COMMENT
+ON COLUMN "public"."t_user"."role" IS '0-none, 1-support, 2-finance, 3-superadmin';
+
+COMMENT
+ON COLUMN "public"."t_user"."tier" IS 'pricing tier that fee rules match, empty for the default';
+
 
 DROP TABLE IF EXISTS "t_wallet";
 DROP SEQUENCE IF EXISTS wallet_id_seq;
@@ -49,12 +73,245 @@ CREATE SEQUENCE wallet_id_seq INCREMENT 1 MINVALUE 1 MAXVALUE 2147483647 START 1
 
 CREATE TABLE "public"."t_wallet"
 (
-    "id"         integer        DEFAULT nextval('wallet_id_seq') NOT NULL,
-    "uid"        integer        DEFAULT '0'                      NOT NULL,
-    "balance"    numeric(15, 2) DEFAULT '0.00'                   NOT NULL,
-    "created_at" timestamp      DEFAULT CURRENT_TIMESTAMP        NOT NULL,
-    "updated_at" timestamp      DEFAULT CURRENT_TIMESTAMP        NOT NULL,
-    CONSTRAINT "wallet_pkey" PRIMARY KEY ("id")
+    "id"          integer        DEFAULT nextval('wallet_id_seq') NOT NULL,
+    "uid"         integer        DEFAULT '0'                      NOT NULL,
+    "balance"     numeric(15, 2) DEFAULT '0.00'                   NOT NULL,
+    "status"      smallint       DEFAULT '1'                      NOT NULL,
+    "fence_token" bigint         DEFAULT '0'                      NOT NULL,
+    "handle"      character varying(32),
+    "created_at"  timestamp      DEFAULT CURRENT_TIMESTAMP        NOT NULL,
+    "updated_at"  timestamp      DEFAULT CURRENT_TIMESTAMP        NOT NULL,
+    CONSTRAINT "wallet_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "wallet_handle" UNIQUE ("handle")
 ) WITH (oids = false);
 
 CREATE INDEX "wallet_uid" ON "public"."t_wallet" USING btree ("uid");
+
+COMMENT
+ON COLUMN "public"."t_wallet"."status" IS '1-active, 2-frozen, 3-debit-blocked, 4-closed';
+
+COMMENT
+ON COLUMN "public"."t_wallet"."fence_token" IS 'fencing token of the last locked write';
+
+COMMENT
+ON COLUMN "public"."t_wallet"."handle" IS 'public handle transfers can be addressed to, NULL for none';
+
+DROP SEQUENCE IF EXISTS wallet_fence_seq;
+CREATE SEQUENCE wallet_fence_seq INCREMENT 1 MINVALUE 1 START 1 CACHE 1;
+
+
+DROP TABLE IF EXISTS "t_balance_snapshot";
+DROP SEQUENCE IF EXISTS balance_snapshot_id_seq;
+CREATE SEQUENCE balance_snapshot_id_seq INCREMENT 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1;
+
+CREATE TABLE "public"."t_balance_snapshot"
+(
+    "id"         integer        DEFAULT nextval('balance_snapshot_id_seq') NOT NULL,
+    "uid"        integer        DEFAULT '0'                                NOT NULL,
+    "day"        date                                                      NOT NULL,
+    "balance"    numeric(15, 2) DEFAULT '0.00'                             NOT NULL,
+    "created_at" timestamp      DEFAULT CURRENT_TIMESTAMP                  NOT NULL,
+    CONSTRAINT "balance_snapshot_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "balance_snapshot_uid_day" UNIQUE ("uid", "day")
+) WITH (oids = false);
+
+COMMENT
+ON COLUMN "public"."t_balance_snapshot"."balance" IS 'closing balance of the day';
+
+
+DROP TABLE IF EXISTS "t_reconcile_discrepancy";
+DROP SEQUENCE IF EXISTS reconcile_discrepancy_id_seq;
+CREATE SEQUENCE reconcile_discrepancy_id_seq INCREMENT 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1;
+
+CREATE TABLE "public"."t_reconcile_discrepancy"
+(
+    "id"             integer        DEFAULT nextval('reconcile_discrepancy_id_seq') NOT NULL,
+    "uid"            integer        DEFAULT '0'                                     NOT NULL,
+    "wallet_balance" numeric(15, 2) DEFAULT '0.00'                                  NOT NULL,
+    "ledger_balance" numeric(15, 2) DEFAULT '0.00'                                  NOT NULL,
+    "difference"     numeric(15, 2) DEFAULT '0.00'                                  NOT NULL,
+    "status"         smallint       DEFAULT '1'                                     NOT NULL,
+    "detected_at"    timestamp      DEFAULT CURRENT_TIMESTAMP                       NOT NULL,
+    "checked_at"     timestamp      DEFAULT CURRENT_TIMESTAMP                       NOT NULL,
+    "resolved_at"    timestamp,
+    CONSTRAINT "reconcile_discrepancy_pkey" PRIMARY KEY ("id")
+) WITH (oids = false);
+
+CREATE UNIQUE INDEX "reconcile_discrepancy_open_uid" ON "public"."t_reconcile_discrepancy" USING btree ("uid") WHERE "status" = 1;
+
+COMMENT
+ON COLUMN "public"."t_reconcile_discrepancy"."status" IS '1-open, 2-resolved';
+
+
+DROP TABLE IF EXISTS "t_audit_log";
+DROP SEQUENCE IF EXISTS audit_log_id_seq;
+CREATE SEQUENCE audit_log_id_seq INCREMENT 1 MINVALUE 1 START 1 CACHE 1;
+
+CREATE TABLE "public"."t_audit_log"
+(
+    "id"         bigint                 DEFAULT nextval('audit_log_id_seq') NOT NULL,
+    "actor_id"   integer                DEFAULT '0'                         NOT NULL,
+    "actor_role" smallint               DEFAULT '0'                         NOT NULL,
+    "ip"         character varying(64)  DEFAULT ''                          NOT NULL,
+    "request_id" character varying(64)  DEFAULT ''                          NOT NULL,
+    "action"     character varying(64)                                      NOT NULL,
+    "entity"     character varying(32)                                      NOT NULL,
+    "entity_id"  integer                DEFAULT '0'                         NOT NULL,
+    "before"     json,
+    "after"      json,
+    "reason"     character varying(500) DEFAULT ''                          NOT NULL,
+    "ticket"     character varying(64)  DEFAULT ''                          NOT NULL,
+    "error"      text                   DEFAULT ''                          NOT NULL,
+    "created_at" timestamp                                                  NOT NULL,
+    "prev_hash"  character varying(64)  DEFAULT ''                          NOT NULL,
+    "hash"       character varying(64)                                      NOT NULL,
+    CONSTRAINT "audit_log_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "audit_log_hash" UNIQUE ("hash")
+) WITH (oids = false);
+
+CREATE INDEX "audit_log_entity_id" ON "public"."t_audit_log" USING btree ("entity", "entity_id", "id");
+
+COMMENT
+ON COLUMN "public"."t_audit_log"."before" IS 'json kept as written, since the hash covers its exact text';
+
+COMMENT
+ON COLUMN "public"."t_audit_log"."hash" IS 'sha256 of prev_hash and the row, chaining every row to the one before';
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 't_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_log_no_update" BEFORE UPDATE OR DELETE ON "public"."t_audit_log"
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER "audit_log_no_truncate" BEFORE TRUNCATE ON "public"."t_audit_log"
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+
+DROP TABLE IF EXISTS "t_quote";
+
+CREATE TABLE "public"."t_quote"
+(
+    "id"          character varying(36)                                   NOT NULL,
+    "operation"   character varying(16)                                   NOT NULL,
+    "uid"         integer        DEFAULT '0'                              NOT NULL,
+    "to_uid"      integer        DEFAULT '0'                              NOT NULL,
+    "amount"      numeric(15, 2) DEFAULT '0.00'                           NOT NULL,
+    "fee"         numeric(15, 2) DEFAULT '0.00'                           NOT NULL,
+    "revenue_uid" integer        DEFAULT '0'                              NOT NULL,
+    "expires_at"  timestamp                                               NOT NULL,
+    "created_at"  timestamp      DEFAULT CURRENT_TIMESTAMP                NOT NULL,
+    CONSTRAINT "quote_pkey" PRIMARY KEY ("id")
+) WITH (oids = false);
+
+CREATE INDEX "quote_expires_at" ON "public"."t_quote" USING btree ("expires_at");
+
+COMMENT
+ON COLUMN "public"."t_quote"."fee" IS 'fee locked in by a dry run, charged instead of the configured one';
+
+
+DROP TABLE IF EXISTS "t_batch";
+DROP SEQUENCE IF EXISTS batch_id_seq;
+CREATE SEQUENCE batch_id_seq INCREMENT 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1;
+
+CREATE TABLE "public"."t_batch"
+(
+    "id"         integer        DEFAULT nextval('batch_id_seq') NOT NULL,
+    "uid"        integer        DEFAULT '0'                     NOT NULL,
+    "mode"       smallint       DEFAULT '1'                     NOT NULL,
+    "status"     smallint       DEFAULT '1'                     NOT NULL,
+    "item_count" integer        DEFAULT '0'                     NOT NULL,
+    "total"      numeric(15, 2) DEFAULT '0.00'                  NOT NULL,
+    "succeeded"  integer        DEFAULT '0'                     NOT NULL,
+    "failed"     integer        DEFAULT '0'                     NOT NULL,
+    "error"      text           DEFAULT ''                      NOT NULL,
+    "created_at" timestamp      DEFAULT CURRENT_TIMESTAMP       NOT NULL,
+    "updated_at" timestamp      DEFAULT CURRENT_TIMESTAMP       NOT NULL,
+    CONSTRAINT "batch_pkey" PRIMARY KEY ("id")
+) WITH (oids = false);
+
+CREATE INDEX "batch_uid" ON "public"."t_batch" USING btree ("uid");
+
+COMMENT
+ON COLUMN "public"."t_batch"."mode" IS '1-atomic, 2-best-effort';
+
+COMMENT
+ON COLUMN "public"."t_batch"."status" IS '1-pending, 2-running, 3-completed, 4-failed, 5-partially completed';
+
+
+DROP TABLE IF EXISTS "t_batch_item";
+DROP SEQUENCE IF EXISTS batch_item_id_seq;
+CREATE SEQUENCE batch_item_id_seq INCREMENT 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1;
+
+CREATE TABLE "public"."t_batch_item"
+(
+    "id"         integer               DEFAULT nextval('batch_item_id_seq') NOT NULL,
+    "batch_id"   integer               DEFAULT '0'                          NOT NULL,
+    "uid"        integer               DEFAULT '0'                          NOT NULL,
+    "seq"        integer               DEFAULT '0'                          NOT NULL,
+    "to_uid"     integer               DEFAULT '0'                          NOT NULL,
+    "amount"     numeric(15, 2)        DEFAULT '0.00'                       NOT NULL,
+    "reference"  character varying(64) DEFAULT ''                           NOT NULL,
+    "status"     smallint              DEFAULT '1'                          NOT NULL,
+    "error"      text                  DEFAULT ''                           NOT NULL,
+    "updated_at" timestamp             DEFAULT CURRENT_TIMESTAMP            NOT NULL,
+    CONSTRAINT "batch_item_pkey" PRIMARY KEY ("id")
+) WITH (oids = false);
+
+CREATE INDEX "batch_item_batch_id" ON "public"."t_batch_item" USING btree ("batch_id", "seq");
+
+CREATE UNIQUE INDEX "batch_item_paid_reference" ON "public"."t_batch_item" USING btree ("uid", "reference") WHERE "status" = 2 AND "reference" <> '';
+
+COMMENT
+ON COLUMN "public"."t_batch_item"."uid" IS 'the funding wallet, copied from the batch to keep references unique per wallet';
+
+COMMENT
+ON COLUMN "public"."t_batch_item"."status" IS '1-pending, 2-succeeded, 3-failed, 4-duplicate';
+
+
+DROP TABLE IF EXISTS "t_payment_request";
+DROP SEQUENCE IF EXISTS payment_request_id_seq;
+CREATE SEQUENCE payment_request_id_seq INCREMENT 1 MINVALUE 1 MAXVALUE 2147483647 START 1 CACHE 1;
+
+CREATE TABLE "public"."t_payment_request"
+(
+    "id"            integer                DEFAULT nextval('payment_request_id_seq') NOT NULL,
+    "requester_uid" integer                DEFAULT '0'                               NOT NULL,
+    "payer_uid"     integer                DEFAULT '0'                               NOT NULL,
+    "amount"        numeric(15, 2)         DEFAULT '0.00'                            NOT NULL,
+    "memo"          character varying(140) DEFAULT ''                                NOT NULL,
+    "status"        smallint               DEFAULT '1'                               NOT NULL,
+    "token_hash"    character(64)                                                    NOT NULL,
+    "expires_at"    timestamp                                                        NOT NULL,
+    "created_at"    timestamp              DEFAULT CURRENT_TIMESTAMP                 NOT NULL,
+    "updated_at"    timestamp              DEFAULT CURRENT_TIMESTAMP                 NOT NULL,
+    CONSTRAINT "payment_request_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "payment_request_token_hash" UNIQUE ("token_hash")
+) WITH (oids = false);
+
+CREATE INDEX "payment_request_requester_uid" ON "public"."t_payment_request" USING btree ("requester_uid", "id");
+
+CREATE INDEX "payment_request_payer_uid" ON "public"."t_payment_request" USING btree ("payer_uid", "id");
+
+COMMENT
+ON COLUMN "public"."t_payment_request"."payer_uid" IS '0 for an open request that anyone with its token can pay, until it is paid';
+
+COMMENT
+ON COLUMN "public"."t_payment_request"."status" IS '1-pending, 2-paid, 3-declined, 4-cancelled; a pending request past expires_at reads as 5-expired';
+
+COMMENT
+ON COLUMN "public"."t_payment_request"."token_hash" IS 'sha256 of the shareable token, which is never stored';
+
+
+DROP TABLE IF EXISTS "t_schema_version";
+
+CREATE TABLE "public"."t_schema_version"
+(
+    "version"    integer                             NOT NULL,
+    "applied_at" timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT "schema_version_pkey" PRIMARY KEY ("version")
+) WITH (oids = false);
+
+INSERT INTO "public"."t_schema_version" ("version") VALUES (10);
@@ -26,7 +26,7 @@ func NewMockTest() *MockTest {
 func getExpect(t *testing.T, sqlDB *sql.DB, logger *zap.SugaredLogger) *httpexpect.Expect {
 	gin.SetMode(gin.TestMode)
 	engine := gin.New()
-	router.Router(engine, sqlDB, logger)
+	router.Router(engine, sqlDB, nil, logger)
 	server := httptest.NewServer(engine)
 	return httpexpect.New(t, server.URL)
 }